@@ -0,0 +1,49 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command xmlrpc-gen writes the XMLRPCEncode and XMLRPCDecode methods of the
+// structs marked //xmlrpc:generate in Go files, so they're encoded and
+// decoded without reflection:
+//
+//    xmlrpc-gen types.go
+//
+// writes the methods of the structs of types.go to types_xmlrpc.go. See
+// xml.GenerateCodecs for the types supported. It's meant for go:generate:
+//
+//    //go:generate xmlrpc-gen $GOFILE
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/AlexStocks/gorilla-xmlrpc/xml"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: xmlrpc-gen file.go ...")
+		os.Exit(2)
+	}
+	for _, name := range os.Args[1:] {
+		if err := generate(name); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
+
+func generate(name string) error {
+	src, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	out, err := xml.GenerateCodecs(name, src)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(strings.TrimSuffix(name, ".go")+"_xmlrpc.go", out, 0644)
+}
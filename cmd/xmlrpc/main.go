@@ -0,0 +1,24 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command xmlrpc calls a XML-RPC method and prints the result:
+//
+//    xmlrpc http://localhost:9001/RPC2 supervisor.getProcessInfo string:nginx
+//
+// See xml.ParseArg for the arguments syntax.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AlexStocks/gorilla-xmlrpc/xml"
+)
+
+func main() {
+	if err := xml.RunCommand(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
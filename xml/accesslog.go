@@ -0,0 +1,129 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AccessLogFormat is the format of the lines of AccessLog.
+type AccessLogFormat int
+
+// Access log formats.
+const (
+	// CommonLogFormat is the Common Log Format of the web servers.
+	CommonLogFormat AccessLogFormat = iota
+
+	// CombinedLogFormat is the Common Log Format followed by the referer
+	// and the user agent.
+	CombinedLogFormat
+)
+
+// faultSniffSize is the size of the responses searched for a fault code.
+const faultSniffSize = 64 << 10
+
+// AccessLog wraps the XML-RPC handler next, logging every request to
+// logger in format, followed by the XML-RPC method called, the fault code
+// of the response, or "-", and the duration in seconds, e.g.
+//
+//	127.0.0.1 - - [10/Oct/2013:13:55:36 +0200] "POST /RPC2 HTTP/1.1" 200 312 "Arith.Multiply" - 0.002
+//
+// so the logs tell the calls apart, not just POST /RPC2. Use WriterLogger
+// to log to an io.Writer, e.g. a file.
+func AccessLog(next http.Handler, logger Logger, format AccessLogFormat) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		method, _, _ := readMethod(r, defaultOptions)
+		aw := &accessLogWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(aw, r)
+		elapsed := time.Since(start)
+
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		user := "-"
+		if name, _, ok := r.BasicAuth(); ok && name != "" {
+			user = name
+		}
+		line := fmt.Sprintf("%s - %s [%s] %s %d %d", host, user, start.Format("02/Jan/2006:15:04:05 -0700"),
+			strconv.Quote(r.Method+" "+r.RequestURI+" "+r.Proto), aw.status, aw.size)
+		if format == CombinedLogFormat {
+			line += " " + strconv.Quote(r.Referer()) + " " + strconv.Quote(r.UserAgent())
+		}
+		called := "-"
+		if method != "" {
+			called = strconv.Quote(method)
+		}
+		logger.Printf("%s %s %s %.3f", line, called, aw.faultCode(), elapsed.Seconds())
+	})
+}
+
+// accessLogWriter records the status, the size and the head of a response.
+type accessLogWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+	head   []byte // up to faultSniffSize
+}
+
+func (w *accessLogWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogWriter) Write(b []byte) (int, error) {
+	if room := faultSniffSize - len(w.head); room > 0 {
+		if room > len(b) {
+			room = len(b)
+		}
+		w.head = append(w.head, b[:room]...)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// faultCode returns the fault code of the response, or "-" if it's not a
+// fault.
+func (w *accessLogWriter) faultCode() string {
+	if w.size > faultSniffSize || !bytes.Contains(w.head, []byte("<fault>")) {
+		return "-"
+	}
+	_, _, _, err := parseDocument(w.head)
+	if fault, ok := asFault(err); ok {
+		return strconv.Itoa(fault.Code)
+	}
+	return "-"
+}
+
+// writerLogger logs the messages as lines of an io.Writer.
+type writerLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// WriterLogger returns a Logger writing every message as a line to w, e.g.
+// an access log file. The lines are written whole, with no prefix.
+func WriterLogger(w io.Writer) Logger {
+	return &writerLogger{w: w}
+}
+
+func (l *writerLogger) Printf(format string, v ...interface{}) {
+	line := fmt.Sprintf(format, v...)
+	if len(line) == 0 || line[len(line)-1] != '\n' {
+		line += "\n"
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.w, line)
+}
@@ -0,0 +1,50 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	handler := MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		if method == "fail" {
+			return nil, FaultInvalidParams
+		}
+		return params, nil
+	})
+	ts := httptest.NewServer(AccessLog(handler, WriterLogger(&out), CombinedLogFormat))
+	defer ts.Close()
+	client := NewClient(ts.URL + "/RPC2")
+
+	client.CallValues("Arith.Multiply", NewInt(2))
+	client.CallValues("fail")
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", lines)
+	}
+	common := `^127\.0\.0\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [-+]\d{4}\] "POST /RPC2 HTTP/1\.1" 200 \d+ "" "Go-http-client/1\.1" `
+	if !regexp.MustCompile(common + `"Arith\.Multiply" - \d+\.\d{3}$`).MatchString(lines[0]) {
+		t.Errorf("Unexpected line %q", lines[0])
+	}
+	if !regexp.MustCompile(common + `"fail" -32602 \d+\.\d{3}$`).MatchString(lines[1]) {
+		t.Errorf("Unexpected line %q", lines[1])
+	}
+
+	out.Reset()
+	ts2 := httptest.NewServer(AccessLog(handler, WriterLogger(&out), CommonLogFormat))
+	defer ts2.Close()
+	http.Post(ts2.URL, "text/xml", strings.NewReader("not xml"))
+	if line := out.String(); !strings.Contains(line, `"POST / HTTP/1.1" 200 `) || !strings.Contains(line, ` - - `) ||
+		strings.Contains(line, "Go-http-client") {
+		t.Errorf("Unexpected line %q", line)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"encoding/base64"
+	"reflect"
+)
+
+// Arena holds the memory of the slices decoded into args or replies: the
+// arrays, the variadic params and the base64 binaries. They are carved out
+// of large slabs, one per element type, instead of being allocated one by
+// one, and the slabs are reused once the arena is Reset.
+//
+// The decoded slices are owned by the arena: they must not be used, or
+// retained, after Reset. Strings aren't allocated from the arena, as they
+// already are by the XML decoder, and stay valid.
+//
+// An Arena isn't safe for concurrent use; use one per request.
+type Arena struct {
+	size  int
+	slabs map[reflect.Type]*slab
+}
+
+// slab is the memory of the slices of a type: items up to used are handed
+// out.
+type slab struct {
+	items reflect.Value
+	used  int
+}
+
+// NewArena returns an Arena whose slabs are size bytes long. Slices larger
+// than a slab are allocated on their own.
+func NewArena(size int) *Arena {
+	return &Arena{size: size, slabs: make(map[reflect.Type]*slab)}
+}
+
+// Reset zeroes the slices handed out, so their memory can be reused for the
+// next decoding.
+func (a *Arena) Reset() {
+	for _, s := range a.slabs {
+		if s.items.Type().Elem().Kind() == reflect.Uint8 {
+			b := s.items.Bytes()[:s.used]
+			for i := range b {
+				b[i] = 0
+			}
+			s.used = 0
+			continue
+		}
+		zero := reflect.Zero(s.items.Type().Elem())
+		for i := 0; i < s.used; i++ {
+			s.items.Index(i).Set(zero)
+		}
+		s.used = 0
+	}
+}
+
+// Decode decodes the methodCall or methodResponse document data into the
+// args or reply struct v, allocating its slices from the arena.
+func (a *Arena) Decode(data []byte, v interface{}) error {
+	opts := *defaultOptions
+	opts.arena = a
+	return decodeRPC(string(data), v, &opts)
+}
+
+// makeSlice returns a slice of type typ and length n, from the arena a if
+// not nil.
+func (a *Arena) makeSlice(typ reflect.Type, n int) reflect.Value {
+	if a == nil || n == 0 {
+		return reflect.MakeSlice(typ, n, n)
+	}
+	s := a.slabs[typ]
+	if s == nil || s.used+n > s.items.Len() {
+		elem := int(typ.Elem().Size())
+		if elem == 0 {
+			elem = 1
+		}
+		capacity := a.size / elem
+		if n > capacity {
+			return reflect.MakeSlice(typ, n, n)
+		}
+		s = &slab{items: reflect.MakeSlice(typ, capacity, capacity)}
+		// the previous slab, if any, is left to the slices carved out of it
+		a.slabs[typ] = s
+	}
+	slice := s.items.Slice3(s.used, s.used+n, s.used+n)
+	s.used += n
+	return slice
+}
+
+// decodeBase64 decodes the base64 text into memory of the arena a if not
+// nil.
+func (a *Arena) decodeBase64(text string) ([]byte, error) {
+	if a == nil {
+		return xml2Base64(text)
+	}
+	data := a.makeSlice(reflect.TypeOf([]byte(nil)), base64.StdEncoding.DecodedLen(len(text))).Interface().([]byte)
+	n, err := base64.StdEncoding.Decode(data, []byte(text))
+	return data[:n:n], err
+}
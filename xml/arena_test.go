@@ -0,0 +1,85 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+var intSliceType = reflect.TypeOf([]int(nil))
+
+type ArenaReply struct {
+	Items  []int
+	Names  []string
+	Binary []byte
+}
+
+func TestArenaDecode(t *testing.T) {
+	doc := []byte(`<methodResponse><params>
+		<param><value><array><data><value><int>1</int></value><value><int>2</int></value></data></array></value></param>
+		<param><value><array><data><value><string>a</string></value></data></array></value></param>
+		<param><value><base64>aGVsbG8=</base64></value></param>
+	</params></methodResponse>`)
+
+	arena := NewArena(1024)
+	var reply ArenaReply
+	if err := arena.Decode(doc, &reply); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if len(reply.Items) != 2 || reply.Items[1] != 2 || len(reply.Names) != 1 || reply.Names[0] != "a" || string(reply.Binary) != "hello" {
+		t.Fatalf("Wrong reply: %+v", reply)
+	}
+	if cap(reply.Items) != 2 {
+		t.Error("Expected the items to be capped, so appending doesn't overwrite the arena, got cap", cap(reply.Items))
+	}
+
+	// a second decoding is carved out of the same slabs
+	var again ArenaReply
+	arena.Decode(doc, &again)
+	if len(arena.slabs) != 3 {
+		t.Error("Expected one slab per type, got", len(arena.slabs))
+	}
+	if s := arena.slabs[intSliceType]; s.used != 4 {
+		t.Error("Expected 4 ints handed out, got", s.used)
+	}
+
+	arena.Reset()
+	if reply.Items[0] != 0 || !bytes.Equal(reply.Binary, make([]byte, 5)) {
+		t.Error("Expected the reset to zero the slices handed out")
+	}
+	var third ArenaReply
+	arena.Decode(doc, &third)
+	if &third.Items[0] != &reply.Items[0] {
+		t.Error("Expected the memory to be reused after the reset")
+	}
+}
+
+func TestArenaLargeSlice(t *testing.T) {
+	arena := NewArena(16)
+	slice := arena.makeSlice(intSliceType, 100)
+	if slice.Len() != 100 || len(arena.slabs) != 0 {
+		t.Error("Expected a slice larger than a slab to be allocated on its own")
+	}
+}
+
+func TestCodecArena(t *testing.T) {
+	codec := NewCodec()
+	codec.ArenaSize = 4096
+	ts := newTestServer(codec)
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	for i := 0; i < 3; i++ {
+		var res Service1Response
+		if err := client.Call("Service1.Multiply", &Service1Request{4, 2}, &res); err != nil {
+			t.Fatal("Expected err to be nil, but got:", err)
+		}
+		if res.Result != 8 {
+			t.Errorf("Wrong response: %v.", res.Result)
+		}
+	}
+}
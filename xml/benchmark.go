@@ -0,0 +1,284 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// BenchmarkPayload is a document of the benchmark corpus, with the Go value
+// it's encoded from and decoded into.
+type BenchmarkPayload struct {
+	// Name is e.g. "supervisor" or "big-base64".
+	Name string
+
+	// Document is the encoded methodCall or methodResponse.
+	Document []byte
+
+	// Method is the method of a methodCall document, "" for a
+	// methodResponse.
+	Method string
+
+	value     interface{}
+	newTarget func() interface{}
+}
+
+// Encode encodes the Go value of p into its document.
+func (p BenchmarkPayload) Encode() ([]byte, error) {
+	var (
+		doc string
+		err error
+	)
+	if p.Method != "" {
+		doc, err = encodeRequest(p.Method, p.value, defaultOptions)
+	} else {
+		doc, err = rpcResponse2XMLStr(p.value)
+	}
+	return []byte(doc), err
+}
+
+// Decode decodes the document of p into a new Go value.
+func (p BenchmarkPayload) Decode() error {
+	return decodeRPC(string(p.Document), p.newTarget(), defaultOptions)
+}
+
+// Parse parses the document of p into value trees.
+func (p BenchmarkPayload) Parse() error {
+	_, _, _, err := parseDocument(p.Document)
+	return err
+}
+
+type benchProcess struct {
+	Name, Group, Description string
+	Start, Stop, Now         int
+	State, Exitstatus, Pid   int
+	Statename, Spawnerr      string
+	Logfile, Stdout, Stderr  string
+}
+
+type benchProcesses struct {
+	Processes []benchProcess
+}
+
+type benchPost struct {
+	PostId      string
+	Title       string
+	Description string
+	DateCreated time.Time
+	Categories  []string
+	Link        string
+}
+
+type benchPosts struct {
+	Posts []benchPost
+}
+
+type benchCall struct {
+	MethodName string
+	Params     []Value
+}
+
+type benchMulticall struct {
+	Calls []benchCall
+}
+
+type benchInts struct {
+	Items []int
+}
+
+type benchBinary struct {
+	Data []byte
+}
+
+var (
+	corpusOnce      sync.Once
+	benchmarkCorpus []BenchmarkPayload
+)
+
+// BenchmarkCorpus returns the benchmark corpus, representative documents of
+// the XML-RPC traffic in the wild:
+//
+//	supervisor  a supervisor.getAllProcessInfo response for 50 processes
+//	wordpress   a metaWeblog.getRecentPosts response with 20 HTML posts
+//	multicall   a system.multicall call batching 100 calls
+//	big-array   a response with an array of 20000 ints
+//	big-base64  a response with 1 MiB of base64
+//
+// The documents are generated, so they're the same for every version of
+// the package and comparable across them.
+func BenchmarkCorpus() []BenchmarkPayload {
+	// generated on demand, not to slow down the programs not using it
+	corpusOnce.Do(func() { benchmarkCorpus = newBenchmarkCorpus() })
+	corpus := make([]BenchmarkPayload, len(benchmarkCorpus))
+	copy(corpus, benchmarkCorpus)
+	return corpus
+}
+
+func newBenchmarkCorpus() []BenchmarkPayload {
+	var processes benchProcesses
+	for i := 0; i < 50; i++ {
+		processes.Processes = append(processes.Processes, benchProcess{
+			Name:        fmt.Sprintf("worker_%02d", i),
+			Group:       "workers",
+			Description: fmt.Sprintf("pid %d, uptime 1:02:%02d", 1000+i, i),
+			Start:       1500000000 + i,
+			Now:         1500003720 + i,
+			State:       20,
+			Pid:         1000 + i,
+			Statename:   "RUNNING",
+			Logfile:     fmt.Sprintf("/var/log/supervisor/worker_%02d.log", i),
+			Stdout:      fmt.Sprintf("/var/log/supervisor/worker_%02d-stdout.log", i),
+			Stderr:      fmt.Sprintf("/var/log/supervisor/worker_%02d-stderr.log", i),
+		})
+	}
+
+	var posts benchPosts
+	body := strings.Repeat(`<p>Lorem ipsum dolor sit amet, <a href="https://example.com/?a=1&b=2">consectetur</a> adipiscing elit.</p>`, 20)
+	for i := 0; i < 20; i++ {
+		posts.Posts = append(posts.Posts, benchPost{
+			PostId:      fmt.Sprint(100 + i),
+			Title:       fmt.Sprintf("Post #%d: \"quotes\" & <tags>", i),
+			Description: body,
+			DateCreated: time.Date(2013, 5, 1+i, 12, 0, 0, 0, time.Local),
+			Categories:  []string{"news", "go", "xml-rpc"},
+			Link:        fmt.Sprintf("https://example.com/%d", 100+i),
+		})
+	}
+
+	var multicall benchMulticall
+	for i := 0; i < 100; i++ {
+		multicall.Calls = append(multicall.Calls, benchCall{
+			MethodName: "supervisor.getProcessInfo",
+			Params:     []Value{NewString(fmt.Sprintf("worker_%02d", i%50)), NewInt(int64(i))},
+		})
+	}
+
+	var ints benchInts
+	for i := 0; i < 20000; i++ {
+		ints.Items = append(ints.Items, i*7919%100003)
+	}
+
+	binary := benchBinary{Data: make([]byte, 1<<20)}
+	for i := range binary.Data {
+		binary.Data[i] = byte(i * 31)
+	}
+
+	corpus := []BenchmarkPayload{
+		{Name: "supervisor", value: &processes, newTarget: func() interface{} { return new(benchProcesses) }},
+		{Name: "wordpress", value: &posts, newTarget: func() interface{} { return new(benchPosts) }},
+		{Name: "multicall", Method: "system.multicall", value: &multicall, newTarget: func() interface{} { return new(benchMulticall) }},
+		{Name: "big-array", value: &ints, newTarget: func() interface{} { return new(benchInts) }},
+		{Name: "big-base64", value: &binary, newTarget: func() interface{} { return new(benchBinary) }},
+	}
+	for i := range corpus {
+		doc, err := corpus[i].Encode()
+		if err != nil {
+			panic("xmlrpc: encoding the benchmark corpus: " + err.Error())
+		}
+		corpus[i].Document = doc
+	}
+	return corpus
+}
+
+// RunBenchmarks runs the corpus as sub-benchmarks of b, encoding, decoding
+// and parsing every payload, e.g. from the tests of a vendored copy:
+//
+//	func BenchmarkXMLRPC(b *testing.B) { xml.RunBenchmarks(b) }
+func RunBenchmarks(b *testing.B) {
+	for _, bench := range benchmarks() {
+		b.Run(bench.name, bench.f)
+	}
+}
+
+// BenchmarkResult is the outcome of a benchmark of the corpus.
+type BenchmarkResult struct {
+	Name        string `json:"name"`
+	NsPerOp     int64  `json:"ns_per_op"`
+	AllocsPerOp int64  `json:"allocs_per_op"`
+	BytesPerOp  int64  `json:"bytes_per_op"`
+}
+
+// MeasureBenchmarks runs the corpus outside of go test and returns the
+// results, e.g. to be saved as a baseline and compared with Regressions.
+func MeasureBenchmarks() []BenchmarkResult {
+	var results []BenchmarkResult
+	for _, bench := range benchmarks() {
+		r := testing.Benchmark(bench.f)
+		results = append(results, BenchmarkResult{
+			Name:        bench.name,
+			NsPerOp:     r.NsPerOp(),
+			AllocsPerOp: r.AllocsPerOp(),
+			BytesPerOp:  r.AllocedBytesPerOp(),
+		})
+	}
+	return results
+}
+
+// Regressions compares the current results to the baseline ones and
+// describes every benchmark slower, or allocating more, than its baseline by
+// more than tolerance, e.g. 0.1 for 10%. Benchmarks missing from the
+// baseline are ignored.
+func Regressions(baseline, current []BenchmarkResult, tolerance float64) []string {
+	base := make(map[string]BenchmarkResult, len(baseline))
+	for _, r := range baseline {
+		base[r.Name] = r
+	}
+
+	var regressions []string
+	check := func(name, metric string, was, is int64) {
+		if was > 0 && float64(is) > float64(was)*(1+tolerance) {
+			regressions = append(regressions, fmt.Sprintf("%s: %s %d -> %d (%+.1f%%)",
+				name, metric, was, is, 100*(float64(is)/float64(was)-1)))
+		}
+	}
+	for _, r := range current {
+		b, ok := base[r.Name]
+		if !ok {
+			continue
+		}
+		check(r.Name, "ns/op", b.NsPerOp, r.NsPerOp)
+		check(r.Name, "allocs/op", b.AllocsPerOp, r.AllocsPerOp)
+		check(r.Name, "B/op", b.BytesPerOp, r.BytesPerOp)
+	}
+	return regressions
+}
+
+type benchmark struct {
+	name string
+	f    func(b *testing.B)
+}
+
+// benchmarks returns the benchmarks of the corpus, named payload/operation.
+func benchmarks() []benchmark {
+	var list []benchmark
+	for _, p := range BenchmarkCorpus() {
+		p := p
+		ops := []struct {
+			name string
+			f    func() error
+		}{
+			{"encode", func() error { _, err := p.Encode(); return err }},
+			{"decode", p.Decode},
+			{"parse", p.Parse},
+		}
+		for _, op := range ops {
+			op := op
+			list = append(list, benchmark{p.Name + "/" + op.name, func(b *testing.B) {
+				b.SetBytes(int64(len(p.Document)))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if err := op.f(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}})
+		}
+	}
+	return list
+}
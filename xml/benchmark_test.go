@@ -0,0 +1,58 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBenchmarkCorpus(t *testing.T) {
+	corpus := BenchmarkCorpus()
+	if len(corpus) != 5 {
+		t.Fatal("Expected 5 payloads, got", len(corpus))
+	}
+	for _, p := range corpus {
+		doc, err := p.Encode()
+		if err != nil || !bytes.Equal(doc, p.Document) {
+			t.Errorf("Expected %s to encode into its document, got %v", p.Name, err)
+		}
+		target := p.newTarget()
+		if err := decodeRPC(string(p.Document), target, defaultOptions); err != nil {
+			t.Errorf("Expected %s to decode, got %v", p.Name, err)
+		}
+		if path, ok := equivalent(reflect.ValueOf(target), reflect.ValueOf(p.value), ""); !ok {
+			t.Errorf("Expected %s to decode into its value, got %s", p.Name, path)
+		}
+		if err := p.Parse(); err != nil {
+			t.Errorf("Expected %s to parse, got %v", p.Name, err)
+		}
+	}
+}
+
+func TestRegressions(t *testing.T) {
+	baseline := []BenchmarkResult{
+		{Name: "a", NsPerOp: 100, AllocsPerOp: 10, BytesPerOp: 1000},
+		{Name: "b", NsPerOp: 100, AllocsPerOp: 10, BytesPerOp: 1000},
+	}
+	current := []BenchmarkResult{
+		{Name: "a", NsPerOp: 105, AllocsPerOp: 10, BytesPerOp: 900},
+		{Name: "b", NsPerOp: 150, AllocsPerOp: 12, BytesPerOp: 1000},
+		{Name: "c", NsPerOp: 1000},
+	}
+	regressions := Regressions(baseline, current, 0.1)
+	if len(regressions) != 2 {
+		t.Fatal("Expected 2 regressions, got", regressions)
+	}
+	if !strings.HasPrefix(regressions[0], "b: ns/op 100 -> 150") || !strings.HasPrefix(regressions[1], "b: allocs/op 10 -> 12") {
+		t.Error("Expected the regressions of b, got", regressions)
+	}
+}
+
+func BenchmarkCorpusPayloads(b *testing.B) {
+	RunBenchmarks(b)
+}
@@ -0,0 +1,95 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// isBinaryString reports whether field is a []byte encoded as a raw <string>
+// instead of <base64>, as tagged with `xmlrpc:",string"`.
+func isBinaryString(field reflect.StructField) bool {
+	_, opts := parseTag(field)
+	return opts.Contains("string") && isBytes(field.Type)
+}
+
+func isBytes(typ reflect.Type) bool {
+	return typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8
+}
+
+// binaryString is a param value encoded as a raw <string>.
+type binaryString []byte
+
+// binaryString2XML writes data as a <string> value, byte for byte. Only the
+// markup characters, and the carriage returns the parsers would normalize,
+// are escaped: the bytes that aren't valid XML, e.g. invalid UTF-8 or
+// control characters, are written as they are, for the legacy peers
+// expecting them, though no conforming parser reads them back.
+func (e *encodeState) binaryString2XML(data []byte, writer io.Writer) error {
+	fmt.Fprintf(writer, "<value>")
+	if !e.opts.UntypedStrings {
+		fmt.Fprintf(writer, "<string>")
+	}
+	start := 0
+	for i, c := range data {
+		var esc string
+		switch c {
+		case '&':
+			esc = "&amp;"
+		case '"':
+			esc = "&quot;"
+		case '<':
+			esc = "&lt;"
+		case '>':
+			esc = "&gt;"
+		case '\r':
+			esc = "&#xD;"
+		default:
+			continue
+		}
+		writer.Write(data[start:i])
+		io.WriteString(writer, esc)
+		start = i + 1
+	}
+	writer.Write(data[start:])
+	if !e.opts.UntypedStrings {
+		fmt.Fprintf(writer, "</string>")
+	}
+	fmt.Fprintf(writer, "</value>")
+	return e.check(nil)
+}
+
+// binaryString2Field decodes a <string> or untyped value into the []byte
+// field, without validating the text. It reports whether value was handled,
+// leaving the other values, e.g. <base64>, to value2Field.
+func binaryString2Field(value value, field *reflect.Value) bool {
+	if !isBytes(field.Type()) {
+		return false
+	}
+	text, ok := stringText(value)
+	if !ok {
+		return false
+	}
+	field.SetBytes([]byte(text))
+	return true
+}
+
+// stringText returns the text of a <string> or untyped value. It reports
+// whether value is one.
+func stringText(value value) (string, bool) {
+	switch raw := strings.TrimSpace(value.Raw); {
+	case value.String != "":
+		return value.String, true
+	case raw == "<string></string>" || raw == "<string/>":
+		return "", true
+	case !strings.Contains(value.Raw, "<"):
+		// untyped
+		return value.Text, true
+	}
+	return "", false
+}
@@ -0,0 +1,84 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"testing"
+)
+
+type binaryArgs struct {
+	Data  []byte `xmlrpc:",string"`
+	Image []byte
+}
+
+func TestBinaryStringEncode(t *testing.T) {
+	var buffer bytes.Buffer
+	args := &binaryArgs{Data: []byte("a<b\r\n\x80"), Image: []byte("img")}
+	if err := rpcParams2XML(args, &buffer, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "<params><param><value><string>a&lt;b&#xD;\n\x80</string></value></param>" +
+		"<param><value><base64>aW1n</base64></value></param></params>"
+	if buffer.String() != want {
+		t.Errorf("Expected %q, got %q", want, buffer.String())
+	}
+
+	buffer.Reset()
+	if err := rpcParams2XML(&struct{ Args binaryArgs }{*args}, &buffer, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buffer.Bytes(), []byte("<name>Data</name><value><string>a&lt;b")) {
+		t.Errorf("Expected the member encoded as a raw string, got %q", buffer.String())
+	}
+}
+
+func TestBinaryStringRoundTrip(t *testing.T) {
+	data := []byte("x&y\r\n\té")
+	var buffer bytes.Buffer
+	if err := rpcParams2XML(&binaryArgs{Data: data}, &buffer, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	var reply binaryArgs
+	if err := decodeRPC(toResponse(buffer.String()), &reply, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reply.Data, data) {
+		t.Errorf("Expected %q, got %q", data, reply.Data)
+	}
+}
+
+func TestBinaryStringsDecode(t *testing.T) {
+	resp := toResponse("<params><param><value><string>raw</string></value></param>" +
+		"<param><value>untyped</value></param></params>")
+	var reply struct{ A, B []byte }
+	if err := decodeRPC(resp, &reply, &Options{}); err == nil {
+		t.Error("Expected strings not decoded into []byte by default")
+	}
+	if err := decodeRPC(resp, &reply, &Options{BinaryStrings: true}); err != nil {
+		t.Fatal(err)
+	}
+	if string(reply.A) != "raw" || string(reply.B) != "untyped" {
+		t.Errorf("Expected raw and untyped, got %q and %q", reply.A, reply.B)
+	}
+
+	// base64 is still decoded
+	resp = toResponse("<params><param><value><base64>aW1n</base64></value></param></params>")
+	var tagged binaryArgs
+	if err := decodeRPC(resp, &tagged, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if string(tagged.Data) != "img" {
+		t.Errorf("Expected img, got %q", tagged.Data)
+	}
+}
+
+func TestBinaryStringValidate(t *testing.T) {
+	resp := toResponse("<params><param><value><string>raw</string></value></param>" +
+		"<param><value><base64>aW1n</base64></value></param></params>")
+	if issues := Validate([]byte(resp), &binaryArgs{}); len(issues) != 0 {
+		t.Errorf("Expected no issues, got %v", issues)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// BoundMethod is a method of a Client whose leading params are constant,
+// e.g. an API key or a blog ID. They are encoded once, by Bind, so only the
+// varying params are encoded on every call.
+type BoundMethod struct {
+	client *Client
+	method string
+	prefix []byte // the call up to the constant params included
+}
+
+// Bind returns method bound to the constant leading params args, encoded
+// with the Options of the client as they are now. The params are
+// positional, even with NamedParams.
+func (c *Client) Bind(method string, args ...interface{}) (*BoundMethod, error) {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "<methodCall><methodName>%s</methodName><params>", escapeString(method))
+	if err := writeParamValues(args, &buffer, &c.Options); err != nil {
+		return nil, err
+	}
+	return &BoundMethod{client: c, method: method, prefix: buffer.Bytes()}, nil
+}
+
+// Call invokes the method with the constant params followed by args, and
+// decodes the response into reply, which may be nil for void methods.
+func (m *BoundMethod) Call(reply interface{}, args ...interface{}) error {
+	return m.CallContext(context.Background(), reply, args...)
+}
+
+// CallContext is like Call, but the call is cancelled when ctx is done.
+func (m *BoundMethod) CallContext(ctx context.Context, reply interface{}, args ...interface{}) error {
+	buffer := bytes.NewBuffer(make([]byte, 0, len(m.prefix)+64))
+	buffer.Write(m.prefix)
+	if err := writeParamValues(args, buffer, &m.client.Options); err != nil {
+		return err
+	}
+	fmt.Fprintf(buffer, "</params></methodCall>")
+
+	resp, err := m.client.post(ctx, m.method, buffer.Bytes())
+	if err != nil {
+		return err
+	}
+	return m.client.decodeReply(ctx, m.method, resp, reply)
+}
+
+// writeParamValues encodes args as <param> elements.
+func writeParamValues(args []interface{}, buffer *bytes.Buffer, opts *Options) error {
+	state := newEncodeState(opts)
+	for _, arg := range args {
+		fmt.Fprintf(buffer, "<param>")
+		if err := state.value2XML(arg, buffer); err != nil {
+			return err
+		}
+		fmt.Fprintf(buffer, "</param>")
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientBind(t *testing.T) {
+	var calls [][]Value
+	rpcs := MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		calls = append(calls, params)
+		return []Value{{Kind: KindInt, Text: "1"}}, nil
+	})
+	ts := httptest.NewServer(rpcs)
+	defer ts.Close()
+
+	getPost, err := NewClient(ts.URL).Bind("wp.getPost", 1, "admin", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reply struct{ OK int }
+	if err := getPost.Call(&reply, 42); err != nil {
+		t.Fatal(err)
+	}
+	if err := getPost.Call(nil, 43, []string{"title"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if reply.OK != 1 {
+		t.Errorf("Expected the reply decoded, got %+v", reply)
+	}
+	if len(calls) != 2 || len(calls[0]) != 4 || len(calls[1]) != 5 {
+		t.Fatalf("Expected calls of 4 and 5 params, got %v", calls)
+	}
+	if calls[0][2].Text != "secret" || calls[0][3].Text != "42" || calls[1][3].Text != "43" {
+		t.Errorf("Expected the constant params followed by the call ones, got %v", calls)
+	}
+}
+
+func TestClientBindError(t *testing.T) {
+	type node struct{ Next *node }
+	cycle := &node{}
+	cycle.Next = cycle
+	if _, err := NewClient("http://localhost/RPC2").Bind("m", cycle); err == nil {
+		t.Error("Expected a cyclic constant param rejected")
+	}
+}
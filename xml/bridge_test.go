@@ -0,0 +1,77 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func bridgeTestValue() Value {
+	return NewStruct(
+		Member{"name", NewString("supervisor")},
+		Member{"long", NewString(strings.Repeat("x", 300))},
+		Member{"small", NewInt(-7)},
+		Member{"pid", NewInt(70000)},
+		Member{"big", Value{Kind: KindInt, Text: "-123456789012345678901234567890"}},
+		Member{"load", NewDouble(0.5)},
+		Member{"running", NewBoolean(true)},
+		Member{"start", NewDateTime(time.Date(2012, time.July, 17, 14, 8, 55, 0, time.Local))},
+		Member{"data", NewBase64([]byte("you can't read this!"))},
+		Member{"tags", NewArray(NewString("a"), Value{Kind: KindNil})},
+		Member{"id", Value{Kind: "uuid", Text: "00ff"}},
+	)
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	v := bridgeTestValue()
+	data, err := ValueToMsgpack(v)
+	if err != nil {
+		t.Fatal("ValueToMsgpack failed", err)
+	}
+	back, err := MsgpackToValue(data)
+	if err != nil {
+		t.Fatal("MsgpackToValue failed", err)
+	}
+	if !reflect.DeepEqual(back, v) {
+		t.Errorf("expected %v, got %v", v, back)
+	}
+
+	// fixmap with 11 members, first key fixstr "name"
+	if !bytes.HasPrefix(data, []byte{0x8b, 0xa4, 'n', 'a', 'm', 'e'}) {
+		t.Errorf("unexpected encoding % x", data[:6])
+	}
+	if _, err := MsgpackToValue(data[:len(data)-1]); err == nil {
+		t.Error("expected error for truncated data")
+	}
+}
+
+func TestCBORRoundTrip(t *testing.T) {
+	v := bridgeTestValue()
+	data, err := ValueToCBOR(v)
+	if err != nil {
+		t.Fatal("ValueToCBOR failed", err)
+	}
+	back, err := CBORToValue(data)
+	if err != nil {
+		t.Fatal("CBORToValue failed", err)
+	}
+	if !reflect.DeepEqual(back, v) {
+		t.Errorf("expected %v, got %v", v, back)
+	}
+
+	// [1.5 (half precision), -1, true]
+	back, err = CBORToValue([]byte{0x83, 0xf9, 0x3e, 0x00, 0x20, 0xf5})
+	if err != nil {
+		t.Fatal("CBORToValue failed", err)
+	}
+	expected := NewArray(NewDouble(1.5), NewInt(-1), NewBoolean(true))
+	if !reflect.DeepEqual(back, expected) {
+		t.Errorf("expected %v, got %v", expected, back)
+	}
+}
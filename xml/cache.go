@@ -0,0 +1,257 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// CacheEntry is a response stored in a Cache.
+type CacheEntry struct {
+	Body   []byte
+	Stored time.Time
+}
+
+// Cache stores the responses of a Client by key, e.g. in memory or on disk.
+// It must be safe for concurrent use. Other backends, e.g. bbolt, are a
+// matter of implementing it.
+type Cache interface {
+	Get(key string) (CacheEntry, bool)
+	Set(key string, entry CacheEntry)
+}
+
+// ResponseCache configures the cached calls of a Client: the successful
+// responses to Methods are stored in Cache, keyed by the URL, the Scope, the
+// method and the params, whatever the order of the struct members. A response is served
+// from the cache for TTL; for StaleWhileRevalidate more, it's still served,
+// but refreshed in the background. The faults aren't cached.
+//
+// It's meant for the tools querying a slow endpoint again and again, e.g. a
+// CLI whose responses last between runs with a FileCache:
+//
+//	client.Cache = &xml.ResponseCache{
+//		Cache:   xml.NewFileCache(filepath.Join(os.TempDir(), "mytool")),
+//		TTL:     time.Hour,
+//		Methods: []string{"get*"},
+//	}
+type ResponseCache struct {
+	Cache                Cache
+	TTL                  time.Duration
+	StaleWhileRevalidate time.Duration
+
+	// Methods are the patterns of the methods cached, as for path.Match.
+	Methods []string
+
+	// Scope, if set, returns the scope of the responses to a call, e.g. the
+	// user it's made for, so the clients or the calls with other credentials
+	// sharing the Cache don't get the responses of each other.
+	Scope func(ctx context.Context) string
+
+	mu         sync.Mutex
+	refreshing map[string]bool
+}
+
+// applies reports whether the calls of method are cached by rc, which may be
+// nil.
+func (rc *ResponseCache) applies(method string) bool {
+	if rc == nil {
+		return false
+	}
+	for _, pattern := range rc.Methods {
+		if ok, _ := path.Match(pattern, method); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// refresh reports whether the entry key should be refreshed, i.e. no other
+// call is refreshing it, and marks it as being refreshed.
+func (rc *ResponseCache) refresh(key string) bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.refreshing[key] {
+		return false
+	}
+	if rc.refreshing == nil {
+		rc.refreshing = make(map[string]bool)
+	}
+	rc.refreshing[key] = true
+	return true
+}
+
+func (rc *ResponseCache) refreshed(key string) {
+	rc.mu.Lock()
+	delete(rc.refreshing, key)
+	rc.mu.Unlock()
+}
+
+// cached posts the request document of method like post, serving the
+// response from the cache when it's fresh enough.
+func (c *Client) cached(ctx context.Context, method string, document []byte) ([]byte, error) {
+	rc := c.Cache
+	var scope string
+	if rc.Scope != nil {
+		scope = rc.Scope(ctx)
+	}
+	key := c.URL + "\x00" + scope + "\x00" + method + "\x00" + string(canonicalCall(document))
+	entry, ok := rc.Cache.Get(key)
+	if ok {
+		age := time.Since(entry.Stored)
+		if age < rc.TTL {
+			return entry.Body, nil
+		}
+		if age < rc.TTL+rc.StaleWhileRevalidate {
+			if rc.refresh(key) {
+				go func() {
+					defer rc.refreshed(key)
+					c.store(detachedContext{ctx}, key, method, document)
+				}()
+			}
+			return entry.Body, nil
+		}
+	}
+	return c.store(ctx, key, method, document)
+}
+
+// store posts the request document of method and caches the response as
+// key, unless it's a fault.
+func (c *Client) store(ctx context.Context, key, method string, document []byte) ([]byte, error) {
+	body, err := c.fetch(ctx, method, document)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ParseMethodResponse(body); err == nil {
+		c.Cache.Cache.Set(key, CacheEntry{Body: body, Stored: time.Now()})
+	}
+	return body, nil
+}
+
+// MemoryCache is a Cache in memory. Its entries are kept until they are
+// older than MaxAge, or dropped to make room for others.
+type MemoryCache struct {
+	// MaxAge, if set, is the age past which the entries are evicted, e.g.
+	// the TTL plus the StaleWhileRevalidate of the ResponseCache.
+	MaxAge time.Duration
+
+	// MaxEntries, if set, caps the entries kept: the oldest one is evicted
+	// to store another.
+	MaxEntries int
+
+	mu      sync.RWMutex
+	entries map[string]CacheEntry
+}
+
+// NewMemoryCache returns an empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]CacheEntry)}
+}
+
+// expired reports whether entry is past MaxAge.
+func (m *MemoryCache) expired(entry CacheEntry, now time.Time) bool {
+	return m.MaxAge > 0 && now.Sub(entry.Stored) >= m.MaxAge
+}
+
+// Get returns the entry key, evicting it if it's expired.
+func (m *MemoryCache) Get(key string) (CacheEntry, bool) {
+	m.mu.RLock()
+	entry, ok := m.entries[key]
+	m.mu.RUnlock()
+	if ok && m.expired(entry, time.Now()) {
+		m.mu.Lock()
+		if entry, ok = m.entries[key]; ok && m.expired(entry, time.Now()) {
+			delete(m.entries, key)
+		}
+		m.mu.Unlock()
+		return CacheEntry{}, false
+	}
+	return entry, ok
+}
+
+// Set stores the entry key, evicting the expired entries, then the oldest
+// one, if the cache is full.
+func (m *MemoryCache) Set(key string, entry CacheEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.entries[key]; !ok && m.MaxEntries > 0 && len(m.entries) >= m.MaxEntries {
+		now := time.Now()
+		var (
+			oldest string
+			stored time.Time
+		)
+		for k, e := range m.entries {
+			if m.expired(e, now) {
+				delete(m.entries, k)
+			} else if stored.IsZero() || e.Stored.Before(stored) {
+				oldest, stored = k, e.Stored
+			}
+		}
+		if len(m.entries) >= m.MaxEntries {
+			delete(m.entries, oldest)
+		}
+	}
+	m.entries[key] = entry
+}
+
+// FileCache is a Cache on disk, a file per entry in the directory Dir,
+// which is created as needed. The entries last between the runs of a
+// program, until the files are removed.
+type FileCache struct {
+	Dir string
+}
+
+// NewFileCache returns a FileCache in dir.
+func NewFileCache(dir string) *FileCache {
+	return &FileCache{Dir: dir}
+}
+
+// file returns the path of the file of the entry key.
+func (f *FileCache) file(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(f.Dir, hex.EncodeToString(sum[:]))
+}
+
+// Get returns the entry key, if its file can be read.
+func (f *FileCache) Get(key string) (CacheEntry, bool) {
+	data, err := ioutil.ReadFile(f.file(key))
+	if err != nil || len(data) < 8 {
+		return CacheEntry{}, false
+	}
+	stored := time.Unix(0, int64(binary.BigEndian.Uint64(data)))
+	return CacheEntry{Body: data[8:], Stored: stored}, true
+}
+
+// Set stores the entry key, replacing its file atomically. The entry is
+// dropped if it can't be written.
+func (f *FileCache) Set(key string, entry CacheEntry) {
+	if err := os.MkdirAll(f.Dir, 0700); err != nil {
+		return
+	}
+	tmp, err := ioutil.TempFile(f.Dir, ".tmp")
+	if err != nil {
+		return
+	}
+	var stored [8]byte
+	binary.BigEndian.PutUint64(stored[:], uint64(entry.Stored.UnixNano()))
+	_, err = tmp.Write(append(stored[:], entry.Body...))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), f.file(key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newCountingServer(requests *int32) *httptest.Server {
+	return httptest.NewServer(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		n := atomic.AddInt32(requests, 1)
+		if method == "getFault" {
+			return nil, FaultApplicationError
+		}
+		return []Value{NewInt(int64(n))}, nil
+	}))
+}
+
+func TestClientCache(t *testing.T) {
+	var requests int32
+	ts := newCountingServer(&requests)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+	client.Cache = &ResponseCache{Cache: NewMemoryCache(), TTL: time.Hour, Methods: []string{"get*"}}
+
+	call := func(method string, params ...Value) int64 {
+		res, err := client.CallValues(method, params...)
+		if err != nil {
+			if _, ok := err.(Fault); !ok {
+				t.Fatal("Expected err to be nil, but got:", err)
+			}
+			return 0
+		}
+		n, _ := res[0].Int()
+		return n
+	}
+
+	if call("getThing", NewInt(1)) != 1 || call("getThing", NewInt(1)) != 1 {
+		t.Error("Expected the second call to be served from the cache")
+	}
+	if call("getThing", NewInt(2)) != 2 {
+		t.Error("Expected the calls with other params not to be served from the cache")
+	}
+	if call("putThing", NewInt(1)) != 3 || call("putThing", NewInt(1)) != 4 {
+		t.Error("Expected the other methods not to be cached")
+	}
+	call("getFault")
+	call("getFault")
+	if n := atomic.LoadInt32(&requests); n != 6 {
+		t.Error("Expected the faults not to be cached, got", n, "requests")
+	}
+}
+
+func TestClientCacheStale(t *testing.T) {
+	var requests int32
+	ts := newCountingServer(&requests)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+	client.Cache = &ResponseCache{Cache: NewMemoryCache(), StaleWhileRevalidate: time.Hour, Methods: []string{"*"}}
+
+	var reply struct{ N int }
+	for i, expected := range []int{1, 1} {
+		if err := client.Call("m", &struct{}{}, &reply); err != nil {
+			t.Fatal("Expected err to be nil, but got:", err)
+		}
+		if reply.N != expected {
+			t.Errorf("Call %d: expected %d, got %d", i, expected, reply.N)
+		}
+	}
+	// the stale response was refreshed in the background
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		if err := client.Call("m", &struct{}{}, &reply); err != nil {
+			t.Fatal("Expected err to be nil, but got:", err)
+		}
+		if reply.N >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the stale response to be refreshed")
+		}
+	}
+}
+
+func TestClientCacheScope(t *testing.T) {
+	var requests int32
+	ts := newCountingServer(&requests)
+	defer ts.Close()
+	other := newCountingServer(&requests)
+	defer other.Close()
+
+	type userKey struct{}
+	shared := &ResponseCache{Cache: NewMemoryCache(), TTL: time.Hour, Methods: []string{"*"},
+		Scope: func(ctx context.Context) string {
+			user, _ := ctx.Value(userKey{}).(string)
+			return user
+		}}
+	call := func(url, user string) int {
+		client := NewClient(url)
+		client.Cache = shared
+		var reply struct{ N int }
+		ctx := context.WithValue(context.Background(), userKey{}, user)
+		if err := client.CallContext(ctx, "m", &struct{}{}, &reply); err != nil {
+			t.Fatal("Expected err to be nil, but got:", err)
+		}
+		return reply.N
+	}
+	if call(ts.URL, "alice") != 1 || call(ts.URL, "alice") != 1 {
+		t.Error("Expected the second call to be served from the cache")
+	}
+	if call(ts.URL, "bob") != 2 {
+		t.Error("Expected the calls of another scope not to be served from the cache")
+	}
+	if call(other.URL, "alice") != 3 {
+		t.Error("Expected the calls to another URL not to be served from the cache")
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	cache := NewMemoryCache()
+	cache.MaxAge = time.Minute
+	cache.Set("old", CacheEntry{Body: []byte("old"), Stored: time.Now().Add(-time.Hour)})
+	if _, ok := cache.Get("old"); ok {
+		t.Error("Expected the expired entry evicted")
+	}
+	if len(cache.entries) != 0 {
+		t.Error("Expected no entries left, got", len(cache.entries))
+	}
+
+	cache.MaxEntries = 2
+	now := time.Now()
+	cache.Set("a", CacheEntry{Stored: now.Add(-2 * time.Second)})
+	cache.Set("b", CacheEntry{Stored: now.Add(-time.Second)})
+	cache.Set("c", CacheEntry{Stored: now})
+	if _, ok := cache.Get("a"); ok {
+		t.Error("Expected the oldest entry evicted")
+	}
+	if _, ok := cache.Get("c"); !ok || len(cache.entries) != 2 {
+		t.Error("Expected the newest entries kept, got", cache.entries)
+	}
+}
+
+func TestFileCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xmlrpc-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var requests int32
+	ts := newCountingServer(&requests)
+	defer ts.Close()
+	for i := 0; i < 2; i++ {
+		// a new client, as in another run of a program
+		client := NewClient(ts.URL)
+		client.Cache = &ResponseCache{Cache: NewFileCache(dir), TTL: time.Hour, Methods: []string{"*"}}
+		var reply struct{ N int }
+		if err := client.Call("m", &struct{}{}, &reply); err != nil {
+			t.Fatal("Expected err to be nil, but got:", err)
+		}
+		if reply.N != 1 {
+			t.Error("Expected the response to be served from the files, got", reply.N)
+		}
+	}
+
+	cache := NewFileCache(dir)
+	if _, ok := cache.Get("missing"); ok {
+		t.Error("Expected no entry")
+	}
+	stored := time.Unix(1000, 5)
+	cache.Set("k", CacheEntry{Body: []byte("body"), Stored: stored})
+	if entry, ok := cache.Get("k"); !ok || string(entry.Body) != "body" || !entry.Stored.Equal(stored) {
+		t.Error("Expected the entry to be read back, got", entry, ok)
+	}
+}
@@ -0,0 +1,337 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+)
+
+// CBOR major types.
+const (
+	cborUint byte = iota << 5
+	cborNegInt
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+// CBOR tags.
+const (
+	cborTagDateTime     = 0
+	cborTagEpoch        = 1
+	cborTagPosBignum    = 2
+	cborTagNegBignum    = 3
+	cborTagTypedScalar  = 27
+	cborTagSelfDescribe = 55799
+)
+
+// ValueToCBOR encodes a value tree as CBOR (RFC 7049), so decoded calls can be
+// forwarded to internal workers without re-serializing them to XML.
+//
+// Structs become maps keeping the members order, base64 becomes a byte
+// string, dateTime.iso8601 becomes a tag 0 date/time string and ints beyond
+// int64 become bignums. Other scalars without a native CBOR type, e.g. from
+// nonstandard tags, are written as tag 27 holding the array [kind, text].
+// CBORToValue reverses the mapping.
+func ValueToCBOR(v Value) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := value2CBOR(v, &buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func value2CBOR(v Value, buffer *bytes.Buffer) error {
+	switch v.Kind {
+	case KindNil:
+		buffer.WriteByte(cborSimple | 22)
+	case KindBoolean:
+		if v.Boolean() {
+			buffer.WriteByte(cborSimple | 21)
+		} else {
+			buffer.WriteByte(cborSimple | 20)
+		}
+	case KindInt:
+		n, ok := new(big.Int).SetString(v.Text, 10)
+		if !ok {
+			return fmt.Errorf("invalid int %q", v.Text)
+		}
+		writeCBORInt(n, buffer)
+	case KindDouble:
+		f, err := v.Double()
+		if err != nil {
+			writeCBORTyped(v, buffer)
+			break
+		}
+		buffer.WriteByte(cborSimple | 27)
+		binary.Write(buffer, binary.BigEndian, math.Float64bits(f))
+	case KindString, "":
+		writeCBORHeader(buffer, cborText, uint64(len(v.Text)))
+		buffer.WriteString(v.Text)
+	case KindBase64:
+		data, err := v.Base64()
+		if err != nil {
+			return fmt.Errorf("invalid base64: %v", err)
+		}
+		writeCBORHeader(buffer, cborBytes, uint64(len(data)))
+		buffer.Write(data)
+	case KindDateTime:
+		t, err := v.DateTime()
+		if err != nil {
+			return fmt.Errorf("invalid dateTime.iso8601 %q", v.Text)
+		}
+		text := t.Format(time.RFC3339Nano)
+		writeCBORHeader(buffer, cborTag, cborTagDateTime)
+		writeCBORHeader(buffer, cborText, uint64(len(text)))
+		buffer.WriteString(text)
+	case KindArray:
+		writeCBORHeader(buffer, cborArray, uint64(len(v.Items)))
+		for _, item := range v.Items {
+			if err := value2CBOR(item, buffer); err != nil {
+				return err
+			}
+		}
+	case KindStruct:
+		writeCBORHeader(buffer, cborMap, uint64(len(v.Members)))
+		for _, m := range v.Members {
+			writeCBORHeader(buffer, cborText, uint64(len(m.Name)))
+			buffer.WriteString(m.Name)
+			if err := value2CBOR(m.Value, buffer); err != nil {
+				return err
+			}
+		}
+	default:
+		writeCBORTyped(v, buffer)
+	}
+	return nil
+}
+
+func writeCBORHeader(buffer *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		buffer.WriteByte(major | byte(n))
+	case n <= math.MaxUint8:
+		buffer.Write([]byte{major | 24, byte(n)})
+	case n <= math.MaxUint16:
+		buffer.WriteByte(major | 25)
+		binary.Write(buffer, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		buffer.WriteByte(major | 26)
+		binary.Write(buffer, binary.BigEndian, uint32(n))
+	default:
+		buffer.WriteByte(major | 27)
+		binary.Write(buffer, binary.BigEndian, n)
+	}
+}
+
+func writeCBORInt(n *big.Int, buffer *bytes.Buffer) {
+	major, tag := cborUint, uint64(cborTagPosBignum)
+	if n.Sign() < 0 {
+		// negative integers are encoded as -1-n
+		n = new(big.Int).Sub(big.NewInt(-1), n)
+		major, tag = cborNegInt, cborTagNegBignum
+	}
+	if n.IsUint64() {
+		writeCBORHeader(buffer, major, n.Uint64())
+		return
+	}
+	data := n.Bytes()
+	writeCBORHeader(buffer, cborTag, tag)
+	writeCBORHeader(buffer, cborBytes, uint64(len(data)))
+	buffer.Write(data)
+}
+
+func writeCBORTyped(v Value, buffer *bytes.Buffer) {
+	writeCBORHeader(buffer, cborTag, cborTagTypedScalar)
+	writeCBORHeader(buffer, cborArray, 2)
+	writeCBORHeader(buffer, cborText, uint64(len(v.Kind)))
+	buffer.WriteString(string(v.Kind))
+	writeCBORHeader(buffer, cborText, uint64(len(v.Text)))
+	buffer.WriteString(v.Text)
+}
+
+// CBORToValue decodes CBOR into a value tree, reversing the mapping of
+// ValueToCBOR. Maps must have text keys, epoch (tag 1) times become
+// dateTime.iso8601, and indefinite-length items aren't supported.
+func CBORToValue(data []byte) (Value, error) {
+	r := &cborReader{data: data}
+	v, err := r.value()
+	if err == nil && r.pos != len(data) {
+		err = fmt.Errorf("cbor: unexpected data after value")
+	}
+	return v, err
+}
+
+type cborReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *cborReader) next(n uint64) ([]byte, error) {
+	if n > uint64(len(r.data)-r.pos) {
+		return nil, fmt.Errorf("cbor: unexpected end of data")
+	}
+	b := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+// header reads the major type and the argument of the next item.
+func (r *cborReader) header() (byte, uint64, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, 0, err
+	}
+	major, info := b[0]&0xe0, b[0]&0x1f
+	if info < 24 {
+		return major, uint64(info), nil
+	}
+	if info > 27 {
+		return major, 0, fmt.Errorf("cbor: unsupported additional information %d", info)
+	}
+	arg, err := r.next(1 << (info - 24))
+	if err != nil {
+		return major, 0, err
+	}
+	var n uint64
+	for _, c := range arg {
+		n = n<<8 | uint64(c)
+	}
+	return major, n, nil
+}
+
+func (r *cborReader) value() (Value, error) {
+	start := r.pos
+	major, n, err := r.header()
+	if err != nil {
+		return Value{}, err
+	}
+
+	switch major {
+	case cborUint:
+		return Value{Kind: KindInt, Text: new(big.Int).SetUint64(n).String()}, nil
+	case cborNegInt:
+		i := new(big.Int).SetUint64(n)
+		return Value{Kind: KindInt, Text: i.Sub(big.NewInt(-1), i).String()}, nil
+	case cborBytes:
+		data, err := r.next(n)
+		return NewBase64(data), err
+	case cborText:
+		data, err := r.next(n)
+		return NewString(string(data)), err
+	case cborArray:
+		v := NewArray()
+		for i := uint64(0); i < n; i++ {
+			item, err := r.value()
+			if err != nil {
+				return v, err
+			}
+			v.Items = append(v.Items, item)
+		}
+		return v, nil
+	case cborMap:
+		v := NewStruct()
+		for i := uint64(0); i < n; i++ {
+			key, err := r.value()
+			if err != nil {
+				return v, err
+			}
+			if key.Kind != KindString {
+				return v, fmt.Errorf("cbor: map key must be text, not %s", key.Kind)
+			}
+			m := Member{Name: key.Text}
+			if m.Value, err = r.value(); err != nil {
+				return v, err
+			}
+			v.Members = append(v.Members, m)
+		}
+		return v, nil
+	case cborTag:
+		return r.tagged(n)
+	}
+
+	switch info := r.data[start] & 0x1f; info {
+	case 20, 21:
+		return NewBoolean(info == 21), nil
+	case 22, 23:
+		return Value{Kind: KindNil}, nil
+	case 25:
+		return NewDouble(halfToFloat(uint16(n))), nil
+	case 26:
+		return NewDouble(float64(math.Float32frombits(uint32(n)))), nil
+	case 27:
+		return NewDouble(math.Float64frombits(n)), nil
+	}
+	return Value{}, fmt.Errorf("cbor: unsupported simple value %d", n)
+}
+
+func (r *cborReader) tagged(tag uint64) (Value, error) {
+	v, err := r.value()
+	if err != nil {
+		return v, err
+	}
+
+	switch tag {
+	case cborTagDateTime:
+		t, err := time.Parse(time.RFC3339Nano, v.Text)
+		if err != nil {
+			return v, fmt.Errorf("cbor: invalid date/time %q", v.Text)
+		}
+		return localDateTime(t), nil
+	case cborTagEpoch:
+		f, err := v.Double()
+		if err != nil {
+			return v, fmt.Errorf("cbor: invalid epoch time %q", v.Text)
+		}
+		sec, frac := math.Modf(f)
+		return NewDateTime(time.Unix(int64(sec), int64(frac*1e9))), nil
+	case cborTagPosBignum, cborTagNegBignum:
+		data, err := v.Base64()
+		if err != nil || v.Kind != KindBase64 {
+			return v, fmt.Errorf("cbor: invalid bignum")
+		}
+		n := new(big.Int).SetBytes(data)
+		if tag == cborTagNegBignum {
+			n.Sub(big.NewInt(-1), n)
+		}
+		return Value{Kind: KindInt, Text: n.String()}, nil
+	case cborTagTypedScalar:
+		if len(v.Items) != 2 || v.Items[0].Kind != KindString || v.Items[1].Kind != KindString {
+			return v, fmt.Errorf("cbor: invalid typed scalar")
+		}
+		return Value{Kind: Kind(v.Items[0].Text), Text: v.Items[1].Text}, nil
+	}
+	// unknown tags, like the self-described CBOR one, are ignored
+	return v, nil
+}
+
+// halfToFloat converts an IEEE 754 half-precision number.
+func halfToFloat(h uint16) float64 {
+	exp, mant := int(h>>10)&0x1f, float64(h&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		f = math.Inf(1)
+		if mant != 0 {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
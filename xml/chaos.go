@@ -0,0 +1,160 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Injection is a failure injected by a FaultInjector into the calls of a
+// method, each with the probability Probability. The failures set are
+// applied in order: the delay, then the fault, or else the corruption or the
+// truncation of the response.
+type Injection struct {
+	Probability float64
+
+	// Delay delays the call.
+	Delay time.Duration
+
+	// Fault, if its Code isn't 0, is returned instead of calling the method.
+	Fault Fault
+
+	// Corrupt makes the response malformed XML.
+	Corrupt bool
+
+	// Truncate cuts the response in half.
+	Truncate bool
+}
+
+// FaultInjector wraps an XML-RPC handler, injecting failures into the
+// calls, so the clients' retries and error handling can be tested against
+// it:
+//
+//	chaos := xml.InjectFaults(s)
+//	chaos.Inject("Service.Slow", xml.Injection{Probability: 0.1, Delay: 2 * time.Second})
+//	chaos.Inject("", xml.Injection{Probability: 0.01, Fault: xml.FaultInternalError})
+//	http.Handle("/RPC2", chaos)
+type FaultInjector struct {
+	next http.Handler
+
+	mu         sync.Mutex
+	injections map[string][]Injection
+	rand       *rand.Rand
+}
+
+// InjectFaults returns a FaultInjector wrapping next, injecting no failure
+// until told to.
+func InjectFaults(next http.Handler) *FaultInjector {
+	return &FaultInjector{
+		next:       next,
+		injections: make(map[string][]Injection),
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Inject adds in to the failures injected into the calls of method, or of
+// every method if it's "".
+func (f *FaultInjector) Inject(method string, in Injection) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.injections[method] = append(f.injections[method], in)
+}
+
+// Clear removes the failures injected into the calls of method, or the
+// failures injected into every method if it's "".
+func (f *FaultInjector) Clear(method string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.injections, method)
+}
+
+// Seed seeds the draws of the injections, making them reproducible.
+func (f *FaultInjector) Seed(seed int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rand.Seed(seed)
+}
+
+// draw returns the failure to inject into a call of method, merging the
+// injections drawn.
+func (f *FaultInjector) draw(method string) (in Injection, ok bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, name := range []string{"", method} {
+		for _, i := range f.injections[name] {
+			if f.rand.Float64() >= i.Probability {
+				continue
+			}
+			ok = true
+			in.Delay += i.Delay
+			if in.Fault.Code == 0 {
+				in.Fault = i.Fault
+			}
+			in.Corrupt = in.Corrupt || i.Corrupt
+			in.Truncate = in.Truncate || i.Truncate
+		}
+	}
+	return in, ok
+}
+
+func (f *FaultInjector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method, _, err := readMethod(r, defaultOptions)
+	if err != nil {
+		f.next.ServeHTTP(w, r)
+		return
+	}
+	in, ok := f.draw(method)
+	if !ok {
+		f.next.ServeHTTP(w, r)
+		return
+	}
+
+	if in.Delay > 0 {
+		timer := time.NewTimer(in.Delay)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			timer.Stop()
+			return
+		}
+	}
+	if in.Fault.Code != 0 {
+		writeFault(w, in.Fault)
+		return
+	}
+	if !in.Corrupt && !in.Truncate {
+		f.next.ServeHTTP(w, r)
+		return
+	}
+
+	resp := newBufferedResponseWriter()
+	f.next.ServeHTTP(resp, r)
+	body := resp.body.Bytes()
+	if in.Corrupt {
+		body = corruptXML(body)
+	}
+	if in.Truncate {
+		body = body[:len(body)/2]
+	}
+	resp.body = *bytes.NewBuffer(body)
+	resp.header.Del("Content-Length")
+	resp.writeTo(w)
+}
+
+// corruptXML mismatches the last end tag of the document b, swapping the
+// first two letters of its name.
+func corruptXML(b []byte) []byte {
+	b = append([]byte(nil), b...)
+	i := bytes.LastIndex(b, []byte("</"))
+	if i < 0 || i+3 >= len(b) || b[i+2] == b[i+3] {
+		return append(b, '<')
+	}
+	b[i+2], b[i+3] = b[i+3], b[i+2]
+	return b
+}
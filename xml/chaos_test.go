@@ -0,0 +1,84 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+func TestFaultInjector(t *testing.T) {
+	s := rpc.NewServer()
+	s.RegisterCodec(NewCodec(), "text/xml")
+	s.RegisterService(new(Service1), "")
+	chaos := InjectFaults(s)
+	ts := httptest.NewServer(chaos)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	call := func() error {
+		var res Service1Response
+		err := client.Call("Service1.Multiply", &Service1Request{4, 2}, &res)
+		if err == nil && res.Result != 8 {
+			t.Errorf("Wrong response: %v.", res.Result)
+		}
+		return err
+	}
+
+	chaos.Inject("Service1.Multiply", Injection{Probability: 0, Fault: FaultSystemError})
+	if err := call(); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	chaos.Clear("Service1.Multiply")
+
+	chaos.Inject("", Injection{Probability: 1, Fault: FaultSystemError})
+	if fault, ok := call().(Fault); !ok || fault.Code != FaultSystemError.Code {
+		t.Error("Expected the fault injected, got:", fault)
+	}
+	chaos.Clear("")
+
+	for _, in := range []Injection{{Probability: 1, Corrupt: true}, {Probability: 1, Truncate: true}} {
+		chaos.Inject("Service1.Multiply", in)
+		if err := call(); err == nil {
+			t.Errorf("Expected %+v to fail the call", in)
+		} else if fault, ok := err.(Fault); ok && fault.Code != FaultDecode.Code {
+			t.Errorf("Expected %+v to make the response malformed, got: %v", in, err)
+		}
+		chaos.Clear("Service1.Multiply")
+	}
+
+	chaos.Inject("Service1.Multiply", Injection{Probability: 1, Delay: 50 * time.Millisecond})
+	start := time.Now()
+	if err := call(); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Error("Expected the call to be delayed, took", elapsed)
+	}
+}
+
+func TestFaultInjectorSeed(t *testing.T) {
+	chaos := InjectFaults(nil)
+	chaos.Inject("", Injection{Probability: 0.5, Truncate: true})
+	draws := func() (n int) {
+		chaos.Seed(1)
+		for i := 0; i < 100; i++ {
+			if _, ok := chaos.draw("m"); ok {
+				n++
+			}
+		}
+		return n
+	}
+	n := draws()
+	if n == 0 || n == 100 {
+		t.Error("Expected about half of the calls to fail, got", n)
+	}
+	if again := draws(); again != n {
+		t.Errorf("Expected the seeded draws to repeat, got %d then %d", n, again)
+	}
+}
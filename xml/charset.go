@@ -0,0 +1,65 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/rogpeppe/go-charset/charset"
+)
+
+// charsetReader converts the documents declared in a non-UTF-8 charset, with
+// o.CharsetReader if set, or the builtin go-charset readers otherwise. If
+// o.CharsetReader fails, the builtin readers are tried, with a WarnCharset
+// warning.
+func (o *Options) charsetReader(label string, input io.Reader) (io.Reader, error) {
+	if o.CharsetReader == nil {
+		return charset.NewReader(label, input)
+	}
+	r, err := o.CharsetReader(label, input)
+	if err == nil {
+		return r, nil
+	}
+	if r, builtinErr := charset.NewReader(label, input); builtinErr == nil {
+		o.warn(WarnCharset, "charset %s decoded with the builtin reader: %v", label, err)
+		return r, nil
+	}
+	return nil, err
+}
+
+// charsetWriter converts UTF-8 documents to the charset name, with
+// o.CharsetWriter if set, or the builtin go-charset writers otherwise.
+func (o *Options) charsetWriter(name string, output io.Writer) (io.WriteCloser, error) {
+	if o.CharsetWriter != nil {
+		return o.CharsetWriter(name, output)
+	}
+	return charset.NewWriter(name, output)
+}
+
+// encodeCharset converts the UTF-8 document body to the charset name, with
+// an XML declaration telling it. A declaration already in body is replaced.
+func encodeCharset(body []byte, name string, opts *Options) ([]byte, error) {
+	if bytes.HasPrefix(body, []byte("<?xml ")) {
+		if end := bytes.Index(body, []byte("?>")); end >= 0 {
+			body = body[end+2:]
+		}
+	}
+
+	buffer := bytes.NewBuffer(make([]byte, 0, len(body)+64))
+	fmt.Fprintf(buffer, `<?xml version="1.0" encoding="%s"?>`, name)
+	w, err := opts.charsetWriter(name, buffer)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
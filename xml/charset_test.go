@@ -0,0 +1,83 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// legacyReader decodes the made-up x-legacy charset, plain ASCII.
+func legacyReader(label string, input io.Reader) (io.Reader, error) {
+	if label != "x-legacy" {
+		return nil, fmt.Errorf("unknown charset %s", label)
+	}
+	return input, nil
+}
+
+func TestCharsetReader(t *testing.T) {
+	xmlraw := `<?xml version="1.0" encoding="x-legacy"?><methodResponse><params><param><value><string>Ivan</string></value></param></params></methodResponse>`
+	var reply struct{ Name string }
+	if err := xml2RPC(xmlraw, &reply); err == nil {
+		t.Error("Expected x-legacy to be unsupported by default")
+	}
+
+	var warnings []Warning
+	opts := &Options{CharsetReader: legacyReader, Warnings: func(w Warning) {
+		warnings = append(warnings, w)
+	}}
+	if err := decodeRPC(xmlraw, &reply, opts); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if reply.Name != "Ivan" || len(warnings) != 0 {
+		t.Errorf("Unexpected decoding %+v, warnings %v", reply, warnings)
+	}
+
+	latin1 := `<?xml version="1.0" encoding="ISO-8859-1"?><methodResponse><params><param><value><string>` + "\xd6\xf1\xe4" + `</string></value></param></params></methodResponse>`
+	if err := decodeRPC(latin1, &reply, opts); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if reply.Name != "Öñä" {
+		t.Error("Expected Öñä, got", reply.Name)
+	}
+	if len(warnings) != 1 || warnings[0].Kind != WarnCharset {
+		t.Error("Expected a charset warning, got", warnings)
+	}
+}
+
+func TestClientCharset(t *testing.T) {
+	var contentType string
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Write([]byte(`<methodResponse><params><param><value><string>ok</string></value></param></params></methodResponse>`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	client.Charset = "ISO-8859-1"
+	var reply struct{ Status string }
+	if err := client.Call("Greeter.Greet", &struct{ Name string }{"Öñä"}, &reply); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if contentType != "text/xml; charset=ISO-8859-1" {
+		t.Error("Unexpected Content-Type", contentType)
+	}
+	if !bytes.HasPrefix(body, []byte(`<?xml version="1.0" encoding="ISO-8859-1"?><methodCall>`)) ||
+		!bytes.Contains(body, []byte("<string>\xd6\xf1\xe4</string>")) {
+		t.Errorf("Unexpected request %q", body)
+	}
+
+	var args struct{ Name string }
+	if err := xml2RPC(string(body), &args); err != nil || args.Name != "Öñä" {
+		t.Errorf("Expected the request to decode back, got %q, %v", args.Name, err)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseArg parses a command-line argument into a value tree.
+//
+// An argument may be prefixed with its type, as in "int:42", "i4:42",
+// "double:1.5", "boolean:1", "string:42", "dateTime.iso8601:20120717T14:08:55"
+// (or RFC 3339), "base64:eW91" and "nil:". Arguments starting with "{" or "["
+// are parsed as JSON (see JSONToValue). Other arguments are guessed: integers
+// become int, other numbers double, true and false boolean, and the rest
+// string.
+func ParseArg(arg string) (Value, error) {
+	if strings.HasPrefix(arg, "{") || strings.HasPrefix(arg, "[") {
+		return JSONToValue([]byte(arg))
+	}
+
+	if i := strings.Index(arg, ":"); i > 0 {
+		typ, text := arg[:i], arg[i+1:]
+		switch Kind(typ) {
+		case KindInt, "i4":
+			if _, err := strconv.ParseInt(text, 10, 64); err != nil {
+				return Value{}, fmt.Errorf("invalid int %q", text)
+			}
+			return Value{Kind: KindInt, Text: text}, nil
+		case KindDouble:
+			if _, err := strconv.ParseFloat(text, 64); err != nil {
+				return Value{}, fmt.Errorf("invalid double %q", text)
+			}
+			return Value{Kind: KindDouble, Text: text}, nil
+		case KindBoolean, "bool":
+			b, err := strconv.ParseBool(text)
+			if err != nil {
+				return Value{}, fmt.Errorf("invalid boolean %q", text)
+			}
+			return NewBoolean(b), nil
+		case KindString:
+			return NewString(text), nil
+		case KindDateTime, "dateTime":
+			if t, err := time.Parse(time.RFC3339, text); err == nil {
+				return localDateTime(t), nil
+			}
+			if _, err := xml2DateTime(text); err != nil {
+				return Value{}, fmt.Errorf("invalid dateTime.iso8601 %q", text)
+			}
+			return Value{Kind: KindDateTime, Text: text}, nil
+		case KindBase64:
+			if _, err := xml2Base64(text); err != nil {
+				return Value{}, fmt.Errorf("invalid base64 %q", text)
+			}
+			return Value{Kind: KindBase64, Text: text}, nil
+		case KindNil:
+			return Value{Kind: KindNil}, nil
+		}
+	}
+
+	if _, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		return Value{Kind: KindInt, Text: arg}, nil
+	}
+	if _, err := strconv.ParseFloat(arg, 64); err == nil {
+		return Value{Kind: KindDouble, Text: arg}, nil
+	}
+	if arg == "true" || arg == "false" {
+		return NewBoolean(arg == "true"), nil
+	}
+	return NewString(arg), nil
+}
+
+// RunCommand performs the call described by the command-line arguments
+//
+//    [-xml | -conformance] URL method [arg ...]
+//
+// and prints every response param to stdout, as JSON or, with -xml, as XML.
+// The args are parsed with ParseArg; an argument "@file" stands for the
+// JSON value in file. A fault response is returned as a Fault error.
+//
+// With -conformance, the method is an echo method of the peer, checked with
+// CheckConformance, and the report is printed.
+func RunCommand(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("xmlrpc", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	asXML := flags.Bool("xml", false, "print the response params as XML")
+	conformance := flags.Bool("conformance", false, "check the conformance of the peer echoing with method")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 2 {
+		return fmt.Errorf("usage: xmlrpc [-xml | -conformance] URL method [arg ...]")
+	}
+
+	if *conformance {
+		report := CheckConformance(context.Background(), NewClient(flags.Arg(0)), flags.Arg(1))
+		fmt.Fprint(stdout, report)
+		if failed := len(report.Failed()); failed != 0 {
+			return fmt.Errorf("%d conformance cases failed", failed)
+		}
+		return nil
+	}
+
+	var params []Value
+	for _, arg := range flags.Args()[2:] {
+		if strings.HasPrefix(arg, "@") {
+			data, err := ioutil.ReadFile(arg[1:])
+			if err != nil {
+				return err
+			}
+			arg = string(data)
+		}
+		v, err := ParseArg(arg)
+		if err != nil {
+			return fmt.Errorf("argument %q: %v", arg, err)
+		}
+		params = append(params, v)
+	}
+
+	results, err := NewClient(flags.Arg(0)).CallValues(flags.Arg(1), params...)
+	if err != nil {
+		return err
+	}
+	for _, v := range results {
+		if *asXML {
+			fmt.Fprintln(stdout, v.String())
+			continue
+		}
+		data, err := ValueToJSON(v)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "%s\n", data)
+	}
+	return nil
+}
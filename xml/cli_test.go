@@ -0,0 +1,62 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseArg(t *testing.T) {
+	tests := []struct {
+		arg      string
+		expected Value
+	}{
+		{"42", NewInt(42)},
+		{"1.5", Value{Kind: KindDouble, Text: "1.5"}},
+		{"true", NewBoolean(true)},
+		{"hello", NewString("hello")},
+		{"string:42", NewString("42")},
+		{"i4:7", NewInt(7)},
+		{"nil:", Value{Kind: KindNil}},
+		{"base64:eW91", Value{Kind: KindBase64, Text: "eW91"}},
+		{`{"a":[1,"b"]}`, NewStruct(Member{"a", NewArray(NewInt(1), NewString("b"))})},
+	}
+	for _, test := range tests {
+		v, err := ParseArg(test.arg)
+		if err != nil {
+			t.Errorf("ParseArg(%q) failed: %v", test.arg, err)
+		} else if !reflect.DeepEqual(v, test.expected) {
+			t.Errorf("ParseArg(%q) = %v, expected %v", test.arg, v, test.expected)
+		}
+	}
+
+	// an offset keeps the instant, in the local time zone
+	v, err := ParseArg("dateTime:2012-07-17T14:08:55+05:45")
+	expected := time.Date(2012, time.July, 17, 14, 8, 55, 0, time.FixedZone("", 5*3600+45*60))
+	if got, derr := v.DateTime(); err != nil || derr != nil || !got.Equal(expected) {
+		t.Errorf("ParseArg of a dateTime with an offset = %v, expected %s", v, expected)
+	}
+
+	if _, err := ParseArg("int:x"); err == nil {
+		t.Error("expected error for invalid int")
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	ts := newTestServer(NewCodec())
+	defer ts.Close()
+
+	var out bytes.Buffer
+	if err := RunCommand([]string{ts.URL, "Service2.GetGreeting", "Johnny", "33", "true"}, &out); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	expected := "\"Hello, user Johnny. You're 33 years old :-P And you has permit.\"\n42\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
@@ -5,10 +5,25 @@
 package xml
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
 )
 
+// ErrClientClosed is returned by calls made after the client was closed.
+var ErrClientClosed = errors.New("xmlrpc: client is closed")
+
+// EncodeHook post-processes an encoded XML document before it's sent, e.g. to
+// inject namespaces or vendor headers demanded by a peer.
+type EncodeHook func(method string, body []byte) ([]byte, error)
+
 // EncodeClientRequest encodes parameters for a XML-RPC client request.
 func EncodeClientRequest(method string, args interface{}) ([]byte, error) {
 	xml, err := rpcRequest2XML(method, args)
@@ -16,7 +31,7 @@ func EncodeClientRequest(method string, args interface{}) ([]byte, error) {
 }
 
 // DecodeClientResponse decodes the response body of a client request into
-// the interface reply.
+// the interface reply. A nil reply only checks the response for a fault.
 func DecodeClientResponse(r io.Reader, reply interface{}) error {
 	rawxml, err := ioutil.ReadAll(r)
 	if err != nil {
@@ -24,3 +39,429 @@ func DecodeClientResponse(r io.Reader, reply interface{}) error {
 	}
 	return xml2RPC(string(rawxml), reply)
 }
+
+// DecodeResponseBody is like DecodeClientResponse, but body, e.g. the Body of
+// an http.Response, is closed in any case, and a response larger than
+// maxSize bytes, if positive, fails with ErrResponseTooLarge:
+//
+//	resp, err := http.Post(url, "text/xml", bytes.NewReader(request))
+//	if err != nil {
+//		return err
+//	}
+//	return xml.DecodeResponseBody(resp.Body, &reply, 8<<20)
+func DecodeResponseBody(body io.ReadCloser, reply interface{}, maxSize int64) error {
+	rawxml, err := readBody(body, maxSize)
+	if err == ErrResponseTooLarge {
+		return err
+	}
+	if err != nil {
+		return FaultSystemError
+	}
+	return xml2RPC(string(rawxml), reply)
+}
+
+// readBody reads and closes body, failing with ErrResponseTooLarge beyond
+// maxSize bytes if positive.
+func readBody(body io.ReadCloser, maxSize int64) ([]byte, error) {
+	defer body.Close()
+	return ioutil.ReadAll(limitSize(body, maxSize))
+}
+
+// sizeLimiter fails the reads beyond max bytes with ErrResponseTooLarge.
+type sizeLimiter struct {
+	r      io.Reader
+	n, max int64
+}
+
+// limitSize returns r failing beyond maxSize bytes, or r if maxSize isn't
+// positive.
+func limitSize(r io.Reader, maxSize int64) io.Reader {
+	if maxSize <= 0 {
+		return r
+	}
+	return &sizeLimiter{r: r, max: maxSize}
+}
+
+func (l *sizeLimiter) Read(p []byte) (int, error) {
+	if l.n > l.max {
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > l.max-l.n+1 {
+		// read a byte more than allowed to tell the excess
+		p = p[:l.max-l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.n > l.max {
+		return n, ErrResponseTooLarge
+	}
+	return n, err
+}
+
+// Client performs XML-RPC calls against a single endpoint.
+type Client struct {
+	// URL is the endpoint, e.g. "http://localhost:1234/RPC2".
+	URL string
+
+	// HTTPClient performs the requests; http.DefaultClient is used if nil.
+	HTTPClient *http.Client
+
+	// EncodeHook, if set, is applied to every encoded request.
+	EncodeHook EncodeHook
+
+	// PropagateDeadline makes calls send the time left until the deadline of
+	// their context in the X-Xmlrpc-Timeout header (see PropagateDeadline).
+	PropagateDeadline bool
+
+	// Options tune the encoding of args and the decoding of replies.
+	Options Options
+
+	// Envelope, if set, wraps every request and unwraps every response.
+	Envelope Envelope
+
+	// Charset, if set, is the charset the requests are sent in, e.g.
+	// "ISO-8859-1" for legacy servers accepting nothing else. It's declared
+	// in the XML declaration and the Content-Type header. By default the
+	// requests are sent in UTF-8.
+	Charset string
+
+	// Debug, if set, is called with every request sent and the response
+	// received, e.g. to log them.
+	Debug func(method string, request, response []byte)
+
+	// Redaction, if set, conceals members of the documents passed to Debug.
+	Redaction *Redaction
+
+	// AssertReplies makes the calls check their response against the reply
+	// before decoding it, failing with a SchemaError when it doesn't fit
+	// (see WithReplySchema).
+	AssertReplies bool
+
+	// MaxResponseSize, if positive, caps the size in bytes of the responses:
+	// the calls receiving larger ones fail with ErrResponseTooLarge.
+	MaxResponseSize int64
+
+	// Spill, if set, bounds the memory buffering the responses read by
+	// CallReader.
+	Spill *Spill
+
+	// SlowCalls, if set, logs the slow calls.
+	SlowCalls *SlowCalls
+
+	// Hedge, if set, duplicates the slow idempotent calls to a second
+	// endpoint (see Hedge).
+	Hedge *Hedge
+
+	// Coalesce are the patterns of the read methods, as for path.Match, whose
+	// identical concurrent calls share a single request and its response,
+	// e.g. the calls of a dashboard fanning out. The calls are identical if
+	// their params are, whatever the order of the struct members. The shared
+	// request carries the values of the context of the first call, but not
+	// its deadline.
+	Coalesce []string
+
+	// Cache, if set, serves the calls of some methods from a cache (see
+	// ResponseCache).
+	Cache *ResponseCache
+
+	// BinarySidecar makes the calls accept the responses whose large base64
+	// values travel as binary parts (see SidecarContentType), a third
+	// smaller, from the servers supporting them; they are decoded as usual.
+	BinarySidecar bool
+
+	mu       sync.Mutex
+	closed   bool
+	lastCall uint64
+	cancels  map[uint64]context.CancelFunc
+	inflight sync.WaitGroup
+	stats    clientStats
+	flights  map[string]*flight
+
+	transformers []transformer
+}
+
+// NewClient returns a Client for the endpoint url.
+func NewClient(url string) *Client {
+	return &Client{URL: url}
+}
+
+// Call invokes method with args and decodes the response into reply.
+//
+// args and reply are pointers to structs, whose fields are the params. reply
+// may be nil for void methods.
+func (c *Client) Call(method string, args, reply interface{}) error {
+	return c.CallContext(context.Background(), method, args, reply)
+}
+
+// CallContext is like Call, but the call is cancelled when ctx is done.
+func (c *Client) CallContext(ctx context.Context, method string, args, reply interface{}) error {
+	body, err := encodeRequest(method, args, &c.Options)
+	if err != nil {
+		return err
+	}
+	resp, err := c.post(ctx, method, []byte(body))
+	if err != nil {
+		return err
+	}
+	return c.decodeReply(ctx, method, resp, reply)
+}
+
+// CallValues invokes method with params given as value trees and returns the
+// response params.
+func (c *Client) CallValues(method string, params ...Value) ([]Value, error) {
+	return c.CallValuesContext(context.Background(), method, params...)
+}
+
+// CallValuesContext is like CallValues, but the call is cancelled when ctx is
+// done.
+func (c *Client) CallValuesContext(ctx context.Context, method string, params ...Value) ([]Value, error) {
+	resp, err := c.post(ctx, method, EncodeMethodCall(method, params))
+	if err != nil {
+		return nil, err
+	}
+	params, err = ParseMethodResponse(resp)
+	c.stats.fault(err)
+	if err != nil {
+		return params, err
+	}
+	return c.transformParams(method, params), nil
+}
+
+// Close cancels the calls in progress, closes the idle connections of the
+// HTTPClient's transport and makes further calls fail with ErrClientClosed.
+// The shared http.DefaultClient and http.DefaultTransport are left open.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	c.cancelAll()
+	c.inflight.Wait()
+	c.closeIdleConnections()
+	return nil
+}
+
+// Shutdown is like Close, but it first waits for the calls in progress to
+// finish until ctx is done; the remaining ones are then cancelled and
+// ctx.Err() is returned. No new calls are accepted meanwhile.
+func (c *Client) Shutdown(ctx context.Context) error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	idle := make(chan struct{})
+	go func() {
+		c.inflight.Wait()
+		close(idle)
+	}()
+
+	var err error
+	select {
+	case <-idle:
+	case <-ctx.Done():
+		err = ctx.Err()
+		c.cancelAll()
+		<-idle
+	}
+	c.closeIdleConnections()
+	return err
+}
+
+// closeIdleConnections closes the idle connections of the transport of
+// HTTPClient, unless it's shared by the whole process.
+func (c *Client) closeIdleConnections() {
+	if c.HTTPClient == nil || c.HTTPClient == http.DefaultClient {
+		return
+	}
+	if t := c.HTTPClient.Transport; t == nil || t == http.DefaultTransport {
+		return
+	}
+	c.HTTPClient.CloseIdleConnections()
+}
+
+func (c *Client) cancelAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cancel := range c.cancels {
+		cancel()
+	}
+}
+
+// begin registers a call in progress, returning its context and the function
+// to call when it's over.
+func (c *Client) begin(ctx context.Context) (context.Context, func(), error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, nil, ErrClientClosed
+	}
+	if c.cancels == nil {
+		c.cancels = make(map[uint64]context.CancelFunc)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	c.lastCall++
+	id := c.lastCall
+	c.cancels[id] = cancel
+	c.inflight.Add(1)
+
+	return ctx, func() {
+		c.mu.Lock()
+		delete(c.cancels, id)
+		c.mu.Unlock()
+		cancel()
+		c.inflight.Done()
+	}, nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
+
+// post sends the encoded request body and returns the response body.
+func (c *Client) post(ctx context.Context, method string, body []byte) ([]byte, error) {
+	if c.Cache.applies(method) {
+		return c.cached(ctx, method, body)
+	}
+	return c.fetch(ctx, method, body)
+}
+
+// fetch sends the encoded request body, unless an identical call is in
+// progress, and returns the response body.
+func (c *Client) fetch(ctx context.Context, method string, body []byte) ([]byte, error) {
+	if c.coalesces(method) {
+		return c.coalesced(ctx, method, body)
+	}
+	return c.roundTrip(ctx, method, body)
+}
+
+// roundTrip sends the encoded request body and returns the response body.
+func (c *Client) roundTrip(ctx context.Context, method string, body []byte) ([]byte, error) {
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	document, body, contentType, err := c.prepare(method, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var respBody []byte
+	start := time.Now()
+	if c.Hedge.applies(ctx, method) {
+		respBody, err = c.hedged(ctx, contentType, body)
+	} else {
+		respBody, err = c.send(ctx, c.URL, contentType, body)
+	}
+	c.observe(method, document, len(body), int64(len(respBody)), time.Since(start), err)
+	if err == nil && c.Envelope != nil {
+		respBody, err = c.Envelope.Unwrap(respBody)
+	}
+	if err == nil && c.Debug != nil {
+		c.debug(method, document, respBody)
+	}
+	return respBody, err
+}
+
+// prepare applies the EncodeHook, the Envelope and the Charset to the
+// encoded request document, returning the document as encoded, the request
+// body and its content type.
+func (c *Client) prepare(method string, document []byte) ([]byte, []byte, string, error) {
+	var err error
+	if c.EncodeHook != nil {
+		if document, err = c.EncodeHook(method, document); err != nil {
+			return nil, nil, "", err
+		}
+	}
+	// the request is debugged as encoded, before the envelope and charset
+	body := document
+	if c.Envelope != nil {
+		if body, err = c.Envelope.Wrap(body); err != nil {
+			return nil, nil, "", err
+		}
+	}
+	contentType := "text/xml"
+	if c.Charset != "" {
+		if body, err = encodeCharset(body, c.Charset, &c.Options); err != nil {
+			return nil, nil, "", err
+		}
+		contentType += "; charset=" + c.Charset
+	}
+	return document, body, contentType, nil
+}
+
+// observe counts a call, sending out bytes and receiving in bytes, and logs
+// it if it's slow.
+func (c *Client) observe(method string, document []byte, out int, in int64, elapsed time.Duration, err error) {
+	c.stats.call(out, in, elapsed, err)
+	if c.SlowCalls.slow(elapsed) {
+		c.SlowCalls.log(method, c.URL, "", bytes.Count(document, []byte("<param>")), len(document), elapsed)
+	}
+}
+
+// send posts the request body to url and returns the response body.
+func (c *Client) send(ctx context.Context, url, contentType string, body []byte) ([]byte, error) {
+	resp, err := c.do(ctx, url, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readBody(resp.Body, c.MaxResponseSize)
+	if err != nil {
+		return nil, err
+	}
+	return inlineSidecar(data, resp.Header.Get("Content-Type"))
+}
+
+// do posts the request body to url and returns the successful response,
+// whose body must be closed.
+func (c *Client) do(ctx context.Context, url, contentType string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if !info.Reused {
+				c.stats.conn()
+			}
+		},
+	}))
+	req.Header.Set("Content-Type", contentType)
+	if c.BinarySidecar {
+		req.Header.Set("Accept", SidecarContentType+", text/xml")
+	}
+	if key, ok := ctx.Value(idempotencyKeyContextKey).(string); ok {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	if c.PropagateDeadline {
+		setTimeoutHeader(ctx, req)
+	}
+	setDepthHeader(ctx, req)
+	if id, ok := CorrelationIDFrom(ctx); ok {
+		req.Header.Set(CorrelationIDHeader, id)
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("xmlrpc: %s returned %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+// debug passes the request and response documents to Debug, redacted.
+func (c *Client) debug(method string, request, response []byte) {
+	if c.Redaction != nil {
+		request = c.Redaction.RedactDocument(method, request)
+		response = c.Redaction.RedactDocument(method, response)
+	}
+	c.Debug(method, request, response)
+}
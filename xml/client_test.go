@@ -0,0 +1,282 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+func newTestServer(codec *Codec) *httptest.Server {
+	s := rpc.NewServer()
+	s.RegisterCodec(codec, "text/xml")
+	s.RegisterService(new(Service1), "")
+	s.RegisterService(new(Service2), "")
+	return httptest.NewServer(s)
+}
+
+func TestClientCall(t *testing.T) {
+	ts := newTestServer(NewCodec())
+	defer ts.Close()
+
+	var res Service1Response
+	if err := NewClient(ts.URL).Call("Service1.Multiply", &Service1Request{4, 2}, &res); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if res.Result != 8 {
+		t.Errorf("Wrong response: %v.", res.Result)
+	}
+}
+
+func TestCodecIgnoreCase(t *testing.T) {
+	codec := NewCodec()
+	codec.Options.IgnoreCase = true
+	codec.Options.IgnoreNamespaces = true
+	ts := newTestServer(codec)
+	defer ts.Close()
+
+	body := `<ns:MethodCall xmlns:ns="urn:x"><ns:MethodName>Service1.Multiply</ns:MethodName><Params>` +
+		`<Param><Value><Int>4</Int></Value></Param><Param><Value><Int>2</Int></Value></Param></Params></ns:MethodCall>`
+	resp, err := http.Post(ts.URL, "text/xml", strings.NewReader(body))
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal("Expected 200, got", resp.Status)
+	}
+	var res Service1Response
+	if err := DecodeClientResponse(resp.Body, &res); err != nil || res.Result != 8 {
+		t.Error("Expected 8, got", res.Result, err)
+	}
+}
+
+func TestEncodeHooks(t *testing.T) {
+	codec := NewCodec()
+	var serverMethod string
+	codec.EncodeHook = func(method string, body []byte) ([]byte, error) {
+		serverMethod = method
+		return bytes.Replace(body, []byte("<int>8</int>"), []byte("<int>9</int>"), 1), nil
+	}
+	ts := newTestServer(codec)
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	client.EncodeHook = func(method string, body []byte) ([]byte, error) {
+		return bytes.Replace(body, []byte(method), []byte("Service1.Multiply"), 1), nil
+	}
+
+	var res Service1Response
+	if err := client.Call("Alias.Multiply", &Service1Request{4, 2}, &res); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if serverMethod != "Service1.Multiply" {
+		t.Errorf("wrong method passed to server hook: %q", serverMethod)
+	}
+	if res.Result != 9 {
+		t.Errorf("Wrong response: %v.", res.Result)
+	}
+}
+
+func TestNamedParams(t *testing.T) {
+	codec := NewCodec()
+	codec.Options.NamedParams = true
+	ts := newTestServer(codec)
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	client.Options.NamedParams = true
+	var body string
+	client.EncodeHook = func(method string, b []byte) ([]byte, error) {
+		body = string(b)
+		return b, nil
+	}
+
+	var res Service2Response
+	if err := client.Call("Service2.GetGreeting", &Service2Request{"Johnny", 33, true}, &res); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	expected := "<methodCall><methodName>Service2.GetGreeting</methodName><params><param><value><struct>" +
+		"<member><name>Name</name><value><string>Johnny</string></value></member>" +
+		"<member><name>Age</name><value><int>33</int></value></member>" +
+		"<member><name>HasPermit</name><value><boolean>1</boolean></value></member>" +
+		"</struct></value></param></params></methodCall>"
+	if body != expected {
+		t.Error("Expected", expected, "got", body)
+	}
+	if res.Message != "Hello, user Johnny. You're 33 years old :-P And you has permit." || res.Status != 42 {
+		t.Errorf("Wrong response: %+v.", res)
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	received := make(chan struct{})
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		w.Write([]byte("<methodResponse><params><param><value><int>8</int></value></param></params></methodResponse>"))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	errs := make(chan error)
+	go func() {
+		var res Service1Response
+		errs <- client.Call("Service1.Multiply", &Service1Request{4, 2}, &res)
+	}()
+	<-received
+	if err := client.Close(); err != nil {
+		t.Fatal("Close failed", err)
+	}
+	if err := <-errs; err == nil {
+		t.Error("expected the call in progress to be cancelled")
+	}
+	var res Service1Response
+	if err := client.Call("Service1.Multiply", &Service1Request{4, 2}, &res); err != ErrClientClosed {
+		t.Errorf("expected ErrClientClosed, got %v", err)
+	}
+
+	client = NewClient(ts.URL)
+	go func() {
+		var res Service1Response
+		errs <- client.Call("Service1.Multiply", &Service1Request{4, 2}, &res)
+	}()
+	<-received
+	shutdown := make(chan error)
+	go func() {
+		shutdown <- client.Shutdown(context.Background())
+	}()
+	close(release)
+	if err := <-errs; err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if err := <-shutdown; err != nil {
+		t.Error("Shutdown failed", err)
+	}
+}
+
+// idleCounter counts the calls to CloseIdleConnections.
+type idleCounter struct {
+	http.RoundTripper
+	closed int32
+}
+
+func (c *idleCounter) CloseIdleConnections() {
+	atomic.AddInt32(&c.closed, 1)
+}
+
+func TestClientCloseTransport(t *testing.T) {
+	var conns int32
+	s := rpc.NewServer()
+	s.RegisterCodec(NewCodec(), "text/xml")
+	s.RegisterService(new(Service1), "")
+	ts := httptest.NewUnstartedServer(s)
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	// the connections of http.DefaultClient are left open
+	var res Service1Response
+	client := NewClient(ts.URL)
+	if err := client.Call("Service1.Multiply", &Service1Request{4, 2}, &res); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	client.Close()
+	if err := NewClient(ts.URL).Call("Service1.Multiply", &Service1Request{4, 2}, &res); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Error("Expected the idle connection reused, got", n, "connections")
+	}
+
+	owned := &idleCounter{RoundTripper: http.DefaultTransport}
+	client = NewClient(ts.URL)
+	client.HTTPClient = &http.Client{Transport: owned}
+	client.Close()
+	if atomic.LoadInt32(&owned.closed) != 1 {
+		t.Error("Expected the idle connections of the transport closed")
+	}
+}
+
+func TestMaxEncodedSize(t *testing.T) {
+	codec := NewCodec()
+	codec.Options.MaxEncodedSize = 100
+	ts := newTestServer(codec)
+	defer ts.Close()
+
+	var res Service2Response
+	err := NewClient(ts.URL).Call("Service2.GetGreeting", &Service2Request{Name: strings.Repeat("x", 100)}, &res)
+	if fault, ok := err.(Fault); !ok || fault.Code != FaultInternalError.Code {
+		t.Error("Expected internal error fault, got", err)
+	}
+}
+
+type closeRecorder struct {
+	*strings.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestDecodeResponseBody(t *testing.T) {
+	resp := "<methodResponse><params><param><value><int>8</int></value></param></params></methodResponse>"
+
+	body := &closeRecorder{Reader: strings.NewReader(resp)}
+	var res Service1Response
+	if err := DecodeResponseBody(body, &res, 0); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if res.Result != 8 || !body.closed {
+		t.Errorf("Expected 8 decoded and the body closed, got %v, %v", res.Result, body.closed)
+	}
+
+	body = &closeRecorder{Reader: strings.NewReader(resp)}
+	if err := DecodeResponseBody(body, &res, int64(len(resp))); err != nil {
+		t.Error("Expected a response of the max size accepted, got", err)
+	}
+	body = &closeRecorder{Reader: strings.NewReader(resp)}
+	if err := DecodeResponseBody(body, &res, int64(len(resp)-1)); err != ErrResponseTooLarge || !body.closed {
+		t.Errorf("Expected ErrResponseTooLarge and the body closed, got %v, %v", err, body.closed)
+	}
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	ts := newTestServer(NewCodec())
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	client.MaxResponseSize = 50
+	var res Service1Response
+	if err := client.Call("Service1.Multiply", &Service1Request{4, 2}, &res); err != ErrResponseTooLarge {
+		t.Error("Expected ErrResponseTooLarge, got", err)
+	}
+	client.Spill = &Spill{Threshold: 10}
+	err := client.CallReader(context.Background(), "Service1.Multiply", &Service1Request{4, 2}, func(r io.Reader) error {
+		return nil
+	})
+	if err != ErrResponseTooLarge {
+		t.Error("Expected ErrResponseTooLarge reading, got", err)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"path"
+	"sort"
+	"time"
+)
+
+// flight is a request in progress shared by identical concurrent calls.
+type flight struct {
+	done    chan struct{}
+	body    []byte
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// coalesces reports whether the calls of method are coalesced.
+func (c *Client) coalesces(method string) bool {
+	for _, pattern := range c.Coalesce {
+		if ok, _ := path.Match(pattern, method); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// coalesced posts the request document of method like post, but the
+// identical calls in progress share the request and its response. The
+// request is cancelled once every call sharing it is.
+func (c *Client) coalesced(ctx context.Context, method string, document []byte) ([]byte, error) {
+	key := method + "\x00" + string(canonicalCall(document))
+
+	c.mu.Lock()
+	if c.flights == nil {
+		c.flights = make(map[string]*flight)
+	}
+	f, ok := c.flights[key]
+	if !ok {
+		// the request outlives the context of the call starting it, but keeps
+		// its values, e.g. the idempotency key
+		fctx, cancel := context.WithCancel(detachedContext{ctx})
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		c.flights[key] = f
+		go func() {
+			f.body, f.err = c.roundTrip(fctx, method, document)
+			c.mu.Lock()
+			if c.flights[key] == f {
+				delete(c.flights, key)
+			}
+			c.mu.Unlock()
+			cancel()
+			close(f.done)
+		}()
+	} else {
+		c.stats.coalesce()
+	}
+	f.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.body, f.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		if f.waiters--; f.waiters == 0 {
+			// the identical calls from now on start a new request instead of
+			// joining the cancelled one
+			if c.flights[key] == f {
+				delete(c.flights, key)
+			}
+			f.cancel()
+		}
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// canonicalCall returns the methodCall document with the members of its
+// structs sorted by name, so the calls whose params only differ by the
+// order of the members are coalesced. The document is returned as is if it
+// can't be parsed.
+func canonicalCall(document []byte) []byte {
+	method, params, err := ParseMethodCall(document)
+	if err != nil {
+		return document
+	}
+	for i := range params {
+		params[i] = canonicalValue(params[i])
+	}
+	return EncodeMethodCall(method, params)
+}
+
+func canonicalValue(v Value) Value {
+	switch v.Kind {
+	case "i4":
+		v.Kind = KindInt
+	case KindStruct:
+		members := make([]Member, len(v.Members))
+		for i, m := range v.Members {
+			members[i] = Member{m.Name, canonicalValue(m.Value)}
+		}
+		sort.SliceStable(members, func(i, j int) bool { return members[i].Name < members[j].Name })
+		v.Members = members
+	case KindArray:
+		items := make([]Value, len(v.Items))
+		for i, item := range v.Items {
+			items[i] = canonicalValue(item)
+		}
+		v.Items = items
+	}
+	return v
+}
+
+// detachedContext keeps the values of a context, but not its cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
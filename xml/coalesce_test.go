@@ -0,0 +1,151 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientCoalesce(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		atomic.AddInt32(&requests, 1)
+		<-release
+		return []Value{NewString(method)}, nil
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL)
+	client.Coalesce = []string{"get*"}
+
+	waitCoalesced := func(n int) {
+		for deadline := time.Now().Add(5 * time.Second); client.Stats().Coalesced < n; {
+			if time.Now().After(deadline) {
+				t.Fatal("Expected", n, "calls to be coalesced, got", client.Stats().Coalesced)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	args := []Value{
+		NewStruct(Member{"a", NewInt(1)}, Member{"b", NewString("x")}),
+		NewStruct(Member{"b", NewString("x")}, Member{"a", NewInt(1)}),
+		NewStruct(Member{"a", NewInt(1)}, Member{"b", NewString("x")}),
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(args))
+	results := make([][]Value, len(args))
+	ctx, cancel := context.WithCancel(context.Background())
+	for i, arg := range args {
+		callCtx := context.Background()
+		if i == 0 {
+			// the first call leaving doesn't cancel the others
+			callCtx = ctx
+		}
+		wg.Add(1)
+		go func(i int, ctx context.Context, arg Value) {
+			defer wg.Done()
+			results[i], errs[i] = client.CallValuesContext(ctx, "getThing", arg)
+		}(i, callCtx, arg)
+		if i == 0 {
+			for atomic.LoadInt32(&requests) == 0 {
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}
+	waitCoalesced(len(args) - 1)
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Error("Expected a single request, got", n)
+	}
+	if errs[0] != context.Canceled {
+		t.Error("Expected the cancelled call to fail, got:", errs[0])
+	}
+	for i := 1; i < len(args); i++ {
+		if errs[i] != nil || len(results[i]) != 1 || results[i][0].Text != "getThing" {
+			t.Errorf("Expected call %d to share the response, got %v, %v", i, results[i], errs[i])
+		}
+	}
+
+	atomic.StoreInt32(&requests, 0)
+	var wg2 sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg2.Add(1)
+		go func() {
+			defer wg2.Done()
+			if _, err := client.CallValues("putThing", NewInt(1)); err != nil {
+				t.Error("Expected err to be nil, but got:", err)
+			}
+		}()
+	}
+	wg2.Wait()
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Error("Expected the other methods not to be coalesced, got", n, "requests")
+	}
+}
+
+func TestClientCoalesceCancelled(t *testing.T) {
+	var requests int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			<-release
+		}
+		return []Value{NewString(method)}, nil
+	}))
+	defer ts.Close()
+	defer close(release)
+	client := NewClient(ts.URL)
+	client.Coalesce = []string{"get*"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := client.CallValuesContext(ctx, "getThing", NewInt(1))
+		done <- err
+	}()
+	for atomic.LoadInt32(&requests) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Error("Expected the cancelled call to fail, got:", err)
+	}
+
+	client.mu.Lock()
+	flights := len(client.flights)
+	client.mu.Unlock()
+	if flights != 0 {
+		t.Error("Expected the cancelled flight to be forgotten, got", flights)
+	}
+	results, err := client.CallValues("getThing", NewInt(1))
+	if err != nil || len(results) != 1 || results[0].Text != "getThing" {
+		t.Error("Expected a new request after the cancelled one, got", results, err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Error("Expected 2 requests, got", n)
+	}
+}
+
+func TestCanonicalCall(t *testing.T) {
+	a := EncodeMethodCall("m", []Value{NewArray(NewStruct(Member{"b", NewInt(2)}, Member{"a", NewInt(1)}))})
+	b := EncodeMethodCall("m", []Value{NewArray(NewStruct(Member{"a", Value{Kind: "i4", Text: "1"}}, Member{"b", NewInt(2)}))})
+	if string(canonicalCall(a)) != string(canonicalCall(b)) {
+		t.Errorf("Expected the calls to be alike, got %s and %s", canonicalCall(a), canonicalCall(b))
+	}
+	if c := []byte("<bad"); string(canonicalCall(c)) != "<bad" {
+		t.Error("Expected a malformed call to be kept as is")
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// generateDirective marks the structs given methods by GenerateCodecs.
+const generateDirective = "//xmlrpc:generate"
+
+const importPath = "github.com/AlexStocks/gorilla-xmlrpc/xml"
+
+// GenerateCodecs returns the source of the XMLRPCEncode and XMLRPCDecode
+// methods of the structs of the Go source src whose doc comment holds the
+// line
+//
+//	//xmlrpc:generate
+//
+// so their values are encoded and decoded without reflection, see
+// StaticEncoder and StaticDecoder. The members are named as by the
+// reflective codec; unknown and <nil/> members are skipped on decoding.
+//
+// The fields may be of types int, int8 to int64, float64, string, bool,
+// time.Time, []byte, xml.Value, of the structs generated in src, and of
+// slices of them. Other fields, and the xmlrpc tag options, are reported
+// as errors. cmd/xmlrpc-gen writes the methods of a file next to it.
+func GenerateCodecs(filename string, src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &codegen{imports: make(map[string]string), generated: make(map[string]bool)}
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		g.imports[name] = path
+	}
+
+	var specs []*ast.TypeSpec
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, s := range gen.Specs {
+			ts := s.(*ast.TypeSpec)
+			if _, ok := ts.Type.(*ast.StructType); !ok {
+				continue
+			}
+			if hasDirective(gen.Doc) || hasDirective(ts.Doc) {
+				specs = append(specs, ts)
+				g.generated[ts.Name.Name] = true
+			}
+		}
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("%s: no struct marked %s", filename, generateDirective)
+	}
+
+	fmt.Fprintf(&g.buf, "// Code generated by xmlrpc-gen from %s. DO NOT EDIT.\n\n", filepath.Base(filename))
+	fmt.Fprintf(&g.buf, "package %s\n\nimport %q\n", file.Name.Name, importPath)
+	for _, ts := range specs {
+		if err := g.structMethods(ts); err != nil {
+			return nil, fmt.Errorf("%s: %s", fset.Position(ts.Pos()), err)
+		}
+	}
+	return format.Source(g.buf.Bytes())
+}
+
+func hasDirective(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == generateDirective {
+			return true
+		}
+	}
+	return false
+}
+
+type codegen struct {
+	buf bytes.Buffer
+
+	// imports maps the import names of the file to their paths.
+	imports map[string]string
+
+	// generated holds the names of the structs given methods.
+	generated map[string]bool
+}
+
+func (g *codegen) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// genField is a field of a generated struct.
+type genField struct {
+	name   string // of the Go field
+	member string // of the struct member
+	typ    ast.Expr
+}
+
+func (g *codegen) structMethods(ts *ast.TypeSpec) error {
+	var fields []genField
+	for _, f := range ts.Type.(*ast.StructType).Fields.List {
+		if len(f.Names) == 0 {
+			return fmt.Errorf("%s: embedded field %s not supported", ts.Name.Name, types.ExprString(f.Type))
+		}
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			text, _ := strconv.Unquote(f.Tag.Value)
+			tag = reflect.StructTag(text)
+		}
+		if _, opts := parseTag(reflect.StructField{Tag: tag}); opts != "" {
+			return fmt.Errorf("%s: xmlrpc tag options %q not supported", ts.Name.Name, opts)
+		}
+		for _, name := range f.Names {
+			if !name.IsExported() {
+				continue
+			}
+			member := name.Name
+			if explicit := explicitName(reflect.StructField{Tag: tag}); explicit != "" {
+				member = explicit
+			}
+			if !g.supported(f.Type) {
+				return fmt.Errorf("%s.%s: type %s not supported", ts.Name.Name, name.Name, types.ExprString(f.Type))
+			}
+			fields = append(fields, genField{name.Name, member, f.Type})
+		}
+	}
+
+	recv := ts.Name.Name
+	g.printf("\n// XMLRPCEncode implements xml.StaticEncoder.\n")
+	g.printf("func (x %s) XMLRPCEncode(b []byte) ([]byte, error) {\n", recv)
+	for _, f := range fields {
+		if g.encodesGenerated(f.typ) {
+			g.printf("var err error\n")
+			break
+		}
+	}
+	g.printf("b = append(b, \"<value><struct>\"...)\n")
+	for _, f := range fields {
+		g.printf("b = append(b, %q...)\n", "<member><name>"+escapeString(f.member)+"</name>")
+		g.encode(f.typ, "x."+f.name, 0)
+		g.printf("b = append(b, \"</member>\"...)\n")
+	}
+	g.printf("return append(b, \"</struct></value>\"...), nil\n}\n")
+
+	g.printf("\n// XMLRPCDecode implements xml.StaticDecoder.\n")
+	g.printf("func (x *%s) XMLRPCDecode(v xml.Value) error {\n", recv)
+	g.printf("members, err := xml.DecodeStruct(v)\nif err != nil {\nreturn err\n}\n")
+	g.printf("for _, m := range members {\nif m.Value.Kind == xml.KindNil {\ncontinue\n}\nswitch m.Name {\n")
+	for _, f := range fields {
+		// the reflective codec matches the member names with the first
+		// letter uppercased
+		cases := []string{strconv.Quote(f.member)}
+		if lower := strings.ToLower(f.name[:1]) + f.name[1:]; lower != f.member {
+			cases = append(cases, strconv.Quote(lower))
+		}
+		if f.name != f.member {
+			cases = append(cases, strconv.Quote(f.name))
+		}
+		g.printf("case %s:\n", strings.Join(cases, ", "))
+		g.decode(f.typ, "x."+f.name, "m.Value", 0)
+	}
+	g.printf("}\n}\nreturn nil\n}\n")
+	return nil
+}
+
+// kind classifies the type expressions supported.
+func (g *codegen) kind(typ ast.Expr) string {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "int", "int8", "int16", "int32", "int64":
+			return "int"
+		case "float64", "string", "bool":
+			return t.Name
+		}
+		if g.generated[t.Name] {
+			return "generated"
+		}
+	case *ast.SelectorExpr:
+		if x, ok := t.X.(*ast.Ident); ok {
+			switch path := g.imports[x.Name]; {
+			case path == "time" && t.Sel.Name == "Time":
+				return "time"
+			case path == importPath && t.Sel.Name == "Value":
+				return "value"
+			}
+		}
+	case *ast.ArrayType:
+		if t.Len != nil {
+			return ""
+		}
+		if elem, ok := t.Elt.(*ast.Ident); ok && (elem.Name == "byte" || elem.Name == "uint8") {
+			return "bytes"
+		}
+		return "slice"
+	}
+	return ""
+}
+
+// encodesGenerated reports whether typ is, or is a slice of, a generated
+// struct.
+func (g *codegen) encodesGenerated(typ ast.Expr) bool {
+	switch g.kind(typ) {
+	case "generated":
+		return true
+	case "slice":
+		return g.encodesGenerated(typ.(*ast.ArrayType).Elt)
+	}
+	return false
+}
+
+func (g *codegen) supported(typ ast.Expr) bool {
+	switch g.kind(typ) {
+	case "":
+		return false
+	case "slice":
+		return g.supported(typ.(*ast.ArrayType).Elt)
+	}
+	return true
+}
+
+// encode prints the appending of the <value> of x, of type typ, to b.
+func (g *codegen) encode(typ ast.Expr, x string, depth int) {
+	switch g.kind(typ) {
+	case "int":
+		g.printf("b = xml.AppendInt(b, int64(%s))\n", x)
+	case "float64":
+		g.printf("b = xml.AppendDouble(b, %s)\n", x)
+	case "string":
+		g.printf("b = xml.AppendString(b, %s)\n", x)
+	case "bool":
+		g.printf("b = xml.AppendBoolean(b, %s)\n", x)
+	case "time":
+		g.printf("b = xml.AppendDateTime(b, %s)\n", x)
+	case "bytes":
+		g.printf("b = xml.AppendBase64(b, %s)\n", x)
+	case "value":
+		g.printf("b = xml.AppendValue(b, %s)\n", x)
+	case "generated":
+		g.printf("if b, err = %s.XMLRPCEncode(b); err != nil {\nreturn b, err\n}\n", x)
+	case "slice":
+		item := fmt.Sprintf("item%d", depth)
+		g.printf("b = append(b, \"<value><array><data>\"...)\n")
+		g.printf("for _, %s := range %s {\n", item, x)
+		g.encode(typ.(*ast.ArrayType).Elt, item, depth+1)
+		g.printf("}\nb = append(b, \"</data></array></value>\"...)\n")
+	}
+}
+
+// decode prints the decoding of the value v into x, of type typ.
+func (g *codegen) decode(typ ast.Expr, x, v string, depth int) {
+	simple := func(fn, conv string) {
+		g.printf("{\nd, err := xml.%s(%s)\nif err != nil {\nreturn err\n}\n", fn, v)
+		if conv != "" {
+			g.printf("%s = %s(d)\n}\n", x, conv)
+		} else {
+			g.printf("%s = d\n}\n", x)
+		}
+	}
+	switch g.kind(typ) {
+	case "int":
+		simple("DecodeInt", types.ExprString(typ))
+	case "float64":
+		simple("DecodeDouble", "")
+	case "string":
+		simple("DecodeString", "")
+	case "bool":
+		simple("DecodeBoolean", "")
+	case "time":
+		simple("DecodeDateTime", "")
+	case "bytes":
+		simple("DecodeBase64", "")
+	case "value":
+		g.printf("%s = %s\n", x, v)
+	case "generated":
+		g.printf("if err := %s.XMLRPCDecode(%s); err != nil {\nreturn err\n}\n", x, v)
+	case "slice":
+		items, i, item := fmt.Sprintf("items%d", depth), fmt.Sprintf("i%d", depth), fmt.Sprintf("item%d", depth)
+		g.printf("{\n%s, err := xml.DecodeArray(%s)\nif err != nil {\nreturn err\n}\n", items, v)
+		g.printf("%s = make(%s, len(%s))\n", x, types.ExprString(typ), items)
+		g.printf("for %s, %s := range %s {\n", i, item, items)
+		g.decode(typ.(*ast.ArrayType).Elt, fmt.Sprintf("%s[%s]", x, i), item, depth+1)
+		g.printf("}\n}\n")
+	}
+}
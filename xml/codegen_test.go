@@ -0,0 +1,46 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestGenerateCodecsGolden(t *testing.T) {
+	src, err := ioutil.ReadFile("static_fixture_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := GenerateCodecs("static_fixture_test.go", src)
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	golden, err := ioutil.ReadFile("static_fixture_xmlrpc_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != string(golden) {
+		t.Error("Expected the generated methods to match static_fixture_xmlrpc_test.go, regenerate it with xmlrpc-gen")
+	}
+}
+
+func TestGenerateCodecsErrors(t *testing.T) {
+	for _, tc := range []struct {
+		src, err string
+	}{
+		{"package p\ntype T struct{ A int }", "no struct marked"},
+		{"package p\n//xmlrpc:generate\ntype T struct{ A map[string]int }", "T.A: type map[string]int not supported"},
+		{"package p\n//xmlrpc:generate\ntype T struct{ A *int }", "T.A: type *int not supported"},
+		{"package p\n//xmlrpc:generate\ntype T struct{ A string `xmlrpc:\",required\"` }", `xmlrpc tag options "required" not supported`},
+		{"package p\n//xmlrpc:generate\ntype T struct{ U }\ntype U struct{}", "embedded field U not supported"},
+	} {
+		_, err := GenerateCodecs("p.go", []byte(tc.src))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("Expected error %q, got %v", tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TypeCodec is the compiled codec of a Go type: the encoders of its fields
+// and items, resolved once instead of on every value. The struct members
+// decode into the fields named alike first.
+type TypeCodec struct {
+	typ    reflect.Type
+	encode encoderFunc
+
+	// reflective tells the types left to value2XML.
+	reflective bool
+}
+
+// encoderFunc appends the <value> of v to b.
+type encoderFunc func(e *encodeState, b []byte, v reflect.Value) ([]byte, error)
+
+// compiledCodecs maps the compiled types to their *TypeCodec.
+var compiledCodecs sync.Map
+
+// Compile compiles the codec of the type of sample, e.g. Compile(Reply{}),
+// and registers it, so the values of the type are encoded and decoded with
+// it from then on, by the Codec, the Client and the package-level
+// functions, instead of by reflection at every step.
+//
+// The enums, custom scalars and tuples of the type are resolved at
+// compilation, so they must be registered first. The values of a compiled
+// type are encoded by reflection anyway when encode hooks or a max encoded
+// size are set.
+func Compile(sample interface{}) *TypeCodec {
+	compiling := make(map[reflect.Type]*TypeCodec)
+	c := compileType(reflect.TypeOf(sample), compiling)
+	// registered once complete, as the recursive types refer to each other
+	for typ, tc := range compiling {
+		compiledCodecs.Store(typ, tc)
+	}
+	return c
+}
+
+// Encode returns the <value> of v, which must be of the compiled type.
+func (c *TypeCodec) Encode(v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || rv.Type() != c.typ {
+		return nil, fmt.Errorf("xmlrpc: %T encoded by the codec of %s", v, c.typ)
+	}
+	return c.encode(newEncodeState(defaultOptions), nil, rv)
+}
+
+// encodeBuffers recycles the buffers of write.
+var encodeBuffers = sync.Pool{New: func() interface{} { return new([]byte) }}
+
+// write writes the <value> of v to writer.
+func (c *TypeCodec) write(e *encodeState, v interface{}, writer io.Writer) error {
+	buffer := encodeBuffers.Get().(*[]byte)
+	b, err := c.encode(e, (*buffer)[:0], reflect.ValueOf(v))
+	writer.Write(b)
+	*buffer = b
+	encodeBuffers.Put(buffer)
+	return err
+}
+
+// compiledCodec returns the registered codec of typ, or nil.
+func compiledCodec(typ reflect.Type) *TypeCodec {
+	if c, ok := compiledCodecs.Load(typ); ok {
+		return c.(*TypeCodec)
+	}
+	return nil
+}
+
+// compileType compiles typ; compiling holds the types being compiled, for
+// the recursive types.
+func compileType(typ reflect.Type, compiling map[reflect.Type]*TypeCodec) *TypeCodec {
+	if c := compiledCodec(typ); c != nil {
+		return c
+	}
+	if c, ok := compiling[typ]; ok {
+		return c
+	}
+	c := &TypeCodec{typ: typ}
+	compiling[typ] = c
+	if c.encode = c.compileEncoder(compiling); c.encode == nil {
+		c.encode, c.reflective = reflective, true
+	}
+	return c
+}
+
+// reflective is the encoder of the values left to value2XML.
+func reflective(e *encodeState, b []byte, v reflect.Value) ([]byte, error) {
+	buffer := bytes.NewBuffer(b)
+	err := e.value2XML(v.Interface(), buffer)
+	return buffer.Bytes(), err
+}
+
+// compileEncoder returns the encoder of the type of c, or nil if its values
+// are left to value2XML.
+func (c *TypeCodec) compileEncoder(compiling map[reflect.Type]*TypeCodec) encoderFunc {
+	typ := c.typ
+	if enumByType(typ) != nil || scalarByType(typ) != nil || typ == valueType ||
+		typ == bigIntType || typ == bigFloatType || isTextType(typ) ||
+		typ.Implements(reflect.TypeOf((*XMLStruct)(nil)).Elem()) {
+		return nil
+	}
+
+	// the types of the kinds; value2XML encodes the named types of the
+	// kinds the same way, if at all
+	switch typ {
+	case reflect.TypeOf(0):
+		return func(e *encodeState, b []byte, v reflect.Value) ([]byte, error) {
+			b = append(b, "<value><int>"...)
+			b = strconv.AppendInt(b, v.Int(), 10)
+			return append(b, "</int></value>"...), nil
+		}
+	case reflect.TypeOf(0.0):
+		return func(e *encodeState, b []byte, v reflect.Value) ([]byte, error) {
+			b = append(b, "<value><double>"...)
+			// as %f
+			b = strconv.AppendFloat(b, v.Float(), 'f', 6, 64)
+			return append(b, "</double></value>"...), nil
+		}
+	case reflect.TypeOf(""):
+		return func(e *encodeState, b []byte, v reflect.Value) ([]byte, error) {
+			b = append(b, "<value><string>"...)
+			b = appendEscaped(b, v.String())
+			return append(b, "</string></value>"...), nil
+		}
+	case reflect.TypeOf(false):
+		return func(e *encodeState, b []byte, v reflect.Value) ([]byte, error) {
+			if v.Bool() {
+				return append(b, "<value><boolean>1</boolean></value>"...), nil
+			}
+			return append(b, "<value><boolean>0</boolean></value>"...), nil
+		}
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		if typ == timeType {
+			return appendTime
+		}
+		return c.compileStruct(compiling)
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return nil
+		}
+		elem := compileType(typ.Elem(), compiling)
+		return func(e *encodeState, b []byte, v reflect.Value) ([]byte, error) {
+			if v.Len() != 0 {
+				if err := e.enter(v); err != nil {
+					return b, err
+				}
+				defer e.leave(v)
+			}
+			return appendItems(e, b, v, elem)
+		}
+	case reflect.Array:
+		elem := compileType(typ.Elem(), compiling)
+		return func(e *encodeState, b []byte, v reflect.Value) ([]byte, error) {
+			return appendItems(e, b, v, elem)
+		}
+	}
+	return nil
+}
+
+// appendItems appends the <array> of the items of v.
+func appendItems(e *encodeState, b []byte, v reflect.Value, elem *TypeCodec) ([]byte, error) {
+	var err error
+	b = append(b, "<value><array><data>"...)
+	for i := 0; i < v.Len(); i++ {
+		var ierr error
+		if b, ierr = elem.encode(e, b, v.Index(i)); err == nil {
+			err = ierr
+		}
+	}
+	return append(b, "</data></array></value>"...), err
+}
+
+// compiledMember is the encoder of a struct field.
+type compiledMember struct {
+	index  int
+	prefix []byte // <member><name>...</name>
+	encode encoderFunc
+}
+
+func (c *TypeCodec) compileStruct(compiling map[reflect.Type]*TypeCodec) encoderFunc {
+	var members []compiledMember
+	for i := 0; i < c.typ.NumField(); i++ {
+		sf := c.typ.Field(i)
+		if sf.PkgPath != "" || isSkipped(sf) {
+			// unexported or skipped field
+			continue
+		}
+		if skipEncode(sf) {
+			continue
+		}
+
+		name := sf.Name
+		if explicitName(sf) != "" {
+			name = explicitName(sf)
+		}
+		m := compiledMember{index: i, prefix: []byte("<member><name>" + name + "</name>")}
+		if names := enumNames(sf); names != nil {
+			m.encode = func(e *encodeState, b []byte, v reflect.Value) ([]byte, error) {
+				buffer := bytes.NewBuffer(b)
+				err := enum2XML(names, v, buffer)
+				return buffer.Bytes(), err
+			}
+		} else if isTuple(sf) {
+			m.encode = func(e *encodeState, b []byte, v reflect.Value) ([]byte, error) {
+				buffer := bytes.NewBuffer(b)
+				err := e.tuple2XML(v, buffer)
+				return buffer.Bytes(), err
+			}
+		} else if isBinaryString(sf) {
+			m.encode = func(e *encodeState, b []byte, v reflect.Value) ([]byte, error) {
+				buffer := bytes.NewBuffer(b)
+				err := e.binaryString2XML(v.Bytes(), buffer)
+				return buffer.Bytes(), err
+			}
+		} else if epoch, double := isEpoch(sf); epoch {
+			m.encode = func(e *encodeState, b []byte, v reflect.Value) ([]byte, error) {
+				buffer := bytes.NewBuffer(b)
+				epoch2XML(v.Interface().(time.Time), double, buffer)
+				return buffer.Bytes(), nil
+			}
+		} else {
+			fc := compileType(sf.Type, compiling)
+			m.encode = func(e *encodeState, b []byte, v reflect.Value) ([]byte, error) {
+				return fc.encode(e, b, v)
+			}
+		}
+		members = append(members, m)
+	}
+
+	return func(e *encodeState, b []byte, v reflect.Value) ([]byte, error) {
+		var err error
+		b = append(b, "<value><struct>"...)
+		for _, m := range members {
+			b = append(b, m.prefix...)
+			var ferr error
+			if b, ferr = m.encode(e, b, v.Field(m.index)); err == nil {
+				err = ferr
+			}
+			b = append(b, "</member>"...)
+		}
+		return append(b, "</struct></value>"...), err
+	}
+}
+
+// appendTime appends the <value> of the time.Time v as time2XML.
+func appendTime(e *encodeState, b []byte, v reflect.Value) ([]byte, error) {
+	t := v.Interface().(time.Time)
+	if t.Year() < 0 || t.Year() > 9999 {
+		buffer := bytes.NewBuffer(append(b, "<value>"...))
+		time2XML(t, buffer)
+		return append(buffer.Bytes(), "</value>"...), nil
+	}
+	b = append(b, "<value><dateTime.iso8601>"...)
+	b = appendDigits(b, t.Year(), 4)
+	b = appendDigits(b, int(t.Month()), 2)
+	b = appendDigits(b, t.Day(), 2)
+	b = append(b, 'T')
+	b = appendDigits(b, t.Hour(), 2)
+	b = append(b, ':')
+	b = appendDigits(b, t.Minute(), 2)
+	b = append(b, ':')
+	b = appendDigits(b, t.Second(), 2)
+	return append(b, "</dateTime.iso8601></value>"...), nil
+}
+
+// appendDigits appends the non-negative n zero-padded to width digits.
+func appendDigits(b []byte, n, width int) []byte {
+	for i := width - 1; i >= 0; i-- {
+		d := n
+		for j := 0; j < i; j++ {
+			d /= 10
+		}
+		b = append(b, byte('0'+d%10))
+	}
+	return b
+}
+
+// appendEscaped appends s escaped as by escapeString.
+func appendEscaped(b []byte, s string) []byte {
+	last := 0
+	for i := 0; i < len(s); i++ {
+		var esc string
+		switch s[i] {
+		case '&':
+			esc = "&amp;"
+		case '"':
+			esc = "&quot;"
+		case '<':
+			esc = "&lt;"
+		case '>':
+			esc = "&gt;"
+		default:
+			continue
+		}
+		b = append(b, s[last:i]...)
+		b = append(b, esc...)
+		last = i + 1
+	}
+	return append(b, s[last:]...)
+}
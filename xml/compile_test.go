@@ -0,0 +1,122 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type CompiledNode struct {
+	Name     string
+	Weight   float64
+	Active   bool
+	Count    int
+	Created  time.Time
+	Blob     []byte
+	Tags     []string
+	Pair     [2]int
+	Children []CompiledNode
+	Level    string `xmlrpc:"level,enum=low|high"`
+	hidden   int
+}
+
+func compiledSample() CompiledNode {
+	return CompiledNode{
+		Name:    `a "quoted" <name> & more`,
+		Weight:  1.5,
+		Active:  true,
+		Count:   -3,
+		Created: time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local),
+		Blob:    []byte("blob"),
+		Tags:    []string{"x", "y"},
+		Pair:    [2]int{1, 2},
+		Children: []CompiledNode{
+			{Name: "child", Level: "high", Tags: []string{"z"}},
+		},
+		Level: "low",
+	}
+}
+
+func TestCompileMatchesReflection(t *testing.T) {
+	sample := compiledSample()
+	var expected bytes.Buffer
+	if err := RPC2XML(sample, &expected); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+
+	c := Compile(CompiledNode{})
+	if c.reflective || compiledCodec(reflect.TypeOf(sample)) != c {
+		t.Fatal("Expected the struct to be compiled and registered")
+	}
+	encoded, err := c.Encode(sample)
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if string(encoded) != expected.String() {
+		t.Errorf("Expected the compiled encoding to match the reflective one:\n%s\n%s", encoded, expected.String())
+	}
+
+	// value2XML uses the compiled codec from now on
+	var again bytes.Buffer
+	RPC2XML(sample, &again)
+	if again.String() != expected.String() {
+		t.Error("Expected the registered codec to encode the same")
+	}
+
+	if _, err := c.Encode(1); err == nil {
+		t.Error("Expected an error encoding a value of another type")
+	}
+}
+
+func TestCompiledDecode(t *testing.T) {
+	Compile(CompiledNode{})
+	sample := compiledSample()
+	if err := RoundTrip(sample); err != nil {
+		t.Error("Expected the compiled type to round trip, got", err)
+	}
+}
+
+func TestCompileCycle(t *testing.T) {
+	Compile(CompiledNode{})
+	nodes := make([]CompiledNode, 1)
+	nodes[0].Children = nodes
+	if _, err := NewValue(CompiledNode{Children: nodes}); err == nil {
+		t.Error("Expected the cycle to be detected")
+	}
+}
+
+// forgetCompiled unregisters the compiled codecs.
+func forgetCompiled() {
+	compiledCodecs.Range(func(typ, _ interface{}) bool {
+		compiledCodecs.Delete(typ)
+		return true
+	})
+}
+
+func BenchmarkEncodeReflective(b *testing.B) {
+	forgetCompiled()
+	posts := *BenchmarkCorpus()[1].value.(*benchPosts)
+	b.ReportAllocs()
+	var buffer bytes.Buffer
+	for i := 0; i < b.N; i++ {
+		buffer.Reset()
+		RPC2XML(posts, &buffer)
+	}
+}
+
+func BenchmarkEncodeCompiled(b *testing.B) {
+	defer forgetCompiled()
+	Compile(benchPosts{})
+	posts := *BenchmarkCorpus()[1].value.(*benchPosts)
+	b.ReportAllocs()
+	var buffer bytes.Buffer
+	for i := 0; i < b.N; i++ {
+		buffer.Reset()
+		RPC2XML(posts, &buffer)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ConformanceCase is a value sent to a peer by CheckConformance, which must
+// be echoed back unchanged.
+type ConformanceCase struct {
+	Name  string
+	Value Value
+}
+
+// ConformanceCases are the values checked by CheckConformance: every type of
+// the spec, and the edge cases the peers tend to get wrong. The last one is
+// the <nil/> extension.
+var ConformanceCases = []ConformanceCase{
+	{"int zero", NewInt(0)},
+	{"int negative", NewInt(-1)},
+	{"int max", NewInt(2147483647)},
+	{"int min", NewInt(-2147483648)},
+	{"boolean true", NewBoolean(true)},
+	{"boolean false", NewBoolean(false)},
+	{"double", NewDouble(-1.5)},
+	{"double fraction", NewDouble(0.1)},
+	{"double large", NewDouble(1e100)},
+	{"string empty", NewString("")},
+	{"string markup", NewString(`<a href="x">&amp;'</a>`)},
+	{"string unicode", NewString("héllo, 世界 🎉")},
+	{"string whitespace", NewString("  lead\ttab\nnewline  ")},
+	{"dateTime", NewDateTime(time.Date(1998, 7, 17, 14, 8, 55, 0, time.UTC))},
+	{"base64 empty", NewBase64(nil)},
+	{"base64 binary", NewBase64(conformanceBytes())},
+	{"struct empty", NewStruct()},
+	{"struct", NewStruct(
+		Member{"name", NewString("x")},
+		Member{"ünïcode key", NewInt(1)},
+		Member{"nested", NewStruct(Member{"list", NewArray(NewInt(1), NewString("a"))})},
+	)},
+	{"array empty", NewArray()},
+	{"array mixed", NewArray(NewInt(1), NewString("a"), NewBoolean(true), NewDouble(2.5))},
+	{"array nested", NewArray(NewArray(NewArray(NewInt(1))), NewArray())},
+	{"nil", Value{Kind: KindNil}},
+}
+
+func conformanceBytes() []byte {
+	b := make([]byte, 256)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+// ConformanceResult is the outcome of a case.
+type ConformanceResult struct {
+	Case string
+
+	// Err is the error of the call, if any.
+	Err error
+
+	// Changes are the differences of the value echoed.
+	Changes []Change
+}
+
+// Passed reports whether the value was echoed unchanged.
+func (r ConformanceResult) Passed() bool {
+	return r.Err == nil && len(r.Changes) == 0
+}
+
+// ConformanceReport is the outcome of CheckConformance.
+type ConformanceReport []ConformanceResult
+
+// Failed returns the results of the failed cases.
+func (r ConformanceReport) Failed() []ConformanceResult {
+	var failed []ConformanceResult
+	for _, result := range r {
+		if !result.Passed() {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// String returns the report as a line per case.
+func (r ConformanceReport) String() string {
+	var b strings.Builder
+	for _, result := range r {
+		switch {
+		case result.Err != nil:
+			fmt.Fprintf(&b, "FAIL %s: %v\n", result.Case, result.Err)
+		case len(result.Changes) != 0:
+			for _, change := range result.Changes {
+				fmt.Fprintf(&b, "FAIL %s: %s\n", result.Case, change)
+			}
+		default:
+			fmt.Fprintf(&b, "ok   %s\n", result.Case)
+		}
+	}
+	fmt.Fprintf(&b, "%d/%d passed\n", len(r)-len(r.Failed()), len(r))
+	return b.String()
+}
+
+// CheckConformance calls the method echo of the peer of c, which must return
+// its param, with each of ConformanceCases, and reports the differences,
+// e.g. to check a peer before talking to it:
+//
+//	report := xml.CheckConformance(ctx, xml.NewClient(url), "echo")
+//	fmt.Print(report)
+//
+// The peers not supporting <nil/> fail the last case.
+func CheckConformance(ctx context.Context, c *Client, echo string) ConformanceReport {
+	report := make(ConformanceReport, 0, len(ConformanceCases))
+	for _, cc := range ConformanceCases {
+		result := ConformanceResult{Case: cc.Name}
+		params, err := c.CallValuesContext(ctx, echo, cc.Value)
+		switch {
+		case err != nil:
+			result.Err = err
+		case len(params) != 1:
+			result.Err = fmt.Errorf("xmlrpc: %d params echoed", len(params))
+		default:
+			result.Changes = Diff(cc.Value, params[0])
+		}
+		report = append(report, result)
+	}
+	return report
+}
+
+// EchoHandler serves any method returning its params, for the peers to check
+// their conformance against this package the other way round.
+var EchoHandler http.Handler = MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+	return params, nil
+})
@@ -0,0 +1,109 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bufio"
+	"context"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestCheckConformanceEcho(t *testing.T) {
+	ts := httptest.NewServer(EchoHandler)
+	defer ts.Close()
+
+	report := CheckConformance(context.Background(), NewClient(ts.URL), "echo")
+	if failed := report.Failed(); len(failed) != 0 || len(report) != len(ConformanceCases) {
+		t.Errorf("Expected every case passed, got:\n%s", report)
+	}
+}
+
+const pythonEchoServer = `
+import xmlrpc.server
+s = xmlrpc.server.SimpleXMLRPCServer(("127.0.0.1", 0), allow_none=True, logRequests=False)
+s.register_function(lambda v: v, "echo")
+print(s.server_address[1], flush=True)
+s.serve_forever()
+`
+
+const pythonEchoClient = `
+import sys, xmlrpc.client as c
+cases = [0, -1, 2**31-1, -2**31, True, False, -1.5, 0.1, 1e100,
+	"", "<a href='x'>&amp;\"</a>", "héllo, 世界 🎉", "  lead\ttab\nnewline  ",
+	c.DateTime("19980717T14:08:55"), c.Binary(b""), c.Binary(bytes(range(256))),
+	{}, {"name": "x", "ünïcode key": 1, "nested": {"list": [1, "a"]}},
+	[], [1, "a", True, 2.5], [[[1]], []], None]
+p = c.ServerProxy(sys.argv[1], allow_none=True)
+failed = 0
+for v in cases:
+	got = p.echo(v)
+	if got != v or type(got) != type(v):
+		print("FAIL %r: got %r" % (v, got))
+		failed += 1
+print("%d/%d passed" % (len(cases) - failed, len(cases)))
+`
+
+// python returns the python3 command, skipping the test if it's missing.
+func python(t *testing.T) string {
+	if testing.Short() {
+		t.Skip("skipping the interoperability test in short mode")
+	}
+	path, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 not found")
+	}
+	return path
+}
+
+func TestConformancePythonServer(t *testing.T) {
+	cmd := exec.Command(python(t), "-c", pythonEchoServer)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+	port, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url := "http://127.0.0.1:" + strings.TrimSpace(port)
+	report := CheckConformance(context.Background(), NewClient(url), "echo")
+	if len(report.Failed()) != 0 {
+		t.Errorf("Expected python to echo every case, got:\n%s", report)
+	}
+}
+
+func TestConformancePythonClient(t *testing.T) {
+	ts := httptest.NewServer(EchoHandler)
+	defer ts.Close()
+
+	out, err := exec.Command(python(t), "-c", pythonEchoClient, ts.URL).CombinedOutput()
+	if err != nil || strings.Contains(string(out), "FAIL") {
+		t.Errorf("Expected python to get every case echoed, got %v:\n%s", err, out)
+	}
+}
+
+func TestRunCommandConformance(t *testing.T) {
+	ts := httptest.NewServer(EchoHandler)
+	defer ts.Close()
+
+	var out strings.Builder
+	if err := RunCommand([]string{"-conformance", ts.URL, "echo"}, &out); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if !strings.HasSuffix(out.String(), "22/22 passed\n") {
+		t.Errorf("Expected every case passed, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var patterns = struct {
+	sync.Mutex
+	byText map[string]*regexp.Regexp
+}{byText: make(map[string]*regexp.Regexp)}
+
+// compilePattern returns the compiled pattern, caching it.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	patterns.Lock()
+	defer patterns.Unlock()
+	if re, ok := patterns.byText[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.byText[pattern] = re
+	return re, nil
+}
+
+// checkArgs enforces the constraints of the xmlrpc tags of args, a pointer to
+// struct, and its nested structs:
+//
+//    required     the field must not be the zero value
+//    min=N        numbers must be >= N
+//    max=N        numbers must be <= N
+//    maxlen=N     strings (in runes), slices and maps must be at most N long
+//    regexp=RE    strings must match RE; as RE may contain commas, it must
+//                 be the last option
+//
+// The violations are reported as a single invalid params fault.
+func checkArgs(args interface{}) error {
+	rv := reflect.ValueOf(args)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	var issues []string
+	checkStruct("", rv.Elem(), &issues)
+	if len(issues) == 0 {
+		return nil
+	}
+	fault := FaultInvalidParams
+	fault.String += ": " + strings.Join(issues, "; ")
+	return fault
+}
+
+func checkStruct(prefix string, v reflect.Value, issues *[]string) {
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		if sf.PkgPath != "" {
+			// unexported field
+			continue
+		}
+		field := v.Field(i)
+		path := prefix + sf.Name
+		checkField(path, sf, field, issues)
+		checkValue(path, field, issues)
+	}
+}
+
+// checkValue checks the constraints of the structs in v, e.g. the items of
+// a []Item, at path, with the indexes or keys of the items.
+func checkValue(path string, v reflect.Value, issues *[]string) {
+	for (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !holdsStructs(v.Type()) {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		checkStruct(path+".", v, issues)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			checkValue(fmt.Sprintf("%s[%d]", path, i), v.Index(i), issues)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+		for _, key := range keys {
+			checkValue(fmt.Sprintf("%s[%v]", path, key), v.MapIndex(key), issues)
+		}
+	}
+}
+
+// holdsStructs reports whether the values of typ may hold structs to check.
+func holdsStructs(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Struct:
+		return typ != timeType
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return holdsStructs(typ.Elem())
+	case reflect.Interface:
+		return true
+	}
+	return false
+}
+
+func checkField(path string, sf reflect.StructField, field reflect.Value, issues *[]string) {
+	_, opts := parseTag(sf)
+	opts, pattern, hasPattern := opts.cut("regexp")
+	addf := func(format string, args ...interface{}) {
+		*issues = append(*issues, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if opts.Contains("required") && reflect.DeepEqual(field.Interface(), reflect.Zero(field.Type()).Interface()) {
+		addf("required")
+		return
+	}
+
+	for _, key := range []string{"min", "max"} {
+		text, ok := opts.Get(key)
+		if !ok {
+			continue
+		}
+		limit, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			addf("invalid %s %q", key, text)
+			continue
+		}
+		var n float64
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = float64(field.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n = float64(field.Uint())
+		case reflect.Float32, reflect.Float64:
+			n = field.Float()
+		default:
+			continue
+		}
+		if key == "min" && n < limit {
+			addf("%v is less than min %s", n, text)
+		}
+		if key == "max" && n > limit {
+			addf("%v is more than max %s", n, text)
+		}
+	}
+
+	if text, ok := opts.Get("maxlen"); ok {
+		limit, err := strconv.Atoi(text)
+		if err != nil {
+			addf("invalid maxlen %q", text)
+		} else {
+			n := -1
+			switch field.Kind() {
+			case reflect.String:
+				n = utf8.RuneCountInString(field.String())
+			case reflect.Slice, reflect.Map, reflect.Array:
+				n = field.Len()
+			}
+			if n > limit {
+				addf("length %d is more than maxlen %d", n, limit)
+			}
+		}
+	}
+
+	if hasPattern && field.Kind() == reflect.String {
+		re, err := compilePattern(pattern)
+		if err != nil {
+			addf("invalid regexp %q", pattern)
+		} else if !re.MatchString(field.String()) {
+			addf("%q doesn't match %s", field.String(), pattern)
+		}
+	}
+}
+
+// rejection receives the fault of a call rejected by checkArgs.
+type rejection struct {
+	fault *Fault
+}
+
+// ValidationFaults wraps the XML-RPC server next so calls whose args break
+// the constraints of their xmlrpc tags are answered with an invalid params
+// fault listing the violations.
+//
+// The codec rejects such calls before they reach the service method, but
+// the rpc server can only answer them with a plain 400 error, which clients
+// see as a transport error rather than a fault. The wrapper is required to
+// get the fault: it replaces the 400 error with it.
+func ValidationFaults(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rej := &rejection{}
+		ctx := context.WithValue(r.Context(), validationContextKey, rej)
+		next.ServeHTTP(&rejectionWriter{ResponseWriter: w, rej: rej}, r.WithContext(ctx))
+	})
+}
+
+// rejectionWriter writes the fault of the rejection, if any, in place of the
+// response.
+type rejectionWriter struct {
+	http.ResponseWriter
+	rej      *rejection
+	started  bool
+	rejected bool
+}
+
+func (w *rejectionWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+	if w.rej.fault != nil {
+		w.rejected = true
+		writeFault(w.ResponseWriter, *w.rej.fault)
+	}
+}
+
+func (w *rejectionWriter) WriteHeader(status int) {
+	w.start()
+	if !w.rejected {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *rejectionWriter) Write(b []byte) (int, error) {
+	w.start()
+	if w.rejected {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,170 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+type SignupAddress struct {
+	Zip string `xmlrpc:",regexp=^[0-9]{5}(-[0-9]{4})?$"`
+}
+
+type SignupRequest struct {
+	Name    string   `xmlrpc:",required,maxlen=8"`
+	Age     int      `xmlrpc:",min=18,max=130"`
+	Tags    []string `xmlrpc:",maxlen=2"`
+	Address SignupAddress
+}
+
+type SignupResponse struct {
+	Ok bool
+}
+
+type Signup struct {
+	calls int
+}
+
+func (s *Signup) Register(r *http.Request, req *SignupRequest, res *SignupResponse) error {
+	s.calls++
+	res.Ok = true
+	return nil
+}
+
+func TestCheckArgs(t *testing.T) {
+	valid := &SignupRequest{"ivan", 30, []string{"a"}, SignupAddress{"12345-6789"}}
+	if err := checkArgs(valid); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+
+	invalid := &SignupRequest{"", 12, []string{"a", "b", "c"}, SignupAddress{"1234"}}
+	err := checkArgs(invalid)
+	fault, ok := err.(Fault)
+	if !ok || fault.Code != FaultInvalidParams.Code {
+		t.Fatal("Expected invalid params fault, got", err)
+	}
+	for _, issue := range []string{
+		"Name: required",
+		"Age: 12 is less than min 18",
+		"Tags: length 3 is more than maxlen 2",
+		`Address.Zip: "1234" doesn't match ^[0-9]{5}(-[0-9]{4})?$`,
+	} {
+		if !strings.Contains(fault.String, issue) {
+			t.Errorf("Expected %q in %q", issue, fault.String)
+		}
+	}
+
+	invalid = &SignupRequest{"ivan the terrible", 200, nil, SignupAddress{"12345"}}
+	err = checkArgs(invalid)
+	if err == nil || !strings.Contains(err.Error(), "length 17 is more than maxlen 8") ||
+		!strings.Contains(err.Error(), "200 is more than max 130") {
+		t.Error("Expected maxlen and max issues, got", err)
+	}
+}
+
+func TestCheckArgsItems(t *testing.T) {
+	type Order struct {
+		Addresses []SignupAddress
+		Pair      [2]*SignupAddress
+		ByName    map[string]SignupAddress
+	}
+	valid := SignupAddress{"12345"}
+	order := &Order{
+		Addresses: []SignupAddress{valid, {"1"}},
+		Pair:      [2]*SignupAddress{{"2"}, nil},
+		ByName:    map[string]SignupAddress{"home": valid, "work": {"3"}},
+	}
+	err := checkArgs(order)
+	fault, ok := err.(Fault)
+	if !ok {
+		t.Fatal("Expected a fault, got", err)
+	}
+	for _, issue := range []string{
+		`Addresses[1].Zip: "1" doesn't match`,
+		`Pair[0].Zip: "2" doesn't match`,
+		`ByName[work].Zip: "3" doesn't match`,
+	} {
+		if !strings.Contains(fault.String, issue) {
+			t.Errorf("Expected %q in %q", issue, fault.String)
+		}
+	}
+	if strings.Contains(fault.String, "Addresses[0]") || strings.Contains(fault.String, "ByName[home]") {
+		t.Error("Expected the valid items to pass, got", fault.String)
+	}
+
+	if err := checkArgs(&Order{Addresses: []SignupAddress{valid}, ByName: map[string]SignupAddress{"home": valid}}); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+}
+
+func TestValidationFaults(t *testing.T) {
+	signup := new(Signup)
+	s := rpc.NewServer()
+	s.RegisterCodec(NewCodec(), "text/xml")
+	s.RegisterService(signup, "")
+	ts := httptest.NewServer(ValidationFaults(s))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	var res SignupResponse
+	err := client.Call("Signup.Register", &SignupRequest{Age: 30, Address: SignupAddress{"12345"}}, &res)
+	fault, ok := err.(Fault)
+	if !ok || fault.Code != FaultInvalidParams.Code || !strings.Contains(fault.String, "Name: required") {
+		t.Error("Expected invalid params fault, got", err)
+	}
+	if signup.calls != 0 {
+		t.Error("Expected the method not to be called")
+	}
+
+	if err := client.Call("Signup.Register", &SignupRequest{Name: "ivan", Age: 30, Address: SignupAddress{"12345"}}, &res); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if !res.Ok || signup.calls != 1 {
+		t.Error("Expected the method to be called")
+	}
+}
+
+func TestValidationFaultsUnwrapped(t *testing.T) {
+	signup := new(Signup)
+	s := rpc.NewServer()
+	s.RegisterCodec(NewCodec(), "text/xml")
+	s.RegisterService(signup, "")
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	var res SignupResponse
+	err := client.Call("Signup.Register", &SignupRequest{Age: 30, Address: SignupAddress{"12345"}}, &res)
+	if _, ok := asFault(err); ok || err == nil || !strings.Contains(err.Error(), "400 Bad Request") {
+		t.Error("Expected a 400 error without the wrapper, got", err)
+	}
+	if signup.calls != 0 {
+		t.Error("Expected the method not to be called")
+	}
+
+	body, err := EncodeClientRequest("Signup.Register", &SignupRequest{Age: 30, Address: SignupAddress{"12345"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(ts.URL, "text/xml", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	text, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(text), "Name: required") {
+		t.Errorf("Expected a 400 with the violations, got %d %q", resp.StatusCode, text)
+	}
+	if signup.calls != 0 {
+		t.Error("Expected the method not to be called")
+	}
+}
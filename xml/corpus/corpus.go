@@ -0,0 +1,225 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package corpus is a golden corpus of real-world XML-RPC payloads from
+// WordPress, Supervisor and Odoo, anonymized, with the value trees they
+// were parsed into when recorded, so the package and its users assert that
+// the decoding stays stable across versions.
+//
+// The value trees are checked by Payload.Check. The typed decoding is
+// checked by decoding a payload replayed by its Handler into the user's
+// types, compared with their golden files by Compare:
+//
+//	var update = flag.Bool("update", false, "update the golden files")
+//
+//	func TestDecodePosts(t *testing.T) {
+//		p, err := corpus.Load("wordpress/getPosts.response")
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		client := xml.NewLoopbackClient(p.Handler())
+//		client.Options = xml.ProfileWordPress.Options
+//		var reply struct{ Posts []Post }
+//		if err := client.Call("wp.getPosts", &struct{}{}, &reply); err != nil {
+//			t.Fatal(err)
+//		}
+//		corpus.Compare(t, "testdata", p.Name, reply, *update)
+//	}
+package corpus
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-xmlrpc/xml"
+)
+
+//go:embed payloads
+var payloads embed.FS
+
+// Payload is a document of the corpus.
+type Payload struct {
+	// Name is the path of the payload, e.g. "wordpress/getPosts.response":
+	// its peer, its method and whether it's a call or a response.
+	Name string
+
+	// Peer is the peer the payload comes from, e.g. "wordpress".
+	Peer string
+
+	// Data is the document.
+	Data []byte
+
+	// Snapshot is the value tree of the document as recorded (see
+	// Snapshot).
+	Snapshot string
+}
+
+// Payloads returns the payloads of the corpus, sorted by name.
+func Payloads() ([]Payload, error) {
+	var list []Payload
+	err := fs.WalkDir(payloads, "payloads", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(name) != ".xml" {
+			return err
+		}
+		p, err := Load(strings.TrimSuffix(strings.TrimPrefix(name, "payloads/"), ".xml"))
+		if err != nil {
+			return err
+		}
+		list = append(list, p)
+		return nil
+	})
+	return list, err
+}
+
+// Load returns the payload name, e.g. "wordpress/getPosts.response".
+func Load(name string) (Payload, error) {
+	data, err := payloads.ReadFile("payloads/" + name + ".xml")
+	if err != nil {
+		return Payload{}, fmt.Errorf("xmlrpc: no payload %s in the corpus", name)
+	}
+	snapshot, err := payloads.ReadFile("payloads/" + name + ".snapshot")
+	if err != nil {
+		return Payload{}, fmt.Errorf("xmlrpc: no snapshot of the payload %s", name)
+	}
+	return Payload{
+		Name:     name,
+		Peer:     path.Dir(name),
+		Data:     data,
+		Snapshot: string(snapshot),
+	}, nil
+}
+
+// Check parses the payload and reports whether its value tree changed since
+// it was recorded, with the first line differing.
+func (p Payload) Check() error {
+	snapshot, err := Snapshot(p.Data)
+	if err != nil {
+		return fmt.Errorf("xmlrpc: payload %s: %v", p.Name, err)
+	}
+	if line, got, want, ok := diffLines(snapshot, p.Snapshot); !ok {
+		return fmt.Errorf("xmlrpc: payload %s changed at line %d: got %q, recorded %q", p.Name, line, got, want)
+	}
+	return nil
+}
+
+// Handler returns a handler answering any call with the payload, a
+// response, e.g. to decode it with a Client through a xml.Loopback.
+func (p Payload) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		w.Write(p.Data)
+	})
+}
+
+// Snapshot returns a text of the value tree of the methodCall or
+// methodResponse document data: the method called, the params with their
+// types and texts as on the wire, or the fault.
+func Snapshot(data []byte) (string, error) {
+	var b strings.Builder
+	method, params, err := xml.ParseMethodCall(data)
+	if err == nil {
+		fmt.Fprintf(&b, "methodCall %s\n", method)
+	} else {
+		if params, err = xml.ParseMethodResponse(data); err != nil {
+			var fault xml.Fault
+			if errors.As(err, &fault) {
+				fmt.Fprintf(&b, "methodResponse\nfault %d %q\n", fault.Code, fault.String)
+				return b.String(), nil
+			}
+			return "", err
+		}
+		b.WriteString("methodResponse\n")
+	}
+	for i, p := range params {
+		fmt.Fprintf(&b, "param %d: ", i+1)
+		writeValue(&b, p, "  ")
+	}
+	return b.String(), nil
+}
+
+// writeValue writes the snapshot of v, its children indented by indent.
+func writeValue(b *strings.Builder, v xml.Value, indent string) {
+	switch v.Kind {
+	case xml.KindStruct:
+		b.WriteString("struct\n")
+		for _, m := range v.Members {
+			fmt.Fprintf(b, "%s%q: ", indent, m.Name)
+			writeValue(b, m.Value, indent+"  ")
+		}
+	case xml.KindArray:
+		b.WriteString("array\n")
+		for i, item := range v.Items {
+			fmt.Fprintf(b, "%s[%d] ", indent, i)
+			writeValue(b, item, indent+"  ")
+		}
+	case xml.KindNil:
+		b.WriteString("nil\n")
+	case "":
+		fmt.Fprintf(b, "untyped %q\n", v.Text)
+	default:
+		fmt.Fprintf(b, "%s %q\n", v.Kind, v.Text)
+	}
+}
+
+// Compare compares got, marshalled as indented JSON, with the golden file
+// of the payload name in dir, e.g. testdata/wordpress/getPosts.response.json,
+// failing t at the first line differing. With update, e.g. set by a -update
+// flag, the golden file is written instead.
+//
+// time.Time values are marshalled in their zone, the local one for the
+// dateTimes with none, so the tests should fix it, e.g. with TZ=UTC.
+func Compare(t testing.TB, dir, name string, got interface{}, update bool) {
+	t.Helper()
+	data, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatalf("xmlrpc: can't marshal %s: %v", name, err)
+	}
+	data = append(data, '\n')
+	file := filepath.Join(dir, filepath.FromSlash(name)+".json")
+	if update {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("xmlrpc: no golden file for %s: %v", name, err)
+	}
+	if line, got, want, ok := diffLines(string(data), string(want)); !ok {
+		t.Errorf("xmlrpc: %s changed at line %d of %s: got %q, want %q", name, line, file, got, want)
+	}
+}
+
+// diffLines compares a and b by lines, returning the first line differing,
+// numbered from 1, and reporting whether they're the same.
+func diffLines(a, b string) (int, string, string, bool) {
+	al, bl := strings.Split(a, "\n"), strings.Split(b, "\n")
+	for i := 0; i < len(al) || i < len(bl); i++ {
+		var x, y string
+		if i < len(al) {
+			x = al[i]
+		}
+		if i < len(bl) {
+			y = bl[i]
+		}
+		if x != y {
+			return i + 1, x, y, false
+		}
+	}
+	return 0, "", "", true
+}
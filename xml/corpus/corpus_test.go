@@ -0,0 +1,141 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package corpus
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlexStocks/gorilla-xmlrpc/xml"
+)
+
+var update = flag.Bool("update", false, "update the snapshots and the golden files")
+
+func TestPayloads(t *testing.T) {
+	list, err := Payloads()
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if len(list) != 7 || list[0].Name != "odoo/execute_kw.call" || list[0].Peer != "odoo" {
+		t.Fatalf("Expected the 7 payloads sorted, got %d", len(list))
+	}
+	for _, p := range list {
+		if *update {
+			snapshot, err := Snapshot(p.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file := filepath.Join("payloads", filepath.FromSlash(p.Name)+".snapshot")
+			if err := ioutil.WriteFile(file, []byte(snapshot), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := p.Check(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	p := list[0]
+	p.Snapshot = strings.Replace(p.Snapshot, "execute_kw", "execute", 1)
+	if err := p.Check(); err == nil || !strings.Contains(err.Error(), "changed at line 1") {
+		t.Errorf("Expected the change reported, got %v", err)
+	}
+	if _, err := Load("wordpress/missing"); err == nil {
+		t.Error("Expected a missing payload to fail")
+	}
+}
+
+type post struct {
+	PostID      int
+	PostTitle   string
+	PostDate    time.Time
+	PostStatus  string
+	PostAuthor  int
+	PostContent string
+	Sticky      bool
+	Terms       []struct {
+		TermID         int
+		Name, Taxonomy string
+	}
+	CustomFields  []struct{ Key, Value string }
+	PostThumbnail []string
+}
+
+type processInfo struct {
+	Name, Group, Description string
+	Start, Stop, Now         int
+	State                    int
+	Statename, Spawnerr      string
+	Exitstatus               int
+	Logfile, StdoutLogfile   string
+	StderrLogfile            string
+	Pid                      int
+}
+
+// many2one is an Odoo relation: the ID and the name of the record, or false.
+type many2one struct {
+	ID   int
+	Name string
+}
+
+type partner struct {
+	ID        int `xmlrpc:"id"`
+	Name      string
+	CountryID many2one `xmlrpc:",tuple"`
+	Email     string
+	WriteDate time.Time
+}
+
+func TestDecodeStability(t *testing.T) {
+	local := time.Local
+	time.Local = time.UTC
+	defer func() { time.Local = local }()
+
+	var (
+		posts     struct{ Posts []post }
+		processes struct{ Processes []processInfo }
+		partners  struct{ Partners []partner }
+		tail      struct {
+			Chunk struct {
+				Bytes    string
+				Offset   int
+				Overflow bool
+			} `xmlrpc:",tuple"`
+		}
+	)
+	for _, test := range []struct {
+		name    string
+		profile *xml.Profile
+		reply   interface{}
+	}{
+		{"wordpress/getPosts.response", xml.ProfileWordPress, &posts},
+		{"supervisor/getAllProcessInfo.response", xml.ProfileSupervisor, &processes},
+		{"supervisor/tailProcessStdoutLog.response", xml.ProfileSupervisor, &tail},
+		{"odoo/search_read.response", xml.ProfileOdoo, &partners},
+	} {
+		p, err := Load(test.name)
+		if err != nil {
+			t.Fatal("Expected err to be nil, but got:", err)
+		}
+		client := xml.NewLoopbackClient(p.Handler())
+		test.profile.ConfigureClient(client)
+		if err := client.Call("corpus", &struct{}{}, test.reply); err != nil {
+			t.Errorf("Expected %s decoded, got %v", test.name, err)
+			continue
+		}
+		Compare(t, "testdata", p.Name, test.reply, *update)
+	}
+
+	p, _ := Load("wordpress/fault.response")
+	err := xml.NewLoopbackClient(p.Handler()).Call("wp.getPosts", &struct{}{}, &posts)
+	if fault, ok := err.(xml.Fault); !ok || fault.Code != 403 {
+		t.Errorf("Expected the fault decoded, got %v", err)
+	}
+}
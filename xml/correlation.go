@@ -0,0 +1,116 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// CorrelationIDHeader is the HTTP header carrying the correlation ID of a
+// call, in the requests and the responses.
+const CorrelationIDHeader = "X-Correlation-Id"
+
+// DefaultFaultIDTemplate is the faultString of the faults with a correlation
+// ID, unless configured otherwise.
+const DefaultFaultIDTemplate = "{fault} (ref {id})"
+
+// CorrelationIDs configures the correlation IDs of a Codec: every request
+// gets one, taken from its context (see CorrelateCalls) or its
+// X-Correlation-Id header, if it's a plain ID, or generated. The ID is returned in the
+// X-Correlation-Id header, included in the faultStrings and in the lines
+// of the FaultSanitizer and SlowCalls loggers, so the users reporting an
+// opaque fault can be matched to the server logs.
+type CorrelationIDs struct {
+	// Template formats the faultStrings, where {fault} is replaced with the
+	// faultString and {id} with the ID; DefaultFaultIDTemplate if empty.
+	Template string
+
+	// Generate, if set, returns the new IDs, 16 random hex digits by
+	// default.
+	Generate func() string
+}
+
+// maxCorrelationIDLength is the length of the longest correlation ID taken
+// from the X-Correlation-Id header of a request.
+const maxCorrelationIDLength = 64
+
+// id returns the ID of the request r. The ID of the X-Correlation-Id header
+// is only taken if it's at most 64 letters, digits, '-',
+// '_' and '.'; a new ID is generated otherwise, so clients can't get
+// arbitrary text into the faultStrings and the logs.
+func (ids *CorrelationIDs) id(r *http.Request) string {
+	if id, ok := CorrelationIDFrom(r.Context()); ok {
+		return id
+	}
+	if id := r.Header.Get(CorrelationIDHeader); validCorrelationID(id) {
+		return id
+	}
+	if ids != nil && ids.Generate != nil {
+		return ids.Generate()
+	}
+	return newCorrelationID()
+}
+
+// stamp returns fault with id in its faultString.
+func (ids *CorrelationIDs) stamp(fault Fault, id string) Fault {
+	template := ids.Template
+	if template == "" {
+		template = DefaultFaultIDTemplate
+	}
+	fault.String = strings.NewReplacer("{fault}", fault.String, "{id}", id).Replace(template)
+	return fault
+}
+
+// validCorrelationID reports whether id is a correlation ID to take from a
+// request.
+func validCorrelationID(id string) bool {
+	if id == "" || len(id) > maxCorrelationIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch c := id[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newCorrelationID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// CorrelateCalls wraps the XML-RPC handler next, so the calls are served
+// with a request context carrying their correlation ID (see
+// CorrelationIDFrom), taken from their X-Correlation-Id header or generated
+// with ids, which may be nil. The methods may then log it, and the clients
+// they call with r.Context() pass it on in the X-Correlation-Id header.
+func CorrelateCalls(next http.Handler, ids *CorrelationIDs) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := ids.id(r)
+		w.Header().Set(CorrelationIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(WithCorrelationID(r.Context(), id)))
+	})
+}
+
+// WithCorrelationID returns a context making the client calls made with it
+// carry id in the X-Correlation-Id header.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDContextKey, id)
+}
+
+// CorrelationIDFrom returns the correlation ID of the call served with ctx,
+// and reports whether there is one.
+func CorrelationIDFrom(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(correlationIDContextKey).(string)
+	return id, ok
+}
@@ -0,0 +1,111 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+func TestCodecCorrelationIDs(t *testing.T) {
+	logger := &testLogger{}
+	codec := NewCodec()
+	codec.FaultSanitizer = &FaultSanitizer{MaxLength: 20, Logger: logger}
+	codec.CorrelationIDs = &CorrelationIDs{Generate: func() string { return "abc" }}
+	s := rpc.NewServer()
+	s.RegisterCodec(codec, "text/xml")
+	s.RegisterService(new(FailingService), "")
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var res Service1Response
+	err := NewClient(ts.URL).Call("FailingService.Fail", &Service1Request{1, 2}, &res)
+	fault, ok := err.(Fault)
+	if !ok {
+		t.Fatalf("Expected a fault, got %v", err)
+	}
+	// the stamped faultString is bounded by MaxLength
+	if expected := "Applica... (ref abc)"; fault.String != expected {
+		t.Errorf("Expected %q, got %q", expected, fault.String)
+	}
+	if len(logger.lines) != 1 || !strings.HasPrefix(logger.lines[0], "xmlrpc: fault -32500 in FailingService.Fail [abc]: ") {
+		t.Errorf("Expected the ID logged, got %q", logger.lines)
+	}
+
+	// the ID of the client, in the response too
+	codec.CorrelationIDs.Template = "[{id}] {fault}"
+	req, _ := http.NewRequest("POST", ts.URL, strings.NewReader(
+		`<methodCall><methodName>FailingService.Fail</methodName><params/></methodCall>`))
+	req.Header.Set("Content-Type", "text/xml")
+	req.Header.Set(CorrelationIDHeader, "client-1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if id := resp.Header.Get(CorrelationIDHeader); id != "client-1" {
+		t.Errorf("Expected the ID client-1 returned, got %q", id)
+	}
+	body, _ := readBody(resp.Body, 0)
+	if _, err := ParseMethodResponse(body); err == nil || !strings.Contains(err.Error(), "[client-1] ") {
+		t.Errorf("Expected the fault stamped with client-1, got %v", err)
+	}
+
+	// IDs too long or with other characters are replaced
+	for _, bad := range []string{strings.Repeat("x", 65), "a b", "<ref>", "id;drop"} {
+		req, _ := http.NewRequest("POST", ts.URL, strings.NewReader(
+			`<methodCall><methodName>FailingService.Fail</methodName><params/></methodCall>`))
+		req.Header.Set("Content-Type", "text/xml")
+		req.Header.Set(CorrelationIDHeader, bad)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := readBody(resp.Body, 0)
+		resp.Body.Close()
+		if id := resp.Header.Get(CorrelationIDHeader); id != "abc" {
+			t.Errorf("Expected the ID %q replaced, got %q", bad, id)
+		}
+		if _, err := ParseMethodResponse(body); err == nil || !strings.HasPrefix(fault2String(err), "[abc] ") {
+			t.Errorf("Expected the fault stamped with abc, got %v", err)
+		}
+	}
+}
+
+func fault2String(err error) string {
+	fault, _ := asFault(err)
+	return fault.String
+}
+
+func TestCorrelateCalls(t *testing.T) {
+	handler := MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		id, _ := CorrelationIDFrom(r.Context())
+		return []Value{NewString(id)}, nil
+	})
+	ts := httptest.NewServer(CorrelateCalls(handler, nil))
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	res, err := client.CallValuesContext(WithCorrelationID(context.Background(), "xyz"), "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0].Text != "xyz" {
+		t.Errorf("Expected the ID of the client passed on, got %v", res)
+	}
+
+	res, err = client.CallValues("id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || len(res[0].Text) != 16 {
+		t.Errorf("Expected an ID generated, got %v", res)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestDateTimeFormat(t *testing.T) {
+	zone := time.FixedZone("CEST", 2*3600)
+	when := time.Date(2012, 7, 17, 14, 8, 55, 123456789, zone)
+	for _, tc := range []struct {
+		format, want string
+	}{
+		{"", "20120717T14:08:55"},
+		{DateTimeOffset, "20120717T14:08:55+02:00"},
+		{DateTimeOffsetMillis, "20120717T14:08:55.123+02:00"},
+		{time.RFC3339, "2012-07-17T14:08:55+02:00"},
+		{time.RFC3339Nano, "2012-07-17T14:08:55.123456789+02:00"},
+	} {
+		var buffer bytes.Buffer
+		if err := rpcParams2XML(&struct{ T time.Time }{when}, &buffer, &Options{DateTimeFormat: tc.format}); err != nil {
+			t.Fatal(err)
+		}
+		want := "<params><param><value><dateTime.iso8601>" + tc.want + "</dateTime.iso8601></value></param></params>"
+		if buffer.String() != want {
+			t.Errorf("Expected %q, got %q", want, buffer.String())
+		}
+	}
+}
+
+func TestDateTimeDecodeISO(t *testing.T) {
+	zone := time.FixedZone("", 2*3600)
+	for text, want := range map[string]time.Time{
+		"20120717T14:08:55":                   time.Date(2012, 7, 17, 14, 8, 55, 0, time.Local),
+		"20120717T14:08:55+02:00":             time.Date(2012, 7, 17, 14, 8, 55, 0, zone),
+		"20120717T14:08:55+0200":              time.Date(2012, 7, 17, 14, 8, 55, 0, zone),
+		"20120717T14:08:55Z":                  time.Date(2012, 7, 17, 14, 8, 55, 0, time.UTC),
+		"20120717T14:08:55.5":                 time.Date(2012, 7, 17, 14, 8, 55, 5e8, time.Local),
+		"2012-07-17T14:08:55.123456789+02:00": time.Date(2012, 7, 17, 14, 8, 55, 123456789, zone),
+		"2012-07-17T14:08:55":                 time.Date(2012, 7, 17, 14, 8, 55, 0, time.Local),
+	} {
+		var reply struct{ T time.Time }
+		resp := toResponse("<params><param><value><dateTime.iso8601>" + text + "</dateTime.iso8601></value></param></params>")
+		if err := decodeRPC(resp, &reply, &Options{}); err != nil {
+			t.Errorf("%s: %v", text, err)
+			continue
+		}
+		if !reply.T.Equal(want) {
+			t.Errorf("%s: expected %v, got %v", text, want, reply.T)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// TimeoutHeader is the HTTP header carrying the time left until the deadline
+// of a call, as a duration like "1.5s".
+const TimeoutHeader = "X-Xmlrpc-Timeout"
+
+// PropagateDeadline wraps the XML-RPC handler next, so a call carrying the
+// X-Xmlrpc-Timeout header is served with a request context that expires
+// after the timeout. Handlers see it as r.Context(), and passing it on to
+// outgoing calls makes timeouts compose across XML-RPC hops.
+//
+// A relative timeout is used instead of an absolute deadline, so clock skew
+// between the hosts doesn't matter.
+func PropagateDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout, err := time.ParseDuration(r.Header.Get(TimeoutHeader))
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// setTimeoutHeader sets the X-Xmlrpc-Timeout header of req from the deadline
+// of ctx, if any.
+func setTimeoutHeader(ctx context.Context, req *http.Request) {
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set(TimeoutHeader, time.Until(deadline).String())
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+type DeadlineReply struct {
+	LeftMs int
+}
+
+type DeadlineService struct{}
+
+func (s *DeadlineService) Left(r *http.Request, args *struct{}, reply *DeadlineReply) error {
+	if deadline, ok := r.Context().Deadline(); ok {
+		reply.LeftMs = int(time.Until(deadline) / time.Millisecond)
+	}
+	return nil
+}
+
+func TestPropagateDeadline(t *testing.T) {
+	s := rpc.NewServer()
+	s.RegisterCodec(NewCodec(), "text/xml")
+	s.RegisterService(new(DeadlineService), "")
+	ts := httptest.NewServer(PropagateDeadline(s))
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	client.PropagateDeadline = true
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var res DeadlineReply
+	if err := client.CallContext(ctx, "DeadlineService.Left", &struct{}{}, &res); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if left := time.Duration(res.LeftMs) * time.Millisecond; left <= 50*time.Second || left > time.Minute {
+		t.Errorf("expected about a minute left, got %v", left)
+	}
+
+	client.PropagateDeadline = false
+	if err := client.CallContext(ctx, "DeadlineService.Left", &struct{}{}, &res); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if res.LeftMs != 0 {
+		t.Errorf("expected no deadline, got %dms", res.LeftMs)
+	}
+}
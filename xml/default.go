@@ -0,0 +1,87 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// MethodHandler serves calls of any method from their params as value trees,
+// e.g. to forward the calls unknown to a gateway upstream:
+//
+//    upstream := xml.NewClient("http://legacy/RPC2")
+//    h := xml.MethodHandler(func(r *http.Request, method string, params []xml.Value) ([]xml.Value, error) {
+//        return upstream.CallValuesContext(r.Context(), method, params...)
+//    })
+//
+// A returned Fault is sent as is, other errors as application error faults.
+type MethodHandler func(r *http.Request, method string, params []Value) ([]Value, error)
+
+// ServeHTTP implements http.Handler.
+func (h MethodHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	buffer := bytes.NewBuffer(make([]byte, 0))
+	if reply, err := h.call(r); err != nil {
+		fault, ok := asFault(err)
+		if !ok {
+			fault = FaultApplicationError
+			fault.String += fmt.Sprintf(": %v", err)
+		}
+		Fault2XML(fault, buffer)
+	} else {
+		buffer.Write(EncodeMethodResponse(reply))
+	}
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	buffer.WriteTo(w)
+}
+
+func (h MethodHandler) call(r *http.Request) ([]Value, error) {
+	rawxml, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, FaultSystemError
+	}
+	method, params, err := ParseMethodCall(rawxml)
+	if err != nil {
+		return nil, FaultDecode
+	}
+	return h(r, method, params)
+}
+
+// MethodServer is a handler knowing the methods it serves, like rpc.Server.
+type MethodServer interface {
+	http.Handler
+	HasMethod(method string) bool
+}
+
+// WithDefault returns a handler serving the calls with s, except the calls of
+// methods s doesn't have, which are served by h, e.g. a MethodHandler, instead
+// of being rejected. codec, if not nil, is the codec registered to s, whose
+// aliases are resolved.
+func WithDefault(s MethodServer, codec *Codec, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opts := defaultOptions
+		if codec != nil {
+			opts = &codec.Options
+		}
+		method, _, err := readMethod(r, opts)
+		if err != nil {
+			writeFault(w, FaultDecode)
+			return
+		}
+		if codec != nil {
+			if alias, ok := codec.aliases[method]; ok {
+				method = alias
+			}
+		}
+		if s.HasMethod(method) {
+			s.ServeHTTP(w, r)
+		} else {
+			h.ServeHTTP(w, r)
+		}
+	})
+}
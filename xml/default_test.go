@@ -0,0 +1,69 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+func TestWithDefault(t *testing.T) {
+	codec := NewCodec()
+	codec.RegisterAlias("math.multiply", "Service1.Multiply")
+	s := rpc.NewServer()
+	s.RegisterCodec(codec, "text/xml")
+	s.RegisterService(new(Service1), "")
+
+	var called string
+	h := MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		called = method
+		if len(params) == 0 {
+			return nil, errors.New("no params")
+		}
+		return params, nil
+	})
+	ts := httptest.NewServer(WithDefault(s, codec, h))
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	var res Service1Response
+	if err := client.Call("math.multiply", &Service1Request{4, 2}, &res); err != nil || res.Result != 8 {
+		t.Error("Expected 8, got", res.Result, err)
+	}
+	if called != "" {
+		t.Error("Expected the default handler not to be called, got", called)
+	}
+
+	params, err := client.CallValues("wp.echo", NewString("hello"))
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if called != "wp.echo" || len(params) != 1 || params[0].Text != "hello" {
+		t.Error("Expected the params echoed, got", called, params)
+	}
+
+	_, err = client.CallValues("wp.echo")
+	if fault, ok := err.(Fault); !ok || fault.Code != FaultApplicationError.Code {
+		t.Error("Expected application error fault, got", err)
+	}
+
+	// the method is read from a body in another charset
+	latin1 := NewClient(ts.URL)
+	latin1.Charset = "ISO-8859-1"
+	if err := latin1.Call("math.multiply", &Service1Request{4, 2}, &res); err != nil || res.Result != 8 {
+		t.Error("Expected 8, got", res.Result, err)
+	}
+	params, err = latin1.CallValues("wp.echo", NewString("héllo"))
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if len(params) != 1 || params[0].Text != "héllo" {
+		t.Error("Expected the params echoed, got", params)
+	}
+}
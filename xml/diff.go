@@ -0,0 +1,133 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+)
+
+// ChangeType tells how a node differs between two value trees.
+type ChangeType int
+
+// Change types.
+const (
+	Added ChangeType = iota
+	Removed
+	Modified
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	default:
+		return "modified"
+	}
+}
+
+// Change is a single difference found by Diff.
+type Change struct {
+	Type ChangeType
+
+	// Path locates the node, e.g. ".processes[2].state"; it's empty for the
+	// root.
+	Path string
+
+	// From is the node in the first tree, To is the node in the second one.
+	// From is zero for Added, and To is zero for Removed.
+	From, To Value
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s: %s -> %s", c.Type, c.Path, c.From, c.To)
+}
+
+// Diff compares two value trees structurally. Struct members are matched by
+// name regardless of their order, array items by index, and scalars by
+// meaning rather than lexical form, so <i4>1</i4> equals <int>01</int>.
+func Diff(a, b Value) []Change {
+	var changes []Change
+	diffValue("", a, b, &changes)
+	return changes
+}
+
+func diffValue(path string, a, b Value, changes *[]Change) {
+	if a.Kind != b.Kind {
+		*changes = append(*changes, Change{Type: Modified, Path: path, From: a, To: b})
+		return
+	}
+
+	switch a.Kind {
+	case KindStruct:
+		for _, m := range a.Members {
+			bv, ok := b.Member(m.Name)
+			if !ok {
+				*changes = append(*changes, Change{Type: Removed, Path: path + "." + m.Name, From: m.Value})
+				continue
+			}
+			diffValue(path+"."+m.Name, m.Value, bv, changes)
+		}
+		for _, m := range b.Members {
+			if _, ok := a.Member(m.Name); !ok {
+				*changes = append(*changes, Change{Type: Added, Path: path + "." + m.Name, To: m.Value})
+			}
+		}
+	case KindArray:
+		for i, item := range a.Items {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			if i >= len(b.Items) {
+				*changes = append(*changes, Change{Type: Removed, Path: itemPath, From: item})
+				continue
+			}
+			diffValue(itemPath, item, b.Items[i], changes)
+		}
+		for i := len(a.Items); i < len(b.Items); i++ {
+			*changes = append(*changes, Change{Type: Added, Path: fmt.Sprintf("%s[%d]", path, i), To: b.Items[i]})
+		}
+	default:
+		if !scalarsEqual(a, b) {
+			*changes = append(*changes, Change{Type: Modified, Path: path, From: a, To: b})
+		}
+	}
+}
+
+// scalarsEqual compares two scalars of the same kind by meaning.
+func scalarsEqual(a, b Value) bool {
+	switch a.Kind {
+	case KindNil:
+		return true
+	case KindInt:
+		x, okx := new(big.Int).SetString(a.Text, 10)
+		y, oky := new(big.Int).SetString(b.Text, 10)
+		if okx && oky {
+			return x.Cmp(y) == 0
+		}
+	case KindDouble:
+		x, errx := a.Double()
+		y, erry := b.Double()
+		if errx == nil && erry == nil {
+			return x == y
+		}
+	case KindBoolean:
+		return a.Boolean() == b.Boolean()
+	case KindDateTime:
+		x, errx := a.DateTime()
+		y, erry := b.DateTime()
+		if errx == nil && erry == nil {
+			return x.Equal(y)
+		}
+	case KindBase64:
+		x, errx := a.Base64()
+		y, erry := b.Base64()
+		if errx == nil && erry == nil {
+			return bytes.Equal(x, y)
+		}
+	}
+	return a.Text == b.Text
+}
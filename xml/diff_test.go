@@ -0,0 +1,38 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a, _ := ParseValue([]byte(`<value><struct>
+	<member><name>id</name><value><i4>1</i4></value></member>
+	<member><name>ok</name><value><boolean>1</boolean></value></member>
+	<member><name>state</name><value><string>RUNNING</string></value></member>
+	<member><name>logs</name><value><array><data><value>a</value></data></array></value></member>
+</struct></value>`))
+	b, _ := ParseValue([]byte(`<value><struct><member><name>ok</name><value><boolean>true</boolean></value></member><member><name>id</name><value><int>01</int></value></member><member><name>logs</name><value><array><data><value>a</value><value>b</value></data></array></value></member><member><name>pid</name><value><int>7</int></value></member></struct></value>`))
+
+	expected := []Change{
+		{Type: Removed, Path: ".state", From: NewString("RUNNING")},
+		{Type: Added, Path: ".logs[1]", To: NewString("b")},
+		{Type: Added, Path: ".pid", To: NewInt(7)},
+	}
+	if changes := Diff(a, b); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %v, got %v", expected, changes)
+	}
+
+	if changes := Diff(a, a); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+
+	changes := Diff(NewInt(1), NewString("1"))
+	if len(changes) != 1 || changes[0].Type != Modified {
+		t.Errorf("expected kind change, got %v", changes)
+	}
+}
@@ -37,6 +37,358 @@ The following types are supported:
     array               []interface{}
     nil                 nil
 
+Integers and doubles may also be decoded into big.Int and big.Float fields (or
+pointers to them), which are encoded back as int and double. A number that
+doesn't fit into int or float64 is decoded into a string field verbatim instead
+of failing.
+
+Arrays may also be decoded into slices of any supported type, e.g.
+[]time.Time, and Go arrays like [3]int, whose length must then match: a fixed
+array receiving more or fewer items is rejected with an invalid params fault.
+An empty <array> leaves a slice empty, whatever the type of its items.
+
+Nonstandard value tags, like <decimal> or <uuid>, can be mapped to Go types with
+RegisterScalar.
+
+Value holds a value tree decoded without a target Go type. A field of type Value
+receives the tree as is, ParseValue parses a single <value> element, and Diff
+compares two trees structurally. ValueToJSON and JSONToValue translate value
+trees to and from JSON.
+
+Params
+
+The fields of the args and reply structs are the params, in order. A slice
+field is a single array param, unless it's tagged `xmlrpc:",variadic"`: then
+its elements are separate params, and on decoding it receives all the
+remaining params, so it must be the last field.
+
+Integer and string fields tagged `xmlrpc:",enum=a|b|c"`, and values of types
+registered with RegisterEnum, travel as the <string> names: the integer i is
+sent as the i-th name, and strings must be one of the names. Other values are
+rejected with an invalid params fault.
+
+A struct field tagged `xmlrpc:",tuple"` travels as an array of its exported
+fields, in order, for the positional tuples of mixed types some services
+return, e.g. ["supervisor", 3, true]. The number of items must match.
+
+The server codec enforces the required, min=N, max=N, maxlen=N and regexp=RE
+options of the args fields, down to the structs in slices, arrays and maps,
+before calling the method, so invalid calls never reach it. The rpc server answers a rejected call with a plain 400 error and
+the violations as text, not with a fault; wrap it with ValidationFaults to
+answer with an invalid params fault listing the violations instead, e.g.
+
+	Name string `xmlrpc:",required,maxlen=64,regexp=^[a-z]+$"`
+
+A param that can't be decoded into its field is reported with its path, line
+and column in the document, and the expected and received types, e.g.
+
+	Invalid Method Parameters: fields type mismatch: value type bool != field type int
+	at params[2].value.struct.member[Data].array.data.value[1] (line 10, column 9):
+	expected int, got <boolean>
+
+The decoding faults keep their codes but wrap error classes, so they can be
+told apart with errors.Is and errors.As: ErrMalformedXML, ErrTypeMismatch,
+ErrUnknownMember and ErrArityMismatch. Get the Fault itself with errors.As
+too, as a classed fault isn't a Fault value.
+
+RunLoad fires randomized calls at a server to size it. The args are
+generated from their types, honoring the enum, min, max, maxlen and required
+options, and the report holds the latency histogram and the faults by code:
+
+	report, err := xml.RunLoad(ctx, xml.NewClient(url), xml.LoadConfig{
+		Methods:     []xml.LoadMethod{{Name: "Arith.Multiply", Args: &Args{}}},
+		Concurrency: 16,
+		Duration:    time.Minute,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(report.Calls, report.Latency.Percentile(99), report.Faults)
+
+The same random data is available from a Generator, for property-based
+tests: RoundTrip checks that a value survives encoding and decoding, and
+RoundTripValue does the same for value trees.
+
+BenchmarkCorpus holds representative documents, from supervisor, WordPress,
+multicalls and big arrays and binaries. RunBenchmarks runs them from a
+benchmark of user code, and MeasureBenchmarks and Regressions gate a build
+on a saved baseline:
+
+	if r := xml.Regressions(baseline, xml.MeasureBenchmarks(), 0.1); len(r) != 0 {
+		log.Fatal(strings.Join(r, "\n"))
+	}
+
+Busy servers can set Codec.ArenaSize to decode the slices of the args into
+an Arena recycled after every response, instead of allocating them one by
+one. The methods must then not retain the slices of their args.
+
+Compile resolves the encoders of a hot type once, instead of on every value,
+and registers them, several times speeding up the encoding of its values:
+
+	xml.Compile(Reply{})
+
+Where reflection is costly, cmd/xmlrpc-gen generates the XMLRPCEncode and
+XMLRPCDecode methods of the structs marked //xmlrpc:generate, which the codec
+uses in place of reflection (see GenerateCodecs):
+
+	//go:generate xmlrpc-gen $GOFILE
+
+A Profile bundles the quirks of a family of peers: member naming, nil and
+string encoding, date layouts, charset. ProfileWordPress, ProfileSupervisor,
+ProfileBugzilla, ProfileOdoo and ProfileRTorrent are prebuilt:
+
+	client := xml.ProfileOdoo.NewClient("http://localhost:8069/xmlrpc/2/object")
+
+Codec.Tune adjusts the Options of every request. Set from RuntimeOptions, it
+lets operators flip e.g. lenient parsing on without a restart:
+
+	flags := xml.NewRuntimeOptions(codec.Options)
+	codec.Tune = flags.Tune
+
+Client.Bind encodes the constant leading params of a method once, e.g. the
+credentials, so only the others are encoded on every call:
+
+	getPost, err := client.Bind("wp.getPost", blogID, user, password)
+	...
+	err = getPost.Call(&post, postID)
+
+A Session calls the APIs taking a login token as first param: it logs in on
+the first call, passes the token, and logs in again and retries when the
+token expired. Session.Expired tells the expired token faults of the API,
+e.g. FaultCodes(401).
+
+Responses may be checked against a schema before they are decoded, so a call
+fails with a SchemaError, carrying the response, instead of leaving zero the
+fields not fitting (see WithReplySchema and Client.AssertReplies).
+
+ProjectResponse parses only the selected parts of a large response, skipping
+the rest, e.g. a few members of every process of supervisor:
+
+	params, err := xml.ProjectResponse(body, "params[0][*].name", "params[0][*].statename")
+
+Proxies forwarding most of the documents untouched can keep the params as
+LazyValue, parsed only in the parts accessed and copied as they are when
+encoded (see ParseLazyMethodCall and EncodeLazyMethodCall).
+
+Client.Stats returns a snapshot of the counters of a client: calls, faults by
+code, bytes, retries, connections and latencies, e.g. to publish with expvar:
+
+	expvar.Publish("xmlrpc", expvar.Func(func() interface{} { return client.Stats() }))
+
+Codec.Stats counts the calls of every method, with their errors and decoding
+and encoding times. ServerStats is also a handler serving them as JSON:
+
+	codec.Stats = &xml.ServerStats{}
+	admin.Handle("/debug/xmlrpc", codec.Stats)
+
+Codec.ProfileLabels labels the calls with their method for pprof, so the CPU
+profiles of a busy server tell the time spent by every method.
+
+SlowCalls logs the calls of a Client or a Codec lasting more than a
+threshold, with their method, params and peer:
+
+	codec.SlowCalls = &xml.SlowCalls{Threshold: time.Second, Logger: log.Default()}
+
+Methods may call other servers, or their own, passing r.Context() on to the
+client. LinkCalls serves the calls with their CallLink, so the nested calls
+carry their depth, and those nested too deep are rejected instead of
+exhausting the server:
+
+	http.Handle("/RPC2", xml.LinkCalls(s, 8))
+
+Client.CallReader passes the response to a function reading it, e.g. with
+ProjectReader. With Client.Spill, the responses larger than a threshold are
+buffered in a temporary file, removed afterwards, bounding the memory:
+
+	client.Spill = &xml.Spill{Threshold: 8 << 20}
+
+Some legacy peers stuff binary data into strings. The []byte fields tagged
+`xmlrpc:",string"` are encoded as raw <string> values instead of <base64>,
+and decoded from either; Options.BinaryStrings decodes the strings into
+any []byte field:
+
+	type Blob struct {
+		Data []byte `xmlrpc:",string"`
+	}
+
+Options.JSONNames names the members after the json tags of the fields, so
+the types shared with JSON APIs are encoded and decoded alike:
+
+	type Post struct {
+		PostID int `json:"post_id"`
+	}
+
+Options.JSONCompat goes further, for the model packages of JSON APIs: the
+structs follow the encoding/json conventions for the names, omitempty, the
+fields tagged "-" and the embedded structs.
+
+The name of the xmlrpc tag of a field, as in `xmlrpc:"user_id"` or
+`xmlrpc:"user_id,required"`, is the name of its member, when encoding and
+decoding. It takes precedence over the xml tag, MemberNames and JSONNames.
+The name of the xml tag, as in `xml:"user_id"`, is only used to encode,
+unless MemberNames or JSONNames is set.
+
+The fields tagged `xmlrpc:"-"`, e.g. secrets or computed values, are left
+out of the structs and tuples: they are never encoded, and never decoded,
+the members matching them being dropped.
+
+The fields tagged `xmlrpc:",encodeonly"` are sent but never decoded, e.g.
+a password, and those tagged `xmlrpc:",decodeonly"` are decoded but never
+sent; in tuples, the latter are sent as zero values to keep the positions.
+
+The string fields tagged e.g. `xmlrpc:",normalize=trim|fold"` are
+normalized once decoded, so inconsistent upstreams key alike. Other
+normalizers, like the Unicode normal forms, are added with
+RegisterNormalizer:
+
+	xml.RegisterNormalizer("nfc", norm.NFC.String)
+
+Some servers mean null by <value></value> or <value><string/></value>.
+Options.EmptyValues decodes them as nil pointers (EmptyNil), zero values
+(EmptyZero) or the Null sentinel (EmptyNull), instead of empty strings.
+
+DecodeResponseBody decodes a response body, e.g. of an http.Response,
+closing it in any case and failing with ErrResponseTooLarge beyond a max
+size; Client.MaxResponseSize caps the responses of the calls likewise.
+
+The Codec serves the github.com/gorilla/rpc servers; the package rpcv2
+adapts it to the github.com/gorilla/rpc/v2 ones.
+
+CheckConformance sends every type and edge case to an echo method of a
+peer, and reports the values not echoed unchanged; EchoHandler is the echo
+method for the peers checking this package. The command does it too:
+
+	xmlrpc -conformance http://localhost:8000/RPC2 echo
+
+RegisterValidator1 serves the methods of the classic validator1 suite
+(validator1.easyStructTest, etc.), and RunValidator1 calls them, so a
+deployment can check its compliance against itself or the validators.
+
+InjectFaults wraps a server injecting failures into the calls of the
+methods with a probability: delays, faults, corrupt or truncated responses,
+for testing the retries and error handling of the clients.
+
+Client.Coalesce names the read methods whose identical concurrent calls
+share a single request and its response, e.g. for a dashboard fanning out.
+
+Client.TransformResponses rewrites the params of the responses to some
+methods before they're decoded, e.g. with UnwrapSingleton for the peers
+wrapping their scalars in one-element arrays.
+
+Client.Cache serves the calls of some methods from a cache, in memory or
+on disk across the runs of a program, with a TTL and a stale-while-revalidate
+period (see ResponseCache).
+
+The values implementing encoding.TextMarshaler, e.g. net.IP, and url.URL
+are encoded as strings, and decoded from them with UnmarshalText or
+url.Parse; the fmt.Stringer values are encoded as strings with the Stringers
+option.
+
+The DateTimeFormat option encodes the dateTime.iso8601 values with an
+offset or fractional seconds, e.g. with DateTimeOffset, for the peers
+requiring them; both are always decoded, in the basic or extended format.
+
+The time.Time fields tagged `xmlrpc:",unix"` are encoded as <int> Unix
+seconds, and those tagged `xmlrpc:",unix=double"` as <double> seconds with
+fractions, for the peers abusing numbers for timestamps; the zero time is 0.
+
+The responses are parsed without reflection, and the decoding of the
+struct fields is planned once per reply type, so the loops decoding many
+replies of the same type, e.g. monitoring, don't inspect their tags again.
+
+The FaultSanitizer of a Codec truncates the faultStrings and strips the
+stack traces, control characters and internal hostnames from them, logging
+the full errors:
+
+	codec.FaultSanitizer = &xml.FaultSanitizer{MaxLength: 256, StripStackTraces: true,
+		StripControl: true, Hostnames: []string{"*.internal"}, Logger: log.Default()}
+
+The CorrelationIDs of a Codec tag every request with an ID, returned in the
+X-Correlation-Id header and included in its faultStrings and log lines, so
+the users reporting an opaque fault can be matched to the server logs;
+CorrelateCalls passes the IDs on to the methods and the calls they make:
+
+	codec.CorrelationIDs = &xml.CorrelationIDs{Template: "{fault} (ref {id})"}
+	http.Handle("/RPC2", xml.CorrelateCalls(s, nil))
+
+AccessLog logs every request of a server in the Common or Combined Log
+Format, followed by the method called, the fault code and the duration, to
+a Logger, or an io.Writer with WriterLogger:
+
+	http.Handle("/RPC2", xml.AccessLog(s, xml.WriterLogger(file), xml.CombinedLogFormat))
+
+Quota attributes the calls of a server to API keys, told by a callback from
+the headers or params, and enforces per-key limits per minute and per day;
+the Quotas serve the usage of the keys as JSON, as the ServerStats do.
+
+TailLog streams a log polled with an offset-based method, e.g.
+supervisor.tailProcessStdoutLog, as an io.Reader, keeping the offsets; the
+method is only called as the stream is read.
+
+Paginate iterates the items of a method taking an offset and a limit and
+returning an array, fetching the pages lazily, with a page size and stop
+conditions.
+
+The BinarySidecar of a Codec sends the large base64 values as binary parts
+of a multipart/related response, a third smaller, to the clients accepting
+it, as those with BinarySidecar set do; the others get them inline:
+
+	codec.BinarySidecar = 64 << 10
+	client.BinarySidecar = true
+
+ServeCGI and ServeStream serve the calls with a CallFunc over CGI, or the
+stdin and stdout of an inetd service, so tiny utility daemons can expose
+XML-RPC without an HTTP stack.
+
+ListenAndServeSystemd serves a handler on the sockets passed by systemd
+socket activation, or on an address when run by hand; SystemdListeners
+returns the sockets, to serve them otherwise.
+
+The AfterCall hook of a Codec is given the wall time, CPU time and
+allocations of every call, to find the pathological methods and payloads
+in production; the allocations are those of the whole process meanwhile,
+so they are approximate on a busy server:
+
+	codec.AfterCall = func(u xml.CallUsage) {
+		if u.AllocBytes > 64<<20 {
+			log.Printf("%s allocated %d bytes in %v", u.Method, u.AllocBytes, u.CPU)
+		}
+	}
+
+A Registry serves calls with services registered and unregistered while it
+serves, so plugins can expose their methods as they load, with no restart:
+
+	reg := xml.NewRegistry(xml.NewCodec())
+	reg.RegisterService(new(PluginService), "")
+	reg.UnregisterService("PluginService")
+
+A MethodProvider implements methods with no Go receiver, e.g. those of a
+plugin .so or of a script, listing them and calling them with Values; it's
+registered to a Registry under a service name, alongside the services:
+
+	reg.RegisterProvider("math", provider)
+
+The package script loads methods written in Starlark as a MethodProvider,
+converting their params and results, for shims and mocks changed without
+recompiling.
+
+NewLoopbackClient returns a Client calling a handler in process through a
+Loopback transport, with no network, so unit tests are fast and
+deterministic while the documents are still encoded and decoded:
+
+	client := xml.NewLoopbackClient(s)
+
+The package corpus embeds real-world payloads of WordPress, Supervisor and
+Odoo, anonymized, with snapshots of their value trees, and helpers
+comparing their typed decoding with golden files, so the decoding is
+checked to stay stable across versions, here and downstream.
+
+With Go 1.18 or later, the generic Call, Decode and DecodeValue return
+their reply typed, and a TypedMethod fixes the args and reply types of a
+method, checked at compile time:
+
+	reply, err := xml.Call[MultiplyArgs, MultiplyReply](ctx, client, "Arith.Multiply", MultiplyArgs{4, 2})
+
 TODO
 
 TODO list:
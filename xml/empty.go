@@ -0,0 +1,73 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"reflect"
+	"strings"
+)
+
+// EmptyPolicy tells how the empty values, <value></value> and
+// <value><string/></value>, are decoded, for servers meaning null by them
+// (see Options.EmptyValues).
+type EmptyPolicy int
+
+const (
+	// EmptyString decodes the empty values as empty strings, failing on the
+	// fields of other types. It's the default.
+	EmptyString EmptyPolicy = iota
+
+	// EmptyNil sets the pointer, interface, slice and map fields to nil,
+	// decoding the empty values as empty strings into the others.
+	EmptyNil
+
+	// EmptyZero sets the fields to their zero value, whatever their type.
+	EmptyZero
+
+	// EmptyNull is like EmptyZero, but sets the interface fields to Null,
+	// and the Value fields to a KindNil value, telling the null values apart
+	// from the missing members.
+	EmptyNull
+)
+
+// Null is the value of the interface fields decoded from an empty value
+// with EmptyNull. It's encoded as <nil/>.
+var Null = null{}
+
+type null struct{}
+
+// isEmptyElement reports whether value is <value></value> or
+// <value><string/></value>.
+func isEmptyElement(value value) bool {
+	switch strings.TrimSpace(value.Raw) {
+	case "", "<string></string>", "<string/>":
+		return true
+	}
+	return false
+}
+
+// empty2Field decodes the empty value into field as told by policy. It
+// reports whether value was handled.
+func empty2Field(policy EmptyPolicy, value value, field *reflect.Value) bool {
+	if policy == EmptyString || !isEmptyElement(value) {
+		return false
+	}
+	switch field.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+	default:
+		if policy == EmptyNil {
+			return false
+		}
+	}
+	switch {
+	case policy == EmptyNull && field.Type() == valueType:
+		field.Set(reflect.ValueOf(Value{Kind: KindNil}))
+	case policy == EmptyNull && field.Kind() == reflect.Interface && reflect.TypeOf(Null).Implements(field.Type()):
+		field.Set(reflect.ValueOf(Null))
+	default:
+		field.Set(reflect.Zero(field.Type()))
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"testing"
+)
+
+type emptyReply struct {
+	Name  string
+	Count int
+	Ptr   *int
+	Any   interface{}
+	Raw   Value
+	List  []string
+}
+
+func emptyResponse() string {
+	var params string
+	for i := 0; i < 6; i++ {
+		if i%2 == 0 {
+			params += "<param><value></value></param>"
+		} else {
+			params += "<param><value><string/></value></param>"
+		}
+	}
+	return toResponse("<params>" + params + "</params>")
+}
+
+func TestEmptyString(t *testing.T) {
+	var reply emptyReply
+	if err := decodeRPC(emptyResponse(), &reply, &Options{}); err == nil {
+		t.Error("Expected the empty values failing on an int field by default")
+	}
+}
+
+func TestEmptyNil(t *testing.T) {
+	n := 1
+	reply := emptyReply{Name: "n", Ptr: &n, Any: 1, List: []string{"a"}}
+	resp := toResponse("<params><param><value></value></param><param><value><int>2</int></value></param>" +
+		"<param><value><string/></value></param><param><value></value></param></params>")
+	if err := decodeRPC(resp, &reply, &Options{EmptyValues: EmptyNil}); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Name != "" || reply.Count != 2 || reply.Ptr != nil || reply.Any != nil {
+		t.Errorf("Expected the nilable fields nil, got %+v", reply)
+	}
+}
+
+func TestEmptyZero(t *testing.T) {
+	n := 1
+	reply := emptyReply{Name: "n", Count: 3, Ptr: &n, Any: 1, List: []string{"a"}}
+	if err := decodeRPC(emptyResponse(), &reply, &Options{EmptyValues: EmptyZero}); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Name != "" || reply.Count != 0 || reply.Ptr != nil || reply.Any != nil || reply.List != nil {
+		t.Errorf("Expected the fields zero, got %+v", reply)
+	}
+}
+
+func TestEmptyNull(t *testing.T) {
+	var reply emptyReply
+	if err := decodeRPC(emptyResponse(), &reply, &Options{EmptyValues: EmptyNull}); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Any != Null || reply.Raw.Kind != KindNil || reply.Count != 0 {
+		t.Errorf("Expected Null, got %+v", reply)
+	}
+
+	var buffer bytes.Buffer
+	if err := rpcParams2XML(&struct{ Any interface{} }{Null}, &buffer, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<params><param><value><nil/></value></param></params>"; buffer.String() != want {
+		t.Errorf("Expected %s, got %s", want, buffer.String())
+	}
+}
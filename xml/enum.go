@@ -0,0 +1,140 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var enums = struct {
+	sync.RWMutex
+	byType map[reflect.Type][]string
+}{byType: make(map[reflect.Type][]string)}
+
+// RegisterEnum makes values of the type of sample travel as the <string>
+// names, instead of their Go value. For an integer type, the value i is sent
+// as names[i]; for a string type, the values are sent as is but must be one
+// of names. Values out of range are rejected with an invalid params fault.
+//
+// A single field is given the same treatment with an `xmlrpc:",enum=a|b|c"`
+// tag. RegisterEnum panics if the type isn't an integer or string type.
+func RegisterEnum(sample interface{}, names ...string) {
+	typ := reflect.TypeOf(sample)
+	if typ == nil || !isEnumKind(typ.Kind()) {
+		panic(fmt.Sprintf("xmlrpc: RegisterEnum of non-integer, non-string type %v", typ))
+	}
+
+	enums.Lock()
+	defer enums.Unlock()
+	enums.byType[typ] = names
+}
+
+func isEnumKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.String:
+		return true
+	}
+	return false
+}
+
+func enumByType(typ reflect.Type) []string {
+	enums.RLock()
+	defer enums.RUnlock()
+	return enums.byType[typ]
+}
+
+// enumNames returns the enum names of field, from its enum tag option or
+// from its registered type, or nil if it isn't an enum.
+func enumNames(field reflect.StructField) []string {
+	_, opts := parseTag(field)
+	opts, _, _ = opts.cut("regexp")
+	if list, ok := opts.Get("enum"); ok && isEnumKind(field.Type.Kind()) {
+		return strings.Split(list, "|")
+	}
+	return enumByType(field.Type)
+}
+
+// enumName returns the name of the enum value v.
+func enumName(names []string, v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		for _, name := range names {
+			if name == v.String() {
+				return name, nil
+			}
+		}
+		return "", invalidParams("%q is not one of %s", v.String(), strings.Join(names, "|"))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i := v.Int(); i >= 0 && i < int64(len(names)) {
+			return names[i], nil
+		}
+		return "", invalidParams("%d is out of the %s enum range", v.Int(), strings.Join(names, "|"))
+	default:
+		if i := v.Uint(); i < uint64(len(names)) {
+			return names[i], nil
+		}
+		return "", invalidParams("%d is out of the %s enum range", v.Uint(), strings.Join(names, "|"))
+	}
+}
+
+// enum2XML writes the enum value v as its <string> name.
+func enum2XML(names []string, v reflect.Value, writer io.Writer) error {
+	name, err := enumName(names, v)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(writer, "<value>")
+	string2XML(name, writer)
+	fmt.Fprintf(writer, "</value>")
+	return nil
+}
+
+// enumIndex returns the <string> name of value and its index in names, or -1
+// if it isn't one of them.
+func enumIndex(names []string, value value) (string, int) {
+	name := value.String
+	if name == "" && len(value.Custom) == 0 {
+		// untyped value
+		name = value.Raw
+	}
+	for i, n := range names {
+		if n == name {
+			return name, i
+		}
+	}
+	return name, -1
+}
+
+// enum2Field decodes the <string> name of value into the enum field.
+func enum2Field(names []string, value value, field *reflect.Value) error {
+	if !field.CanSet() {
+		return FaultApplicationError
+	}
+	name, i := enumIndex(names, value)
+	if i < 0 {
+		return invalidParams("%q is not one of %s", name, strings.Join(names, "|"))
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(name)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		field.SetInt(int64(i))
+	default:
+		field.SetUint(uint64(i))
+	}
+	return nil
+}
+
+// enumValue is a param value carrying the names of its enum.
+type enumValue struct {
+	names []string
+	value reflect.Value
+}
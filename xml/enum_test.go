@@ -0,0 +1,89 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"reflect"
+	"testing"
+)
+
+type ProcessState int
+
+const (
+	StateStopped ProcessState = iota
+	StateStarting
+	StateRunning
+)
+
+func init() {
+	RegisterEnum(StateStopped, "STOPPED", "STARTING", "RUNNING")
+}
+
+type EnumStruct struct {
+	Level  int    `xmlrpc:",enum=debug|info|warn"`
+	Color  string `xmlrpc:",enum=red|green"`
+	States []ProcessState
+}
+
+type EnumParams struct {
+	State  ProcessState
+	Detail EnumStruct
+}
+
+func TestEnumRoundTrip(t *testing.T) {
+	req := &EnumParams{StateRunning, EnumStruct{1, "green", []ProcessState{StateStopped, StateStarting}}}
+	xmlStr, err := rpcRequest2XML("Enum", req)
+	if err != nil {
+		t.Fatal("RPC2XML conversion failed", err)
+	}
+	expected := "<methodCall><methodName>Enum</methodName><params>" +
+		"<param><value><string>RUNNING</string></value></param>" +
+		"<param><value><struct>" +
+		"<member><name>Level</name><value><string>info</string></value></member>" +
+		"<member><name>Color</name><value><string>green</string></value></member>" +
+		"<member><name>States</name><value><array><data>" +
+		"<value><string>STOPPED</string></value><value><string>STARTING</string></value>" +
+		"</data></array></value></member>" +
+		"</struct></value></param>" +
+		"</params></methodCall>"
+	if xmlStr != expected {
+		t.Error("Expected", expected)
+		t.Error("Got", xmlStr)
+	}
+
+	res := new(EnumParams)
+	if err := xml2RPC(xmlStr, res); err != nil {
+		t.Fatal("XML2RPC conversion failed", err)
+	}
+	if !reflect.DeepEqual(res, req) {
+		t.Error("Expected", req)
+		t.Error("Got", res)
+	}
+	if issues := Validate([]byte(xmlStr), res); len(issues) != 0 {
+		t.Error("Expected no validation issues, got", issues)
+	}
+}
+
+func TestEnumOutOfRange(t *testing.T) {
+	for _, req := range []*EnumParams{
+		{State: 3},
+		{Detail: EnumStruct{Level: -1, Color: "red"}},
+		{Detail: EnumStruct{Color: "blue"}},
+	} {
+		if _, err := rpcRequest2XML("Enum", req); err == nil {
+			t.Error("Expected encoding to fail for", req)
+		}
+	}
+
+	xmlStr := "<methodResponse><params><param><value><string>PAUSED</string></value></param></params></methodResponse>"
+	err := xml2RPC(xmlStr, new(EnumParams))
+	fault, ok := err.(Fault)
+	if !ok || fault.Code != FaultInvalidParams.Code {
+		t.Error("Expected invalid params fault, got", err)
+	}
+	if issues := Validate([]byte(xmlStr), new(EnumParams)); len(issues) == 0 || issues[0].Path != "params[0]" {
+		t.Error("Expected an issue for params[0], got", issues)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// Envelope adds and removes vendor-specific wrappers around the XML-RPC
+// documents exchanged with a peer. Wrap is applied to the documents sent,
+// after the EncodeHook, and Unwrap to the documents received, before they
+// are parsed.
+type Envelope interface {
+	Wrap(body []byte) ([]byte, error)
+	Unwrap(body []byte) ([]byte, error)
+}
+
+// ElementEnvelope wraps documents in an element, preceded by processing
+// instructions, e.g.
+//
+//    <?vendor version="2"?><rpc xmlns="urn:vendor"><methodResponse>...</methodResponse></rpc>
+//
+// Its Unwrap extracts the <methodCall> or <methodResponse> element wherever
+// it is, so it accepts documents with or without any wrapper.
+type ElementEnvelope struct {
+	// Name is the wrapper element, e.g. "rpc". No element is added if empty.
+	Name string
+
+	// Attrs are written as is in the wrapper start tag, e.g.
+	// `xmlns="urn:vendor"`.
+	Attrs string
+
+	// ProcInsts are written as is before the wrapper, e.g.
+	// `<?vendor version="2"?>`.
+	ProcInsts []string
+}
+
+// Wrap implements Envelope.
+func (e ElementEnvelope) Wrap(body []byte) ([]byte, error) {
+	buffer := bytes.NewBuffer(make([]byte, 0, len(body)+64))
+	for _, pi := range e.ProcInsts {
+		buffer.WriteString(pi)
+	}
+	if e.Name == "" {
+		buffer.Write(body)
+		return buffer.Bytes(), nil
+	}
+	if e.Attrs != "" {
+		fmt.Fprintf(buffer, "<%s %s>", e.Name, e.Attrs)
+	} else {
+		fmt.Fprintf(buffer, "<%s>", e.Name)
+	}
+	buffer.Write(body)
+	fmt.Fprintf(buffer, "</%s>", e.Name)
+	return buffer.Bytes(), nil
+}
+
+// Unwrap implements Envelope.
+func (e ElementEnvelope) Unwrap(body []byte) ([]byte, error) {
+	return extractDocument(body)
+}
+
+// extractDocument returns the <methodCall> or <methodResponse> element of
+// body, preceded by the XML declaration of body, if any, so its encoding is
+// kept.
+func extractDocument(body []byte) ([]byte, error) {
+	var decl []byte
+	d := xml.NewDecoder(bytes.NewReader(body))
+	// only the markup up to the document is parsed, so the offsets are kept
+	// in body bytes whatever the encoding
+	d.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	for {
+		start := d.InputOffset()
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("xmlrpc: no methodCall or methodResponse element")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.ProcInst:
+			if t.Target == "xml" {
+				decl = body[start:d.InputOffset()]
+			}
+		case xml.StartElement:
+			if t.Name.Local != "methodCall" && t.Name.Local != "methodResponse" {
+				continue
+			}
+			end := documentEnd(body, int(start), t.Name.Local)
+			if end < 0 {
+				return nil, fmt.Errorf("xmlrpc: unterminated %s element", t.Name.Local)
+			}
+			if decl == nil && len(bytes.TrimSpace(body[:start])) == 0 && len(bytes.TrimSpace(body[end:])) == 0 {
+				// nothing to strip
+				return body, nil
+			}
+			doc := append([]byte{}, decl...)
+			return append(doc, body[start:end]...), nil
+		}
+	}
+}
+
+// documentEnd returns the offset following the last end tag of the element
+// local, possibly prefixed, after start, or -1.
+func documentEnd(body []byte, start int, local string) int {
+	if bytes.HasPrefix(bytes.TrimSpace(body[start:]), []byte("<"+local+"/>")) {
+		return start + bytes.Index(body[start:], []byte("/>")) + 2
+	}
+	i := bytes.LastIndex(body[start:], []byte(local+">"))
+	if i < 0 {
+		return -1
+	}
+	i += start
+	j := bytes.LastIndex(body[start:i], []byte("</"))
+	if j < 0 || bytes.ContainsAny(body[start+j+2:i], "<> \t\r\n") {
+		return -1
+	}
+	return i + len(local) + 1
+}
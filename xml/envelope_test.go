@@ -0,0 +1,75 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestElementEnvelope(t *testing.T) {
+	env := ElementEnvelope{Name: "rpc", Attrs: `xmlns="urn:vendor"`, ProcInsts: []string{`<?vendor version="2"?>`}}
+	doc := "<methodResponse><params><param><value><int>1</int></value></param></params></methodResponse>"
+
+	wrapped, err := env.Wrap([]byte(doc))
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	expected := `<?vendor version="2"?><rpc xmlns="urn:vendor">` + doc + "</rpc>"
+	if string(wrapped) != expected {
+		t.Error("Expected", expected, "got", string(wrapped))
+	}
+
+	for body, expected := range map[string]string{
+		string(wrapped): doc,
+		doc:             doc,
+		`<?xml version="1.0" encoding="ISO-8859-1"?><?vendor?><wrap><methodResponse/></wrap>`: `<?xml version="1.0" encoding="ISO-8859-1"?><methodResponse/>`,
+		"<a><b><methodCall><methodName>x</methodName></methodCall></b>\n</a>":                 "<methodCall><methodName>x</methodName></methodCall>",
+	} {
+		doc, err := env.Unwrap([]byte(body))
+		if err != nil {
+			t.Error("Expected err to be nil, but got:", err)
+		}
+		if string(doc) != expected {
+			t.Error("Expected", expected, "got", string(doc))
+		}
+	}
+
+	if _, err := env.Unwrap([]byte("<rpc><nothing/></rpc>")); err == nil {
+		t.Error("Expected err for a document without methodResponse")
+	}
+}
+
+func TestEnvelopeClientServer(t *testing.T) {
+	env := ElementEnvelope{Name: "rpc"}
+	codec := NewCodec()
+	codec.Envelope = env
+	ts := newTestServer(codec)
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	client.Envelope = env
+	var res Service1Response
+	if err := client.Call("Service1.Multiply", &Service1Request{4, 2}, &res); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if res.Result != 8 {
+		t.Errorf("Wrong response: %v.", res.Result)
+	}
+
+	body := "<rpc><methodCall><methodName>Service1.Multiply</methodName><params><param><value><int>3</int></value></param><param><value><int>3</int></value></param></params></methodCall></rpc>"
+	resp, err := http.Post(ts.URL, "text/xml", strings.NewReader(body))
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	defer resp.Body.Close()
+	data, _ := ioutil.ReadAll(resp.Body)
+	if !bytes.HasPrefix(data, []byte("<rpc><methodResponse>")) || !bytes.Contains(data, []byte("<int>9</int>")) {
+		t.Error("Unexpected response", string(data))
+	}
+}
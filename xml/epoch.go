@@ -0,0 +1,87 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isEpoch reports whether field is a time.Time encoded as Unix seconds, as
+// tagged with `xmlrpc:",unix"` for <int> seconds or `xmlrpc:",unix=double"`
+// for <double> seconds with fractions, and whether it's a double.
+func isEpoch(field reflect.StructField) (epoch, double bool) {
+	_, opts := parseTag(field)
+	opts, _, _ = opts.cut("regexp")
+	form, ok := opts.Get("unix")
+	if !ok || field.Type != timeType {
+		return false, false
+	}
+	return true, form == "double"
+}
+
+// epochTime is a param value encoded as Unix seconds.
+type epochTime struct {
+	t      time.Time
+	double bool
+}
+
+// epoch2XML writes t as Unix seconds, an <int>, or a <double> with the
+// fractions. The zero time is written as 0.
+func epoch2XML(t time.Time, double bool, writer io.Writer) {
+	var sec int64
+	var nsec int
+	if !t.IsZero() {
+		sec, nsec = t.Unix(), t.Nanosecond()
+	}
+	if !double {
+		fmt.Fprintf(writer, "<value><int>%d</int></value>", sec)
+		return
+	}
+	f := float64(sec) + float64(nsec)/1e9
+	fmt.Fprintf(writer, "<value><double>%s</double></value>", strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// epoch2Field decodes the Unix seconds of an <int>, <i4>, <i8> or <double>
+// value into the time.Time field, in the local time. 0 is decoded as the
+// zero time.
+func epoch2Field(value value, field *reflect.Value) error {
+	var text string
+	for _, s := range []string{value.Int, value.Int4, value.Int8, value.Double} {
+		if s != "" {
+			text = strings.TrimSpace(s)
+			break
+		}
+	}
+	if text == "" {
+		return invalidParams("expected Unix seconds for %s, got <%s>", field.Type(), wireType(value))
+	}
+	var t time.Time
+	if value.Double == "" {
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return invalidParams("can't decode Unix seconds %q", text)
+		}
+		if n != 0 {
+			t = time.Unix(n, 0)
+		}
+	} else {
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return invalidParams("can't decode Unix seconds %q", text)
+		}
+		if f != 0 {
+			sec, frac := math.Modf(f)
+			t = time.Unix(int64(sec), int64(math.Round(frac*1e9)))
+		}
+	}
+	field.Set(reflect.ValueOf(t))
+	return nil
+}
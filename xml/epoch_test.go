@@ -0,0 +1,90 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+type epochArgs struct {
+	Started time.Time `xmlrpc:",unix"`
+	Now     time.Time `xmlrpc:",unix=double"`
+	Stopped time.Time `xmlrpc:",unix"`
+}
+
+func TestEpochEncode(t *testing.T) {
+	args := epochArgs{Started: time.Unix(1342526935, 0), Now: time.Unix(1342526935, 250e6)}
+	var buffer bytes.Buffer
+	if err := rpcParams2XML(&args, &buffer, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "<params><param><value><int>1342526935</int></value></param>" +
+		"<param><value><double>1342526935.25</double></value></param>" +
+		"<param><value><int>0</int></value></param></params>"
+	if buffer.String() != want {
+		t.Errorf("Expected %q, got %q", want, buffer.String())
+	}
+
+	// the members, compiled or not
+	for _, opts := range []*Options{{}, {UntypedStrings: true}} {
+		buffer.Reset()
+		if err := rpcParams2XML(&struct{ Args epochArgs }{args}, &buffer, opts); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(buffer.Bytes(), []byte("<name>Now</name><value><double>1342526935.25</double></value>")) {
+			t.Errorf("Expected the members as Unix seconds, got %q", buffer.String())
+		}
+	}
+}
+
+func TestEpochDecode(t *testing.T) {
+	resp := toResponse("<params><param><value><i4>1342526935</i4></value></param>" +
+		"<param><value><double>1342526935.25</double></value></param>" +
+		"<param><value><int>0</int></value></param></params>")
+	var reply epochArgs
+	if err := decodeRPC(resp, &reply, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if !reply.Started.Equal(time.Unix(1342526935, 0)) || !reply.Now.Equal(time.Unix(1342526935, 250e6)) ||
+		!reply.Stopped.IsZero() {
+		t.Errorf("Unexpected times %+v", reply)
+	}
+
+	bad := toResponse("<params><param><value><string>1342526935</string></value></param></params>")
+	if err := decodeRPC(bad, &reply, &Options{}); err == nil {
+		t.Error("Expected a string not to be decoded as Unix seconds")
+	}
+	if issues := Validate([]byte(bad), &reply); len(issues) == 0 {
+		t.Error("Expected a validation issue")
+	}
+	if issues := Validate([]byte(resp), &reply); len(issues) != 0 {
+		t.Error("Expected no validation issue, got", issues)
+	}
+}
+
+func TestEpochTuple(t *testing.T) {
+	type point struct {
+		At    time.Time `xmlrpc:",unix"`
+		Value int
+	}
+	args := struct {
+		P point `xmlrpc:",tuple"`
+	}{point{time.Unix(100, 0), 1}}
+	var buffer bytes.Buffer
+	if err := rpcParams2XML(&args, &buffer, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buffer.Bytes(), []byte("<data><value><int>100</int></value><value><int>1</int></value></data>")) {
+		t.Errorf("Expected the tuple item as Unix seconds, got %q", buffer.String())
+	}
+	var reply struct {
+		P point `xmlrpc:",tuple"`
+	}
+	if err := decodeRPC(toResponse(buffer.String()), &reply, &Options{}); err != nil || !reply.P.At.Equal(args.P.At) {
+		t.Error("Expected the tuple to round-trip, got", reply, err)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error classes of the faults returned on decoding. The faults keep their
+// codes, so they are sent to the peer as before, but callers can tell the
+// classes apart with errors.Is and errors.As instead of matching the fault
+// strings, e.g.
+//
+//    var mismatch xml.ErrTypeMismatch
+//    if errors.As(err, &mismatch) {
+//        log.Printf("bad %s: %s sent for %s", mismatch.Field, mismatch.Got, mismatch.Expected)
+//    }
+var (
+	// ErrMalformedXML is the class of the parsing faults: the document isn't
+	// well formed XML-RPC.
+	ErrMalformedXML = errors.New("xmlrpc: malformed XML")
+
+	// ErrUnknownMember is the class of the faults for struct members with no
+	// matching field.
+	ErrUnknownMember = errors.New("xmlrpc: unknown struct member")
+
+	// ErrArityMismatch is the class of the faults for calls with a wrong
+	// number of params.
+	ErrArityMismatch = errors.New("xmlrpc: wrong number of params")
+
+	// ErrResponseTooLarge is returned for the responses exceeding their max
+	// size (see Client.MaxResponseSize and DecodeResponseBody).
+	ErrResponseTooLarge = errors.New("xmlrpc: response too large")
+)
+
+// ErrTypeMismatch is the class of the faults for values that can't be decoded
+// into their fields.
+type ErrTypeMismatch struct {
+	// Field is the path of the value in the document, e.g.
+	// "params[0].value.struct.member[Age]".
+	Field string
+
+	// Expected is the Go type of the field, e.g. "int".
+	Expected string
+
+	// Got is the element name of the type received, e.g. "boolean".
+	Got string
+}
+
+func (e ErrTypeMismatch) Error() string {
+	return fmt.Sprintf("xmlrpc: %s: expected %s, got <%s>", e.Field, e.Expected, e.Got)
+}
@@ -0,0 +1,59 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrorClasses(t *testing.T) {
+	var args struct {
+		Name string
+		Age  int
+	}
+
+	err := xml2RPC("<methodCall><params>", &args)
+	if !errors.Is(err, ErrMalformedXML) {
+		t.Error("Expected ErrMalformedXML, got", err)
+	}
+	if err != FaultDecode {
+		t.Error("Expected FaultDecode, got", err)
+	}
+
+	err = xml2RPC(`<methodCall><params><param><value><string>Ivan</string></value></param><param><value><boolean>1</boolean></value></param></params></methodCall>`, &args)
+	var mismatch ErrTypeMismatch
+	if !errors.As(err, &mismatch) {
+		t.Fatal("Expected ErrTypeMismatch, got", err)
+	}
+	if mismatch.Field != "params[1].value" || mismatch.Expected != "int" || mismatch.Got != "boolean" {
+		t.Errorf("Unexpected mismatch %+v", mismatch)
+	}
+
+	var person struct {
+		P struct{ Name string }
+	}
+	err = xml2RPC(`<methodCall><params><param><value><struct><member><name>Nick</name><value><string>vano</string></value></member></struct></value></param></params></methodCall>`, &person)
+	if !errors.Is(err, ErrUnknownMember) {
+		t.Error("Expected ErrUnknownMember, got", err)
+	}
+
+	err = decodeRPC(`<methodCall><params><param><value><string>Ivan</string></value></param></params></methodCall>`, &args, &Options{StrictParams: true})
+	if !errors.Is(err, ErrArityMismatch) || errors.Is(err, ErrMalformedXML) {
+		t.Error("Expected ErrArityMismatch, got", err)
+	}
+	var fault Fault
+	if !errors.As(err, &fault) || fault.Code != FaultWrongArgumentsNumber.Code {
+		t.Error("Expected wrong arguments number fault, got", err)
+	}
+
+	// the faults keep their shape
+	if fault != (Fault{-32602, fault.String}) {
+		t.Error("Expected the fault comparable, got", fault)
+	}
+	if errors.Is(FaultInvalidParams, ErrArityMismatch) {
+		t.Error("Expected no class for a fault value")
+	}
+}
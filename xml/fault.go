@@ -5,6 +5,7 @@
 package xml
 
 import (
+	"errors"
 	"fmt"
 	"io"
 )
@@ -33,6 +34,54 @@ func (f Fault) Error() string {
 	return fmt.Sprintf("%d: %s", f.Code, f.String)
 }
 
+// Is reports whether the fault is of the class target. Every parsing fault,
+// e.g. FaultDecode, is an ErrMalformedXML, even if received from the peer.
+func (f Fault) Is(target error) bool {
+	return target == ErrMalformedXML && f.Code == FaultDecode.Code
+}
+
+// withCause returns the fault with the error class cause.
+func (f Fault) withCause(cause error) error {
+	return classedFault{f, cause}
+}
+
+// classedFault is a fault raised locally on decoding with its error class,
+// see errors.go. It's sent as the fault, and errors.As gets the fault out of
+// it.
+type classedFault struct {
+	Fault
+	class error
+}
+
+// Unwrap returns the error class of the fault, e.g. ErrTypeMismatch.
+func (f classedFault) Unwrap() error {
+	return f.class
+}
+
+// As sets target to the fault, if it's a *Fault.
+func (f classedFault) As(target interface{}) bool {
+	if fault, ok := target.(*Fault); ok {
+		*fault = f.Fault
+		return true
+	}
+	return false
+}
+
+// asFault returns the fault err is or wraps, e.g. a classedFault.
+func asFault(err error) (Fault, bool) {
+	var fault Fault
+	ok := errors.As(err, &fault)
+	return fault, ok
+}
+
+// invalidParams returns FaultInvalidParams detailed with the formatted
+// message.
+func invalidParams(format string, args ...interface{}) Fault {
+	fault := FaultInvalidParams
+	fault.String += ": " + fmt.Sprintf(format, args...)
+	return fault
+}
+
 // Fault2XML is a quick 'marshalling' replacemnt for the Fault case.
 func Fault2XML(fault Fault, buffer io.Writer) {
 	fmt.Fprintf(buffer, "<methodResponse><fault>")
@@ -0,0 +1,100 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import "path"
+
+// ResponseFilter transforms the params of the responses to the calls of
+// method before they are sent, e.g. to enforce a policy on every method of a
+// Codec or a Proxy without modifying the handlers. Faults aren't filtered.
+type ResponseFilter func(method string, params []Value) []Value
+
+// FilterMethods returns a ResponseFilter applying f to the responses to the
+// methods matching pattern only, where * matches any run of characters, as
+// with Router.
+func FilterMethods(pattern string, f ResponseFilter) ResponseFilter {
+	return func(method string, params []Value) []Value {
+		if ok, _ := path.Match(pattern, method); !ok {
+			return params
+		}
+		return f(method, params)
+	}
+}
+
+// StripMembers returns a ResponseFilter removing the struct members called
+// names, at any depth.
+func StripMembers(names ...string) ResponseFilter {
+	strip := make(map[string]bool, len(names))
+	for _, name := range names {
+		strip[name] = true
+	}
+	var filter func(v Value) Value
+	filter = func(v Value) Value {
+		switch v.Kind {
+		case KindStruct:
+			members := make([]Member, 0, len(v.Members))
+			for _, m := range v.Members {
+				if !strip[m.Name] {
+					members = append(members, Member{m.Name, filter(m.Value)})
+				}
+			}
+			v.Members = members
+		case KindArray:
+			v.Items = mapValues(v.Items, filter)
+		}
+		return v
+	}
+	return func(method string, params []Value) []Value {
+		return mapValues(params, filter)
+	}
+}
+
+// TruncateArrays returns a ResponseFilter keeping the first max items of the
+// arrays, at any depth.
+func TruncateArrays(max int) ResponseFilter {
+	var filter func(v Value) Value
+	filter = func(v Value) Value {
+		switch v.Kind {
+		case KindStruct:
+			members := make([]Member, len(v.Members))
+			for i, m := range v.Members {
+				members[i] = Member{m.Name, filter(m.Value)}
+			}
+			v.Members = members
+		case KindArray:
+			items := v.Items
+			if len(items) > max {
+				items = items[:max]
+			}
+			v.Items = mapValues(items, filter)
+		}
+		return v
+	}
+	return func(method string, params []Value) []Value {
+		return mapValues(params, filter)
+	}
+}
+
+// mapValues returns the values returned by f for values.
+func mapValues(values []Value, f func(Value) Value) []Value {
+	mapped := make([]Value, len(values))
+	for i, v := range values {
+		mapped[i] = f(v)
+	}
+	return mapped
+}
+
+// filterResponse runs filters on the methodResponse document data to the call
+// of method. Faults and documents that can't be parsed are returned as is.
+func filterResponse(filters []ResponseFilter, method string, data []byte) []byte {
+	params, err := ParseMethodResponse(data)
+	if err != nil {
+		return data
+	}
+	for _, f := range filters {
+		params = f(method, params)
+	}
+	return EncodeMethodResponse(params)
+}
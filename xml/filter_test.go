@@ -0,0 +1,77 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+func TestResponseFilters(t *testing.T) {
+	v := NewStruct(
+		Member{"login", NewString("ivan")},
+		Member{"password", NewString("secret")},
+		Member{"keys", NewArray(NewInt(1), NewInt(2), NewInt(3), NewStruct(Member{"password", NewString("x")}))},
+	)
+	filtered := StripMembers("password")("", []Value{v})
+	filtered = TruncateArrays(2)("", filtered)
+	expected := NewStruct(
+		Member{"login", NewString("ivan")},
+		Member{"keys", NewArray(NewInt(1), NewInt(2))},
+	)
+	if filtered[0].String() != expected.String() {
+		t.Errorf("Expected %s, got %s", expected, filtered[0])
+	}
+	if _, ok := v.Member("password"); !ok {
+		t.Error("Expected the original value to be left untouched")
+	}
+}
+
+func TestCodecResponseFilters(t *testing.T) {
+	codec := NewCodec()
+	codec.ResponseFilters = []ResponseFilter{FilterMethods("Greeter.*", StripMembers("Name"))}
+	s := rpc.NewServer()
+	s.RegisterCodec(codec, "text/xml")
+	s.RegisterService(new(Greeter), "")
+	s.RegisterService(new(Service2), "")
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	var reply GreetReply
+	if err := client.Call("Greeter.Greet", &GreetArgs{GreetPerson{"Ivan"}, "!"}, &reply); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if reply.Person.Name != "" || reply.Greeting != "Hello, Ivan!" {
+		t.Errorf("Expected the name to be stripped, got %+v", reply)
+	}
+
+	var res Service2Response
+	if err := client.Call("Service2.GetGreeting", &Service2Request{"Johnny", 33, true}, &res); err != nil || res.Status != 42 {
+		t.Error("Expected other methods to be unfiltered, got", res, err)
+	}
+}
+
+func TestProxyResponseFilters(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(EncodeMethodResponse([]Value{NewArray(NewInt(1), NewInt(2), NewInt(3))}))
+	}))
+	defer upstream.Close()
+	proxy := NewProxy(upstream.URL)
+	proxy.ResponseFilters = []ResponseFilter{TruncateArrays(1)}
+	ts := httptest.NewServer(proxy)
+	defer ts.Close()
+
+	params, err := NewClient(ts.URL).CallValues("list")
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if len(params) != 1 || len(params[0].Items) != 1 {
+		t.Error("Expected the array to be truncated, got", params)
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxGeneratedLen caps the length of the generated strings, slices and
+	// binaries with no maxlen constraint.
+	maxGeneratedLen = 8
+
+	// maxGeneratedDepth caps the nesting of the generated slices.
+	maxGeneratedDepth = 3
+)
+
+const generatedRunes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 _-.<>&\"'"
+
+// Generator fills values with random data the codec can encode and decode:
+// the enum names, and numbers and lengths honoring the min, max and maxlen
+// options of the xmlrpc tags. The regexp option isn't honored. Interface
+// fields are left nil.
+//
+// Together with RoundTrip it checks that the values of a type survive the
+// codec, e.g. in a test:
+//
+//	g := xml.NewGenerator(1)
+//	for i := 0; i < 100; i++ {
+//		var args Args
+//		g.Fill(&args)
+//		if err := xml.RoundTrip(&args); err != nil {
+//			t.Error(err)
+//		}
+//	}
+//
+// A Generator isn't safe for concurrent use.
+type Generator struct {
+	rng   *rand.Rand
+	depth int
+}
+
+// NewGenerator returns a Generator seeded with seed, so the values can be
+// generated again.
+func NewGenerator(seed int64) *Generator {
+	return &Generator{rng: rand.New(rand.NewSource(seed))}
+}
+
+// Fill sets the value target points to to random data.
+func (g *Generator) Fill(target interface{}) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("xmlrpc: Fill target must be a non-nil pointer, not %T", target)
+	}
+	g.fill(rv.Elem(), nil)
+	return nil
+}
+
+// Value returns a random value tree, nested up to 3 levels.
+func (g *Generator) Value() Value {
+	return g.value()
+}
+
+// fill sets v, described by the struct field sf if not nil, to random data.
+func (g *Generator) fill(v reflect.Value, sf *reflect.StructField) {
+	var opts tagOptions
+	if sf != nil {
+		_, opts = parseTag(*sf)
+		opts, _, _ = opts.cut("regexp")
+		if names := enumNames(*sf); names != nil {
+			g.fillEnum(v, names)
+			return
+		}
+	}
+	if names := enumByType(v.Type()); names != nil {
+		g.fillEnum(v, names)
+		return
+	}
+
+	switch v.Type() {
+	case timeType:
+		// whole seconds in the local time, as on the wire
+		t := time.Unix(g.rng.Int63n(int64(70*365*24*time.Hour/time.Second)), 0)
+		v.Set(reflect.ValueOf(time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local)))
+		return
+	case bigIntType:
+		v.Set(reflect.ValueOf(new(big.Int).Lsh(big.NewInt(g.rng.Int63()), uint(g.rng.Intn(64)))).Elem())
+		return
+	case bigFloatType:
+		v.Set(reflect.ValueOf(new(big.Float).SetFloat64(g.float(opts))).Elem())
+		return
+	case valueType:
+		v.Set(reflect.ValueOf(g.value()))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(g.rng.Intn(2) == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		lo, hi := g.bounds(opts, -1000, 1000)
+		v.SetInt(int64(lo) + g.rng.Int63n(int64(hi-lo)+1))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		lo, hi := g.bounds(opts, 0, 1000)
+		v.SetUint(uint64(lo) + uint64(g.rng.Int63n(int64(hi-lo)+1)))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(g.float(opts))
+	case reflect.String:
+		runes := make([]byte, g.length(opts))
+		for i := range runes {
+			runes[i] = generatedRunes[g.rng.Intn(len(generatedRunes))]
+		}
+		v.SetString(string(runes))
+	case reflect.Slice:
+		n := g.length(opts)
+		if g.depth >= maxGeneratedDepth {
+			n = 0
+		}
+		slice := reflect.MakeSlice(v.Type(), n, n)
+		g.depth++
+		for i := 0; i < n; i++ {
+			g.fill(slice.Index(i), nil)
+		}
+		g.depth--
+		v.Set(slice)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			g.fill(v.Index(i), nil)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath == "" && !skipEncode(field) && !skipDecode(field) {
+				g.fill(v.Field(i), &field)
+			}
+		}
+	case reflect.Ptr:
+		p := reflect.New(v.Type().Elem())
+		g.fill(p.Elem(), sf)
+		v.Set(p)
+	}
+
+	if opts.Contains("required") && (v.IsZero() || v.Kind() == reflect.Slice && v.Len() == 0) {
+		g.fillRequired(v)
+	}
+}
+
+func (g *Generator) fillEnum(v reflect.Value, names []string) {
+	i := g.rng.Intn(len(names))
+	if v.Kind() == reflect.String {
+		v.SetString(names[i])
+	} else if v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64 {
+		v.SetUint(uint64(i))
+	} else {
+		v.SetInt(int64(i))
+	}
+}
+
+// fillRequired sets the zero value or empty slice v to a non-zero one.
+func (g *Generator) fillRequired(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1)
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Slice:
+		slice := reflect.MakeSlice(v.Type(), 1, 1)
+		g.fill(slice.Index(0), nil)
+		v.Set(slice)
+	}
+}
+
+// bounds returns the min and max options, defaulting to lo and hi.
+func (g *Generator) bounds(opts tagOptions, lo, hi float64) (float64, float64) {
+	if text, ok := opts.Get("min"); ok {
+		if n, err := strconv.ParseFloat(text, 64); err == nil {
+			lo = math.Ceil(n)
+			if hi < lo {
+				hi = lo + 1000
+			}
+		}
+	}
+	if text, ok := opts.Get("max"); ok {
+		if n, err := strconv.ParseFloat(text, 64); err == nil {
+			hi = math.Floor(n)
+			if lo > hi {
+				lo = hi - 1000
+			}
+		}
+	}
+	return lo, hi
+}
+
+// float returns a float within the bounds, with 3 decimals so it survives
+// the <double> encoding.
+func (g *Generator) float(opts tagOptions) float64 {
+	lo, hi := g.bounds(opts, -1000, 1000)
+	return math.Round((lo+g.rng.Float64()*(hi-lo))*1000) / 1000
+}
+
+// length returns a length up to the maxlen option.
+func (g *Generator) length(opts tagOptions) int {
+	limit := maxGeneratedLen
+	if text, ok := opts.Get("maxlen"); ok {
+		if n, err := strconv.Atoi(text); err == nil && n < limit {
+			limit = n
+		}
+	}
+	return g.rng.Intn(limit + 1)
+}
+
+// value returns a random value tree.
+func (g *Generator) value() Value {
+	kinds := []Kind{KindInt, KindDouble, KindBoolean, KindString, KindDateTime, KindBase64, KindStruct, KindArray}
+	if g.depth >= maxGeneratedDepth {
+		kinds = kinds[:6]
+	}
+	switch kinds[g.rng.Intn(len(kinds))] {
+	case KindInt:
+		return NewInt(g.rng.Int63n(2001) - 1000)
+	case KindDouble:
+		return NewDouble(g.float(""))
+	case KindBoolean:
+		return NewBoolean(g.rng.Intn(2) == 1)
+	case KindString:
+		var s string
+		g.fill(reflect.ValueOf(&s).Elem(), nil)
+		return NewString(s)
+	case KindDateTime:
+		var t time.Time
+		g.fill(reflect.ValueOf(&t).Elem(), nil)
+		return NewDateTime(t)
+	case KindBase64:
+		var b []byte
+		g.fill(reflect.ValueOf(&b).Elem(), nil)
+		return NewBase64(b)
+	case KindStruct:
+		g.depth++
+		defer func() { g.depth-- }()
+		members := make([]Member, g.rng.Intn(4))
+		for i := range members {
+			members[i] = Member{"m" + strconv.Itoa(i), g.value()}
+		}
+		return NewStruct(members...)
+	default:
+		g.depth++
+		defer func() { g.depth-- }()
+		items := make([]Value, g.rng.Intn(4))
+		for i := range items {
+			items[i] = g.value()
+		}
+		return NewArray(items...)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package xml
+
+import "context"
+
+// Decode decodes the methodResponse document data into a new T, a struct
+// whose fields are the params, as DecodeClientResponse:
+//
+//	reply, err := xml.Decode[MultiplyReply](body)
+func Decode[T any](data []byte) (T, error) {
+	var reply T
+	err := xml2RPC(string(data), &reply)
+	return reply, err
+}
+
+// DecodeValue stores the value tree v into a new T, as Value.Decode.
+func DecodeValue[T any](v Value) (T, error) {
+	var target T
+	err := v.Decode(&target)
+	return target, err
+}
+
+// Call invokes method with args, a struct whose fields are the params, and
+// returns the reply decoded into a new TReply, as Client.CallContext:
+//
+//	reply, err := xml.Call[MultiplyArgs, MultiplyReply](ctx, client, "Arith.Multiply", MultiplyArgs{4, 2})
+func Call[TArgs, TReply any](ctx context.Context, c *Client, method string, args TArgs) (TReply, error) {
+	var reply TReply
+	err := c.CallContext(ctx, method, &args, &reply)
+	return reply, err
+}
+
+// TypedMethod is a method of a Client whose args and reply types are fixed,
+// so its calls are checked at compile time:
+//
+//	var multiply = xml.NewTypedMethod[MultiplyArgs, MultiplyReply](client, "Arith.Multiply")
+//	reply, err := multiply.Call(ctx, MultiplyArgs{4, 2})
+type TypedMethod[TArgs, TReply any] struct {
+	client *Client
+	method string
+}
+
+// NewTypedMethod returns method of c, taking TArgs and returning TReply.
+func NewTypedMethod[TArgs, TReply any](c *Client, method string) TypedMethod[TArgs, TReply] {
+	return TypedMethod[TArgs, TReply]{client: c, method: method}
+}
+
+// Call invokes the method with args and returns its reply.
+func (m TypedMethod[TArgs, TReply]) Call(ctx context.Context, args TArgs) (TReply, error) {
+	return Call[TArgs, TReply](ctx, m.client, m.method, args)
+}
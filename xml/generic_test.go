@@ -0,0 +1,58 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package xml
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGenericCall(t *testing.T) {
+	ts := newTestServer(NewCodec())
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	res, err := Call[Service1Request, Service1Response](context.Background(), client, "Service1.Multiply", Service1Request{4, 2})
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if res.Result != 8 {
+		t.Errorf("Wrong response: %v.", res.Result)
+	}
+
+	greet := NewTypedMethod[Service2Request, Service2Response](client, "Service2.GetGreeting")
+	greeting, err := greet.Call(context.Background(), Service2Request{"Johnny", 33, true})
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if greeting.Status != 42 {
+		t.Errorf("Wrong response: %+v.", greeting)
+	}
+
+	if _, err := Call[Service1Request, Service1Response](context.Background(), client, "Service1.Unknown", Service1Request{}); err == nil {
+		t.Error("Expected the unknown method to fail")
+	}
+}
+
+func TestGenericDecode(t *testing.T) {
+	reply, err := Decode[Service1Response]([]byte(`<methodResponse><params><param><value><int>42</int></value></param></params></methodResponse>`))
+	if err != nil || reply.Result != 42 {
+		t.Errorf("Expected 42, got %d and %v", reply.Result, err)
+	}
+	if _, err := Decode[Service1Response]([]byte(`<methodResponse><fault><value><struct>
+		<member><name>faultCode</name><value><int>4</int></value></member>
+		<member><name>faultString</name><value><string>Too many</string></value></member>
+		</struct></value></fault></methodResponse>`)); err == nil || err.(Fault).Code != 4 {
+		t.Errorf("Expected the fault, got %v", err)
+	}
+
+	items, err := DecodeValue[[]int](NewArray(NewInt(1), NewInt(2)))
+	if err != nil || len(items) != 2 || items[1] != 2 {
+		t.Errorf("Expected [1 2], got %v and %v", items, err)
+	}
+}
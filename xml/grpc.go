@@ -0,0 +1,135 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+// GRPCInvoker performs a unary gRPC call. It has the shape of
+// grpc.ClientConn.Invoke without the call options, so a connection is adapted
+// with
+//
+//    func(ctx context.Context, method string, req, reply interface{}) error {
+//        return conn.Invoke(ctx, method, req, reply)
+//    }
+type GRPCInvoker func(ctx context.Context, fullMethod string, req, reply interface{}) error
+
+// GRPCMethod maps a XML-RPC method to a unary gRPC method.
+type GRPCMethod struct {
+	// FullMethod is the gRPC method, e.g. "/supervisor.Supervisor/GetState".
+	FullMethod string
+
+	// NewRequest and NewReply return pointers to empty request and reply
+	// messages, e.g. new(pb.GetStateRequest).
+	NewRequest func() interface{}
+	NewReply   func() interface{}
+}
+
+// GRPCGateway serves XML-RPC calls over HTTP by forwarding them to gRPC
+// backends, so legacy XML-RPC clients can talk to gRPC services.
+//
+// A call with a single struct param fills the request message fields by
+// member name; otherwise the params fill the exported message fields in
+// order. The reply message is returned as a single struct param whose
+// members are the exported reply fields. Errors of the backend are returned
+// as application error faults.
+type GRPCGateway struct {
+	invoke GRPCInvoker
+
+	mu      sync.RWMutex
+	methods map[string]GRPCMethod
+}
+
+// NewGRPCGateway returns a GRPCGateway performing calls with invoke.
+func NewGRPCGateway(invoke GRPCInvoker) *GRPCGateway {
+	return &GRPCGateway{
+		invoke:  invoke,
+		methods: make(map[string]GRPCMethod),
+	}
+}
+
+// Register routes the XML-RPC method to the gRPC method m.
+func (g *GRPCGateway) Register(method string, m GRPCMethod) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.methods[method] = m
+}
+
+// ServeHTTP implements http.Handler.
+func (g *GRPCGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	buffer := bytes.NewBuffer(make([]byte, 0))
+	if reply, err := g.call(r); err != nil {
+		fault, ok := asFault(err)
+		if !ok {
+			fault = FaultApplicationError
+			fault.String += fmt.Sprintf(": %v", err)
+		}
+		Fault2XML(fault, buffer)
+	} else {
+		buffer.Write(EncodeMethodResponse([]Value{reply}))
+	}
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	buffer.WriteTo(w)
+}
+
+func (g *GRPCGateway) call(r *http.Request) (Value, error) {
+	rawxml, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return Value{}, FaultSystemError
+	}
+	method, params, err := ParseMethodCall(rawxml)
+	if err != nil {
+		return Value{}, FaultDecode
+	}
+
+	g.mu.RLock()
+	m, ok := g.methods[method]
+	g.mu.RUnlock()
+	if !ok {
+		return Value{}, FaultInvalidMethodName
+	}
+
+	req := m.NewRequest()
+	if err := params2Message(params, req); err != nil {
+		return Value{}, err
+	}
+	reply := m.NewReply()
+	if err := g.invoke(r.Context(), m.FullMethod, req, reply); err != nil {
+		return Value{}, err
+	}
+	return NewValue(reply)
+}
+
+// params2Message fills the message msg, a pointer to struct, from params.
+func params2Message(params []Value, msg interface{}) error {
+	if len(params) == 1 && params[0].Kind == KindStruct {
+		return params[0].Decode(msg)
+	}
+
+	var fields []reflect.Value
+	rv := reflect.ValueOf(msg).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		if rv.Type().Field(i).PkgPath == "" {
+			fields = append(fields, rv.Field(i))
+		}
+	}
+	if len(params) > len(fields) {
+		return FaultWrongArgumentsNumber.withCause(ErrArityMismatch)
+	}
+	for i, p := range params {
+		if err := p.Decode(fields[i].Addr().Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+// messages shaped like protoc-gen-go output
+type greetRequest struct {
+	sizeCache int32
+	Name      string
+	Age       int
+}
+
+type greetReply struct {
+	sizeCache int32
+	Message   string
+}
+
+func TestGRPCGateway(t *testing.T) {
+	var called string
+	g := NewGRPCGateway(func(ctx context.Context, method string, req, reply interface{}) error {
+		called = method
+		r := req.(*greetRequest)
+		if r.Name == "nobody" {
+			return errors.New("unknown user")
+		}
+		reply.(*greetReply).Message = "Hello, " + r.Name
+		return nil
+	})
+	g.Register("greeter.greet", GRPCMethod{
+		FullMethod: "/greeter.Greeter/Greet",
+		NewRequest: func() interface{} { return new(greetRequest) },
+		NewReply:   func() interface{} { return new(greetReply) },
+	})
+	ts := httptest.NewServer(g)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	var res struct{ Reply struct{ Message string } }
+	if err := client.Call("greeter.greet", &struct {
+		Name string
+		Age  int
+	}{"Johnny", 33}, &res); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if called != "/greeter.Greeter/Greet" || res.Reply.Message != "Hello, Johnny" {
+		t.Errorf("Wrong response: %q from %q", res.Reply.Message, called)
+	}
+
+	type named struct{ Name string }
+	if err := client.Call("greeter.greet", &struct{ Args named }{named{"Jane"}}, &res); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if res.Reply.Message != "Hello, Jane" {
+		t.Errorf("Wrong response: %q", res.Reply.Message)
+	}
+
+	err := client.Call("greeter.greet", &struct{ Args named }{named{"nobody"}}, &res)
+	if fault, ok := err.(Fault); !ok || fault.Code != FaultApplicationError.Code {
+		t.Errorf("expected application error fault, got %v", err)
+	}
+	err = client.Call("greeter.unknown", &struct{}{}, &res)
+	if fault, ok := err.(Fault); !ok || fault.Code != FaultInvalidMethodName.Code {
+		t.Errorf("expected method not found fault, got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+// Probe checks whether a dependency of the server is ready.
+type Probe func(ctx context.Context) error
+
+// ProbeResult is the outcome of a single Probe.
+type ProbeResult struct {
+	Name  string `xml:"name"`
+	Ready bool   `xml:"ready"`
+	Error string `xml:"error"`
+}
+
+// HealthReport is the aggregate outcome of the readiness probes.
+type HealthReport struct {
+	Ready  bool          `xml:"ready"`
+	Probes []ProbeResult `xml:"probes"`
+}
+
+// PingReply is the reply of system.ping.
+type PingReply struct {
+	OK bool
+}
+
+// HealthReply is the reply of system.health.
+type HealthReply struct {
+	Report HealthReport
+}
+
+// Health is a service answering system.ping, which returns true while the
+// server is serving, and system.health, which runs the registered readiness
+// probes and returns a HealthReport. Health is also an http.Handler
+// answering 200 OK when all the probes pass and 503 otherwise, for load
+// balancers that can't speak XML-RPC.
+type Health struct {
+	mu     sync.RWMutex
+	probes map[string]Probe
+}
+
+// NewHealth returns a Health service without probes.
+func NewHealth() *Health {
+	return &Health{probes: make(map[string]Probe)}
+}
+
+// RegisterHealth registers h with s as the "system" service and makes codec
+// route system.ping and system.health to it.
+func RegisterHealth(s *rpc.Server, codec *Codec, h *Health) error {
+	if err := s.RegisterService(h, "system"); err != nil {
+		return err
+	}
+	codec.RegisterAlias("system.ping", "system.Ping")
+	codec.RegisterAlias("system.health", "system.Health")
+	return nil
+}
+
+// AddProbe registers the readiness probe name, replacing any previous one.
+func (h *Health) AddProbe(name string, probe Probe) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.probes[name] = probe
+}
+
+// RemoveProbe unregisters the readiness probe name.
+func (h *Health) RemoveProbe(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.probes, name)
+}
+
+// Check runs all the probes, ordered by name.
+func (h *Health) Check(ctx context.Context) HealthReport {
+	h.mu.RLock()
+	names := make([]string, 0, len(h.probes))
+	for name := range h.probes {
+		names = append(names, name)
+	}
+	probes := make(map[string]Probe, len(h.probes))
+	for name, probe := range h.probes {
+		probes[name] = probe
+	}
+	h.mu.RUnlock()
+	sort.Strings(names)
+
+	report := HealthReport{Ready: true, Probes: make([]ProbeResult, 0, len(names))}
+	for _, name := range names {
+		result := ProbeResult{Name: name, Ready: true}
+		if err := probes[name](ctx); err != nil {
+			result.Ready, result.Error = false, err.Error()
+			report.Ready = false
+		}
+		report.Probes = append(report.Probes, result)
+	}
+	return report
+}
+
+// Ping handles system.ping.
+func (h *Health) Ping(r *http.Request, args *struct{}, reply *PingReply) error {
+	reply.OK = true
+	return nil
+}
+
+// Health handles system.health.
+func (h *Health) Health(r *http.Request, args *struct{}, reply *HealthReply) error {
+	reply.Report = h.Check(r.Context())
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Health) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := h.Check(r.Context())
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if !report.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	for _, p := range report.Probes {
+		if p.Ready {
+			fmt.Fprintf(w, "%s: ok\n", p.Name)
+		} else {
+			fmt.Fprintf(w, "%s: %s\n", p.Name, p.Error)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+func TestHealth(t *testing.T) {
+	s := rpc.NewServer()
+	codec := NewCodec()
+	s.RegisterCodec(codec, "text/xml")
+	health := NewHealth()
+	if err := RegisterHealth(s, codec, health); err != nil {
+		t.Fatal("RegisterHealth failed", err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	var ping PingReply
+	if err := client.Call("system.ping", &struct{}{}, &ping); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if !ping.OK {
+		t.Error("expected ping to return true")
+	}
+
+	health.AddProbe("db", func(ctx context.Context) error { return nil })
+	health.AddProbe("cache", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	var res HealthReply
+	if err := client.Call("system.health", &struct{}{}, &res); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	expected := HealthReport{Ready: false, Probes: []ProbeResult{
+		{Name: "cache", Ready: false, Error: "connection refused"},
+		{Name: "db", Ready: true, Error: ""},
+	}}
+	if !reflect.DeepEqual(res.Report, expected) {
+		t.Errorf("expected %v, got %v", expected, res.Report)
+	}
+
+	w := httptest.NewRecorder()
+	health.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", w.Code)
+	}
+
+	health.RemoveProbe("cache")
+	w = httptest.NewRecorder()
+	health.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"path"
+	"time"
+)
+
+// Hedge configures the hedged calls of a Client: a call still waiting for
+// its response after Delay is duplicated to URL, and the first successful
+// response of the two is taken, the other request being cancelled. A call
+// failing before Delay is duplicated at once. This cuts the tail latency to
+// a server that's slow now and then, at the cost of some duplicate calls.
+//
+// As the duplicate may be executed too, only the idempotent calls are
+// hedged: the ones to Methods, and the ones made with WithIdempotencyKey,
+// whose key both requests carry.
+type Hedge struct {
+	// URL is the endpoint of the duplicate calls. It may be the URL of the
+	// Client, e.g. behind a load balancer.
+	URL string
+
+	// Delay is the time a call is given before it's duplicated, e.g. the
+	// 95th percentile latency of the server.
+	Delay time.Duration
+
+	// Methods are the patterns of the idempotent methods, as for path.Match,
+	// e.g. "system.*" or "blogger.getPost".
+	Methods []string
+}
+
+// applies reports whether the call of method with ctx is hedged by h, which
+// may be nil.
+func (h *Hedge) applies(ctx context.Context, method string) bool {
+	if h == nil {
+		return false
+	}
+	if _, ok := ctx.Value(idempotencyKeyContextKey).(string); ok {
+		return true
+	}
+	for _, pattern := range h.Methods {
+		if ok, _ := path.Match(pattern, method); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hedged sends the request body to the URL of the client and, after the
+// hedge delay, to the hedge URL, and returns the first successful response
+// body, or the first error if both fail.
+func (c *Client) hedged(ctx context.Context, contentType string, body []byte) ([]byte, error) {
+	// cancels the request left behind
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		body []byte
+		err  error
+	}
+	results := make(chan result, 2)
+	send := func(url string) {
+		b, err := c.send(ctx, url, contentType, body)
+		results <- result{b, err}
+	}
+
+	go send(c.URL)
+	timer := time.NewTimer(c.Hedge.Delay)
+	defer timer.Stop()
+
+	pending, hedged := 1, false
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			if !hedged {
+				hedged = true
+				pending++
+				c.stats.retry()
+				go send(c.Hedge.URL)
+			}
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				return r.body, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if !hedged {
+				hedged = true
+				pending++
+				c.stats.retry()
+				go send(c.Hedge.URL)
+			} else if pending == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// hedgeServer answers every call with its name after delay, counting the
+// calls and the cancelled ones.
+func hedgeServer(name string, delay time.Duration, calls, cancelled *int32) *httptest.Server {
+	return httptest.NewServer(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		atomic.AddInt32(calls, 1)
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			atomic.AddInt32(cancelled, 1)
+		}
+		return []Value{NewString(name)}, nil
+	}))
+}
+
+func TestHedgeTakesFirstResponse(t *testing.T) {
+	var slowCalls, slowCancelled, fastCalls, fastCancelled int32
+	slow := hedgeServer("slow", time.Second, &slowCalls, &slowCancelled)
+	defer slow.Close()
+	fast := hedgeServer("fast", 0, &fastCalls, &fastCancelled)
+	defer fast.Close()
+
+	client := NewClient(slow.URL)
+	client.Hedge = &Hedge{URL: fast.URL, Delay: 20 * time.Millisecond, Methods: []string{"get*"}}
+
+	start := time.Now()
+	params, err := client.CallValues("getThing")
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if params[0].Text != "fast" {
+		t.Error("Expected the hedged response, got", params[0].Text)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Error("Expected the slow call not to be waited for, took", elapsed)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if atomic.LoadInt32(&slowCancelled) != 1 {
+		t.Error("Expected the slow call to be cancelled")
+	}
+
+	// non-idempotent methods aren't hedged
+	atomic.StoreInt32(&fastCalls, 0)
+	client.URL = fast.URL
+	client.Hedge.URL = slow.URL
+	if _, err := client.CallValues("setThing"); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if atomic.LoadInt32(&fastCalls) != 1 {
+		t.Error("Expected a single call")
+	}
+}
+
+func TestHedgeIdempotencyKey(t *testing.T) {
+	var calls, cancelled int32
+	fast := hedgeServer("fast", 0, &calls, &cancelled)
+	defer fast.Close()
+
+	client := NewClient(fast.URL)
+	client.Hedge = &Hedge{URL: fast.URL, Delay: time.Millisecond}
+	if !client.Hedge.applies(WithIdempotencyKey(context.Background(), "k"), "setThing") {
+		t.Error("Expected the calls with an idempotency key to be hedged")
+	}
+	if client.Hedge.applies(context.Background(), "setThing") {
+		t.Error("Expected the other calls not to be hedged")
+	}
+}
+
+func TestHedgeFailover(t *testing.T) {
+	var calls, cancelled int32
+	fast := hedgeServer("fast", 0, &calls, &cancelled)
+	defer fast.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	client := NewClient(down.URL)
+	client.Hedge = &Hedge{URL: fast.URL, Delay: time.Hour, Methods: []string{"*"}}
+	params, err := client.CallValues("getThing")
+	if err != nil || params[0].Text != "fast" {
+		t.Error("Expected a failed call to be duplicated at once, got", err)
+	}
+
+	client.Hedge.URL = down.URL
+	if _, err := client.CallValues("getThing"); err == nil {
+		t.Error("Expected an error when both calls fail")
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+)
+
+// FieldDecodeHook transforms the wire value v, of Kind from, before it's
+// decoded into a field of type to, e.g. to decode epoch seconds into a
+// time.Time or "yes"/"no" strings into a bool.
+//
+// Returning a Value, e.g. v itself when the hook doesn't apply, makes the
+// decoding go on from it; any other result is stored into the field, which
+// it must be assignable or convertible to.
+type FieldDecodeHook func(from Kind, to reflect.Type, v Value) (interface{}, error)
+
+// FieldEncodeHook transforms the value v, of type from, before it's encoded,
+// reversing a FieldDecodeHook, e.g. to encode a time.Time as epoch seconds.
+//
+// Returning a Value writes it as is; any other result, e.g. v itself when
+// the hook doesn't apply, is encoded in place of v.
+type FieldEncodeHook func(from reflect.Type, v interface{}) (interface{}, error)
+
+// encodeHooks runs hooks on value and returns the value to encode.
+func encodeHooks(hooks []FieldEncodeHook, value interface{}) (interface{}, error) {
+	for _, hook := range hooks {
+		res, err := hook(reflect.TypeOf(value), value)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := res.(Value); ok {
+			return res, nil
+		}
+		value = res
+	}
+	return value, nil
+}
+
+// decodeHooks runs hooks on value. It returns the value to go on decoding
+// from, and reports whether the field has been set instead.
+func decodeHooks(hooks []FieldDecodeHook, value value, field *reflect.Value) (value, bool, error) {
+	v, err := value2Value(value)
+	if err != nil {
+		return value, false, FaultDecode
+	}
+	orig := v.String()
+
+	for _, hook := range hooks {
+		res, err := hook(v.Kind, field.Type(), v)
+		if err != nil {
+			if _, ok := asFault(err); ok {
+				return value, false, err
+			}
+			fault := FaultInvalidParams
+			fault.String += fmt.Sprintf(": %v", err)
+			return value, false, fault
+		}
+		if next, ok := res.(Value); ok {
+			v = next
+			continue
+		}
+		return value, true, setField(field, res)
+	}
+
+	if v.String() == orig {
+		return value, false, nil
+	}
+	var p param
+	if err := xml.Unmarshal([]byte("<param>"+v.String()+"</param>"), &p); err != nil {
+		return value, false, FaultDecode
+	}
+	return p.Value, false, nil
+}
+
+// setField stores res into field, converting it if needed.
+func setField(field *reflect.Value, res interface{}) error {
+	if res == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	rv := reflect.ValueOf(res)
+	switch {
+	case rv.Type().AssignableTo(field.Type()):
+		field.Set(rv)
+	case rv.Type().ConvertibleTo(field.Type()):
+		field.Set(rv.Convert(field.Type()))
+	default:
+		fault := FaultInvalidParams
+		fault.String += fmt.Sprintf(": fields type mismatch: value type %s != field type %s",
+			rv.Type(), field.Type())
+		return fault
+	}
+	return nil
+}
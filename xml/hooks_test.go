@@ -0,0 +1,121 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type HookedEvent struct {
+	Name    string
+	At      time.Time
+	Enabled bool
+	Retries int
+}
+
+var boolType = reflect.TypeOf(false)
+
+func epochDecodeHook(from Kind, to reflect.Type, v Value) (interface{}, error) {
+	if from != KindInt || to != timeType {
+		return v, nil
+	}
+	sec, err := v.Int()
+	if err != nil {
+		return nil, err
+	}
+	return time.Unix(sec, 0), nil
+}
+
+func yesNoDecodeHook(from Kind, to reflect.Type, v Value) (interface{}, error) {
+	if from != KindString || to != boolType {
+		return v, nil
+	}
+	switch v.Text {
+	case "yes":
+		return NewBoolean(true), nil
+	case "no":
+		return NewBoolean(false), nil
+	}
+	return nil, fmt.Errorf("%q is neither yes nor no", v.Text)
+}
+
+func TestDecodeHooks(t *testing.T) {
+	opts := &Options{DecodeHooks: []FieldDecodeHook{epochDecodeHook, yesNoDecodeHook}}
+	xmlStr := "<methodResponse><params><param><value><struct>" +
+		"<member><name>Name</name><value><string>deploy</string></value></member>" +
+		"<member><name>At</name><value><int>1400000000</int></value></member>" +
+		"<member><name>Enabled</name><value><string>yes</string></value></member>" +
+		"<member><name>Retries</name><value><int>3</int></value></member>" +
+		"</struct></value></param></params></methodResponse>"
+
+	res := new(struct{ Event HookedEvent })
+	if err := decodeRPC(xmlStr, res, opts); err != nil {
+		t.Fatal("XML2RPC conversion failed", err)
+	}
+	expected := HookedEvent{"deploy", time.Unix(1400000000, 0), true, 3}
+	if !reflect.DeepEqual(res.Event, expected) {
+		t.Error("Expected", expected)
+		t.Error("Got", res.Event)
+	}
+
+	xmlStr = "<methodResponse><params><param><value><struct>" +
+		"<member><name>Enabled</name><value><string>maybe</string></value></member>" +
+		"</struct></value></param></params></methodResponse>"
+	err := decodeRPC(xmlStr, res, opts)
+	if fault, ok := err.(Fault); !ok || fault.Code != FaultInvalidParams.Code {
+		t.Error("Expected invalid params fault, got", err)
+	}
+}
+
+func epochEncodeHook(from reflect.Type, v interface{}) (interface{}, error) {
+	if t, ok := v.(time.Time); ok {
+		return NewInt(t.Unix()), nil
+	}
+	return v, nil
+}
+
+func yesNoEncodeHook(from reflect.Type, v interface{}) (interface{}, error) {
+	if b, ok := v.(bool); ok {
+		if b {
+			return "yes", nil
+		}
+		return "no", nil
+	}
+	return v, nil
+}
+
+func TestEncodeHooksRoundTrip(t *testing.T) {
+	opts := &Options{
+		DecodeHooks: []FieldDecodeHook{epochDecodeHook, yesNoDecodeHook},
+		EncodeHooks: []FieldEncodeHook{epochEncodeHook, yesNoEncodeHook},
+	}
+	req := &struct{ Event HookedEvent }{HookedEvent{"deploy", time.Unix(1400000000, 0), false, 3}}
+	xmlStr, err := encodeRequest("Schedule", req, opts)
+	if err != nil {
+		t.Fatal("RPC2XML conversion failed", err)
+	}
+	expected := "<methodCall><methodName>Schedule</methodName><params><param><value><struct>" +
+		"<member><name>Name</name><value><string>deploy</string></value></member>" +
+		"<member><name>At</name><value><int>1400000000</int></value></member>" +
+		"<member><name>Enabled</name><value><string>no</string></value></member>" +
+		"<member><name>Retries</name><value><int>3</int></value></member>" +
+		"</struct></value></param></params></methodCall>"
+	if xmlStr != expected {
+		t.Error("Expected", expected)
+		t.Error("Got", xmlStr)
+	}
+
+	res := new(struct{ Event HookedEvent })
+	if err := decodeRPC(xmlStr, res, opts); err != nil {
+		t.Fatal("XML2RPC conversion failed", err)
+	}
+	if !reflect.DeepEqual(res, req) {
+		t.Error("Expected", req)
+		t.Error("Got", res)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the HTTP header carrying the idempotency key of a
+// call.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+type contextKey int
+
+const (
+	idempotencyKeyContextKey contextKey = iota
+	validationContextKey
+	replySchemaContextKey
+	callLinkContextKey
+	correlationIDContextKey
+)
+
+// WithIdempotencyKey returns a context making the client calls made with it
+// carry key in the Idempotency-Key header. Retries of one logical call must
+// use the same key, so the server can serve them from its cache.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey, key)
+}
+
+// idempotencyKey is the key of a call, within the scope of its caller.
+type idempotencyKey struct {
+	scope, key string
+}
+
+type idempotentCall struct {
+	depth   int
+	key     idempotencyKey
+	hash    [sha256.Size]byte
+	expires time.Time
+	done    chan struct{}
+	resp    *bufferedResponseWriter
+}
+
+// idempotencyHandler implements Idempotent.
+type idempotencyHandler struct {
+	next   http.Handler
+	window time.Duration
+	scope  func(r *http.Request) string
+
+	mu    sync.Mutex
+	calls map[idempotencyKey]*idempotentCall
+	queue []*idempotentCall // in expiry order
+}
+
+// Idempotent wraps the XML-RPC handler next, so calls carrying an
+// Idempotency-Key header are served once within window: repeated calls with
+// the same key get the original response, waiting for it if it's still in
+// progress. Calls without the header are passed through.
+//
+// Only the successful responses are kept: once a call of a key fails, with
+// a fault or an HTTP error, the next call of the key is served again.
+//
+// Reusing a key for a different request, or for a call nested in the call
+// of the key (see LinkCalls), which would wait for itself, is answered with
+// an invalid params fault.
+//
+// Keys are scoped by the Authorization header, so a caller can't get the
+// response to the call of another one by sending the same key and request;
+// see IdempotentScoped for other ways to tell callers apart.
+func Idempotent(next http.Handler, window time.Duration) http.Handler {
+	return IdempotentScoped(next, window, authorizationScope)
+}
+
+// IdempotentScoped is like Idempotent, but keys are scoped by the scope of
+// the request, e.g. the user of a session cookie or of a client
+// certificate. Calls of different scopes never share a response.
+func IdempotentScoped(next http.Handler, window time.Duration, scope func(r *http.Request) string) http.Handler {
+	return &idempotencyHandler{
+		next:   next,
+		window: window,
+		scope:  scope,
+		calls:  make(map[idempotencyKey]*idempotentCall),
+	}
+}
+
+func authorizationScope(r *http.Request) string {
+	return r.Header.Get("Authorization")
+}
+
+func (h *idempotencyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	header := r.Header.Get(IdempotencyKeyHeader)
+	if header == "" {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	_, rawxml, err := readMethod(r, defaultOptions)
+	if err != nil {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	hash := sha256.Sum256(rawxml)
+	depth, _ := strconv.Atoi(r.Header.Get(DepthHeader))
+	key := idempotencyKey{key: header}
+	if h.scope != nil {
+		key.scope = h.scope(r)
+	}
+
+	h.mu.Lock()
+	h.expire(time.Now())
+	call, ok := h.calls[key]
+	if !ok {
+		call = &idempotentCall{
+			depth:   depth,
+			key:     key,
+			hash:    hash,
+			expires: time.Now().Add(h.window),
+			done:    make(chan struct{}),
+		}
+		h.calls[key] = call
+		h.queue = append(h.queue, call)
+	}
+	h.mu.Unlock()
+
+	if ok {
+		if call.hash != hash {
+			fault := FaultInvalidParams
+			fault.String += ": idempotency key reused for a different request"
+			writeFault(w, fault)
+			return
+		}
+		select {
+		case <-call.done:
+		default:
+			if depth > call.depth {
+				// nested in the call, waiting for it would deadlock
+				fault := FaultInvalidParams
+				fault.String += ": idempotency key reused by a nested call"
+				writeFault(w, fault)
+				return
+			}
+			select {
+			case <-call.done:
+			case <-r.Context().Done():
+				// the client is gone
+				return
+			}
+		}
+		call.resp.writeTo(w)
+		return
+	}
+
+	call.resp = newBufferedResponseWriter()
+	defer close(call.done)
+	h.next.ServeHTTP(call.resp, r)
+	if !call.resp.succeeded() {
+		h.forget(call)
+	}
+	call.resp.writeTo(w)
+}
+
+// forget forgets call before its window is over, so its key can be served
+// again.
+func (h *idempotencyHandler) forget(call *idempotentCall) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.calls[call.key] == call {
+		delete(h.calls, call.key)
+	}
+}
+
+// expire forgets the calls whose window is over. Calls still in progress
+// are kept until they finish, without holding back the calls after them.
+func (h *idempotencyHandler) expire(now time.Time) {
+	var pending []*idempotentCall
+	i := 0
+	for ; i < len(h.queue) && now.After(h.queue[i].expires); i++ {
+		call := h.queue[i]
+		select {
+		case <-call.done:
+		default:
+			pending = append(pending, call)
+			continue
+		}
+		if h.calls[call.key] == call {
+			delete(h.calls, call.key)
+		}
+	}
+	if i > 0 {
+		h.queue = append(pending, h.queue[i:]...)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+type CounterArgs struct {
+	Delta int
+}
+
+type CounterReply struct {
+	Total int
+}
+
+type Counter struct {
+	total int
+}
+
+func (c *Counter) Add(r *http.Request, args *CounterArgs, reply *CounterReply) error {
+	c.total += args.Delta
+	reply.Total = c.total
+	return nil
+}
+
+func TestIdempotent(t *testing.T) {
+	s := rpc.NewServer()
+	s.RegisterCodec(NewCodec(), "text/xml")
+	s.RegisterService(new(Counter), "")
+	ts := httptest.NewServer(Idempotent(s, time.Minute))
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	ctx := WithIdempotencyKey(context.Background(), "start-1")
+	for i := 0; i < 2; i++ {
+		var res CounterReply
+		if err := client.CallContext(ctx, "Counter.Add", &CounterArgs{5}, &res); err != nil {
+			t.Fatal("Expected err to be nil, but got:", err)
+		}
+		if res.Total != 5 {
+			t.Errorf("call %d: expected total 5, got %d", i, res.Total)
+		}
+	}
+
+	var res CounterReply
+	err := client.CallContext(ctx, "Counter.Add", &CounterArgs{1}, &res)
+	if fault, ok := err.(Fault); !ok || fault.Code != FaultInvalidParams.Code {
+		t.Errorf("expected invalid params fault, got %v", err)
+	}
+
+	if err := client.Call("Counter.Add", &CounterArgs{1}, &res); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if res.Total != 6 {
+		t.Errorf("expected total 6, got %d", res.Total)
+	}
+}
+
+func TestIdempotentFailures(t *testing.T) {
+	calls := 0
+	release := make(chan struct{})
+	h := Idempotent(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		calls++
+		if method == "block" {
+			<-release
+		}
+		if calls == 1 {
+			return nil, FaultSystemError
+		}
+		return []Value{NewInt(int64(calls))}, nil
+	}), time.Minute)
+	serve := func(ctx context.Context, key, method string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/", bytes.NewReader(EncodeMethodCall(method, nil))).WithContext(ctx)
+		r.Header.Set(IdempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	// the fault isn't kept, the retry is served
+	if _, err := ParseMethodResponse(serve(context.Background(), "k1", "m").Body.Bytes()); err == nil {
+		t.Fatal("Expected the first call to fail")
+	}
+	params, err := ParseMethodResponse(serve(context.Background(), "k1", "m").Body.Bytes())
+	if err != nil || len(params) != 1 {
+		t.Fatal("Expected the retry served, got", params, err)
+	}
+	if n, _ := params[0].Int(); n != 2 {
+		t.Error("Expected the retry to call the method, got", n)
+	}
+
+	// a waiter gives up with its request
+	go serve(context.Background(), "k2", "block")
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		h.(*idempotencyHandler).mu.Lock()
+		_, started := h.(*idempotencyHandler).calls[idempotencyKey{key: "k2"}]
+		h.(*idempotencyHandler).mu.Unlock()
+		if started {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the call to start")
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		serve(ctx, "k2", "block")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("Expected the waiter to give up")
+	}
+	close(release)
+}
+
+func TestIdempotentScope(t *testing.T) {
+	calls := 0
+	h := Idempotent(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		calls++
+		return []Value{NewString(r.Header.Get("Authorization"))}, nil
+	}), time.Minute)
+	serve := func(auth string) string {
+		r := httptest.NewRequest("POST", "/", bytes.NewReader(EncodeMethodCall("whoami", nil)))
+		r.Header.Set(IdempotencyKeyHeader, "k1")
+		r.Header.Set("Authorization", auth)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		params, err := ParseMethodResponse(w.Body.Bytes())
+		if err != nil || len(params) != 1 {
+			t.Fatal("Expected err to be nil, but got:", params, err)
+		}
+		return params[0].Text
+	}
+
+	if got := serve("Bearer alice"); got != "Bearer alice" {
+		t.Error("Expected the call of alice, got", got)
+	}
+	if got := serve("Bearer bob"); got != "Bearer bob" || calls != 2 {
+		t.Errorf("Expected bob not to get the response of alice, got %q after %d calls", got, calls)
+	}
+	if got := serve("Bearer alice"); got != "Bearer alice" || calls != 2 {
+		t.Errorf("Expected the response of alice replayed, got %q after %d calls", got, calls)
+	}
+}
+
+func TestIdempotentExpire(t *testing.T) {
+	h := Idempotent(http.NotFoundHandler(), time.Minute).(*idempotencyHandler)
+	now := time.Now()
+	add := func(key string, expires time.Time, done bool) {
+		call := &idempotentCall{key: idempotencyKey{key: key}, expires: expires, done: make(chan struct{})}
+		if done {
+			close(call.done)
+		}
+		h.calls[call.key] = call
+		h.queue = append(h.queue, call)
+	}
+	add("slow", now.Add(-2*time.Minute), false)
+	add("done", now.Add(-time.Minute), true)
+	add("recent", now.Add(time.Minute), true)
+
+	h.expire(now)
+	if _, ok := h.calls[idempotencyKey{key: "done"}]; ok {
+		t.Error("Expected a call after a call in progress to expire")
+	}
+	if len(h.calls) != 2 || len(h.queue) != 2 || h.queue[0].key.key != "slow" || h.queue[1].key.key != "recent" {
+		t.Errorf("Expected the call in progress and the recent call kept, got %d calls, %d queued", len(h.calls), len(h.queue))
+	}
+
+	close(h.queue[0].done)
+	h.expire(now)
+	if len(h.calls) != 1 || len(h.queue) != 1 {
+		t.Errorf("Expected the finished call to expire, got %d calls, %d queued", len(h.calls), len(h.queue))
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// ValueToJSON converts a value tree into JSON using the following mapping:
+//
+//    XML-RPC             JSON
+//    -------             ----
+//    nil                 null
+//    int, i4             number
+//    double              number
+//    boolean             true or false
+//    string              string
+//    struct              object, keeping the members order
+//    array               array
+//    dateTime.iso8601    {"$dateTime.iso8601": "2012-07-17T14:08:55+02:00"}
+//    base64              {"$base64": "eW91IGNhbid0IHJlYWQgdGhpcyE="}
+//    other tags          {"$<tag>": "<text>"}
+//
+// dateTime values are formatted as RFC 3339 in the local time zone, as they're
+// decoded into time.Time. An object with a single "$"-prefixed key is a typed
+// scalar marker, so JSONToValue restores every value tree exactly. Member
+// names starting with "$" are escaped with another "$", e.g. "$ref" is
+// written as "$$ref", so a struct is never read back as a marker.
+//
+// NaN and infinite doubles have no JSON form and are rejected.
+func ValueToJSON(v Value) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := value2JSON(v, &buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func value2JSON(v Value, buffer *bytes.Buffer) error {
+	switch v.Kind {
+	case KindNil:
+		buffer.WriteString("null")
+	case KindInt:
+		n, ok := new(big.Int).SetString(strings.TrimSpace(v.Text), 10)
+		if !ok {
+			return fmt.Errorf("invalid int %q", v.Text)
+		}
+		buffer.WriteString(n.String())
+	case KindDouble:
+		text := strings.TrimSpace(v.Text)
+		f, _, err := big.ParseFloat(text, 10, uint(len(text))*4+64, big.ToNearestEven)
+		if err != nil || f.IsInf() {
+			return fmt.Errorf("invalid double %q", v.Text)
+		}
+		buffer.WriteString(f.Text('g', -1))
+	case KindBoolean:
+		if v.Boolean() {
+			buffer.WriteString("true")
+		} else {
+			buffer.WriteString("false")
+		}
+	case KindString, "":
+		writeJSONString(v.Text, buffer)
+	case KindDateTime:
+		t, err := v.DateTime()
+		if err != nil {
+			return fmt.Errorf("invalid dateTime.iso8601 %q", v.Text)
+		}
+		writeJSONMarker(KindDateTime, t.Format(time.RFC3339), buffer)
+	case KindStruct:
+		buffer.WriteByte('{')
+		for i, m := range v.Members {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			name := m.Name
+			if strings.HasPrefix(name, "$") {
+				name = "$" + name
+			}
+			writeJSONString(name, buffer)
+			buffer.WriteByte(':')
+			if err := value2JSON(m.Value, buffer); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte('}')
+	case KindArray:
+		buffer.WriteByte('[')
+		for i, item := range v.Items {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			if err := value2JSON(item, buffer); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte(']')
+	default:
+		writeJSONMarker(v.Kind, v.Text, buffer)
+	}
+	return nil
+}
+
+func writeJSONString(s string, buffer *bytes.Buffer) {
+	b, _ := json.Marshal(s)
+	buffer.Write(b)
+}
+
+func writeJSONMarker(kind Kind, text string, buffer *bytes.Buffer) {
+	buffer.WriteByte('{')
+	writeJSONString("$"+string(kind), buffer)
+	buffer.WriteByte(':')
+	writeJSONString(text, buffer)
+	buffer.WriteByte('}')
+}
+
+// JSONToValue converts JSON into a value tree, reversing the mapping of
+// ValueToJSON. Integral numbers become int, other numbers become double.
+// Keys starting with "$$" are member names with their first "$" removed.
+func JSONToValue(data []byte) (Value, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	v, err := json2Value(d)
+	if err != nil {
+		return v, err
+	}
+	if _, err := d.Token(); err == nil {
+		return v, fmt.Errorf("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+func json2Value(d *json.Decoder) (Value, error) {
+	tok, err := d.Token()
+	if err != nil {
+		return Value{}, err
+	}
+
+	switch t := tok.(type) {
+	case nil:
+		return Value{Kind: KindNil}, nil
+	case bool:
+		return NewBoolean(t), nil
+	case string:
+		return NewString(t), nil
+	case json.Number:
+		if _, ok := new(big.Int).SetString(t.String(), 10); ok {
+			return Value{Kind: KindInt, Text: t.String()}, nil
+		}
+		return Value{Kind: KindDouble, Text: t.String()}, nil
+	case json.Delim:
+		if t == '[' {
+			v := NewArray()
+			for d.More() {
+				item, err := json2Value(d)
+				if err != nil {
+					return v, err
+				}
+				v.Items = append(v.Items, item)
+			}
+			_, err := d.Token()
+			return v, err
+		}
+
+		v, escaped := NewStruct(), false
+		for d.More() {
+			tok, err := d.Token()
+			if err != nil {
+				return v, err
+			}
+			m := Member{Name: tok.(string)}
+			if strings.HasPrefix(m.Name, "$$") {
+				m.Name, escaped = m.Name[1:], true
+			}
+			if m.Value, err = json2Value(d); err != nil {
+				return v, err
+			}
+			v.Members = append(v.Members, m)
+		}
+		if _, err := d.Token(); err != nil {
+			return v, err
+		}
+		if escaped {
+			return v, nil
+		}
+		return jsonMarker2Value(v)
+	}
+	return Value{}, fmt.Errorf("unexpected JSON token %v", tok)
+}
+
+// jsonMarker2Value converts a {"$<tag>": "<text>"} object into the scalar it
+// stands for; other objects are returned as is.
+func jsonMarker2Value(v Value) (Value, error) {
+	if len(v.Members) != 1 || !strings.HasPrefix(v.Members[0].Name, "$") || v.Members[0].Value.Kind != KindString {
+		return v, nil
+	}
+
+	kind, text := Kind(v.Members[0].Name[1:]), v.Members[0].Value.Text
+	if kind == KindDateTime {
+		t, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			return v, fmt.Errorf("invalid dateTime.iso8601 %q", text)
+		}
+		return localDateTime(t), nil
+	}
+	if kind == KindDouble {
+		if f, _, err := big.ParseFloat(strings.TrimSpace(text), 10, 64, big.ToNearestEven); err != nil || f.IsInf() {
+			return v, fmt.Errorf("invalid double %q", text)
+		}
+	}
+	return Value{Kind: kind, Text: text}, nil
+}
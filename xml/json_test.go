@@ -0,0 +1,98 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValueJSON(t *testing.T) {
+	v := NewStruct(
+		Member{"name", NewString("supervisor")},
+		Member{"pid", NewInt(42)},
+		Member{"big", Value{Kind: KindInt, Text: "123456789012345678901234567890"}},
+		Member{"load", NewDouble(0.5)},
+		Member{"running", NewBoolean(true)},
+		Member{"start", NewDateTime(time.Date(2012, time.July, 17, 14, 8, 55, 0, time.Local))},
+		Member{"data", NewBase64([]byte("you can't read this!"))},
+		Member{"tags", NewArray(NewString("a"), Value{Kind: KindNil})},
+		Member{"id", Value{Kind: "uuid", Text: "00ff"}},
+	)
+
+	data, err := ValueToJSON(v)
+	if err != nil {
+		t.Fatal("ValueToJSON failed", err)
+	}
+	start := time.Date(2012, time.July, 17, 14, 8, 55, 0, time.Local).Format(time.RFC3339)
+	expected := `{"name":"supervisor","pid":42,"big":123456789012345678901234567890,"load":0.5,"running":true,"start":{"$dateTime.iso8601":"` + start + `"},"data":{"$base64":"eW91IGNhbid0IHJlYWQgdGhpcyE="},"tags":["a",null],"id":{"$uuid":"00ff"}}`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+
+	back, err := JSONToValue(data)
+	if err != nil {
+		t.Fatal("JSONToValue failed", err)
+	}
+	if !reflect.DeepEqual(back, v) {
+		t.Errorf("expected %v, got %v", v, back)
+	}
+}
+
+func TestValueJSONDollarMembers(t *testing.T) {
+	v := NewStruct(Member{"$ref", NewString("x")})
+	data, err := ValueToJSON(v)
+	if err != nil {
+		t.Fatal("ValueToJSON failed", err)
+	}
+	if expected := `{"$$ref":"x"}`; string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+	back, err := JSONToValue(data)
+	if err != nil {
+		t.Fatal("JSONToValue failed", err)
+	}
+	if !reflect.DeepEqual(back, v) {
+		t.Errorf("expected %v, got %v", v, back)
+	}
+
+	v = NewStruct(Member{"$$id", NewInt(1)}, Member{"name", NewString("x")})
+	if data, err = ValueToJSON(v); err != nil {
+		t.Fatal("ValueToJSON failed", err)
+	}
+	if back, err = JSONToValue(data); err != nil || !reflect.DeepEqual(back, v) {
+		t.Errorf("expected %v, got %v (%v)", v, back, err)
+	}
+}
+
+func TestValueToJSONNonFinite(t *testing.T) {
+	for _, text := range []string{"NaN", "Inf", "+Inf", "-Inf", "inf"} {
+		if data, err := ValueToJSON(NewArray(Value{Kind: KindDouble, Text: text})); err == nil {
+			t.Errorf("expected error for %s, got %s", text, data)
+		}
+	}
+}
+
+func TestJSONToValueDateTimeOffset(t *testing.T) {
+	for _, text := range []string{"2012-07-17T14:08:55Z", "2012-07-17T14:08:55+05:45", "2012-07-17T14:08:55-11:00"} {
+		v, err := JSONToValue([]byte(`{"$dateTime.iso8601":"` + text + `"}`))
+		if err != nil {
+			t.Fatal("JSONToValue failed", err)
+		}
+		expected, _ := time.Parse(time.RFC3339, text)
+		if got, err := v.DateTime(); err != nil || !got.Equal(expected) {
+			t.Errorf("expected %s, got %v (%v)", expected, got, err)
+		}
+	}
+}
+
+func TestJSONToValueErrors(t *testing.T) {
+	for _, data := range []string{`{"a":`, `[1] 2`, `{"$dateTime.iso8601":"yesterday"}`, `{"$double":"NaN"}`, `{"$double":"-Inf"}`} {
+		if _, err := JSONToValue([]byte(data)); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+}
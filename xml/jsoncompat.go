@@ -0,0 +1,228 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// jsonField is a member of a struct following the encoding/json conventions
+// (see Options.JSONCompat).
+type jsonField struct {
+	name      string
+	tagged    bool
+	omitEmpty bool
+	index     []int
+	sf        reflect.StructField
+}
+
+// jsonFieldsCache maps the struct types to their []jsonField.
+var jsonFieldsCache sync.Map
+
+// jsonFields returns the members of the struct typ, as encoding/json does:
+// the fields of the embedded structs without a json name are promoted, a
+// name hiding the deeper ones, and the conflicting names at the same depth
+// being dropped unless a single one is tagged.
+func jsonFields(typ reflect.Type) []jsonField {
+	if f, ok := jsonFieldsCache.Load(typ); ok {
+		return f.([]jsonField)
+	}
+
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+	var (
+		fields  []jsonField
+		next    = []embedded{{typ: typ}}
+		visited = make(map[reflect.Type]bool)
+	)
+	for len(next) > 0 {
+		current := next
+		next = nil
+		for _, em := range current {
+			if visited[em.typ] {
+				continue
+			}
+			visited[em.typ] = true
+			for i := 0; i < em.typ.NumField(); i++ {
+				sf := em.typ.Field(i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if sf.Anonymous {
+					if sf.PkgPath != "" && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if sf.PkgPath != "" {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" || isSkipped(sf) {
+					continue
+				}
+				name, opts := tag, ""
+				if i := strings.Index(tag, ","); i >= 0 {
+					name, opts = tag[:i], tag[i+1:]
+				}
+				index := append(em.index[:len(em.index):len(em.index)], i)
+				if name == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+					next = append(next, embedded{ft, index})
+					continue
+				}
+				f := jsonField{name: name, tagged: name != "", index: index, sf: sf}
+				if name == "" {
+					f.name = sf.Name
+				}
+				f.omitEmpty = tagOptions(opts).Contains("omitempty")
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	// keep the dominant field of every name
+	sort.SliceStable(fields, func(i, j int) bool {
+		if fields[i].name != fields[j].name {
+			return fields[i].name < fields[j].name
+		}
+		if len(fields[i].index) != len(fields[j].index) {
+			return len(fields[i].index) < len(fields[j].index)
+		}
+		return fields[i].tagged && !fields[j].tagged
+	})
+	dominant := fields[:0]
+	for i := 0; i < len(fields); {
+		j := i + 1
+		for j < len(fields) && fields[j].name == fields[i].name {
+			j++
+		}
+		group := fields[i:j]
+		if len(group) == 1 || len(group[1].index) > len(group[0].index) ||
+			group[0].tagged && !group[1].tagged {
+			dominant = append(dominant, group[0])
+		}
+		i = j
+	}
+	fields = dominant
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i].index, fields[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+
+	f, _ := jsonFieldsCache.LoadOrStore(typ, fields)
+	return f.([]jsonField)
+}
+
+// jsonMember returns the field of the struct value v decoding the member
+// name, matched exactly or else case insensitively, allocating the embedded
+// structs pointers on the way.
+func jsonMember(v reflect.Value, name string) (reflect.Value, reflect.StructField, bool) {
+	fields := jsonFields(v.Type())
+	match := -1
+	for i := range fields {
+		if fields[i].name == name {
+			match = i
+			break
+		}
+	}
+	if match < 0 {
+		for i := range fields {
+			if strings.EqualFold(fields[i].name, name) {
+				match = i
+				break
+			}
+		}
+	}
+	if match < 0 || skipDecode(fields[match].sf) {
+		return reflect.Value{}, reflect.StructField{}, false
+	}
+
+	f := v
+	for _, i := range fields[match].index {
+		if f.Kind() == reflect.Ptr {
+			if f.IsNil() {
+				if !f.CanSet() {
+					// a pointer to an unexported struct
+					return reflect.Value{}, reflect.StructField{}, false
+				}
+				f.Set(reflect.New(f.Type().Elem()))
+			}
+			f = f.Elem()
+		}
+		f = f.Field(i)
+	}
+	return f, fields[match].sf, true
+}
+
+// jsonStruct2XML writes the struct v following the encoding/json
+// conventions.
+func (e *encodeState) jsonStruct2XML(v reflect.Value, writer io.Writer) error {
+	var err error
+	fmt.Fprintf(writer, "<struct>")
+	for _, jf := range jsonFields(v.Type()) {
+		field, ok := fieldByIndex(v, jf.index)
+		if !ok || skipEncode(jf.sf) {
+			// in a nil embedded struct, or decode-only
+			continue
+		}
+		if jf.omitEmpty && isEmptyValue(field) {
+			continue
+		}
+		if e.opts.OmitNil && (field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface) && field.IsNil() {
+			continue
+		}
+		if ferr := e.member2XML(jf.name, jf.sf, field, writer); err == nil {
+			err = ferr
+		}
+	}
+	fmt.Fprintf(writer, "</struct>")
+	return err
+}
+
+// fieldByIndex returns the nested field of v at index, reporting false if
+// it's in a nil embedded struct.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for _, i := range index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v, true
+}
+
+// isEmptyValue reports whether v is empty as meant by the omitempty option
+// of encoding/json.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
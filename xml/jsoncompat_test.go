@@ -0,0 +1,93 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type jsonBase struct {
+	ID      int    `json:"id"`
+	Created string `json:"created,omitempty"`
+}
+
+type jsonAudit struct {
+	Author string
+}
+
+type jsonModel struct {
+	jsonBase
+	*jsonAudit
+	Name   string `json:"name"`
+	Note   string `json:",omitempty"`
+	Secret string `json:"-"`
+	Tags   []string
+}
+
+func TestJSONCompatEncode(t *testing.T) {
+	opts := &Options{JSONCompat: true}
+	var buffer bytes.Buffer
+	model := jsonModel{jsonBase: jsonBase{ID: 1}, Name: "n", Secret: "s"}
+	if err := rpcParams2XML(&struct{ M jsonModel }{model}, &buffer, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := "<params><param><value><struct>" +
+		"<member><name>id</name><value><int>1</int></value></member>" +
+		"<member><name>name</name><value><string>n</string></value></member>" +
+		"<member><name>Tags</name><value><array><data></data></array></value></member>" +
+		"</struct></value></param></params>"
+	if buffer.String() != want {
+		t.Errorf("Expected %s, got %s", want, buffer.String())
+	}
+
+	buffer.Reset()
+	model.jsonAudit = &jsonAudit{Author: "a"}
+	if err := rpcParams2XML(&struct{ M jsonModel }{model}, &buffer, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buffer.String(), "<name>Author</name>") {
+		t.Errorf("Expected the embedded pointer promoted, got %s", buffer.String())
+	}
+}
+
+func TestJSONCompatDecode(t *testing.T) {
+	resp := toResponse(`<params><param><value><struct>
+<member><name>id</name><value><int>2</int></value></member>
+<member><name>NAME</name><value><string>n</string></value></member>
+<member><name>Secret</name><value><string>s</string></value></member>
+<member><name>unknown</name><value><string>u</string></value></member>
+</struct></value></param></params>`)
+	var reply struct{ M jsonModel }
+	if err := decodeRPC(resp, &reply, &Options{JSONCompat: true}); err != nil {
+		t.Fatal(err)
+	}
+	if reply.M.ID != 2 || reply.M.Name != "n" || reply.M.Secret != "" {
+		t.Errorf("Expected id 2 and name n only, got %+v", reply.M)
+	}
+}
+
+func TestJSONFieldsConflicts(t *testing.T) {
+	type a struct{ X, Y int }
+	type b struct {
+		X int
+		Y int `json:"Y"`
+	}
+	type c struct {
+		a
+		b
+		Z int
+	}
+	var names []string
+	for _, f := range jsonFields(reflect.TypeOf(c{})) {
+		names = append(names, f.name)
+	}
+	// X is ambiguous, the tagged Y wins
+	if got := strings.Join(names, ","); got != "Y,Z" {
+		t.Errorf("Expected Y,Z, got %s", got)
+	}
+}
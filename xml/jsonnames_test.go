@@ -0,0 +1,64 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type jsonPost struct {
+	PostID  int    `json:"post_id"`
+	Title   string `json:"title,omitempty"`
+	Secret  string `json:"-"`
+	Created string `xml:"ctime" json:"created"`
+}
+
+func TestJSONNamesDecode(t *testing.T) {
+	resp := toResponse(`<params><param><value><struct>
+<member><name>post_id</name><value><int>3</int></value></member>
+<member><name>title</name><value><string>t</string></value></member>
+<member><name>ctime</name><value><string>now</string></value></member>
+</struct></value></param></params>`)
+
+	var reply struct{ Post jsonPost }
+	if err := decodeRPC(resp, &reply, &Options{}); err != nil || reply.Post.PostID != 0 || reply.Post.Created != "" {
+		t.Errorf("Expected the json names unknown by default, got %+v, %v", reply.Post, err)
+	}
+	if err := decodeRPC(resp, &reply, &Options{JSONNames: true}); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Post.PostID != 3 || reply.Post.Title != "t" || reply.Post.Created != "now" {
+		t.Errorf("Expected the members decoded by json names, got %+v", reply.Post)
+	}
+}
+
+func TestJSONNamesEncode(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := rpcParams2XML(&struct{ Post jsonPost }{}, &buffer, &Options{JSONNames: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, member := range []string{"<name>post_id</name>", "<name>title</name>", "<name>Secret</name>", "<name>ctime</name>"} {
+		if !strings.Contains(buffer.String(), member) {
+			t.Errorf("Expected %s in %s", member, buffer.String())
+		}
+	}
+}
+
+func TestJSONNamesSharedDTO(t *testing.T) {
+	var buffer bytes.Buffer
+	args := &MulticallArgs{Methods: []ApiMethod{{MethodName: "m", Params: []string{"a"}}}}
+	if err := rpcParams2XML(args, &buffer, &Options{JSONNames: true}); err != nil {
+		t.Fatal(err)
+	}
+	var reply MulticallArgs
+	if err := decodeRPC(toResponse(buffer.String()), &reply, &Options{JSONNames: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.Methods) != 1 || reply.Methods[0].MethodName != "m" {
+		t.Errorf("Expected the methods round-tripped, got %+v", reply)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"runtime/pprof"
+	"strings"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+type LabeledService struct{}
+
+type LabeledResponse struct {
+	Labeled bool
+}
+
+// Profile reports whether the goroutine profile shows the method label.
+func (LabeledService) Profile(r *http.Request, args *struct{}, res *LabeledResponse) error {
+	var buffer bytes.Buffer
+	pprof.Lookup("goroutine").WriteTo(&buffer, 1)
+	res.Labeled = strings.Contains(buffer.String(), `"xmlrpc.method":"LabeledService.Profile"`)
+	return nil
+}
+
+func TestProfileLabels(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		codec := NewCodec()
+		codec.ProfileLabels = enabled
+		s := rpc.NewServer()
+		s.RegisterCodec(codec, "text/xml")
+		s.RegisterService(LabeledService{}, "")
+		ts := httptest.NewServer(s)
+
+		var res LabeledResponse
+		if err := NewClient(ts.URL).Call("LabeledService.Profile", &struct{}{}, &res); err != nil {
+			t.Fatal(err)
+		}
+		if res.Labeled != enabled {
+			t.Errorf("Expected labeled %v with ProfileLabels %v, got %v", enabled, enabled, res.Labeled)
+		}
+		ts.Close()
+	}
+}
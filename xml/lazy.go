@@ -0,0 +1,297 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/rogpeppe/go-charset/charset"
+)
+
+// LazyValue is a value tree kept as its <value> element, parsed only when
+// accessed, e.g. for proxies forwarding most of the documents untouched.
+// Member and Items parse a single level, returning lazy children sharing the
+// element, so only the parts accessed are parsed. It's safe for concurrent
+// use.
+type LazyValue struct {
+	raw []byte
+
+	once sync.Once
+	v    Value
+	err  error
+}
+
+// NewLazyValue returns a LazyValue of the value tree v.
+func NewLazyValue(v Value) *LazyValue {
+	l := &LazyValue{raw: []byte(v.String()), v: v}
+	l.once.Do(func() {})
+	return l
+}
+
+// Raw returns the <value> element, as in the document for the parsed ones.
+func (l *LazyValue) Raw() []byte {
+	return l.raw
+}
+
+// Value returns the value tree, parsing it on the first call.
+func (l *LazyValue) Value() (Value, error) {
+	l.once.Do(func() {
+		l.v, l.err = ParseValue(l.raw)
+	})
+	return l.v, l.err
+}
+
+// Kind returns the type of the value, parsing only its start.
+func (l *LazyValue) Kind() (Kind, error) {
+	d := l.decoder()
+	if err := enterValue(d); err != nil {
+		return "", err
+	}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch kind := Kind(t.Name.Local); kind {
+			case "i4", "i8":
+				return KindInt, nil
+			default:
+				return kind, nil
+			}
+		case xml.EndElement:
+			return KindString, nil
+		}
+	}
+}
+
+// Member returns the member name of a struct value, unparsed.
+func (l *LazyValue) Member(name string) (*LazyValue, bool, error) {
+	var member *LazyValue
+	err := l.children("struct", func(memberName string, child *LazyValue) bool {
+		if memberName == name {
+			member = child
+			return false
+		}
+		return true
+	})
+	return member, member != nil, err
+}
+
+// Items returns the items of an array value, unparsed.
+func (l *LazyValue) Items() ([]*LazyValue, error) {
+	var items []*LazyValue
+	err := l.children("array", func(_ string, child *LazyValue) bool {
+		items = append(items, child)
+		return true
+	})
+	return items, err
+}
+
+// Decode stores the value into target, which must be a pointer, like
+// Value.Decode but without building the value tree.
+func (l *LazyValue) Decode(target interface{}) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("xmlrpc: Decode target must be a non-nil pointer, not %T", target)
+	}
+
+	var tmp value
+	if err := xml.Unmarshal(l.raw, &tmp); err != nil {
+		return FaultDecode
+	}
+	field := rv.Elem()
+	return located(value2Field(tmp, &field, defaultOptions), nil, defaultOptions)
+}
+
+func (l *LazyValue) decoder() rawTokens {
+	return rawTokens{xml.NewDecoder(bytes.NewReader(l.raw))}
+}
+
+// enterValue consumes the tokens up to the start of the <value> element.
+func enterValue(d rawTokens) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			if start.Name.Local != "value" {
+				return fmt.Errorf("expected <value>, got <%s>", start.Name.Local)
+			}
+			return nil
+		}
+	}
+}
+
+// children calls f with the members or items of the value, of Kind kind,
+// until it returns false.
+func (l *LazyValue) children(kind Kind, f func(name string, child *LazyValue) bool) error {
+	d := l.decoder()
+	if err := enterValue(d); err != nil {
+		return err
+	}
+	var (
+		name  string
+		found bool
+	)
+	for depth := 0; ; {
+		offset := d.InputOffset()
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 1 && Kind(t.Name.Local) != kind:
+				return fmt.Errorf("xmlrpc: %s value has no %s children", t.Name.Local, kind)
+			case depth == 1:
+				found = true
+			case t.Name.Local == "name":
+				if name, err = elementText(d); err != nil {
+					return err
+				}
+				depth--
+			case t.Name.Local == "value":
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				depth--
+				if !f(name, &LazyValue{raw: l.raw[offset:d.InputOffset()]}) {
+					return nil
+				}
+			}
+		case xml.EndElement:
+			depth--
+			if depth < 0 {
+				if !found {
+					return fmt.Errorf("xmlrpc: string value has no %s children", kind)
+				}
+				return nil
+			}
+		}
+	}
+}
+
+// ParseLazyMethodCall is like ParseMethodCall, but the params are kept lazy.
+func ParseLazyMethodCall(data []byte) (string, []*LazyValue, error) {
+	root, method, params, err := parseLazyDocument(data)
+	if err == nil && root != "methodCall" {
+		err = fmt.Errorf("expected <methodCall>, got <%s>", root)
+	}
+	return method, params, err
+}
+
+// ParseLazyMethodResponse is like ParseMethodResponse, but the params are
+// kept lazy.
+func ParseLazyMethodResponse(data []byte) ([]*LazyValue, error) {
+	root, _, params, err := parseLazyDocument(data)
+	if err == nil && root != "methodResponse" {
+		err = fmt.Errorf("expected <methodResponse>, got <%s>", root)
+	}
+	return params, err
+}
+
+// EncodeLazyMethodCall is like EncodeMethodCall, the params being copied as
+// they are.
+func EncodeLazyMethodCall(method string, params []*LazyValue) []byte {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "<methodCall><methodName>%s</methodName>", escapeString(method))
+	writeLazyParams(params, &buffer)
+	fmt.Fprintf(&buffer, "</methodCall>")
+	return buffer.Bytes()
+}
+
+// EncodeLazyMethodResponse is like EncodeMethodResponse, the params being
+// copied as they are.
+func EncodeLazyMethodResponse(params []*LazyValue) []byte {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "<methodResponse>")
+	writeLazyParams(params, &buffer)
+	fmt.Fprintf(&buffer, "</methodResponse>")
+	return buffer.Bytes()
+}
+
+func writeLazyParams(params []*LazyValue, buffer *bytes.Buffer) {
+	fmt.Fprintf(buffer, "<params>")
+	for _, p := range params {
+		fmt.Fprintf(buffer, "<param>")
+		buffer.Write(p.raw)
+		fmt.Fprintf(buffer, "</param>")
+	}
+	fmt.Fprintf(buffer, "</params>")
+}
+
+// parseLazyDocument is like parseDocument, but the params are kept lazy.
+// The params of documents in another charset than UTF-8 are parsed, and
+// kept in UTF-8.
+func parseLazyDocument(data []byte) (root, method string, params []*LazyValue, err error) {
+	converted := false
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		// the offsets are then in the converted document
+		converted = true
+		return charset.NewReader(label, input)
+	}
+	d := rawTokens{dec}
+
+	inFault := false
+	for {
+		offset := d.InputOffset()
+		tok, err := d.Token()
+		if err == io.EOF {
+			if root == "" {
+				return root, method, params, fmt.Errorf("empty document")
+			}
+			return root, method, params, nil
+		}
+		if err != nil {
+			return root, method, params, err
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "methodCall", "methodResponse":
+			root = start.Name.Local
+		case "methodName":
+			if method, err = elementText(d); err != nil {
+				return root, method, params, err
+			}
+		case "params", "param":
+			// values follow
+		case "fault":
+			inFault = true
+		case "value":
+			if inFault || converted {
+				v, err := parseValue(d)
+				if err != nil {
+					return root, method, params, err
+				}
+				if inFault {
+					return root, method, nil, value2Fault(v)
+				}
+				params = append(params, NewLazyValue(v))
+				continue
+			}
+			if err := d.Skip(); err != nil {
+				return root, method, params, err
+			}
+			params = append(params, &LazyValue{raw: data[offset:d.InputOffset()]})
+		default:
+			return root, method, params, fmt.Errorf("unexpected <%s>", start.Name.Local)
+		}
+	}
+}
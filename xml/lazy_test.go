@@ -0,0 +1,115 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"testing"
+)
+
+const lazyCall = `<?xml version="1.0"?>
+<methodCall><methodName>blog.post</methodName><params>
+<param><value><i4>7</i4></value></param>
+<param><value><struct>
+<member><name>title</name><value><string>Hello &amp; bye</string></value></member>
+<member><name>tags</name><value><array><data><value>a</value><value><int>2</int></value></data></array></value></member>
+</struct></value></param>
+<param><value>untyped</value></param>
+</params></methodCall>`
+
+func TestLazyValue(t *testing.T) {
+	method, params, err := ParseLazyMethodCall([]byte(lazyCall))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method != "blog.post" || len(params) != 3 {
+		t.Fatalf("Expected blog.post with 3 params, got %s with %d", method, len(params))
+	}
+	if raw := string(params[0].Raw()); raw != "<value><i4>7</i4></value>" {
+		t.Errorf("Expected the raw param kept, got %s", raw)
+	}
+
+	for i, want := range []Kind{KindInt, KindStruct, KindString} {
+		if kind, err := params[i].Kind(); err != nil || kind != want {
+			t.Errorf("Expected param %d of kind %s, got %s, %v", i, want, kind, err)
+		}
+	}
+
+	title, ok, err := params[1].Member("title")
+	if err != nil || !ok {
+		t.Fatal("Expected the title member, got:", err)
+	}
+	v, err := title.Value()
+	if err != nil || v.Text != "Hello & bye" {
+		t.Errorf("Expected the title parsed, got %v, %v", v, err)
+	}
+	if _, ok, _ := params[1].Member("body"); ok {
+		t.Error("Expected no body member")
+	}
+
+	tags, _, _ := params[1].Member("tags")
+	items, err := tags.Items()
+	if err != nil || len(items) != 2 {
+		t.Fatalf("Expected 2 tags, got %d, %v", len(items), err)
+	}
+	var n int
+	if err := items[1].Decode(&n); err != nil || n != 2 {
+		t.Errorf("Expected the second tag decoded, got %d, %v", n, err)
+	}
+
+	if _, err := params[0].Items(); err == nil {
+		t.Error("Expected no items of an int")
+	}
+	if _, _, err := params[2].Member("x"); err == nil {
+		t.Error("Expected no members of a string")
+	}
+}
+
+func TestLazyForward(t *testing.T) {
+	method, params, err := ParseLazyMethodCall([]byte(lazyCall))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params[0] = NewLazyValue(NewInt(8))
+	eager, err := ParseValue(params[1].Raw())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, forwarded, err := ParseMethodCall(EncodeLazyMethodCall(method, params))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forwarded) != 3 || forwarded[0].Text != "8" || len(Diff(eager, forwarded[1])) != 0 {
+		t.Errorf("Expected the params forwarded, got %v", forwarded)
+	}
+}
+
+func TestLazyMethodResponse(t *testing.T) {
+	params, err := ParseLazyMethodResponse(EncodeMethodResponse([]Value{NewString("ok")}))
+	if err != nil || len(params) != 1 {
+		t.Fatalf("Expected 1 param, got %d, %v", len(params), err)
+	}
+	if _, err := ParseLazyMethodResponse([]byte(lazyCall)); err == nil {
+		t.Error("Expected a methodCall rejected")
+	}
+
+	fault := `<methodResponse><fault><value><struct>
+<member><name>faultCode</name><value><int>4</int></value></member>
+<member><name>faultString</name><value><string>Too many params</string></value></member>
+</struct></value></fault></methodResponse>`
+	if _, err := ParseLazyMethodResponse([]byte(fault)); err == nil || err.(Fault).Code != 4 {
+		t.Errorf("Expected the fault returned, got %v", err)
+	}
+
+	latin1 := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><methodResponse><params>" +
+		"<param><value><string>caf\xe9</string></value></param></params></methodResponse>"
+	params, err = ParseLazyMethodResponse([]byte(latin1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := params[0].Value(); v.Text != "café" {
+		t.Errorf("Expected the ISO-8859-1 param converted, got %q", v.Text)
+	}
+}
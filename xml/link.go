@@ -0,0 +1,72 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// DepthHeader is the HTTP header carrying the nesting depth of a call made
+// while serving another one: 1 for a call made by a method, 2 for a call
+// made by a method called by a method, and so on.
+const DepthHeader = "X-Xmlrpc-Depth"
+
+// CallLink links a call being served to the call it's nested in.
+type CallLink struct {
+	// Method is the method being served.
+	Method string
+
+	// Depth is the nesting depth of the call, 0 for a call made from
+	// outside of a method.
+	Depth int
+}
+
+// LinkCalls wraps the XML-RPC handler next, so the calls are served with a
+// request context carrying their CallLink (see CallLinkFrom). Handlers
+// passing r.Context() on to outgoing calls, including calls to the server
+// itself, make them carry their depth + 1 in the X-Xmlrpc-Depth header.
+//
+// Calls nested deeper than maxDepth are answered with a fault, so a method
+// calling itself endlessly, e.g. through a gateway, fails instead of
+// exhausting the server.
+//
+// The server and the clients share no buffers, so methods may call the
+// server they are served by, with the same or another client.
+func LinkCalls(next http.Handler, maxDepth int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		depth, _ := strconv.Atoi(r.Header.Get(DepthHeader))
+		if depth > maxDepth {
+			fault := FaultApplicationError
+			fault.String += fmt.Sprintf(": calls nested deeper than %d", maxDepth)
+			writeFault(w, fault)
+			return
+		}
+		method, _, err := readMethod(r, defaultOptions)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := context.WithValue(r.Context(), callLinkContextKey, CallLink{Method: method, Depth: depth})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// CallLinkFrom returns the CallLink of the call served with ctx, and reports
+// whether there is one.
+func CallLinkFrom(ctx context.Context) (CallLink, bool) {
+	link, ok := ctx.Value(callLinkContextKey).(CallLink)
+	return link, ok
+}
+
+// setDepthHeader sets the X-Xmlrpc-Depth header of a call made while serving
+// the call of ctx, if any.
+func setDepthHeader(ctx context.Context, req *http.Request) {
+	if link, ok := CallLinkFrom(ctx); ok {
+		req.Header.Set(DepthHeader, strconv.Itoa(link.Depth+1))
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newRecursiveServer serves "recurse", calling itself n times with the
+// request context and returning the depth of the innermost call.
+func newRecursiveServer(wrap func(http.Handler) http.Handler) *httptest.Server {
+	var client *Client
+	ts := httptest.NewServer(wrap(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		link, ok := CallLinkFrom(r.Context())
+		if !ok || link.Method != method {
+			return nil, FaultApplicationError
+		}
+		n, _ := params[0].Int()
+		if n == 0 {
+			return []Value{NewInt(int64(link.Depth))}, nil
+		}
+		ctx := r.Context()
+		if key := r.Header.Get(IdempotencyKeyHeader); key != "" {
+			ctx = WithIdempotencyKey(ctx, key)
+			n++ // the same call again
+		}
+		return client.CallValuesContext(ctx, method, NewInt(n-1))
+	})))
+	client = NewClient(ts.URL)
+	return ts
+}
+
+func TestLinkCalls(t *testing.T) {
+	ts := newRecursiveServer(func(h http.Handler) http.Handler { return LinkCalls(h, 3) })
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	params, err := client.CallValues("recurse", NewInt(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if depth, _ := params[0].Int(); depth != 3 {
+		t.Errorf("Expected the innermost call at depth 3, got %d", depth)
+	}
+
+	_, err = client.CallValues("recurse", NewInt(4))
+	if fault, ok := err.(Fault); !ok || !strings.Contains(fault.String, "nested deeper than 3") {
+		t.Errorf("Expected the calls too deep rejected, got %v", err)
+	}
+}
+
+func TestIdempotentNestedCall(t *testing.T) {
+	ts := newRecursiveServer(func(h http.Handler) http.Handler {
+		return Idempotent(LinkCalls(h, 3), time.Minute)
+	})
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := NewClient(ts.URL).CallValuesContext(WithIdempotencyKey(ctx, "k"), "recurse", NewInt(1))
+	if fault, ok := err.(Fault); !ok || !strings.Contains(fault.String, "reused by a nested call") {
+		t.Errorf("Expected the nested call with the key of its caller rejected, got %v", err)
+	}
+}
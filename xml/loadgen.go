@@ -0,0 +1,248 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// LoadMethod is a method called by RunLoad.
+type LoadMethod struct {
+	// Name is the method called, e.g. "Arith.Multiply".
+	Name string
+
+	// Args is a sample of the args, e.g. &Args{}. Only its type is used:
+	// every call sends args of this type filled with random data, honoring
+	// the enum, min, max, maxlen and required options of the xmlrpc tags.
+	Args interface{}
+
+	// Weight is the share of the calls going to the method, relative to
+	// the other methods. A weight of 0 counts as 1.
+	Weight int
+}
+
+// LoadConfig configures RunLoad.
+type LoadConfig struct {
+	Methods []LoadMethod
+
+	// Concurrency is the number of concurrent callers, 1 if 0.
+	Concurrency int
+
+	// Requests is the total number of calls, and Duration the time calls
+	// are made for. The load stops at whichever comes first; at least one
+	// must be set.
+	Requests int
+	Duration time.Duration
+
+	// Seed seeds the random args, so a load can be replayed.
+	Seed int64
+}
+
+// LoadReport is the outcome of RunLoad.
+type LoadReport struct {
+	// Calls is the number of calls made, and Errors the number of them
+	// failing other than with a fault, e.g. on transport errors.
+	Calls  int
+	Errors int
+
+	// Faults counts the faults returned by code.
+	Faults map[int]int
+
+	// Latency is the histogram of the latency of all calls.
+	Latency Histogram
+
+	// Elapsed is the duration of the load.
+	Elapsed time.Duration
+}
+
+// Histogram is a latency histogram with exponential buckets: bucket 0 holds
+// latencies under 100µs, and every next bucket twice the latencies of the
+// previous one.
+type Histogram struct {
+	Buckets []int
+	Count   int
+	Sum     time.Duration
+	Max     time.Duration
+}
+
+const histogramBase = 100 * time.Microsecond
+
+// BucketBound returns the upper bound of bucket i.
+func (h *Histogram) BucketBound(i int) time.Duration {
+	return histogramBase << uint(i)
+}
+
+// Observe records the latency d.
+func (h *Histogram) Observe(d time.Duration) {
+	i := 0
+	for d >= h.BucketBound(i) && i < 40 {
+		i++
+	}
+	for len(h.Buckets) <= i {
+		h.Buckets = append(h.Buckets, 0)
+	}
+	h.Buckets[i]++
+	h.Count++
+	h.Sum += d
+	if d > h.Max {
+		h.Max = d
+	}
+}
+
+// Mean returns the mean latency.
+func (h *Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Percentile returns the upper bound of the bucket holding the p-th
+// percentile, p in [0, 100], capped to the max latency.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	rank := int(float64(h.Count)*p/100 + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	seen := 0
+	for i, n := range h.Buckets {
+		seen += n
+		if seen >= rank {
+			if bound := h.BucketBound(i); bound < h.Max {
+				return bound
+			}
+			return h.Max
+		}
+	}
+	return h.Max
+}
+
+func (h *Histogram) merge(o Histogram) {
+	for len(h.Buckets) < len(o.Buckets) {
+		h.Buckets = append(h.Buckets, 0)
+	}
+	for i, n := range o.Buckets {
+		h.Buckets[i] += n
+	}
+	h.Count += o.Count
+	h.Sum += o.Sum
+	if o.Max > h.Max {
+		h.Max = o.Max
+	}
+}
+
+// RunLoad calls the methods of cfg with randomized args through client, and
+// reports the latencies and the faults. It returns early when ctx is done.
+// Replies are parsed as value trees, whatever their type, and discarded.
+//
+// An error is returned, and no call made, if the Args of a method isn't a
+// pointer to a struct.
+func RunLoad(ctx context.Context, client *Client, cfg LoadConfig) (LoadReport, error) {
+	report := LoadReport{Faults: make(map[int]int)}
+	argTypes := make([]reflect.Type, len(cfg.Methods))
+	for i, m := range cfg.Methods {
+		typ := reflect.TypeOf(m.Args)
+		if typ == nil || typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Struct {
+			return report, fmt.Errorf("xmlrpc: the args of %s must be a pointer to a struct, got %T", m.Name, m.Args)
+		}
+		argTypes[i] = typ.Elem()
+	}
+	if len(cfg.Methods) == 0 || cfg.Requests <= 0 && cfg.Duration <= 0 {
+		return report, nil
+	}
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var weights []int
+	total := 0
+	for _, m := range cfg.Methods {
+		w := m.Weight
+		if w <= 0 {
+			w = 1
+		}
+		total += w
+		weights = append(weights, total)
+	}
+
+	var (
+		mu   sync.Mutex
+		sent int
+		wg   sync.WaitGroup
+	)
+	// next reserves a call, reporting false once the load is over.
+	next := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil || cfg.Requests > 0 && sent >= cfg.Requests {
+			return false
+		}
+		sent++
+		return true
+	}
+
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			g := NewGenerator(seed)
+			local := LoadReport{Faults: make(map[int]int)}
+			for next() {
+				pick := g.rng.Intn(total)
+				m, typ := cfg.Methods[0], argTypes[0]
+				for i, bound := range weights {
+					if pick < bound {
+						m, typ = cfg.Methods[i], argTypes[i]
+						break
+					}
+				}
+				args := reflect.New(typ)
+				g.fill(args.Elem(), nil)
+
+				body, err := encodeRequest(m.Name, args.Interface(), &client.Options)
+				if err != nil {
+					local.Calls++
+					local.Errors++
+					continue
+				}
+				begin := time.Now()
+				resp, err := client.post(ctx, m.Name, []byte(body))
+				if err == nil {
+					_, err = ParseMethodResponse(resp)
+				}
+				local.Latency.Observe(time.Since(begin))
+				local.Calls++
+				if fault, ok := asFault(err); ok {
+					local.Faults[fault.Code]++
+				} else if err != nil {
+					local.Errors++
+				}
+			}
+
+			mu.Lock()
+			report.Calls += local.Calls
+			report.Errors += local.Errors
+			for code, n := range local.Faults {
+				report.Faults[code] += n
+			}
+			report.Latency.merge(local.Latency)
+			mu.Unlock()
+		}(cfg.Seed + int64(w))
+	}
+	wg.Wait()
+	report.Elapsed = time.Since(start)
+	return report, nil
+}
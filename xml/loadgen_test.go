@@ -0,0 +1,154 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type LoadArgs struct {
+	Name   string   `xmlrpc:"name,maxlen=4"`
+	Level  int      `xmlrpc:"level,min=1,max=3"`
+	Color  string   `xmlrpc:"color,enum=red|green"`
+	Tags   []string `xmlrpc:"tags,required"`
+	Ratio  float64
+	Nested *GreetPerson
+}
+
+func TestGeneratorHonorsTags(t *testing.T) {
+	g := NewGenerator(1)
+	for i := 0; i < 100; i++ {
+		var args LoadArgs
+		g.Fill(&args)
+		if len(args.Name) > 4 {
+			t.Error("Expected name of at most 4 bytes, got", args.Name)
+		}
+		if args.Level < 1 || args.Level > 3 {
+			t.Error("Expected level in [1, 3], got", args.Level)
+		}
+		if args.Color != "red" && args.Color != "green" {
+			t.Error("Expected an enum color, got", args.Color)
+		}
+		if len(args.Tags) == 0 {
+			t.Error("Expected required tags")
+		}
+		if args.Nested == nil {
+			t.Error("Expected a nested struct")
+		}
+		if err := checkArgs(&args); err != nil {
+			t.Error("Expected valid args, got", err)
+		}
+	}
+}
+
+func TestRunLoad(t *testing.T) {
+	ts := httptest.NewServer(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		if method == "Fail" {
+			return nil, FaultInvalidParams
+		}
+		return params, nil
+	}))
+	defer ts.Close()
+
+	report, err := RunLoad(context.Background(), NewClient(ts.URL), LoadConfig{
+		Methods: []LoadMethod{
+			{Name: "Echo", Args: &LoadArgs{}, Weight: 3},
+			{Name: "Fail", Args: &Service1Request{}},
+		},
+		Concurrency: 4,
+		Requests:    40,
+		Seed:        1,
+	})
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if report.Calls != 40 || report.Latency.Count != 40 {
+		t.Errorf("Expected 40 calls, got %d (%d observed)", report.Calls, report.Latency.Count)
+	}
+	if report.Errors != 0 {
+		t.Error("Expected no errors, got", report.Errors)
+	}
+	if n := report.Faults[FaultInvalidParams.Code]; n == 0 || n == 40 {
+		t.Error("Expected some calls to fault, got", report.Faults)
+	}
+	if p := report.Latency.Percentile(50); p <= 0 || p > report.Latency.Max {
+		t.Error("Expected a median latency up to the max, got", p)
+	}
+}
+
+func TestRunLoadDuration(t *testing.T) {
+	ts := newTestServer(NewCodec())
+	defer ts.Close()
+
+	report, err := RunLoad(context.Background(), NewClient(ts.URL), LoadConfig{
+		Methods:  []LoadMethod{{Name: "Service1.Multiply", Args: &Service1Request{}}},
+		Duration: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if report.Calls == 0 {
+		t.Error("Expected calls within the duration")
+	}
+	if report.Elapsed > time.Second {
+		t.Error("Expected the load to stop after the duration, took", report.Elapsed)
+	}
+}
+
+func TestRunLoadInvalidArgs(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		atomic.AddInt32(&requests, 1)
+		return params, nil
+	}))
+	defer ts.Close()
+
+	var nilArgs *LoadArgs
+	for _, args := range []interface{}{nil, LoadArgs{}, new(int)} {
+		report, err := RunLoad(context.Background(), NewClient(ts.URL), LoadConfig{
+			Methods:  []LoadMethod{{Name: "Echo", Args: &LoadArgs{}}, {Name: "Bad", Args: args}},
+			Requests: 10,
+		})
+		if err == nil || !strings.Contains(err.Error(), "the args of Bad must be a pointer to a struct") {
+			t.Errorf("Expected an error for args %#v, got %v", args, err)
+		}
+		if report.Calls != 0 {
+			t.Errorf("Expected no calls for args %#v, got %d", args, report.Calls)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Error("Expected no requests, got", n)
+	}
+
+	// only the type of the args is used
+	if _, err := RunLoad(context.Background(), NewClient(ts.URL), LoadConfig{
+		Methods:  []LoadMethod{{Name: "Echo", Args: nilArgs}},
+		Requests: 1,
+	}); err != nil {
+		t.Error("Expected a nil *LoadArgs to be accepted, got", err)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	var h Histogram
+	for _, d := range []time.Duration{50 * time.Microsecond, 150 * time.Microsecond, 150 * time.Microsecond, 3 * time.Millisecond} {
+		h.Observe(d)
+	}
+	if h.Count != 4 || h.Max != 3*time.Millisecond {
+		t.Error("Expected 4 observations with a 3ms max, got", h.Count, h.Max)
+	}
+	if p := h.Percentile(50); p != 200*time.Microsecond {
+		t.Error("Expected a median bound of 200µs, got", p)
+	}
+	if p := h.Percentile(100); p != 3*time.Millisecond {
+		t.Error("Expected the 100th percentile at the max, got", p)
+	}
+}
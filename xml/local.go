@@ -0,0 +1,44 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ServeXML runs the methodCall document read from r through handler,
+// typically a *rpc.Server with the XML-RPC codec registered for "text/xml",
+// and writes the response document to w. No network is involved, so a server's
+// services can be smoke tested with documents read from stdin or files.
+//
+// A fault response is written to w and returned as a Fault error; a non-XML
+// error reply of the handler, e.g. for an unknown method, is returned as error.
+func ServeXML(handler http.Handler, r io.Reader, w io.Writer) error {
+	req, err := http.NewRequest("POST", "/RPC2", r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/xml")
+
+	rw := newBufferedResponseWriter()
+	handler.ServeHTTP(rw, req)
+
+	if rw.status != http.StatusOK {
+		return fmt.Errorf("xmlrpc: %d %s: %s", rw.status, http.StatusText(rw.status),
+			strings.TrimSpace(rw.body.String()))
+	}
+	if _, err := w.Write(rw.body.Bytes()); err != nil {
+		return err
+	}
+	if _, err := ParseMethodResponse(rw.body.Bytes()); err != nil {
+		if _, ok := asFault(err); ok {
+			return err
+		}
+	}
+	return nil
+}
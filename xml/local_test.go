@@ -0,0 +1,34 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+func TestServeXML(t *testing.T) {
+	s := rpc.NewServer()
+	s.RegisterCodec(NewCodec(), "text/xml")
+	s.RegisterService(new(Service1), "")
+
+	var out bytes.Buffer
+	req := "<methodCall><methodName>Service1.Multiply</methodName><params><param><value><int>4</int></value></param><param><value><int>2</int></value></param></params></methodCall>"
+	if err := ServeXML(s, strings.NewReader(req), &out); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	expected := "<methodResponse><params><param><value><int>8</int></value></param></params></methodResponse>"
+	if out.String() != expected {
+		t.Errorf("expected %s, got %s", expected, out.String())
+	}
+
+	req = "<methodCall><methodName>Service1.Divide</methodName><params></params></methodCall>"
+	if err := ServeXML(s, strings.NewReader(req), &out); err == nil {
+		t.Error("expected error for unknown method")
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// pathStep is a step down the document tree: the i-th param, a struct
+// member or the i-th array item.
+type pathStep struct {
+	kind  byte // 'p', 'm' or 'i'
+	index int
+	name  string
+}
+
+func paramStep(i int) pathStep        { return pathStep{kind: 'p', index: i} }
+func memberStep(name string) pathStep { return pathStep{kind: 'm', name: name} }
+func itemStep(i int) pathStep         { return pathStep{kind: 'i', index: i} }
+
+func (s pathStep) String() string {
+	switch s.kind {
+	case 'p':
+		return fmt.Sprintf("params[%d].value", s.index)
+	case 'm':
+		return fmt.Sprintf("struct.member[%s]", s.name)
+	default:
+		return fmt.Sprintf("array.data.value[%d]", s.index)
+	}
+}
+
+// pathError is a decoding fault on its way up the document tree, collecting
+// the path to the offending node.
+type pathError struct {
+	fault Fault
+	class error
+	steps []pathStep
+
+	// expected is the field type, got the wire type, when they mismatch.
+	expected string
+	got      string
+}
+
+func (e *pathError) Error() string {
+	return e.toFault(nil, defaultOptions).Error()
+}
+
+func (e *pathError) path() string {
+	parts := make([]string, len(e.steps))
+	for i, s := range e.steps {
+		parts[i] = s.String()
+	}
+	return strings.Join(parts, ".")
+}
+
+// toFault returns the fault detailed with the path and, if the document data
+// is given, the line and column of the offending node, with its class.
+func (e *pathError) toFault(data []byte, opts *Options) error {
+	fault := e.fault
+	if len(e.steps) != 0 {
+		fault.String += " at " + e.path()
+		if line, col := locate(data, e.steps, opts); line > 0 {
+			fault.String += fmt.Sprintf(" (line %d, column %d)", line, col)
+		}
+	}
+	if e.expected != "" {
+		fault.String += fmt.Sprintf(": expected %s, got <%s>", e.expected, e.got)
+		return fault.withCause(ErrTypeMismatch{Field: e.path(), Expected: e.expected, Got: e.got})
+	}
+	if e.class != nil {
+		return fault.withCause(e.class)
+	}
+	return fault
+}
+
+// mismatch returns fault for value not fitting field.
+func mismatch(fault Fault, value value, field *reflect.Value) error {
+	return &pathError{fault: fault, expected: field.Type().String(), got: wireType(value)}
+}
+
+// atStep prepends step to the path of err.
+func atStep(err error, step pathStep) error {
+	switch e := err.(type) {
+	case *pathError:
+		e.steps = append([]pathStep{step}, e.steps...)
+		return e
+	case Fault:
+		return &pathError{fault: e, steps: []pathStep{step}}
+	case classedFault:
+		return &pathError{fault: e.Fault, class: e.class, steps: []pathStep{step}}
+	}
+	return err
+}
+
+// located returns err, turning a pathError into a Fault locating the
+// offending node in data, which may be nil.
+func located(err error, data []byte, opts *Options) error {
+	if e, ok := err.(*pathError); ok {
+		return e.toFault(data, opts)
+	}
+	return err
+}
+
+// xmlNode is an element with its offset in the document.
+type xmlNode struct {
+	name     string
+	offset   int64
+	text     string
+	children []*xmlNode
+}
+
+// child returns the i-th child element called name, or nil. Names are matched
+// case insensitively, as documents decoded with IgnoreCase are located as
+// received.
+func (n *xmlNode) child(name string, i int) *xmlNode {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.children {
+		if strings.EqualFold(c.name, name) {
+			if i == 0 {
+				return c
+			}
+			i--
+		}
+	}
+	return nil
+}
+
+func parseNodes(data []byte, opts *Options) *xmlNode {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.CharsetReader = opts.charsetReader
+	root := &xmlNode{}
+	stack := []*xmlNode{root}
+	for {
+		offset := d.InputOffset()
+		tok, err := d.Token()
+		if err == io.EOF {
+			if len(root.children) == 0 {
+				return nil
+			}
+			return root.children[0]
+		}
+		if err != nil || len(stack) == 0 {
+			return nil
+		}
+
+		top := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: t.Name.Local, offset: offset}
+			top.children = append(top.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			top.text += string(t)
+		}
+	}
+}
+
+// locate returns the line and column of the node at the end of steps in the
+// document data, or 0, 0 if it can't be found.
+func locate(data []byte, steps []pathStep, opts *Options) (int, int) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+	node := parseNodes(data, opts)
+	for _, s := range steps {
+		if node == nil {
+			return 0, 0
+		}
+		switch s.kind {
+		case 'p':
+			node = node.child("params", 0).child("param", s.index).child("value", 0)
+		case 'm':
+			var found *xmlNode
+			for _, m := range node.child("struct", 0).children {
+				if strings.EqualFold(m.name, "member") && strings.TrimSpace(m.child("name", 0).textOrEmpty()) == s.name {
+					found = m.child("value", 0)
+					break
+				}
+			}
+			node = found
+		case 'i':
+			node = node.child("array", 0).child("data", 0).child("value", s.index)
+		}
+	}
+	if node == nil {
+		return 0, 0
+	}
+
+	before := data[:node.offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := len(before) - bytes.LastIndex(before, []byte("\n"))
+	return line, col
+}
+
+func (n *xmlNode) textOrEmpty() string {
+	if n == nil {
+		return ""
+	}
+	return n.text
+}
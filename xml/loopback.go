@@ -0,0 +1,67 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+)
+
+// LoopbackAddr is the remote address of the requests served by a Loopback.
+const LoopbackAddr = "loopback"
+
+// Loopback is an http.RoundTripper serving the requests in process with
+// Handler, typically a *rpc.Server with the XML-RPC codec, with no network
+// nor goroutine involved. The documents are still encoded and decoded as
+// they would be over HTTP, so the unit tests of services and clients run
+// fast and deterministic while exercising the wire format.
+type Loopback struct {
+	Handler http.Handler
+}
+
+// NewLoopbackClient returns a Client calling handler in process through a
+// Loopback.
+func NewLoopbackClient(handler http.Handler) *Client {
+	c := NewClient("http://" + LoopbackAddr + "/RPC2")
+	c.HTTPClient = &http.Client{Transport: &Loopback{Handler: handler}}
+	return c
+}
+
+// RoundTrip implements http.RoundTripper.
+func (l *Loopback) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	r := req.Clone(req.Context())
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.RemoteAddr = LoopbackAddr
+	r.RequestURI = req.URL.RequestURI()
+
+	w := newBufferedResponseWriter()
+	l.Handler.ServeHTTP(w, r)
+	if w.header.Get("Content-Length") == "" {
+		w.header.Set("Content-Length", strconv.Itoa(w.body.Len()))
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(w.status) + " " + http.StatusText(w.status),
+		StatusCode:    w.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        w.header,
+		Body:          ioutil.NopCloser(bytes.NewReader(w.body.Bytes())),
+		ContentLength: int64(w.body.Len()),
+		Request:       req,
+	}, nil
+}
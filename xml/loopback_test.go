@@ -0,0 +1,55 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+func TestLoopback(t *testing.T) {
+	var remote string
+	codec := NewCodec()
+	codec.Tune = func(r *http.Request, opts *Options) {
+		remote = r.RemoteAddr
+	}
+	s := rpc.NewServer()
+	s.RegisterCodec(codec, "text/xml")
+	s.RegisterService(new(Service1), "")
+	s.RegisterService(new(Service2), "")
+	client := NewLoopbackClient(s)
+
+	var res Service1Response
+	if err := client.Call("Service1.Multiply", &Service1Request{4, 2}, &res); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if res.Result != 8 {
+		t.Errorf("Wrong response: %v.", res.Result)
+	}
+	if remote != LoopbackAddr {
+		t.Errorf("Expected the request from %q, got %q", LoopbackAddr, remote)
+	}
+
+	var greeting Service2Response
+	if err := client.Call("Service2.GetGreeting", &Service2Request{"Johnny", 33, true}, &greeting); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if expected := "Hello, user Johnny. You're 33 years old :-P And you has permit."; greeting.Message != expected {
+		t.Errorf("Wrong response: %q.", greeting.Message)
+	}
+
+	if err := client.Call("Service1.Unknown", &Service1Request{4, 2}, &res); err == nil {
+		t.Error("Expected the unknown method to fail")
+	}
+
+	client = NewLoopbackClient(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		return nil, FaultInvalidParams
+	}))
+	if err := client.Call("any", &Service1Request{4, 2}, &res); err == nil || err.(Fault).Code != FaultInvalidParams.Code {
+		t.Errorf("Expected the fault, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+
+	"github.com/rogpeppe/go-charset/charset"
+)
+
+// NewValue converts v, as it would be encoded as a param, into a value tree.
+func NewValue(v interface{}) (Value, error) {
+	var buffer bytes.Buffer
+	if err := RPC2XML(v, &buffer); err != nil {
+		return Value{}, err
+	}
+	return ParseValue(buffer.Bytes())
+}
+
+// Decode stores the value tree into target, which must be a pointer, the way
+// a param is decoded into a field.
+func (v Value) Decode(target interface{}) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("xmlrpc: Decode target must be a non-nil pointer, not %T", target)
+	}
+
+	var tmp value
+	if err := xml.Unmarshal([]byte(v.String()), &tmp); err != nil {
+		return FaultDecode
+	}
+	field := rv.Elem()
+	return located(value2Field(tmp, &field, defaultOptions), nil, defaultOptions)
+}
+
+// ParseMethodCall parses a methodCall document into the method name and the
+// params.
+func ParseMethodCall(data []byte) (string, []Value, error) {
+	root, method, params, err := parseDocument(data)
+	if err == nil && root != "methodCall" {
+		err = fmt.Errorf("expected <methodCall>, got <%s>", root)
+	}
+	return method, params, err
+}
+
+// ParseMethodResponse parses a methodResponse document into the params. A
+// fault response is returned as a Fault error.
+func ParseMethodResponse(data []byte) ([]Value, error) {
+	root, _, params, err := parseDocument(data)
+	if err == nil && root != "methodResponse" {
+		err = fmt.Errorf("expected <methodResponse>, got <%s>", root)
+	}
+	return params, err
+}
+
+// EncodeMethodCall encodes a methodCall document.
+func EncodeMethodCall(method string, params []Value) []byte {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "<methodCall><methodName>%s</methodName>", escapeString(method))
+	writeParams(params, &buffer)
+	fmt.Fprintf(&buffer, "</methodCall>")
+	return buffer.Bytes()
+}
+
+// EncodeMethodResponse encodes a methodResponse document.
+func EncodeMethodResponse(params []Value) []byte {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "<methodResponse>")
+	writeParams(params, &buffer)
+	fmt.Fprintf(&buffer, "</methodResponse>")
+	return buffer.Bytes()
+}
+
+func writeParams(params []Value, writer io.Writer) {
+	fmt.Fprintf(writer, "<params>")
+	for _, p := range params {
+		fmt.Fprintf(writer, "<param>")
+		p.writeXML(writer)
+		fmt.Fprintf(writer, "</param>")
+	}
+	fmt.Fprintf(writer, "</params>")
+}
+
+// parseDocument parses a methodCall or methodResponse document, returning
+// the root element name, the method name (for calls) and the params.
+func parseDocument(data []byte) (root, method string, params []Value, err error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.CharsetReader = charset.NewReader
+
+	inFault := false
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			if root == "" {
+				return root, method, params, fmt.Errorf("empty document")
+			}
+			return root, method, params, nil
+		}
+		if err != nil {
+			return root, method, params, err
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "methodCall", "methodResponse":
+			root = start.Name.Local
+		case "methodName":
+			if method, err = elementText(d); err != nil {
+				return root, method, params, err
+			}
+		case "params", "param":
+			// values follow
+		case "fault":
+			inFault = true
+		case "value":
+			v, err := parseValue(d)
+			if err != nil {
+				return root, method, params, err
+			}
+			if inFault {
+				return root, method, nil, value2Fault(v)
+			}
+			params = append(params, v)
+		default:
+			return root, method, params, fmt.Errorf("unexpected <%s>", start.Name.Local)
+		}
+	}
+}
+
+// value2Fault converts a fault struct into Fault.
+func value2Fault(v Value) Fault {
+	var fault Fault
+	if code, ok := v.Member("faultCode"); ok {
+		fault.Code, _ = strconv.Atoi(code.Text)
+	}
+	if str, ok := v.Member("faultString"); ok {
+		fault.String = str.Text
+	}
+	return fault
+}
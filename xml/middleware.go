@@ -0,0 +1,87 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+)
+
+// readMethod reads the request body and returns it along with the method
+// name of the call, decoding the charsets and the element names as opts. The
+// body is restored, so the request can still be served.
+func readMethod(r *http.Request, opts *Options) (string, []byte, error) {
+	rawxml, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(rawxml))
+	if err != nil {
+		return "", nil, err
+	}
+
+	doc := rawxml
+	if opts.IgnoreNamespaces || opts.IgnoreCase {
+		if doc, err = normalizeDocument(rawxml, opts); err != nil {
+			return "", rawxml, err
+		}
+	}
+	var request ServerRequest
+	decoder := xml.NewDecoder(bytes.NewReader(doc))
+	decoder.CharsetReader = opts.charsetReader
+	if err := decoder.Decode(&request); err != nil {
+		return "", rawxml, err
+	}
+	return request.Method, rawxml, nil
+}
+
+// writeFault writes fault as a complete XML-RPC response.
+func writeFault(w http.ResponseWriter, fault Fault) {
+	buffer := bytes.NewBuffer(make([]byte, 0))
+	Fault2XML(fault, buffer)
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	buffer.WriteTo(w)
+}
+
+// bufferedResponseWriter keeps a response in memory.
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponseWriter() *bufferedResponseWriter {
+	return &bufferedResponseWriter{header: make(http.Header), status: http.StatusOK}
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+// succeeded reports whether w holds a successful response, not a fault.
+func (w *bufferedResponseWriter) succeeded() bool {
+	if w.status != http.StatusOK {
+		return false
+	}
+	_, err := ParseMethodResponse(w.body.Bytes())
+	return err == nil
+}
+
+// writeTo replays the buffered response to w.
+func (w *bufferedResponseWriter) writeTo(rw http.ResponseWriter) {
+	for k, v := range w.header {
+		rw.Header()[k] = v
+	}
+	rw.WriteHeader(w.status)
+	rw.Write(w.body.Bytes())
+}
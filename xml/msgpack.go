@@ -0,0 +1,331 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// msgpackTaggedExt is the msgpack extension type of a scalar that has no
+// native representation: an int beyond int64, a double beyond float64 or a
+// nonstandard tag. Its data is the Kind, a zero byte and the Text.
+const msgpackTaggedExt = 1
+
+// ValueToMsgpack encodes a value tree as msgpack, so decoded calls can be
+// forwarded to internal workers without re-serializing them to XML.
+//
+// Structs become maps keeping the members order, base64 becomes bin and
+// dateTime.iso8601 becomes the timestamp extension. Scalars without a
+// native msgpack type are written as extension type 1 holding
+// "<kind>\x00<text>". MsgpackToValue reverses the mapping.
+func ValueToMsgpack(v Value) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := value2Msgpack(v, &buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func value2Msgpack(v Value, buffer *bytes.Buffer) error {
+	switch v.Kind {
+	case KindNil:
+		buffer.WriteByte(0xc0)
+	case KindBoolean:
+		if v.Boolean() {
+			buffer.WriteByte(0xc3)
+		} else {
+			buffer.WriteByte(0xc2)
+		}
+	case KindInt:
+		i, err := v.Int()
+		if err != nil {
+			writeMsgpackTagged(v, buffer)
+			break
+		}
+		writeMsgpackInt(i, buffer)
+	case KindDouble:
+		f, err := v.Double()
+		if err != nil {
+			writeMsgpackTagged(v, buffer)
+			break
+		}
+		buffer.WriteByte(0xcb)
+		binary.Write(buffer, binary.BigEndian, math.Float64bits(f))
+	case KindString, "":
+		writeMsgpackHeader(buffer, len(v.Text), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		buffer.WriteString(v.Text)
+	case KindBase64:
+		data, err := v.Base64()
+		if err != nil {
+			return fmt.Errorf("invalid base64: %v", err)
+		}
+		writeMsgpackHeader(buffer, len(data), 0, -1, 0xc4, 0xc5, 0xc6)
+		buffer.Write(data)
+	case KindDateTime:
+		t, err := v.DateTime()
+		if err != nil {
+			return fmt.Errorf("invalid dateTime.iso8601 %q", v.Text)
+		}
+		// timestamp 96: ext 8 with length 12 and type -1
+		buffer.Write([]byte{0xc7, 12, 0xff})
+		binary.Write(buffer, binary.BigEndian, uint32(t.Nanosecond()))
+		binary.Write(buffer, binary.BigEndian, t.Unix())
+	case KindArray:
+		writeMsgpackHeader(buffer, len(v.Items), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, item := range v.Items {
+			if err := value2Msgpack(item, buffer); err != nil {
+				return err
+			}
+		}
+	case KindStruct:
+		writeMsgpackHeader(buffer, len(v.Members), 0x80, 15, 0, 0xde, 0xdf)
+		for _, m := range v.Members {
+			writeMsgpackHeader(buffer, len(m.Name), 0xa0, 31, 0xd9, 0xda, 0xdb)
+			buffer.WriteString(m.Name)
+			if err := value2Msgpack(m.Value, buffer); err != nil {
+				return err
+			}
+		}
+	default:
+		writeMsgpackTagged(v, buffer)
+	}
+	return nil
+}
+
+// writeMsgpackHeader writes the type byte(s) and length n, using the fix
+// format up to fixMax (fixMax < 0 disables it) and the 8, 16 or 32 bit
+// formats otherwise (f8 == 0 disables the 8 bit one).
+func writeMsgpackHeader(buffer *bytes.Buffer, n int, fix byte, fixMax int, f8, f16, f32 byte) {
+	switch {
+	case n <= fixMax:
+		buffer.WriteByte(fix | byte(n))
+	case f8 != 0 && n <= math.MaxUint8:
+		buffer.Write([]byte{f8, byte(n)})
+	case n <= math.MaxUint16:
+		buffer.WriteByte(f16)
+		binary.Write(buffer, binary.BigEndian, uint16(n))
+	default:
+		buffer.WriteByte(f32)
+		binary.Write(buffer, binary.BigEndian, uint32(n))
+	}
+}
+
+func writeMsgpackInt(i int64, buffer *bytes.Buffer) {
+	switch {
+	case i >= 0 && i <= 127, i < 0 && i >= -32:
+		buffer.WriteByte(byte(i))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		buffer.Write([]byte{0xd0, byte(i)})
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		buffer.WriteByte(0xd1)
+		binary.Write(buffer, binary.BigEndian, int16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		buffer.WriteByte(0xd2)
+		binary.Write(buffer, binary.BigEndian, int32(i))
+	default:
+		buffer.WriteByte(0xd3)
+		binary.Write(buffer, binary.BigEndian, i)
+	}
+}
+
+func writeMsgpackTagged(v Value, buffer *bytes.Buffer) {
+	data := string(v.Kind) + "\x00" + v.Text
+	writeMsgpackHeader(buffer, len(data), 0, -1, 0xc7, 0xc8, 0xc9)
+	buffer.WriteByte(msgpackTaggedExt)
+	buffer.WriteString(data)
+}
+
+// MsgpackToValue decodes msgpack into a value tree, reversing the mapping of
+// ValueToMsgpack. Maps must have string keys, unsigned integers beyond int64
+// become int values all the same and unknown extension types become base64.
+func MsgpackToValue(data []byte) (Value, error) {
+	r := &msgpackReader{data: data}
+	v, err := r.value()
+	if err == nil && r.pos != len(data) {
+		err = fmt.Errorf("msgpack: unexpected data after value")
+	}
+	return v, err
+}
+
+type msgpackReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *msgpackReader) next(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.data) {
+		return nil, fmt.Errorf("msgpack: unexpected end of data")
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
+
+// uint reads a big-endian unsigned integer of size bytes.
+func (r *msgpackReader) uint(size int) (uint64, error) {
+	b, err := r.next(size)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+func (r *msgpackReader) value() (Value, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return Value{}, err
+	}
+
+	switch c := b[0]; {
+	case c <= 0x7f:
+		return NewInt(int64(c)), nil
+	case c >= 0xe0:
+		return NewInt(int64(int8(c))), nil
+	case c&0xf0 == 0x80:
+		return r.mapValue(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return r.array(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return r.str(int(c & 0x1f))
+	}
+
+	switch c := b[0]; c {
+	case 0xc0:
+		return Value{Kind: KindNil}, nil
+	case 0xc2, 0xc3:
+		return NewBoolean(c == 0xc3), nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := r.uint(1 << (c - 0xc4))
+		if err != nil {
+			return Value{}, err
+		}
+		data, err := r.next(int(n))
+		return NewBase64(data), err
+	case 0xc7, 0xc8, 0xc9:
+		n, err := r.uint(1 << (c - 0xc7))
+		if err != nil {
+			return Value{}, err
+		}
+		return r.ext(int(n))
+	case 0xca:
+		n, err := r.uint(4)
+		return NewDouble(float64(math.Float32frombits(uint32(n)))), err
+	case 0xcb:
+		n, err := r.uint(8)
+		return NewDouble(math.Float64frombits(n)), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := r.uint(1 << (c - 0xcc))
+		return Value{Kind: KindInt, Text: fmt.Sprint(n)}, err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (c - 0xd0)
+		n, err := r.uint(size)
+		// sign-extend the size bytes wide integer
+		shift := uint(64 - 8*size)
+		return NewInt(int64(n<<shift) >> shift), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return r.ext(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := r.uint(1 << (c - 0xd9))
+		if err != nil {
+			return Value{}, err
+		}
+		return r.str(int(n))
+	case 0xdc, 0xdd:
+		n, err := r.uint(2 << (c - 0xdc))
+		if err != nil {
+			return Value{}, err
+		}
+		return r.array(int(n))
+	case 0xde, 0xdf:
+		n, err := r.uint(2 << (c - 0xde))
+		if err != nil {
+			return Value{}, err
+		}
+		return r.mapValue(int(n))
+	}
+	return Value{}, fmt.Errorf("msgpack: unsupported type byte 0x%02x", b[0])
+}
+
+func (r *msgpackReader) str(n int) (Value, error) {
+	b, err := r.next(n)
+	return NewString(string(b)), err
+}
+
+func (r *msgpackReader) array(n int) (Value, error) {
+	v := NewArray()
+	for i := 0; i < n; i++ {
+		item, err := r.value()
+		if err != nil {
+			return v, err
+		}
+		v.Items = append(v.Items, item)
+	}
+	return v, nil
+}
+
+func (r *msgpackReader) mapValue(n int) (Value, error) {
+	v := NewStruct()
+	for i := 0; i < n; i++ {
+		key, err := r.value()
+		if err != nil {
+			return v, err
+		}
+		if key.Kind != KindString {
+			return v, fmt.Errorf("msgpack: map key must be a string, not %s", key.Kind)
+		}
+		m := Member{Name: key.Text}
+		if m.Value, err = r.value(); err != nil {
+			return v, err
+		}
+		v.Members = append(v.Members, m)
+	}
+	return v, nil
+}
+
+func (r *msgpackReader) ext(n int) (Value, error) {
+	typ, err := r.next(1)
+	if err != nil {
+		return Value{}, err
+	}
+	data, err := r.next(n)
+	if err != nil {
+		return Value{}, err
+	}
+
+	switch int8(typ[0]) {
+	case -1:
+		var sec, nsec int64
+		switch n {
+		case 4:
+			sec = int64(binary.BigEndian.Uint32(data))
+		case 8:
+			t := binary.BigEndian.Uint64(data)
+			sec, nsec = int64(t&(1<<34-1)), int64(t>>34)
+		case 12:
+			nsec = int64(binary.BigEndian.Uint32(data))
+			sec = int64(binary.BigEndian.Uint64(data[4:]))
+		default:
+			return Value{}, fmt.Errorf("msgpack: invalid timestamp length %d", n)
+		}
+		return NewDateTime(time.Unix(sec, nsec)), nil
+	case msgpackTaggedExt:
+		i := strings.IndexByte(string(data), 0)
+		if i < 0 {
+			return Value{}, fmt.Errorf("msgpack: invalid tagged scalar")
+		}
+		return Value{Kind: Kind(data[:i]), Text: string(data[i+1:])}, nil
+	}
+	return Value{Kind: KindBase64, Text: base64.StdEncoding.EncodeToString(data)}, nil
+}
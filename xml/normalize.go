@@ -0,0 +1,80 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// specElements maps the lowercased names of the elements of the spec to their
+// canonical spelling.
+var specElements = make(map[string]string)
+
+func init() {
+	for _, name := range []string{
+		"methodCall", "methodName", "methodResponse", "params", "param",
+		"fault", "value", "i4", "i8", "int", "boolean", "string", "double",
+		"dateTime.iso8601", "base64", "struct", "member", "name", "array",
+		"data", "nil",
+	} {
+		specElements[strings.ToLower(name)] = name
+	}
+}
+
+// normalizeDocument rewrites data as required by the IgnoreNamespaces and
+// IgnoreCase options, in UTF-8.
+func normalizeDocument(data []byte, opts *Options) ([]byte, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.CharsetReader = opts.charsetReader
+	buffer := bytes.NewBuffer(make([]byte, 0, len(data)))
+	name := func(n xml.Name) string {
+		local := n.Local
+		if opts.IgnoreCase {
+			if canonical, ok := specElements[strings.ToLower(local)]; ok {
+				local = canonical
+			}
+		}
+		if n.Space == "" || opts.IgnoreNamespaces {
+			return local
+		}
+		return n.Space + ":" + local
+	}
+
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			return buffer.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			buffer.WriteString("<" + name(t.Name))
+			for _, attr := range t.Attr {
+				isDecl := attr.Name.Space == "xmlns" || attr.Name.Space == "" && attr.Name.Local == "xmlns"
+				if isDecl && opts.IgnoreNamespaces {
+					continue
+				}
+				if attr.Name.Space != "" && !opts.IgnoreNamespaces {
+					buffer.WriteString(" " + attr.Name.Space + ":" + attr.Name.Local + `="`)
+				} else {
+					buffer.WriteString(" " + attr.Name.Local + `="`)
+				}
+				xml.EscapeText(buffer, []byte(attr.Value))
+				buffer.WriteString(`"`)
+			}
+			buffer.WriteString(">")
+		case xml.EndElement:
+			buffer.WriteString("</" + name(t.Name) + ">")
+		case xml.CharData:
+			xml.EscapeText(buffer, t)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Normalizer rewrites a decoded string, e.g. trimming it.
+type Normalizer func(s string) string
+
+var normalizers = struct {
+	sync.RWMutex
+	byName map[string]Normalizer
+}{
+	byName: map[string]Normalizer{
+		"trim":  strings.TrimSpace,
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+		"fold":  foldCase,
+	},
+}
+
+// RegisterNormalizer names the normalizer n, for the string fields tagged
+// with `xmlrpc:",normalize=name"`, which are normalized once decoded. Several
+// names, e.g. "nfc|trim|fold", are applied in order. The "trim", "lower",
+// "upper" and "fold" (Unicode simple case folding) normalizers are builtin;
+// the Unicode normal forms are registered from golang.org/x/text, which the
+// package doesn't depend on:
+//
+//	xml.RegisterNormalizer("nfc", norm.NFC.String)
+//	xml.RegisterNormalizer("nfkc", norm.NFKC.String)
+func RegisterNormalizer(name string, n Normalizer) {
+	normalizers.Lock()
+	defer normalizers.Unlock()
+	normalizers.byName[name] = n
+}
+
+func normalizerByName(name string) Normalizer {
+	normalizers.RLock()
+	defer normalizers.RUnlock()
+	return normalizers.byName[name]
+}
+
+// normalizeField normalizes the string field as requested by the normalize
+// option of the struct field sf.
+func normalizeField(sf reflect.StructField, field *reflect.Value) error {
+	_, opts := parseTag(sf)
+	opts, _, _ = opts.cut("regexp")
+	list, ok := opts.Get("normalize")
+	if !ok || field.Kind() != reflect.String {
+		return nil
+	}
+	s := field.String()
+	for _, name := range strings.Split(list, "|") {
+		n := normalizerByName(name)
+		if n == nil {
+			return fmt.Errorf("xmlrpc: unknown normalizer %q of field %s", name, sf.Name)
+		}
+		s = n(s)
+	}
+	field.SetString(s)
+	return nil
+}
+
+// foldCase maps every letter of s to the lowercase of the smallest of its
+// case variants, so the strings equal under strings.EqualFold are folded
+// alike, e.g. the Kelvin sign as k.
+func foldCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		min := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < min {
+				min = f
+			}
+		}
+		return unicode.ToLower(min)
+	}, s)
+}
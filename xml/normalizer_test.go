@@ -0,0 +1,59 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	RegisterNormalizer("nospace", func(s string) string { return strings.Replace(s, " ", "", -1) })
+
+	resp := toResponse(`<params><param><value><struct>
+<member><name>Key</name><value><string>  Straße KEY </string></value></member>
+<member><name>Name</name><value><string> a b </string></value></member>
+<member><name>Nick</name><value><string> Nick </string></value></member>
+</struct></value></param><param><value><string> X </string></value></param></params>`)
+	var reply struct {
+		User struct {
+			Key  string `xmlrpc:",normalize=trim|fold"`
+			Name string `xmlrpc:",normalize=nospace"`
+			Nick string `xmlrpc:",normalize=trim|lower"`
+		}
+		Code string `xmlrpc:",normalize=trim|lower"`
+	}
+	if err := decodeRPC(resp, &reply, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if reply.User.Key != "straße key" {
+		t.Errorf("Expected the key trimmed and folded, got %q", reply.User.Key)
+	}
+	if reply.User.Name != "ab" {
+		t.Errorf("Expected ab, got %q", reply.User.Name)
+	}
+	if reply.User.Nick != "nick" {
+		t.Errorf("Expected nick, got %q", reply.User.Nick)
+	}
+	if reply.Code != "x" {
+		t.Errorf("Expected x, got %q", reply.Code)
+	}
+}
+
+func TestNormalizeUnknown(t *testing.T) {
+	var reply struct {
+		S string `xmlrpc:",normalize=nfd"`
+	}
+	err := decodeRPC(toResponse("<params><param><value><string>s</string></value></param></params>"), &reply, &Options{})
+	if err == nil || !strings.Contains(err.Error(), "nfd") {
+		t.Errorf("Expected an unknown normalizer error, got %v", err)
+	}
+}
+
+func TestFoldCase(t *testing.T) {
+	if got := foldCase("Kelvin \u212A \u017F"); got != "kelvin k s" {
+		t.Errorf("Expected kelvin k s, got %q", got)
+	}
+}
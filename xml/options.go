@@ -0,0 +1,219 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"io"
+	"reflect"
+	"time"
+)
+
+// Options tune how the args and reply structs are encoded and decoded by a
+// Codec or a Client. The zero value is the default behavior.
+type Options struct {
+	// NamedParams makes the args and reply structs travel as a single struct
+	// param whose members are the fields, as expected by services taking
+	// named arguments, instead of one param per field.
+	NamedParams bool
+
+	// StrictParams makes decoding fail with a wrong arguments number fault
+	// when there are fewer params than fields to fill. By default the
+	// missing fields are left untouched, so e.g. void methods replying with
+	// <params/> or no params at all are accepted.
+	StrictParams bool
+
+	// LenientBool permits decoding <int> values 0 and 1 into bool fields,
+	// and <boolean> values into integer fields, for peers that don't tell
+	// booleans and integers apart.
+	LenientBool bool
+
+	// CoerceStrings permits decoding numeric <string> values into integer
+	// and float fields, and numbers into string fields, for peers sending
+	// e.g. <string>42</string> for integers.
+	CoerceStrings bool
+
+	// DecodeHooks are run, in order, on every value before it's decoded
+	// into a field.
+	DecodeHooks []FieldDecodeHook
+
+	// EncodeHooks are run, in order, on every value before it's encoded.
+	EncodeHooks []FieldEncodeHook
+
+	// MaxEncodedSize, if positive, caps the size in bytes of the encoded
+	// params. The encoding is given up as soon as it's exceeded, so e.g. a
+	// handler returning an enormous slice makes a fault instead of
+	// exhausting the memory.
+	MaxEncodedSize int
+
+	// IgnoreNamespaces makes decoding match elements by local name only,
+	// dropping the namespace prefixes and declarations, for documents like
+	// <ns:methodResponse xmlns:ns="..."> produced by some gateways.
+	IgnoreNamespaces bool
+
+	// IgnoreCase makes decoding match the elements of the spec case
+	// insensitively, for peers sending e.g. <Boolean> or <DateTime.iso8601>.
+	IgnoreCase bool
+
+	// CharsetReader, if set, converts the documents declared in a
+	// non-UTF-8 charset to UTF-8, like the field of encoding/xml.Decoder,
+	// e.g. to plug golang.org/x/text decoders for charsets the builtin
+	// go-charset readers don't ship.
+	CharsetReader func(charset string, input io.Reader) (io.Reader, error)
+
+	// CharsetWriter, if set, converts the documents sent in a non-UTF-8
+	// charset (see Client.Charset) from UTF-8, in place of the builtin
+	// go-charset writers.
+	CharsetWriter func(charset string, output io.Writer) (io.WriteCloser, error)
+
+	// StrictValues makes decoding fail with an invalid params fault on
+	// <value> elements mixing text with a typed element, like
+	// <value>42<int>42</int></value>. By default the element takes
+	// precedence and the text is dropped, with a WarnMixedContent warning.
+	// Whitespace around the element is always ignored.
+	StrictValues bool
+
+	// Warnings, if set, is called with the anomalies tolerated on decoding,
+	// e.g. the coercions permitted by LenientBool and CoerceStrings, so the
+	// drift of a peer from the protocol can be monitored without making
+	// decoding strict. It may be called concurrently.
+	Warnings func(Warning)
+
+	// UntypedStrings makes strings encoded as <value>text</value>, without
+	// the optional <string> element, for peers choking on it.
+	UntypedStrings bool
+
+	// OmitNil makes nil pointer and interface struct fields omitted from
+	// the encoded structs, instead of encoded as <nil/>, for peers not
+	// supporting the extension.
+	OmitNil bool
+
+	// I8 makes the ints beyond the 32 bits range encoded as <i8>, for peers
+	// limiting <int> to 32 bits like xmlrpc-c. <i8> is always decoded.
+	I8 bool
+
+	// MemberNames, if set, names the struct members after the fields, e.g.
+	// SnakeCase for Python services, on encoding and decoding. Fields
+	// tagged with a xml name keep it. By default the members are named as
+	// the fields, and matched with their first letter uppercased.
+	MemberNames func(field string) string
+
+	// DateTimeLayouts are the time layouts tried, after the standard one,
+	// to decode the <dateTime.iso8601> and <string> values into time.Time
+	// fields, e.g. "2006-01-02 15:04:05" for peers sending dates as strings.
+	// The times without offset are taken in the local time.
+	DateTimeLayouts []string
+
+	// DateTimeFormat, if set, is the time layout of the encoded
+	// <dateTime.iso8601> values, e.g. DateTimeOffset or time.RFC3339Nano
+	// for the peers requiring an offset or fractional seconds. By default
+	// they are encoded without either, e.g. "20120717T14:08:55". The offsets
+	// and fractional seconds are always decoded.
+	DateTimeFormat string
+
+	// BinaryStrings permits decoding <string> and untyped values into
+	// []byte fields, byte for byte and without validating the text, for
+	// peers stuffing binary data into strings. The fields tagged
+	// `xmlrpc:",string"` are always decoded so, and are encoded as raw
+	// <string> values instead of <base64>.
+	BinaryStrings bool
+
+	// JSONNames names the struct members after the json tags of the fields
+	// without a xml tag, on encoding and decoding, so the types shared with
+	// JSON APIs map the same members without duplicating their tags.
+	JSONNames bool
+
+	// JSONCompat makes the structs follow the encoding/json conventions,
+	// for the model packages of JSON APIs: the members are named after the
+	// json tags, or else the fields; the fields tagged "-" are skipped, and
+	// those tagged omitempty are left out when empty; the fields of the
+	// embedded structs are promoted. The members are matched case
+	// insensitively when no name matches exactly, and the unknown members
+	// are dropped.
+	JSONCompat bool
+
+	// EmptyValues tells how the empty values, <value></value> and
+	// <value><string/></value>, are decoded, for servers meaning null by
+	// them. By default they are empty strings.
+	EmptyValues EmptyPolicy
+
+	// Stringers makes the values implementing fmt.Stringer encoded as
+	// <string> values with their String method. They can only be decoded
+	// if they implement encoding.TextUnmarshaler too. The values
+	// implementing encoding.TextMarshaler, e.g. net.IP, and url.URL are
+	// always encoded as strings, and decoded from them.
+	Stringers bool
+
+	// arena, if set, holds the decoded slices; it's set per request (see
+	// Codec.ArenaSize) or per call of Arena.Decode.
+	arena *Arena
+}
+
+// customEncoding reports whether the options change how values are encoded,
+// so the compiled codecs can't be used.
+func (o *Options) customEncoding() bool {
+	return len(o.EncodeHooks) != 0 || o.UntypedStrings || o.OmitNil || o.I8 || o.MemberNames != nil ||
+		o.JSONNames || o.JSONCompat || o.Stringers || o.DateTimeFormat != ""
+}
+
+// Layouts of Options.DateTimeFormat.
+const (
+	// DateTimeOffset is the standard layout with the offset of the time,
+	// e.g. "20120717T14:08:55+02:00".
+	DateTimeOffset = "20060102T15:04:05Z07:00"
+
+	// DateTimeOffsetMillis adds the milliseconds to DateTimeOffset, e.g.
+	// "20120717T14:08:55.123+02:00".
+	DateTimeOffsetMillis = "20060102T15:04:05.000Z07:00"
+)
+
+// memberName returns the member name of the struct field sf.
+func (o *Options) memberName(sf reflect.StructField) string {
+	if name := explicitName(sf); name != "" {
+		return name
+	}
+	if name := jsonName(sf); o.JSONNames && name != "" {
+		return name
+	}
+	if o.MemberNames != nil {
+		return o.MemberNames(sf.Name)
+	}
+	return sf.Name
+}
+
+// memberField returns the field of the struct type typ whose member is named
+// name by its xmlrpc tag, or by MemberNames or JSONNames when set.
+func (o *Options) memberField(typ reflect.Type, name string) (reflect.StructField, bool) {
+	if fp, ok := bindPlanOf(typ).named[name]; ok {
+		return fp.sf, true
+	}
+	if o.MemberNames == nil && !o.JSONNames {
+		return reflect.StructField{}, false
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath == "" && o.memberName(sf) == name {
+			return sf, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// parseTime parses the text of a <dateTime.iso8601> or <string> value with
+// the standard layout, then with DateTimeLayouts.
+func (o *Options) parseTime(text string) (time.Time, error) {
+	t, err := xml2DateTime(text)
+	if err == nil {
+		return t, nil
+	}
+	for _, layout := range o.DateTimeLayouts {
+		if t, lerr := time.ParseInLocation(layout, text, time.Local); lerr == nil {
+			return t, nil
+		}
+	}
+	return t, err
+}
+
+// defaultOptions are used by the package-level functions.
+var defaultOptions = &Options{}
@@ -0,0 +1,133 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import "context"
+
+// DefaultPageSize is the page size of a Pager when none is given.
+const DefaultPageSize = 100
+
+type pageReply struct {
+	// a Value, as an empty array can't be decoded into a []Value
+	Page Value
+}
+
+// Pager iterates the items of a method paginated by offset and limit, e.g.
+// wp.getPosts-like listings, fetching the pages lazily as the items are
+// iterated:
+//
+//	p, err := client.Paginate(ctx, "blog.listPosts", blogID)
+//	for p.Next() {
+//		var post Post
+//		if err := p.Decode(&post); err != nil { ... }
+//	}
+//	if err := p.Err(); err != nil { ... }
+//
+// The iteration stops after a page shorter than PageSize, or on the first
+// error, or when a stop condition is met.
+type Pager struct {
+	// PageSize is the limit of every call, DefaultPageSize if zero.
+	PageSize int
+
+	// MaxItems, if positive, stops the iteration after MaxItems items.
+	MaxItems int
+
+	// Stop, if set, stops the iteration at the first item it returns true
+	// for, which isn't yielded, e.g. at the first post older than a date.
+	Stop func(item Value) bool
+
+	ctx    context.Context
+	method *BoundMethod
+
+	page   []Value
+	offset int // of the next page
+	count  int // the items yielded
+	item   Value
+	last   bool // the page is the last one
+	done   bool
+	err    error
+}
+
+// Paginate returns a Pager over the items returned, as a single array
+// param, by method called with the constant leading params args, e.g. a
+// blog ID, followed by the offset and the limit of every page.
+func (c *Client) Paginate(ctx context.Context, method string, args ...interface{}) (*Pager, error) {
+	m, err := c.Bind(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &Pager{ctx: ctx, method: m}, nil
+}
+
+// Next advances to the next item, fetching the next page if needed. It
+// returns false at the end of the iteration; Err then tells whether it
+// failed.
+func (p *Pager) Next() bool {
+	if p.done {
+		return false
+	}
+	if p.MaxItems > 0 && p.count >= p.MaxItems {
+		return p.stop(nil)
+	}
+	if len(p.page) == 0 {
+		if p.last {
+			return p.stop(nil)
+		}
+		if err := p.fetch(); err != nil {
+			return p.stop(err)
+		}
+		if len(p.page) == 0 {
+			return p.stop(nil)
+		}
+	}
+	item := p.page[0]
+	p.page = p.page[1:]
+	if p.Stop != nil && p.Stop(item) {
+		return p.stop(nil)
+	}
+	p.item = item
+	p.count++
+	return true
+}
+
+// fetch calls the method for the next page.
+func (p *Pager) fetch() error {
+	size := p.PageSize
+	if size <= 0 {
+		size = DefaultPageSize
+	}
+	var reply pageReply
+	if err := p.method.CallContext(p.ctx, &reply, p.offset, size); err != nil {
+		return err
+	}
+	if reply.Page.Kind != KindArray {
+		return invalidParams("expected an array page, got <%s>", reply.Page.Kind)
+	}
+	p.page = reply.Page.Items
+	p.offset += len(p.page)
+	p.last = len(p.page) < size
+	return nil
+}
+
+func (p *Pager) stop(err error) bool {
+	p.done, p.err, p.page, p.item = true, err, nil, Value{}
+	return false
+}
+
+// Value returns the current item.
+func (p *Pager) Value() Value {
+	return p.item
+}
+
+// Decode stores the current item into target, which must be a pointer, as
+// Value.Decode.
+func (p *Pager) Decode(target interface{}) error {
+	return p.item.Decode(target)
+}
+
+// Err returns the error the iteration failed with, if any.
+func (p *Pager) Err() error {
+	return p.err
+}
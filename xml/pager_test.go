@@ -0,0 +1,123 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type pagedPost struct {
+	Id    int
+	Title string
+}
+
+// newPagedServer serves total posts of the blog 7 paginated by offset and
+// limit, counting the calls.
+func newPagedServer(t *testing.T, total int, calls *int) *httptest.Server {
+	return httptest.NewServer(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		*calls++
+		if len(params) != 3 || params[0].Text != "7" {
+			t.Errorf("Unexpected params %v", params)
+		}
+		offset, _ := params[1].Int()
+		limit, _ := params[2].Int()
+		var items []Value
+		for i := int(offset); i < total && i < int(offset+limit); i++ {
+			items = append(items, NewStruct(Member{"id", NewInt(int64(i))}, Member{"title", NewString("post")}))
+		}
+		return []Value{NewArray(items...)}, nil
+	}))
+}
+
+func TestPaginate(t *testing.T) {
+	var calls int
+	ts := newPagedServer(t, 25, &calls)
+	defer ts.Close()
+
+	p, err := NewClient(ts.URL).Paginate(context.Background(), "blog.listPosts", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.PageSize = 10
+	var ids []int
+	for p.Next() {
+		var post pagedPost
+		if err := p.Decode(&post); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, post.Id)
+		if len(ids) == 5 && calls != 1 {
+			t.Errorf("Expected the pages fetched lazily, got %d calls", calls)
+		}
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 25 || ids[24] != 24 {
+		t.Errorf("Expected the 25 posts in order, got %v", ids)
+	}
+	if calls != 3 {
+		t.Errorf("Expected no call after the short page, got %d calls", calls)
+	}
+	if p.Next() {
+		t.Error("Expected the iteration over")
+	}
+}
+
+func TestPaginateStop(t *testing.T) {
+	var calls int
+	ts := newPagedServer(t, 20, &calls)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	// a full last page, then an empty one
+	p, _ := client.Paginate(context.Background(), "blog.listPosts", 7)
+	p.PageSize = 10
+	n := 0
+	for p.Next() {
+		n++
+	}
+	if n != 20 || calls != 3 || p.Err() != nil {
+		t.Errorf("Expected 20 posts in 3 calls, got %d in %d: %v", n, calls, p.Err())
+	}
+
+	calls = 0
+	p, _ = client.Paginate(context.Background(), "blog.listPosts", 7)
+	p.PageSize, p.MaxItems = 10, 12
+	for n = 0; p.Next(); n++ {
+	}
+	if n != 12 || calls != 2 {
+		t.Errorf("Expected 12 posts in 2 calls, got %d in %d", n, calls)
+	}
+
+	calls = 0
+	p, _ = client.Paginate(context.Background(), "blog.listPosts", 7)
+	p.Stop = func(item Value) bool {
+		id, _ := item.Member("id")
+		return id.Text == "3"
+	}
+	for n = 0; p.Next(); n++ {
+	}
+	if n != 3 || calls != 1 || p.Value().Kind != "" {
+		t.Errorf("Expected 3 posts before the stop, got %d", n)
+	}
+}
+
+func TestPaginateError(t *testing.T) {
+	ts := httptest.NewServer(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		return nil, FaultInvalidParams
+	}))
+	defer ts.Close()
+	p, _ := NewClient(ts.URL).Paginate(context.Background(), "blog.listPosts")
+	if p.Next() {
+		t.Error("Expected no items")
+	}
+	if _, ok := p.Err().(Fault); !ok {
+		t.Errorf("Expected the fault, got %v", p.Err())
+	}
+}
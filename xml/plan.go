@@ -0,0 +1,126 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// fieldPlan is the decoding of a struct field, as told by its tags,
+// resolved once instead of at every value.
+type fieldPlan struct {
+	sf reflect.StructField
+
+	// enum are the names of the enum option, if any; the enums registered
+	// by type are looked up at every value, as they may be registered late.
+	enum []string
+
+	tuple, binary, epoch, skip bool
+	variadic, normalize        bool
+
+	// omitted fields, tagged "-", take no param
+	omitted bool
+}
+
+func newFieldPlan(sf reflect.StructField) *fieldPlan {
+	_, opts := parseTag(sf)
+	opts, _, _ = opts.cut("regexp")
+	fp := &fieldPlan{
+		sf:        sf,
+		tuple:     isTuple(sf),
+		binary:    isBinaryString(sf),
+		skip:      skipDecode(sf),
+		omitted:   isSkipped(sf),
+		variadic:  isVariadic(sf),
+		normalize: opts.Contains("normalize"),
+	}
+	fp.epoch, _ = isEpoch(sf)
+	if list, ok := opts.Get("enum"); ok && isEnumKind(sf.Type.Kind()) {
+		fp.enum = strings.Split(list, "|")
+	}
+	return fp
+}
+
+// decode decodes value into field as member2Field.
+func (fp *fieldPlan) decode(value value, field *reflect.Value, opts *Options) error {
+	if fp.enum != nil {
+		return enum2Field(fp.enum, value, field)
+	}
+	if names := enumByType(fp.sf.Type); names != nil {
+		return enum2Field(names, value, field)
+	}
+	if fp.tuple {
+		if len(opts.DecodeHooks) != 0 {
+			// e.g. FalseAsZero, for a relation sent as false
+			v, done, err := decodeHooks(opts.DecodeHooks, value, field)
+			if done || err != nil {
+				return err
+			}
+			value = v
+		}
+		return tuple2Field(value, field, opts)
+	}
+	if fp.binary && binaryString2Field(value, field) {
+		return nil
+	}
+	if fp.epoch {
+		return epoch2Field(value, field)
+	}
+	if err := value2Field(value, field, opts); err != nil {
+		return err
+	}
+	if !fp.normalize {
+		return nil
+	}
+	return normalizeField(fp.sf, field)
+}
+
+// bindPlan is the decoding of the members or params of a struct type into
+// its fields, resolved once per type, so the replies of the same type are
+// decoded without inspecting their tags again.
+type bindPlan struct {
+	// fields are the plans of the fields, in order.
+	fields []*fieldPlan
+
+	// byName maps the names of the direct exported fields to their plans,
+	// and named the member names given by their xmlrpc tags.
+	byName map[string]*fieldPlan
+	named  map[string]*fieldPlan
+}
+
+// bindPlans maps the struct types to their *bindPlan.
+var bindPlans sync.Map
+
+// bindPlanOf returns the plan of the struct type typ.
+func bindPlanOf(typ reflect.Type) *bindPlan {
+	if p, ok := bindPlans.Load(typ); ok {
+		return p.(*bindPlan)
+	}
+	p := &bindPlan{
+		fields: make([]*fieldPlan, typ.NumField()),
+		byName: make(map[string]*fieldPlan, typ.NumField()),
+		named:  make(map[string]*fieldPlan),
+	}
+	for i := range p.fields {
+		sf := typ.Field(i)
+		p.fields[i] = newFieldPlan(sf)
+		if sf.PkgPath == "" {
+			p.byName[sf.Name] = p.fields[i]
+			if name := tagName(sf); name != "" {
+				p.named[name] = p.fields[i]
+			}
+		}
+	}
+	actual, _ := bindPlans.LoadOrStore(typ, p)
+	return actual.(*bindPlan)
+}
+
+// field returns the plan of the direct exported field called name.
+func (p *bindPlan) field(name string) (*fieldPlan, bool) {
+	fp, ok := p.byName[name]
+	return fp, ok
+}
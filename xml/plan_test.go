@@ -0,0 +1,119 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// processInfo is a reply of the monitoring hot path, as
+// supervisor.getAllProcessInfo.
+type processInfo struct {
+	Name          string `xml:"name"`
+	Group         string `xml:"group"`
+	Start         int    `xml:"start"`
+	Stop          int    `xml:"stop"`
+	Now           int    `xml:"now"`
+	State         int    `xml:"state"`
+	Statename     string `xml:"statename"`
+	Spawnerr      string `xml:"spawnerr"`
+	Exitstatus    int    `xml:"exitstatus"`
+	Logfile       string `xml:"logfile"`
+	StdoutLogfile string `xml:"stdout_logfile"`
+	StderrLogfile string `xml:"stderr_logfile"`
+	Pid           int    `xml:"pid"`
+	Description   string `xml:"description"`
+}
+
+type processInfoReply struct {
+	Processes []processInfo
+}
+
+func processInfoResponse(n int) []byte {
+	processes := make([]processInfo, n)
+	for i := range processes {
+		processes[i] = processInfo{Name: fmt.Sprintf("worker%d", i), Group: "workers", Start: 1342526935,
+			Now: 1342530000, State: 20, Statename: "RUNNING", Logfile: "/var/log/w.log", Pid: 1000 + i,
+			Description: "pid 1000, uptime 0:51:05"}
+	}
+	var buffer bytes.Buffer
+	if err := rpcResponse2XML(&processInfoReply{processes}, &buffer, &Options{}); err != nil {
+		panic(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestDecodePlan(t *testing.T) {
+	resp := processInfoResponse(3)
+	for i := 0; i < 2; i++ {
+		// the second time with the cached plan
+		var reply processInfoReply
+		if err := DecodeClientResponse(bytes.NewReader(resp), &reply); err != nil {
+			t.Fatal(err)
+		}
+		if len(reply.Processes) != 3 || reply.Processes[2].Pid != 1002 || reply.Processes[0].Statename != "RUNNING" {
+			t.Errorf("Unexpected reply %+v", reply)
+		}
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	docs := []string{
+		string(processInfoResponse(2)),
+		`<?xml version="1.0"?><methodResponse><params><param><value>untyped &amp; <!-- c --> text</value></param>` +
+			`<param><value/></param><param><value></value></param><param><value><string><![CDATA[<a>]]></string></value></param></params></methodResponse>`,
+		`<methodResponse><params><param><value><array><data><value><i4>1</i4></value><value><struct><member>` +
+			`<name>a</name><value><nil/></value></member></struct></value></data></array></value></param></params></methodResponse>`,
+		`<methodResponse><fault><value><struct><member><name>faultCode</name><value><int>4</int></value></member>` +
+			`</struct></value></fault></methodResponse>`,
+		"<methodResponse>\n  <params>\n    <param>\n      <value>\n        <boolean>1</boolean>\n      </value>\n    </param>\n  </params>\n</methodResponse>\n",
+	}
+	for _, doc := range docs {
+		var want, got response
+		if err := xml.Unmarshal([]byte(doc), &want); err != nil {
+			t.Fatal(err)
+		}
+		if err := parseResponse([]byte(doc), &got); err != nil {
+			t.Errorf("Expected %q to be parsed, got %v", doc, err)
+			continue
+		}
+		want.Name = xml.Name{}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected %q to be parsed as %+v, got %+v", doc, want, got)
+		}
+	}
+
+	// left to xml.Unmarshal
+	for _, doc := range []string{
+		`<methodResponse><params><param><value><int>1</int></value></param></params>`,
+		`<methodResponse><params><param><value><int>1</i4></value></param></params></methodResponse>`,
+		`<?xml version="1.0" encoding="ISO-8859-1"?><methodResponse></methodResponse>`,
+		`<methodResponse><params><param><value><ex:nil xmlns:ex="http://ws.apache.org/xmlrpc/namespaces/extensions"/></value></param></params></methodResponse>`,
+	} {
+		var ret response
+		if err := parseResponse([]byte(doc), &ret); err == nil {
+			t.Errorf("Expected %q not to be parsed", doc)
+		}
+	}
+}
+
+// BenchmarkDecodeReplies decodes 1k responses into the same reply type, as
+// the monitoring loops do.
+func BenchmarkDecodeReplies(b *testing.B) {
+	resp := processInfoResponse(10)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 1000; j++ {
+			var reply processInfoReply
+			if err := DecodeClientResponse(bytes.NewReader(resp), &reply); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Profile bundles the quirks of a family of XML-RPC peers, so a Client or a
+// Codec talking to them is configured at once.
+type Profile struct {
+	// Name describes the peers, e.g. "WordPress".
+	Name string
+
+	// Options tune the encoding and decoding for the peers.
+	Options Options
+
+	// Charset, if set, is the charset the peers expect the requests in.
+	Charset string
+}
+
+// NewClient returns a Client for the endpoint url configured by the profile.
+func (p *Profile) NewClient(url string) *Client {
+	c := NewClient(url)
+	p.ConfigureClient(c)
+	return c
+}
+
+// ConfigureClient sets the Options and Charset of c.
+func (p *Profile) ConfigureClient(c *Client) {
+	c.Options = p.Options
+	c.Charset = p.Charset
+}
+
+// NewCodec returns a Codec configured by the profile.
+func (p *Profile) NewCodec() *Codec {
+	c := NewCodec()
+	p.ConfigureCodec(c)
+	return c
+}
+
+// ConfigureCodec sets the Options of c.
+func (p *Profile) ConfigureCodec(c *Codec) {
+	c.Options = p.Options
+}
+
+// Prebuilt profiles of well-known peers. They are shared: copy one before
+// changing it.
+var (
+	// ProfileWordPress talks to the WordPress XML-RPC API, whose members
+	// are snake_case, which sends numbers as strings and booleans as ints,
+	// and which doesn't know <nil/>.
+	ProfileWordPress = &Profile{Name: "WordPress", Options: Options{
+		LenientBool:   true,
+		CoerceStrings: true,
+		OmitNil:       true,
+		MemberNames:   SnakeCase,
+	}}
+
+	// ProfileSupervisor talks to the supervisord XML-RPC API, whose members
+	// are snake_case, and which doesn't know <nil/>.
+	ProfileSupervisor = &Profile{Name: "Supervisor", Options: Options{
+		OmitNil:     true,
+		MemberNames: SnakeCase,
+	}}
+
+	// ProfileBugzilla talks to the Bugzilla XML-RPC API, whose members are
+	// snake_case.
+	ProfileBugzilla = &Profile{Name: "Bugzilla", Options: Options{
+		MemberNames: SnakeCase,
+	}}
+
+	// ProfileOdoo talks to the Odoo (OpenERP) XML-RPC API, whose members
+	// are snake_case, which sends the dates as "2006-01-02 15:04:05"
+	// strings, and false for the empty fields of any type.
+	ProfileOdoo = &Profile{Name: "Odoo", Options: Options{
+		OmitNil:         true,
+		MemberNames:     SnakeCase,
+		DateTimeLayouts: []string{"2006-01-02 15:04:05", "2006-01-02"},
+		DecodeHooks:     []FieldDecodeHook{FalseAsZero},
+	}}
+
+	// ProfileRTorrent talks to the rTorrent XML-RPC API, which uses xmlrpc-c
+	// and takes the 64 bits ints as <i8>.
+	ProfileRTorrent = &Profile{Name: "rTorrent", Options: Options{
+		I8: true,
+	}}
+)
+
+// SnakeCase is a naming strategy for Options.MemberNames, e.g. naming the
+// field PostID "post_id".
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// a word starts after a lowercase letter or digit, or before one
+			// ending an acronym
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) ||
+				i+1 < len(runes) && unicode.IsLower(runes[i+1])) && runes[i-1] != '_' {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// FalseAsZero is a FieldDecodeHook leaving the non-bool fields zero when
+// decoding <boolean>0</boolean>, which some peers send for empty values.
+func FalseAsZero(from Kind, to reflect.Type, v Value) (interface{}, error) {
+	if from == KindBoolean && to.Kind() != reflect.Bool && strings.TrimSpace(v.Text) == "0" {
+		return nil, nil
+	}
+	return v, nil
+}
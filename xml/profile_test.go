@@ -0,0 +1,180 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"Name":      "name",
+		"PostID":    "post_id",
+		"HTTPProxy": "http_proxy",
+		"Stop2Go":   "stop2_go",
+		"Already_":  "already_",
+		"":          "",
+	} {
+		if got := SnakeCase(name); got != want {
+			t.Errorf("Expected SnakeCase(%q) %q, got %q", name, want, got)
+		}
+	}
+}
+
+type profilePost struct {
+	PostID   int
+	Title    string
+	Author   *string
+	Hits     int
+	Created  time.Time
+	Modified time.Time `xml:"mtime"`
+}
+
+func TestProfileMemberNames(t *testing.T) {
+	opts := ProfileBugzilla.Options
+	var buffer bytes.Buffer
+	if err := rpcParams2XML(&struct{ Post profilePost }{profilePost{PostID: 7, Title: "t"}}, &buffer, &opts); err != nil {
+		t.Fatal(err)
+	}
+	for _, member := range []string{"<name>post_id</name>", "<name>title</name>", "<name>mtime</name>"} {
+		if !strings.Contains(buffer.String(), member) {
+			t.Errorf("Expected %s in %s", member, buffer.String())
+		}
+	}
+
+	var reply struct{ Post profilePost }
+	if err := decodeRPC(toResponse(buffer.String()), &reply, &opts); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Post.PostID != 7 || reply.Post.Title != "t" {
+		t.Errorf("Expected the snake_case members decoded, got %+v", reply.Post)
+	}
+}
+
+func TestProfileOmitNil(t *testing.T) {
+	opts := ProfileSupervisor.Options
+	var buffer bytes.Buffer
+	if err := rpcParams2XML(&struct{ Post profilePost }{}, &buffer, &opts); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buffer.String(), "author") || strings.Contains(buffer.String(), "<nil/>") {
+		t.Errorf("Expected the nil author omitted, got %s", buffer.String())
+	}
+}
+
+func TestProfileUntypedStrings(t *testing.T) {
+	opts := Options{UntypedStrings: true}
+	var buffer bytes.Buffer
+	if err := rpcParams2XML(&struct{ S string }{"a<b"}, &buffer, &opts); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<params><param><value>a&lt;b</value></param></params>"; buffer.String() != want {
+		t.Errorf("Expected %s, got %s", want, buffer.String())
+	}
+}
+
+func TestProfileI8(t *testing.T) {
+	opts := ProfileRTorrent.Options
+	var buffer bytes.Buffer
+	if err := rpcParams2XML(&struct{ Small, Big int }{1, 1 << 40}, &buffer, &opts); err != nil {
+		t.Fatal(err)
+	}
+	want := "<params><param><value><int>1</int></value></param><param><value><i8>1099511627776</i8></value></param></params>"
+	if buffer.String() != want {
+		t.Errorf("Expected %s, got %s", want, buffer.String())
+	}
+
+	var reply struct{ Small, Big int }
+	if err := decodeRPC(toResponse(buffer.String()), &reply, &opts); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Small != 1 || reply.Big != 1<<40 {
+		t.Errorf("Expected the <i8> decoded, got %+v", reply)
+	}
+}
+
+func TestI8Kinds(t *testing.T) {
+	type ints struct {
+		Small  int64
+		Big    int64
+		Neg    int32
+		Huge   uint64
+		Little uint8
+	}
+	args := ints{1, 1 << 40, -5, 1 << 33, 255}
+	for _, test := range []struct {
+		i8   bool
+		want string
+	}{
+		{false, "<int>1</int>|<int>1099511627776</int>|<int>-5</int>|<int>8589934592</int>|<int>255</int>"},
+		{true, "<int>1</int>|<i8>1099511627776</i8>|<int>-5</int>|<i8>8589934592</i8>|<int>255</int>"},
+	} {
+		opts := &Options{I8: test.i8}
+		var buffer bytes.Buffer
+		if err := rpcParams2XML(&args, &buffer, opts); err != nil {
+			t.Fatal(err)
+		}
+		want := "<params><param><value>" + strings.Replace(test.want, "|", "</value></param><param><value>", -1) + "</value></param></params>"
+		if buffer.String() != want {
+			t.Errorf("Expected %s, got %s", want, buffer.String())
+		}
+
+		var reply ints
+		if err := decodeRPC(toResponse(buffer.String()), &reply, opts); err != nil {
+			t.Fatal(err)
+		}
+		if reply != args {
+			t.Errorf("Expected %+v decoded, got %+v", args, reply)
+		}
+	}
+
+	var reply struct{ Little uint8 }
+	err := decodeRPC(toResponse("<params><param><value><int>256</int></value></param></params>"), &reply, &Options{})
+	if err == nil || !strings.Contains(err.Error(), "overflows uint8") {
+		t.Error("Expected an overflow, got", err)
+	}
+}
+
+func TestProfileOdoo(t *testing.T) {
+	opts := ProfileOdoo.Options
+	resp := `<methodResponse><params><param><value><struct>
+<member><name>post_id</name><value><int>3</int></value></member>
+<member><name>title</name><value><boolean>0</boolean></value></member>
+<member><name>created</name><value><string>2024-05-06 07:08:09</string></value></member>
+<member><name>mtime</name><value><string>2024-05-06</string></value></member>
+</struct></value></param></params></methodResponse>`
+	var reply struct{ Post profilePost }
+	if err := decodeRPC(resp, &reply, &opts); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Post.PostID != 3 || reply.Post.Title != "" {
+		t.Errorf("Expected post 3 without title, got %+v", reply.Post)
+	}
+	if want := time.Date(2024, 5, 6, 7, 8, 9, 0, time.Local); !reply.Post.Created.Equal(want) {
+		t.Errorf("Expected created %v, got %v", want, reply.Post.Created)
+	}
+	if want := time.Date(2024, 5, 6, 0, 0, 0, 0, time.Local); !reply.Post.Modified.Equal(want) {
+		t.Errorf("Expected mtime %v, got %v", want, reply.Post.Modified)
+	}
+}
+
+func TestProfileNewClient(t *testing.T) {
+	p := &Profile{Name: "legacy", Charset: "ISO-8859-1", Options: Options{I8: true}}
+	c := p.NewClient("http://localhost/RPC2")
+	if c.Charset != "ISO-8859-1" || !c.Options.I8 {
+		t.Errorf("Expected the client configured by the profile, got %+v", c)
+	}
+	if codec := p.NewCodec(); !codec.Options.I8 {
+		t.Error("Expected the codec configured by the profile")
+	}
+}
+
+// toResponse wraps encoded params into a methodResponse.
+func toResponse(params string) string {
+	return "<methodResponse>" + params + "</methodResponse>"
+}
@@ -0,0 +1,308 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/rogpeppe/go-charset/charset"
+)
+
+// projStep is a step of a projection path: a member name, an item or param
+// index, or a wildcard of either.
+type projStep struct {
+	member string
+	index  int // -1 for any
+	item   bool
+}
+
+func (s projStep) matches(t projStep) bool {
+	if s.item != t.item {
+		return false
+	}
+	if s.item {
+		return s.index < 0 || s.index == t.index
+	}
+	return s.member == "*" || s.member == t.member
+}
+
+// parseProjection parses a path like "params[0][*].name".
+func parseProjection(path string) ([]projStep, error) {
+	rest := path
+	if !strings.HasPrefix(rest, "params[") {
+		return nil, fmt.Errorf("xmlrpc: projection %q doesn't start with params[", path)
+	}
+	rest = rest[len("params"):]
+	var steps []projStep
+	for rest != "" {
+		switch rest[0] {
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("xmlrpc: projection %q misses a ]", path)
+			}
+			step := projStep{item: true, index: -1}
+			if index := rest[1:end]; index != "*" {
+				n, err := strconv.Atoi(index)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("xmlrpc: projection %q has an invalid index %q", path, index)
+				}
+				step.index = n
+			}
+			steps = append(steps, step)
+			rest = rest[end+1:]
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("xmlrpc: projection %q has an empty member name", path)
+			}
+			steps = append(steps, projStep{member: rest[1 : end+1]})
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("xmlrpc: projection %q is malformed at %q", path, rest)
+		}
+	}
+	return steps, nil
+}
+
+// projection is a set of parsed paths.
+type projection [][]projStep
+
+// match reports whether the node at path is selected as a whole, or has
+// selected descendants.
+func (p projection) match(path []projStep) (whole, descend bool) {
+	for _, pattern := range p {
+		n := len(path)
+		if len(pattern) < n {
+			n = len(pattern)
+		}
+		i := 0
+		for i < n && pattern[i].matches(path[i]) {
+			i++
+		}
+		if i < n {
+			continue
+		}
+		if len(pattern) <= len(path) {
+			return true, true
+		}
+		descend = true
+	}
+	return false, descend
+}
+
+// ProjectResponse parses the params of the methodResponse document data,
+// like ParseMethodResponse, keeping only the parts selected by paths, and
+// skipping the rest of the document without building it, e.g. to get a few
+// members of every item of a large array:
+//
+//	params, err := xml.ProjectResponse(body, "params[0][*].name", "params[0][*].statename")
+//
+// A path starts with the param index, followed by member names and array
+// item indexes; "*" matches any member or item. The struct members not
+// selected are left out, the params and array items not selected are nil.
+// The params can then be decoded into a reply, whose fields not selected
+// are left untouched.
+func ProjectResponse(data []byte, paths ...string) ([]Value, error) {
+	return ProjectReader(bytes.NewReader(data), paths...)
+}
+
+// ProjectReader is like ProjectResponse, but the document is read from r,
+// e.g. in Client.CallReader.
+func ProjectReader(r io.Reader, paths ...string) ([]Value, error) {
+	proj := make(projection, len(paths))
+	for i, path := range paths {
+		steps, err := parseProjection(path)
+		if err != nil {
+			return nil, err
+		}
+		proj[i] = steps
+	}
+
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charset.NewReader
+	d := rawTokens{dec}
+	var (
+		params  []Value
+		root    string
+		inFault bool
+	)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			if root != "methodResponse" {
+				return nil, fmt.Errorf("expected <methodResponse>, got <%s>", root)
+			}
+			return params, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "methodResponse", "methodCall":
+			root = start.Name.Local
+		case "params", "param":
+			// values follow
+		case "fault":
+			inFault = true
+		case "value":
+			if inFault {
+				v, err := parseValue(d)
+				if err != nil {
+					return nil, err
+				}
+				return nil, value2Fault(v)
+			}
+			v, err := projectValue(d, proj, []projStep{{item: true, index: len(params)}})
+			if err != nil {
+				return nil, err
+			}
+			params = append(params, v)
+		default:
+			return nil, fmt.Errorf("unexpected <%s>", start.Name.Local)
+		}
+	}
+}
+
+// projectValue parses the content of a <value> element at path, whose start
+// has been consumed, keeping the parts selected by proj.
+func projectValue(d tokenSource, proj projection, path []projStep) (Value, error) {
+	whole, descend := proj.match(path)
+	switch {
+	case whole:
+		return parseValue(d)
+	case !descend:
+		return Value{Kind: KindNil}, d.Skip()
+	}
+
+	var v Value
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return v, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "struct":
+				v, err = projectStruct(d, proj, path)
+			case "array":
+				v, err = projectArray(d, proj, path)
+			default:
+				v, err = parseTyped(d, t)
+			}
+			if err != nil {
+				return v, err
+			}
+		case xml.EndElement:
+			return v, nil
+		}
+	}
+}
+
+func projectStruct(d tokenSource, proj projection, path []projStep) (Value, error) {
+	v := Value{Kind: KindStruct}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return v, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "member" {
+				return v, fmt.Errorf("unexpected <%s> in struct", t.Name.Local)
+			}
+			m, selected, err := projectMember(d, proj, path)
+			if err != nil {
+				return v, err
+			}
+			if selected {
+				v.Members = append(v.Members, m)
+			}
+		case xml.EndElement:
+			return v, nil
+		}
+	}
+}
+
+// projectMember parses a <member> element, reporting whether it's selected.
+func projectMember(d tokenSource, proj projection, path []projStep) (Member, bool, error) {
+	var (
+		m        Member
+		selected bool
+	)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return m, selected, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "name":
+				if m.Name, err = elementText(d); err != nil {
+					return m, selected, err
+				}
+			case "value":
+				memberPath := append(path[:len(path):len(path)], projStep{member: m.Name})
+				if _, selected = proj.match(memberPath); !selected {
+					if err := d.Skip(); err != nil {
+						return m, selected, err
+					}
+					continue
+				}
+				if m.Value, err = projectValue(d, proj, memberPath); err != nil {
+					return m, selected, err
+				}
+			default:
+				return m, selected, fmt.Errorf("unexpected <%s> in member", t.Name.Local)
+			}
+		case xml.EndElement:
+			return m, selected, nil
+		}
+	}
+}
+
+func projectArray(d tokenSource, proj projection, path []projStep) (Value, error) {
+	v := Value{Kind: KindArray}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return v, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "data":
+				// items follow
+			case "value":
+				itemPath := append(path[:len(path):len(path)], projStep{item: true, index: len(v.Items)})
+				item, err := projectValue(d, proj, itemPath)
+				if err != nil {
+					return v, err
+				}
+				v.Items = append(v.Items, item)
+			default:
+				return v, fmt.Errorf("unexpected <%s> in array", t.Name.Local)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "array" {
+				return v, nil
+			}
+		}
+	}
+}
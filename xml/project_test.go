@@ -0,0 +1,126 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"strings"
+	"testing"
+)
+
+const projectDoc = `<?xml version="1.0"?><methodResponse><params>
+<param><value><array><data>
+<value><struct>
+<member><name>name</name><value><string>web</string></value></member>
+<member><name>statename</name><value><string>RUNNING</string></value></member>
+<member><name>pid</name><value><int>42</int></value></member>
+<member><name>description</name><value><string>pid 42, uptime 0:01:00</string></value></member>
+</struct></value>
+<value><struct>
+<member><name>name</name><value><string>worker</string></value></member>
+<member><name>statename</name><value><string>STOPPED</string></value></member>
+<member><name>pid</name><value><int>0</int></value></member>
+<member><name>logs</name><value><array><data><value>a</value><value>b</value></data></array></value></member>
+</struct></value>
+</data></array></value></param>
+<param><value><int>2</int></value></param>
+</params></methodResponse>`
+
+func TestProjectResponse(t *testing.T) {
+	params, err := ProjectResponse([]byte(projectDoc), "params[0][*].name", "params[0][*].statename")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<value><array><data>` +
+		`<value><struct><member><name>name</name><value><string>web</string></value></member>` +
+		`<member><name>statename</name><value><string>RUNNING</string></value></member></struct></value>` +
+		`<value><struct><member><name>name</name><value><string>worker</string></value></member>` +
+		`<member><name>statename</name><value><string>STOPPED</string></value></member></struct></value>` +
+		`</data></array></value>`
+	if len(params) != 2 || params[0].String() != want || params[1].Kind != KindNil {
+		t.Fatalf("Expected the names and states only, got %v", params)
+	}
+
+	var procs []struct {
+		Name, Statename string
+		Pid             int
+	}
+	if err := params[0].Decode(&procs); err != nil {
+		t.Fatal(err)
+	}
+	if len(procs) != 2 || procs[1].Name != "worker" || procs[1].Statename != "STOPPED" || procs[0].Pid != 0 {
+		t.Errorf("Expected the projection decoded, got %+v", procs)
+	}
+}
+
+func TestProjectResponsePaths(t *testing.T) {
+	for _, tt := range []struct {
+		paths []string
+		want  string
+	}{
+		{[]string{"params[1]"}, `[<value><nil/></value> <value><int>2</int></value>]`},
+		{[]string{"params[0][1].logs[1]"}, `[<value><array><data><value><nil/></value>` +
+			`<value><struct><member><name>logs</name><value><array><data><value><nil/></value>` +
+			`<value><string>b</string></value></data></array></value></member></struct></value>` +
+			`</data></array></value> <value><nil/></value>]`},
+		{[]string{"params[0][0].*"}, ""},
+	} {
+		params, err := ProjectResponse([]byte(projectDoc), tt.paths...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.want == "" {
+			if !strings.Contains(params[0].String(), "uptime") {
+				t.Errorf("Expected every member of %v, got %v", tt.paths, params)
+			}
+			continue
+		}
+		var got []string
+		for _, p := range params {
+			got = append(got, p.String())
+		}
+		if s := "[" + strings.Join(got, " ") + "]"; s != tt.want {
+			t.Errorf("Expected %v to project\n%s, got\n%s", tt.paths, tt.want, s)
+		}
+	}
+}
+
+func TestProjectResponseErrors(t *testing.T) {
+	for _, path := range []string{"name", "params[x]", "params[0].", "params[0", "params[0]name"} {
+		if _, err := ProjectResponse([]byte(projectDoc), path); err == nil {
+			t.Errorf("Expected projection %q rejected", path)
+		}
+	}
+
+	fault := `<methodResponse><fault><value><struct>
+<member><name>faultCode</name><value><int>10</int></value></member>
+<member><name>faultString</name><value><string>BAD_NAME</string></value></member>
+</struct></value></fault></methodResponse>`
+	if _, err := ProjectResponse([]byte(fault), "params[0]"); err == nil || err.(Fault).Code != 10 {
+		t.Errorf("Expected the fault returned, got %v", err)
+	}
+}
+
+func BenchmarkProjectResponse(b *testing.B) {
+	var doc strings.Builder
+	doc.WriteString("<methodResponse><params><param><value><array><data>")
+	for i := 0; i < 1000; i++ {
+		doc.WriteString(`<value><struct><member><name>name</name><value>p</value></member>` +
+			`<member><name>description</name><value><string>pid 42, uptime 0:01:00</string></value></member>` +
+			`<member><name>stdout_logfile</name><value><string>/var/log/p.log</string></value></member></struct></value>`)
+	}
+	doc.WriteString("</data></array></value></param></params></methodResponse>")
+	data := []byte(doc.String())
+
+	b.Run("Parse", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ParseMethodResponse(data)
+		}
+	})
+	b.Run("Project", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ProjectResponse(data, "params[0][*].name")
+		}
+	})
+}
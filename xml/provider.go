@@ -0,0 +1,58 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// MethodProvider implements methods with no Go receiver to reflect on, e.g.
+// those of a plugin loaded with the plugin package, or of a script, so they
+// are registered to a Registry alongside the reflection-based services.
+type MethodProvider interface {
+	// ListMethods returns the names of the methods provided, without their
+	// service name, e.g. "run" for "plugin.run".
+	ListMethods() []string
+
+	// Call calls method, as listed, with its params as an array, and returns
+	// its result. A returned Fault is sent as is, other errors as
+	// application error faults.
+	Call(method string, params Value) (Value, error)
+}
+
+// RegisterProvider registers the methods listed by p under the service
+// name, replacing the service previously registered under that name, if
+// any. The methods are listed once, here: register p again to update them.
+//
+// The calls are served as by a MethodHandler, with the aliases of the
+// Registry's Codec resolved, but none of its other settings.
+func (reg *Registry) RegisterProvider(name string, p MethodProvider) error {
+	if name == "" {
+		return errors.New("xmlrpc: no service name for the method provider")
+	}
+	methods := make(map[string]bool)
+	for _, method := range p.ListMethods() {
+		methods[method] = true
+	}
+	reg.register(name, MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		if m, ok := reg.codec.aliases[method]; ok {
+			method = m
+		}
+		short := strings.TrimPrefix(method, name+".")
+		if !methods[short] {
+			fault := FaultInvalidMethodName
+			fault.String += ": " + method
+			return nil, fault
+		}
+		result, err := p.Call(short, NewArray(params...))
+		if err != nil {
+			return nil, err
+		}
+		return []Value{result}, nil
+	}))
+	return nil
+}
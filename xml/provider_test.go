@@ -0,0 +1,76 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type sumProvider struct{}
+
+func (sumProvider) ListMethods() []string {
+	return []string{"sum", "fail"}
+}
+
+func (sumProvider) Call(method string, params Value) (Value, error) {
+	if method == "fail" {
+		return Value{}, errors.New("boom")
+	}
+	var sum int64
+	for _, p := range params.Items {
+		n, err := p.Int()
+		if err != nil {
+			return Value{}, invalidParams("expected ints, got <%s>", p.Kind)
+		}
+		sum += n
+	}
+	return NewInt(sum), nil
+}
+
+func TestRegistryProvider(t *testing.T) {
+	codec := NewCodec()
+	codec.RegisterAlias("add", "math.sum")
+	reg := NewRegistry(codec)
+	if err := reg.RegisterProvider("math", sumProvider{}); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	reg.RegisterService(new(Service1), "")
+	if names := reg.Services(); !reflect.DeepEqual(names, []string{"Service1", "math"}) {
+		t.Errorf("Expected the provider with the services, got %q", names)
+	}
+	ts := httptest.NewServer(reg)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	for _, method := range []string{"math.sum", "add"} {
+		reply, err := client.CallValues(method, NewInt(1), NewInt(2), NewInt(3))
+		if err != nil {
+			t.Fatal("Expected err to be nil, but got:", err)
+		}
+		if len(reply) != 1 || reply[0].Text != "6" {
+			t.Errorf("Expected 6 from %s, got %v", method, reply)
+		}
+	}
+	if _, err := client.CallValues("math.sum", NewString("x")); err == nil || err.(Fault).Code != FaultInvalidParams.Code {
+		t.Errorf("Expected the provider's fault, got %v", err)
+	}
+	if _, err := client.CallValues("math.fail"); err == nil || err.(Fault).String != FaultApplicationError.String+": boom" {
+		t.Errorf("Expected an application error, got %v", err)
+	}
+	if _, err := client.CallValues("math.product"); err == nil || err.(Fault).Code != FaultInvalidMethodName.Code {
+		t.Errorf("Expected an unlisted method not found, got %v", err)
+	}
+
+	var res Service1Response
+	if err := client.Call("Service1.Multiply", &Service1Request{4, 2}, &res); err != nil || res.Result != 8 {
+		t.Errorf("Expected 8, got %d and %v", res.Result, err)
+	}
+	if err := reg.RegisterProvider("", sumProvider{}); err == nil {
+		t.Error("Expected a provider with no name to be rejected")
+	}
+}
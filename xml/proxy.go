@@ -0,0 +1,157 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// Proxy is an XML-RPC reverse proxy forwarding the calls to upstream servers,
+// in turn, and passing their responses through, e.g. as an authenticating or
+// rate-limiting shield in front of legacy services.
+type Proxy struct {
+	// Upstreams are the URLs of the servers the calls are forwarded to.
+	Upstreams []string
+
+	// HTTPClient forwards the calls; http.DefaultClient is used if nil.
+	HTTPClient *http.Client
+
+	// Inspect, if set, is called with every call before it's forwarded. It
+	// returns the method and params to forward, possibly rewritten, or an
+	// error to reject the call with; a Fault is sent as is, other errors as
+	// application error faults. Calls are forwarded byte for byte if nil.
+	Inspect func(r *http.Request, method string, params []Value) (string, []Value, error)
+
+	// ResponseFilters are run, in order, on the successful responses. The
+	// responses are passed through byte for byte if empty.
+	ResponseFilters []ResponseFilter
+
+	next uint32
+}
+
+// NewProxy returns a Proxy forwarding the calls to upstreams.
+func NewProxy(upstreams ...string) *Proxy {
+	return &Proxy{Upstreams: upstreams}
+}
+
+// ServeHTTP implements http.Handler.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeFault(w, FaultSystemError)
+		return
+	}
+	if p.Inspect != nil {
+		if body, err = p.inspect(r, body); err != nil {
+			fault, ok := asFault(err)
+			if !ok {
+				fault = FaultApplicationError
+				fault.String += fmt.Sprintf(": %v", err)
+			}
+			writeFault(w, fault)
+			return
+		}
+	}
+	if len(p.Upstreams) == 0 {
+		fault := FaultSystemError
+		fault.String += ": no upstream"
+		writeFault(w, fault)
+		return
+	}
+
+	upstream := p.Upstreams[int(atomic.AddUint32(&p.next, 1)-1)%len(p.Upstreams)]
+	req, err := http.NewRequest("POST", upstream, bytes.NewReader(body))
+	if err != nil {
+		writeFault(w, FaultSystemError)
+		return
+	}
+	req = req.WithContext(r.Context())
+	copyEndToEndHeader(req.Header, r.Header)
+
+	resp, err := p.httpClient().Do(req)
+	if err != nil {
+		fault := FaultSystemError
+		fault.String += fmt.Sprintf(": %v", err)
+		writeFault(w, fault)
+		return
+	}
+	defer resp.Body.Close()
+	copyEndToEndHeader(w.Header(), resp.Header)
+	if len(p.ResponseFilters) == 0 || resp.StatusCode != http.StatusOK {
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+		return
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		writeFault(w, FaultSystemError)
+		return
+	}
+	method, _, _ := ParseMethodCall(body)
+	respBody = filterResponse(p.ResponseFilters, method, respBody)
+	w.Write(respBody)
+}
+
+// hopByHopHeaders are the headers of a connection, not forwarded by proxies
+// (RFC 7230, section 6.1).
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"TE",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// copyEndToEndHeader copies the header src to dst, but the hop-by-hop
+// headers, those listed in its Connection header, and the Content-Length,
+// which is set again for the body forwarded.
+func copyEndToEndHeader(dst, src http.Header) {
+	for k, v := range src {
+		dst[k] = append([]string(nil), v...)
+	}
+	for _, v := range src["Connection"] {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				dst.Del(name)
+			}
+		}
+	}
+	for _, name := range hopByHopHeaders {
+		dst.Del(name)
+	}
+	dst.Del("Content-Length")
+}
+
+// inspect runs Inspect on the methodCall document body and returns the
+// document to forward.
+func (p *Proxy) inspect(r *http.Request, body []byte) ([]byte, error) {
+	method, params, err := ParseMethodCall(body)
+	if err != nil {
+		return nil, FaultDecode
+	}
+	method, params, err = p.Inspect(r, method, params)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeMethodCall(method, params), nil
+}
+
+func (p *Proxy) httpClient() *http.Client {
+	if p.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return p.HTTPClient
+}
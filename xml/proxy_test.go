@@ -0,0 +1,106 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProxy(t *testing.T) {
+	upstream1 := newTestServer(NewCodec())
+	defer upstream1.Close()
+	upstream2 := newTestServer(NewCodec())
+	defer upstream2.Close()
+
+	proxy := NewProxy(upstream1.URL, upstream2.URL)
+	ts := httptest.NewServer(proxy)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	for i := 0; i < 2; i++ {
+		var res Service1Response
+		if err := client.Call("Service1.Multiply", &Service1Request{4, 2}, &res); err != nil || res.Result != 8 {
+			t.Error("Expected 8, got", res.Result, err)
+		}
+	}
+	if proxy.next != 2 {
+		t.Error("Expected the calls to be spread over the upstreams, got", proxy.next)
+	}
+
+	proxy.Inspect = func(r *http.Request, method string, params []Value) (string, []Value, error) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			return "", nil, FaultApplicationError
+		}
+		// double the first operand
+		n, _ := params[0].Int()
+		params[0] = NewInt(2 * n)
+		return method, params, nil
+	}
+	var res Service1Response
+	err := client.Call("Service1.Multiply", &Service1Request{4, 2}, &res)
+	if fault, ok := err.(Fault); !ok || fault.Code != FaultApplicationError.Code {
+		t.Error("Expected application error fault, got", err)
+	}
+
+	client.HTTPClient = &http.Client{Transport: headerTransport{"Authorization", "Bearer secret"}}
+	if err := client.Call("Service1.Multiply", &Service1Request{4, 2}, &res); err != nil || res.Result != 16 {
+		t.Error("Expected 16, got", res.Result, err)
+	}
+}
+
+// headerTransport sets a header on every request.
+type headerTransport struct {
+	key, value string
+}
+
+func (h headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.Header.Set(h.key, h.value)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestProxyHopByHopHeaders(t *testing.T) {
+	var got http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("Connection", "X-Upstream-Hop")
+		w.Header().Set("X-Upstream-Hop", "1")
+		w.Header().Set("Keep-Alive", "timeout=5")
+		w.Header().Set("X-End", "1")
+		Fault2XML(FaultApplicationError, w)
+	}))
+	defer upstream.Close()
+	ts := httptest.NewServer(NewProxy(upstream.URL))
+	defer ts.Close()
+
+	req, _ := http.NewRequest("POST", ts.URL, strings.NewReader(string(EncodeMethodCall("m", nil))))
+	req.Header.Set("Connection", "X-Client-Hop")
+	req.Header.Set("X-Client-Hop", "1")
+	req.Header.Set("Proxy-Authorization", "Basic c2VjcmV0")
+	req.Header.Set("Te", "trailers")
+	req.Header.Set("X-End", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	resp.Body.Close()
+
+	for _, name := range []string{"X-Client-Hop", "Proxy-Authorization", "Te"} {
+		if got.Get(name) != "" {
+			t.Errorf("Expected %s not forwarded upstream", name)
+		}
+	}
+	for _, name := range []string{"X-Upstream-Hop", "Keep-Alive"} {
+		if resp.Header.Get(name) != "" {
+			t.Errorf("Expected %s not passed through", name)
+		}
+	}
+	if got.Get("X-End") == "" || resp.Header.Get("X-End") == "" {
+		t.Error("Expected the end-to-end headers forwarded")
+	}
+}
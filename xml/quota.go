@@ -0,0 +1,204 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// QuotaLimits are the numbers of calls an API key may make per minute and
+// per day, in the calendar minutes and UTC days. Zero is no limit.
+type QuotaLimits struct {
+	PerMinute int `json:"per_minute"`
+	PerDay    int `json:"per_day"`
+}
+
+// Quotas account the calls of a server per API key, and enforce the limits
+// of the keys (see Quota). It's also a handler serving the usage of the
+// keys as JSON, to mount on an admin mux next to the ServerStats:
+//
+//	quotas := &xml.Quotas{
+//		Key: func(r *http.Request, method string, params []xml.Value) string {
+//			return r.Header.Get("X-Api-Key")
+//		},
+//		Default: xml.QuotaLimits{PerMinute: 60, PerDay: 10000},
+//	}
+//	http.Handle("/RPC2", xml.Quota(s, quotas))
+//	admin.Handle("/debug/xmlrpc/quotas", quotas)
+type Quotas struct {
+	// Key returns the API key of a call of method with params, e.g. from a
+	// header or the first param; the calls with no key aren't accounted.
+	Key func(r *http.Request, method string, params []Value) string
+
+	// Limits, if set, returns the limits of a key, e.g. from its plan; the
+	// keys get the Default limits otherwise. They are resolved again every
+	// minute, so the changes of plans apply. Limits is called concurrently,
+	// without holding back the calls of the other keys.
+	Limits  func(key string) QuotaLimits
+	Default QuotaLimits
+
+	mu    sync.Mutex
+	keys  map[string]*quotaUsage
+	swept time.Time        // the day the idle keys were last forgotten
+	now   func() time.Time // time.Now if nil
+}
+
+// KeyUsage is the usage of an API key.
+type KeyUsage struct {
+	Key    string      `json:"key"`
+	Limits QuotaLimits `json:"limits"`
+
+	// Minute and Day are the calls made in the current minute and day,
+	// Total since the key was first seen. The keys with no call in a whole
+	// day are forgotten.
+	Minute int `json:"minute"`
+	Day    int `json:"day"`
+	Total  int `json:"total"`
+
+	// Rejected are the calls rejected for breaking the limits.
+	Rejected int `json:"rejected"`
+}
+
+type quotaUsage struct {
+	limits          QuotaLimits
+	minute, day     time.Time // the current windows
+	inMinute, inDay int
+	total, rejected int
+}
+
+// Quota wraps the XML-RPC handler next, so the calls are attributed to
+// their API key and accounted in quotas. The calls breaking the limits of
+// their key are answered with a fault, and a Retry-After header telling
+// when the window is over.
+func Quota(next http.Handler, quotas *Quotas) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, rawxml, err := readMethod(r, defaultOptions)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		_, method, params, err := parseDocument(rawxml)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := quotas.Key(r, method, params)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if retry, err := quotas.take(key); err != nil {
+			w.Header().Set("Retry-After", strconv.Itoa(int((retry+time.Second-1)/time.Second)))
+			fault := FaultApplicationError
+			fault.String += fmt.Sprintf(": %v", err)
+			writeFault(w, fault)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take accounts a call of key. If it breaks the limits of key, it returns
+// why and the time until the window is over.
+func (q *Quotas) take(key string) (time.Duration, error) {
+	now, minute, day := q.windows()
+	limits, resolved := q.Default, q.Limits == nil
+
+	q.mu.Lock()
+	u := q.usage(key, day)
+	for !u.minute.Equal(minute) && !resolved {
+		// resolved without the lock, as Limits may be slow, e.g. a lookup
+		// in a plan database
+		q.mu.Unlock()
+		limits, resolved = q.Limits(key), true
+		q.mu.Lock()
+		u = q.usage(key, day)
+	}
+	defer q.mu.Unlock()
+	if !u.minute.Equal(minute) {
+		u.minute, u.inMinute = minute, 0
+		u.limits = limits
+	}
+	if !u.day.Equal(day) {
+		u.day, u.inDay = day, 0
+	}
+
+	switch {
+	case u.limits.PerDay > 0 && u.inDay >= u.limits.PerDay:
+		u.rejected++
+		return day.Add(24 * time.Hour).Sub(now), fmt.Errorf("quota of %d calls per day exceeded", u.limits.PerDay)
+	case u.limits.PerMinute > 0 && u.inMinute >= u.limits.PerMinute:
+		u.rejected++
+		return minute.Add(time.Minute).Sub(now), fmt.Errorf("quota of %d calls per minute exceeded", u.limits.PerMinute)
+	}
+	u.inMinute++
+	u.inDay++
+	u.total++
+	return 0, nil
+}
+
+// usage returns the usage of key, forgetting the keys idle since yesterday
+// at least once a day. q.mu must be held.
+func (q *Quotas) usage(key string, day time.Time) *quotaUsage {
+	if !q.swept.Equal(day) {
+		for k, u := range q.keys {
+			if u.day.Before(day.Add(-24 * time.Hour)) {
+				delete(q.keys, k)
+			}
+		}
+		q.swept = day
+	}
+	u := q.keys[key]
+	if u == nil {
+		if q.keys == nil {
+			q.keys = make(map[string]*quotaUsage)
+		}
+		u = &quotaUsage{}
+		q.keys[key] = u
+	}
+	return u
+}
+
+// windows returns the time, and the current minute and day.
+func (q *Quotas) windows() (now, minute, day time.Time) {
+	now = time.Now()
+	if q.now != nil {
+		now = q.now()
+	}
+	return now, now.Truncate(time.Minute), now.UTC().Truncate(24 * time.Hour)
+}
+
+// Snapshot returns the usage of the keys seen, sorted by key.
+func (q *Quotas) Snapshot() []KeyUsage {
+	_, minute, day := q.windows()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	usage := make([]KeyUsage, 0, len(q.keys))
+	for key, u := range q.keys {
+		ku := KeyUsage{Key: key, Limits: u.limits, Total: u.total, Rejected: u.rejected}
+		if u.minute.Equal(minute) {
+			ku.Minute = u.inMinute
+		}
+		if u.day.Equal(day) {
+			ku.Day = u.inDay
+		}
+		usage = append(usage, ku)
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Key < usage[j].Key })
+	return usage
+}
+
+// ServeHTTP implements http.Handler.
+func (q *Quotas) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(q.Snapshot())
+}
@@ -0,0 +1,178 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestQuota(t *testing.T) {
+	now := time.Date(2013, 10, 10, 13, 55, 30, 0, time.UTC)
+	quotas := &Quotas{
+		Key: func(r *http.Request, method string, params []Value) string {
+			if len(params) == 0 {
+				return ""
+			}
+			return params[0].Text
+		},
+		Limits: func(key string) QuotaLimits {
+			if key == "gold" {
+				return QuotaLimits{}
+			}
+			return QuotaLimits{PerMinute: 2, PerDay: 3}
+		},
+		now: func() time.Time { return now },
+	}
+	handler := MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		return params, nil
+	})
+	ts := httptest.NewServer(Quota(handler, quotas))
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	call := func(key string) error {
+		_, err := client.CallValues("get", NewString(key))
+		return err
+	}
+	for i := 0; i < 2; i++ {
+		if err := call("free"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := call("free"); err == nil {
+		t.Error("Expected the minute quota exceeded")
+	} else if fault, ok := err.(Fault); !ok || fault.String != "Application Error: quota of 2 calls per minute exceeded" {
+		t.Errorf("Unexpected error %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := call("gold"); err != nil {
+			t.Error("Expected no limits for gold, got", err)
+		}
+	}
+	if _, err := client.CallValues("get"); err != nil {
+		t.Error("Expected the calls with no key passed through, got", err)
+	}
+
+	now = now.Add(time.Minute)
+	if err := call("free"); err != nil {
+		t.Error("Expected the minute quota reset, got", err)
+	}
+	if err := call("free"); err == nil {
+		t.Error("Expected the day quota exceeded")
+	}
+
+	rec := httptest.NewRecorder()
+	quotas.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	var usage []KeyUsage
+	if err := json.Unmarshal(rec.Body.Bytes(), &usage); err != nil {
+		t.Fatal(err)
+	}
+	expected := []KeyUsage{
+		{Key: "free", Limits: QuotaLimits{2, 3}, Minute: 1, Day: 3, Total: 3, Rejected: 2},
+		{Key: "gold", Minute: 0, Day: 5, Total: 5},
+	}
+	if len(usage) != 2 || usage[0] != expected[0] || usage[1] != expected[1] {
+		t.Errorf("Expected usage %+v, got %+v", expected, usage)
+	}
+}
+
+func TestQuotaRetryAfter(t *testing.T) {
+	quotas := &Quotas{
+		Key:     func(r *http.Request, method string, params []Value) string { return "k" },
+		Default: QuotaLimits{PerMinute: 1},
+		now:     func() time.Time { return time.Date(2013, 10, 10, 13, 55, 30, 0, time.UTC) },
+	}
+	handler := Quota(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		return nil, nil
+	}), quotas)
+	var rec *httptest.ResponseRecorder
+	for i := 0; i < 2; i++ {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/", bytes.NewReader(EncodeMethodCall("m", nil))))
+	}
+	if retry := rec.Header().Get("Retry-After"); retry != "30" {
+		t.Errorf("Expected Retry-After 30, got %q", retry)
+	}
+}
+
+func TestQuotaRollover(t *testing.T) {
+	now := time.Date(2013, 10, 10, 13, 55, 30, 0, time.UTC)
+	plan := QuotaLimits{PerMinute: 1}
+	quotas := &Quotas{
+		Limits: func(key string) QuotaLimits { return plan },
+		now:    func() time.Time { return now },
+	}
+	if _, err := quotas.take("k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := quotas.take("k"); err == nil {
+		t.Error("Expected the minute quota exceeded")
+	}
+	plan = QuotaLimits{PerMinute: 2}
+	now = now.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		if _, err := quotas.take("k"); err != nil {
+			t.Error("Expected the new limits applied, got", err)
+		}
+	}
+
+	quotas.take("idle")
+	now = now.Add(24 * time.Hour)
+	quotas.take("k")
+	if len(quotas.Snapshot()) != 2 {
+		t.Error("Expected the keys idle for less than a day kept")
+	}
+	now = now.Add(24 * time.Hour)
+	quotas.take("k")
+	if usage := quotas.Snapshot(); len(usage) != 1 || usage[0].Key != "k" {
+		t.Error("Expected the idle key forgotten, got", usage)
+	}
+}
+
+func TestQuotaSlowLimits(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	quotas := &Quotas{
+		Limits: func(key string) QuotaLimits {
+			if key == "slow" {
+				close(entered)
+				<-release
+			}
+			return QuotaLimits{PerMinute: 1}
+		},
+	}
+	done := make(chan error)
+	go func() {
+		_, err := quotas.take("slow")
+		done <- err
+	}()
+	<-entered
+
+	// the lookup of a key doesn't hold back the calls of the others
+	fast := make(chan error)
+	go func() {
+		_, err := quotas.take("fast")
+		fast <- err
+	}()
+	select {
+	case err := <-fast:
+		if err != nil {
+			t.Error("Expected err to be nil, but got:", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the call of another key not to wait for the lookup")
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if _, err := quotas.take("slow"); err == nil {
+		t.Error("Expected the limits of the slow key applied")
+	}
+}
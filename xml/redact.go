@@ -0,0 +1,146 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"strings"
+	"sync"
+)
+
+// RedactAction is how a redacted member is concealed.
+type RedactAction string
+
+// Redaction actions.
+const (
+	// RedactMask replaces the value with "***", as a string.
+	RedactMask RedactAction = "mask"
+
+	// RedactHash replaces the value with the hex HMAC-SHA256 of its text (or
+	// of its XML, for structs and arrays) under the Key of the Redaction, as
+	// a string, so equal values can still be told apart.
+	RedactHash RedactAction = "hash"
+
+	// RedactDrop removes the member.
+	RedactDrop RedactAction = "drop"
+)
+
+// RedactRule conceals the members at Path in the params of the calls and
+// responses of the methods matching Method.
+type RedactRule struct {
+	// Method is a method pattern, where * matches any run of characters, as
+	// with Router. Rules with no Method apply to every method.
+	Method string `json:"method"`
+
+	// Path is the dot-separated names of the members from a param, e.g.
+	// "user.password", where * matches any member name. Arrays are
+	// traversed, so "users.password" conceals the password of every item of
+	// the users array.
+	Path string `json:"path"`
+
+	Action RedactAction `json:"action"`
+}
+
+// Redaction is a declarative redaction config, e.g. loaded from JSON, to
+// keep personal data out of the responses of a server and the debug logs of
+// a client. Use its Redact method as a ResponseFilter of a Codec or a Proxy,
+// and set it as the Redaction of a Client.
+type Redaction struct {
+	Rules []RedactRule `json:"rules"`
+
+	// Key is the secret key of the hash action, so guessable values, e.g.
+	// emails, can't be recovered by hashing candidates. With no Key, a
+	// random key is used, so the hashes only match within the process.
+	Key string `json:"key"`
+
+	keyOnce sync.Once
+	key     []byte
+}
+
+// Redact returns params of a call or response of method with the members
+// concealed as configured.
+func (rd *Redaction) Redact(method string, params []Value) []Value {
+	for _, rule := range rd.Rules {
+		if rule.Method != "" {
+			if ok, _ := path.Match(rule.Method, method); !ok {
+				continue
+			}
+		}
+		steps := strings.Split(rule.Path, ".")
+		params = mapValues(params, func(v Value) Value {
+			return rd.redactValue(v, steps, rule.Action)
+		})
+	}
+	return params
+}
+
+// RedactDocument returns the methodCall or methodResponse document data to
+// the call of method, with the members concealed as configured. Faults and
+// documents that can't be parsed are returned as is.
+func (rd *Redaction) RedactDocument(method string, data []byte) []byte {
+	root, called, params, err := parseDocument(data)
+	if err != nil {
+		return data
+	}
+	if root == "methodCall" {
+		return EncodeMethodCall(called, rd.Redact(called, params))
+	}
+	return EncodeMethodResponse(rd.Redact(method, params))
+}
+
+// redactValue returns v with the members at steps concealed by action.
+func (rd *Redaction) redactValue(v Value, steps []string, action RedactAction) Value {
+	switch v.Kind {
+	case KindArray:
+		v.Items = mapValues(v.Items, func(item Value) Value {
+			return rd.redactValue(item, steps, action)
+		})
+	case KindStruct:
+		members := make([]Member, 0, len(v.Members))
+		for _, m := range v.Members {
+			if ok, _ := path.Match(steps[0], m.Name); !ok {
+				members = append(members, m)
+				continue
+			}
+			if len(steps) > 1 {
+				members = append(members, Member{m.Name, rd.redactValue(m.Value, steps[1:], action)})
+				continue
+			}
+			switch action {
+			case RedactDrop:
+			case RedactHash:
+				members = append(members, Member{m.Name, NewString(rd.hashValue(m.Value))})
+			default:
+				members = append(members, Member{m.Name, NewString("***")})
+			}
+		}
+		v.Members = members
+	}
+	return v
+}
+
+func (rd *Redaction) hashValue(v Value) string {
+	rd.keyOnce.Do(func() {
+		if rd.Key != "" {
+			rd.key = []byte(rd.Key)
+			return
+		}
+		rd.key = make([]byte, sha256.BlockSize)
+		if _, err := rand.Read(rd.key); err != nil {
+			panic("xmlrpc: can't generate the redaction key: " + err.Error())
+		}
+	})
+	text := v.Text
+	if v.Kind == KindStruct || v.Kind == KindArray {
+		text = v.String()
+	}
+	mac := hmac.New(sha256.New, rd.key)
+	mac.Write([]byte(text))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,85 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+func TestRedaction(t *testing.T) {
+	var rd Redaction
+	config := `{"rules": [
+		{"method": "user.*", "path": "password", "action": "drop"},
+		{"path": "cards.number", "action": "mask"},
+		{"path": "*.email", "action": "hash"}
+	], "key": "pepper"}`
+	if err := json.Unmarshal([]byte(config), &rd); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+
+	user := NewStruct(
+		Member{"login", NewString("ivan")},
+		Member{"password", NewString("secret")},
+		Member{"cards", NewArray(NewStruct(Member{"number", NewString("4111")}))},
+		Member{"contact", NewStruct(Member{"email", NewString("ivan@example.com")})},
+	)
+	redacted := rd.Redact("user.get", []Value{user})
+	expected := NewStruct(
+		Member{"login", NewString("ivan")},
+		Member{"cards", NewArray(NewStruct(Member{"number", NewString("***")}))},
+		Member{"contact", NewStruct(Member{"email", NewString("10f43653e58afbdef7ac08d98c406702acaf8a223811ad09f15f676e925861f2")})},
+	)
+	if redacted[0].String() != expected.String() {
+		t.Errorf("Expected %s, got %s", expected, redacted[0])
+	}
+	if redacted = rd.Redact("other.get", []Value{user}); len(redacted[0].Members) != 4 {
+		t.Error("Expected the password to be kept for other methods, got", redacted[0])
+	}
+
+	email := []Value{NewStruct(Member{"contact", NewStruct(Member{"email", NewString("ivan@example.com")})})}
+	other := &Redaction{Rules: rd.Rules, Key: "salt"}
+	if rd.Redact("user.get", email)[0].String() == other.Redact("user.get", email)[0].String() {
+		t.Error("Expected the hashes to depend on the key")
+	}
+	random := &Redaction{Rules: rd.Rules}
+	first := random.Redact("user.get", email)[0].String()
+	if second := random.Redact("user.get", email)[0].String(); first != second || first == rd.Redact("user.get", email)[0].String() {
+		t.Errorf("Expected stable hashes under a random key, got %s and %s", first, second)
+	}
+}
+
+func TestClientRedaction(t *testing.T) {
+	codec := NewCodec()
+	rd := &Redaction{Rules: []RedactRule{{Path: "Name", Action: RedactMask}}}
+	codec.ResponseFilters = []ResponseFilter{rd.Redact}
+	s := rpc.NewServer()
+	s.RegisterCodec(codec, "text/xml")
+	s.RegisterService(new(Greeter), "")
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var request, response []byte
+	client := NewClient(ts.URL)
+	client.Redaction = &Redaction{Rules: []RedactRule{{Path: "Name", Action: RedactDrop}}}
+	client.Debug = func(method string, req, resp []byte) {
+		request, response = req, resp
+	}
+	var reply GreetReply
+	if err := client.Call("Greeter.Greet", &GreetArgs{GreetPerson{"Ivan"}, "!"}, &reply); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if reply.Person.Name != "***" || reply.Greeting != "Hello, Ivan!" {
+		t.Errorf("Expected the name to be masked, got %+v", reply)
+	}
+	if bytes.Contains(request, []byte("<name>Name</name>")) || bytes.Contains(response, []byte("<name>Name</name>")) ||
+		!bytes.Contains(response, []byte("Hello, Ivan!")) {
+		t.Errorf("Expected the names to be dropped from the debugged documents, got %s and %s", request, response)
+	}
+}
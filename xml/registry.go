@@ -0,0 +1,109 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+// Registry serves XML-RPC calls with services registered and unregistered
+// while it serves, e.g. as the plugins of an application are loaded and
+// unloaded, where the services of an rpc.Server can't change once it
+// serves:
+//
+//	reg := xml.NewRegistry(xml.NewCodec())
+//	http.Handle("/RPC2", reg)
+//	...
+//	reg.RegisterService(new(PluginService), "")
+//	...
+//	reg.UnregisterService("PluginService")
+//
+// A call is routed by the service of its method, the part before the first
+// dot, so "PluginService.Run" goes to the service PluginService. The calls
+// under way when a service is unregistered complete; the next ones are
+// answered with a method not found fault.
+type Registry struct {
+	codec *Codec
+
+	mu       sync.RWMutex
+	services map[string]http.Handler
+}
+
+// NewRegistry returns an empty Registry serving the calls with codec.
+func NewRegistry(codec *Codec) *Registry {
+	return &Registry{codec: codec, services: make(map[string]http.Handler)}
+}
+
+// RegisterService registers the methods of receiver under the service name,
+// or the name of its type if empty, as rpc.Server.RegisterService does,
+// replacing the service previously registered under that name, if any.
+func (reg *Registry) RegisterService(receiver interface{}, name string) error {
+	s := rpc.NewServer()
+	s.RegisterCodec(reg.codec, "text/xml")
+	if err := s.RegisterService(receiver, name); err != nil {
+		return err
+	}
+	if name == "" {
+		name = reflect.Indirect(reflect.ValueOf(receiver)).Type().Name()
+	}
+	reg.register(name, s)
+	return nil
+}
+
+func (reg *Registry) register(name string, h http.Handler) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.services[name] = h
+}
+
+// UnregisterService removes the service name, and reports whether it was
+// registered.
+func (reg *Registry) UnregisterService(name string) bool {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	_, ok := reg.services[name]
+	delete(reg.services, name)
+	return ok
+}
+
+// Services returns the names of the services registered, sorted.
+func (reg *Registry) Services() []string {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	names := make([]string, 0, len(reg.services))
+	for name := range reg.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ServeHTTP implements http.Handler.
+func (reg *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method, _, err := readMethod(r, &reg.codec.Options)
+	if err != nil {
+		writeFault(w, FaultDecode)
+		return
+	}
+	if m, ok := reg.codec.aliases[method]; ok {
+		method = m
+	}
+	reg.mu.RLock()
+	h := reg.services[strings.SplitN(method, ".", 2)[0]]
+	reg.mu.RUnlock()
+	if h == nil {
+		fault := FaultInvalidMethodName
+		fault.String += ": " + method
+		writeFault(w, fault)
+		return
+	}
+	h.ServeHTTP(w, r)
+}
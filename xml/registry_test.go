@@ -0,0 +1,98 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	reg := NewRegistry(NewCodec())
+	ts := httptest.NewServer(reg)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	var res Service1Response
+	if err := client.Call("Service1.Multiply", &Service1Request{4, 2}, &res); err == nil ||
+		err.(Fault).Code != FaultInvalidMethodName.Code {
+		t.Errorf("Expected a method not found fault, got %v", err)
+	}
+
+	if err := reg.RegisterService(new(Service1), ""); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if err := reg.RegisterService(new(Service2), "Greeter"); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if names := reg.Services(); !reflect.DeepEqual(names, []string{"Greeter", "Service1"}) {
+		t.Errorf("Expected the services registered, got %q", names)
+	}
+	if err := client.Call("Service1.Multiply", &Service1Request{4, 2}, &res); err != nil || res.Result != 8 {
+		t.Errorf("Expected 8, got %d and %v", res.Result, err)
+	}
+	var greeting Service2Response
+	if err := client.Call("Greeter.GetGreeting", &Service2Request{"Johnny", 33, true}, &greeting); err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+
+	if !reg.UnregisterService("Service1") || reg.UnregisterService("Service1") {
+		t.Error("Expected the service unregistered once")
+	}
+	if err := client.Call("Service1.Multiply", &Service1Request{4, 2}, &res); err == nil {
+		t.Error("Expected the unregistered service to fail")
+	}
+	if err := reg.RegisterService(struct{}{}, ""); err == nil {
+		t.Error("Expected an unnamed receiver to be rejected")
+	}
+}
+
+func TestRegistryCharset(t *testing.T) {
+	reg := NewRegistry(NewCodec())
+	reg.RegisterService(new(Service2), "Greeter")
+	ts := httptest.NewServer(reg)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+	client.Charset = "ISO-8859-1"
+
+	var greeting Service2Response
+	if err := client.Call("Greeter.GetGreeting", &Service2Request{"Jöhnny", 33, true}, &greeting); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if !strings.Contains(greeting.Message, "Jöhnny") {
+		t.Error("Expected the name decoded, got", greeting.Message)
+	}
+}
+
+func TestRegistryConcurrent(t *testing.T) {
+	reg := NewRegistry(NewCodec())
+	reg.RegisterService(new(Service1), "")
+	ts := httptest.NewServer(reg)
+	defer ts.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client := NewClient(ts.URL)
+			for j := 0; j < 20; j++ {
+				var res Service1Response
+				if err := client.Call("Service1.Multiply", &Service1Request{j, 2}, &res); err != nil {
+					t.Error("Expected err to be nil, but got:", err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		reg.RegisterService(new(Service2), "")
+		reg.UnregisterService("Service2")
+	}
+	wg.Wait()
+}
@@ -0,0 +1,251 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+var (
+	// errNotUTF8 stops the fast parsing of the documents in other charsets.
+	errNotUTF8 = errors.New("xmlrpc: not UTF-8")
+
+	// errNamespaced stops the fast parsing of the documents with namespaces,
+	// which xml.Unmarshal translates.
+	errNamespaced = errors.New("xmlrpc: namespaced document")
+)
+
+// parseResponse parses a methodResponse document into ret like
+// xml.Unmarshal, but by walking the raw tokens, skipping the reflection and
+// the namespace translation of xml.Unmarshal, which dominate the decoding
+// of the replies. It fails on the documents it can't parse like
+// xml.Unmarshal, e.g. in another charset than UTF-8 or with namespaces,
+// which are then left to it.
+func parseResponse(data []byte, ret *response) error {
+	p := &replyParser{data: data, d: xml.NewDecoder(bytes.NewReader(data))}
+	p.d.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return nil, errNotUTF8
+	}
+
+	// the root element, whatever its name
+	for {
+		tok, err := p.d.RawToken()
+		if err != nil {
+			return err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			if err := namespaced(start); err != nil {
+				return err
+			}
+			return p.children(start, func(child xml.StartElement) error {
+				switch child.Name.Local {
+				case "params":
+					return p.children(child, func(child xml.StartElement) error {
+						if child.Name.Local != "param" {
+							return p.skip(child)
+						}
+						ret.Params = append(ret.Params, param{})
+						param := &ret.Params[len(ret.Params)-1]
+						return p.children(child, func(child xml.StartElement) error {
+							if child.Name.Local != "value" {
+								return p.skip(child)
+							}
+							return p.value(child, &param.Value)
+						})
+					})
+				case "fault":
+					return p.children(child, func(child xml.StartElement) error {
+						if child.Name.Local != "value" {
+							return p.skip(child)
+						}
+						return p.value(child, &ret.Fault.Value)
+					})
+				}
+				return p.skip(child)
+			})
+		}
+	}
+}
+
+// replyParser walks the raw tokens of a document, checking that the
+// elements are balanced as xml.Decoder.Token would.
+type replyParser struct {
+	data []byte
+	d    *xml.Decoder
+}
+
+// next returns the next token within the element start, or nil at its end.
+func (p *replyParser) next(start xml.StartElement) (xml.Token, error) {
+	tok, err := p.d.RawToken()
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case xml.EndElement:
+		if t.Name != start.Name {
+			return nil, errors.New("xmlrpc: element mismatch")
+		}
+		return nil, nil
+	case xml.StartElement:
+		if err := namespaced(t); err != nil {
+			return nil, err
+		}
+	}
+	return tok, nil
+}
+
+// namespaced fails if the element start has a prefix or declares a
+// namespace.
+func namespaced(start xml.StartElement) error {
+	if start.Name.Space != "" {
+		return errNamespaced
+	}
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+			return errNamespaced
+		}
+	}
+	return nil
+}
+
+// children calls f with the child elements of start, up to its end.
+func (p *replyParser) children(start xml.StartElement, f func(child xml.StartElement) error) error {
+	for {
+		tok, err := p.next(start)
+		if err != nil || tok == nil {
+			return err
+		}
+		if child, ok := tok.(xml.StartElement); ok {
+			if err := f(child); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// skip skips the element start.
+func (p *replyParser) skip(start xml.StartElement) error {
+	return p.children(start, p.skip)
+}
+
+// text returns the character data of the element start, skipping its child
+// elements.
+func (p *replyParser) text(start xml.StartElement) (string, error) {
+	var text []byte
+	for {
+		tok, err := p.next(start)
+		if err != nil || tok == nil {
+			return string(text), err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			text = append(text, t...)
+		case xml.StartElement:
+			if err := p.skip(t); err != nil {
+				return "", err
+			}
+		}
+	}
+}
+
+// value parses the <value> element start into v.
+func (p *replyParser) value(start xml.StartElement, v *value) error {
+	begin := p.d.InputOffset()
+	var text []byte
+	for {
+		tok, err := p.next(start)
+		if err != nil {
+			return err
+		}
+		if tok == nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			text = append(text, t...)
+		case xml.StartElement:
+			if err := p.member(t, v); err != nil {
+				return err
+			}
+		}
+	}
+	v.Text = string(text)
+
+	// the inner XML, up to the end tag unless the element is empty
+	v.Raw = ""
+	if end := p.d.InputOffset(); end > begin {
+		inner := p.data[begin:end]
+		v.Raw = string(inner[:bytes.LastIndex(inner, []byte("</"))])
+	}
+	return nil
+}
+
+// member parses the child element start of a <value> into v.
+func (p *replyParser) member(start xml.StartElement, v *value) error {
+	var field *string
+	switch start.Name.Local {
+	case "array":
+		return p.children(start, func(child xml.StartElement) error {
+			if child.Name.Local != "data" {
+				return p.skip(child)
+			}
+			return p.children(child, func(child xml.StartElement) error {
+				if child.Name.Local != "value" {
+					return p.skip(child)
+				}
+				v.Array = append(v.Array, value{})
+				return p.value(child, &v.Array[len(v.Array)-1])
+			})
+		})
+	case "struct":
+		return p.children(start, func(child xml.StartElement) error {
+			if child.Name.Local != "member" {
+				return p.skip(child)
+			}
+			v.Struct = append(v.Struct, member{})
+			m := &v.Struct[len(v.Struct)-1]
+			return p.children(child, func(child xml.StartElement) error {
+				switch child.Name.Local {
+				case "name":
+					name, err := p.text(child)
+					m.Name = name
+					return err
+				case "value":
+					return p.value(child, &m.Value)
+				}
+				return p.skip(child)
+			})
+		})
+	case "string":
+		field = &v.String
+	case "int":
+		field = &v.Int
+	case "i4":
+		field = &v.Int4
+	case "i8":
+		field = &v.Int8
+	case "double":
+		field = &v.Double
+	case "boolean":
+		field = &v.Boolean
+	case "dateTime.iso8601":
+		field = &v.DateTime
+	case "base64":
+		field = &v.Base64
+	default:
+		text, err := p.text(start)
+		v.Custom = append(v.Custom, custom{XMLName: start.Name, Text: text})
+		return err
+	}
+	text, err := p.text(start)
+	*field = text
+	return err
+}
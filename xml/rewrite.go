@@ -0,0 +1,146 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import "sync"
+
+// Rewrite is a rule translating the calls of another dialect of an API into
+// the one served, and the responses back.
+type Rewrite struct {
+	// Method is the method the rule applies to, as called. Rules with no
+	// Method apply to every call.
+	Method string
+
+	// RenameMethod, if set, is the method actually called.
+	RenameMethod string
+
+	// RenameMembers maps the names of struct members, at any depth, in the
+	// params as called to the names served. The members of the responses are
+	// renamed back.
+	RenameMembers map[string]string
+
+	// AppendParams are constant params added after the params of the call.
+	AppendParams []Value
+}
+
+// Rewriter applies a set of Rewrite rules, e.g. for an adapter reconciling
+// two dialects of the same API with no code per difference. The rules can be
+// replaced at runtime with SetRules.
+//
+// Set it as the Codec Rewriter to rewrite the calls before they are decoded,
+// and the responses after they are encoded.
+type Rewriter struct {
+	mu    sync.RWMutex
+	rules []Rewrite
+}
+
+// NewRewriter returns a Rewriter applying rules.
+func NewRewriter(rules ...Rewrite) *Rewriter {
+	return &Rewriter{rules: rules}
+}
+
+// SetRules replaces the rules, for the calls to come.
+func (rw *Rewriter) SetRules(rules ...Rewrite) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	rw.rules = rules
+}
+
+// matching returns the rules applying to method, in order.
+func (rw *Rewriter) matching(method string) []Rewrite {
+	rw.mu.RLock()
+	defer rw.mu.RUnlock()
+	var rules []Rewrite
+	for _, rule := range rw.rules {
+		if rule.Method == "" || rule.Method == method {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// RewriteCall returns the call of method with params as it's to be served.
+func (rw *Rewriter) RewriteCall(method string, params []Value) (string, []Value) {
+	called := method
+	for _, rule := range rw.matching(called) {
+		if rule.RenameMethod != "" {
+			method = rule.RenameMethod
+		}
+		if len(rule.RenameMembers) != 0 {
+			renamed := make([]Value, len(params))
+			for i, p := range params {
+				renamed[i] = renameMembers(p, rule.RenameMembers)
+			}
+			params = renamed
+		}
+		params = append(params[:len(params):len(params)], rule.AppendParams...)
+	}
+	return method, params
+}
+
+// RewriteResponse returns the response params to the call of method, as
+// called, with the struct members renamed back.
+func (rw *Rewriter) RewriteResponse(method string, params []Value) []Value {
+	for _, rule := range rw.matching(method) {
+		if len(rule.RenameMembers) == 0 {
+			continue
+		}
+		back := make(map[string]string, len(rule.RenameMembers))
+		for from, to := range rule.RenameMembers {
+			back[to] = from
+		}
+		renamed := make([]Value, len(params))
+		for i, p := range params {
+			renamed[i] = renameMembers(p, back)
+		}
+		params = renamed
+	}
+	return params
+}
+
+// rewriteCall rewrites the methodCall document data, which is returned as is
+// if it can't be parsed, along with the method as called.
+func (rw *Rewriter) rewriteCall(data []byte) (string, []byte) {
+	called, params, err := ParseMethodCall(data)
+	if err != nil {
+		return "", data
+	}
+	method, params := rw.RewriteCall(called, params)
+	return called, EncodeMethodCall(method, params)
+}
+
+// rewriteResponse rewrites the methodResponse document data to the call of
+// method. Faults and documents that can't be parsed are returned as is.
+func (rw *Rewriter) rewriteResponse(method string, data []byte) []byte {
+	params, err := ParseMethodResponse(data)
+	if err != nil {
+		return data
+	}
+	return EncodeMethodResponse(rw.RewriteResponse(method, params))
+}
+
+// renameMembers returns v with the struct members renamed after names, at
+// any depth.
+func renameMembers(v Value, names map[string]string) Value {
+	switch v.Kind {
+	case KindStruct:
+		members := make([]Member, len(v.Members))
+		for i, m := range v.Members {
+			name := m.Name
+			if to, ok := names[name]; ok {
+				name = to
+			}
+			members[i] = Member{name, renameMembers(m.Value, names)}
+		}
+		v.Members = members
+	case KindArray:
+		items := make([]Value, len(v.Items))
+		for i, item := range v.Items {
+			items[i] = renameMembers(item, names)
+		}
+		v.Items = items
+	}
+	return v
+}
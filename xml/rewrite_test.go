@@ -0,0 +1,70 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+type GreetPerson struct {
+	Name string
+}
+
+type GreetArgs struct {
+	Person GreetPerson
+	Suffix string
+}
+
+type GreetReply struct {
+	Person   GreetPerson
+	Greeting string
+}
+
+type Greeter struct{}
+
+func (g *Greeter) Greet(r *http.Request, args *GreetArgs, reply *GreetReply) error {
+	reply.Person = args.Person
+	reply.Greeting = "Hello, " + args.Person.Name + args.Suffix
+	return nil
+}
+
+func TestRewriter(t *testing.T) {
+	rewriter := NewRewriter(Rewrite{
+		Method:        "legacy.greet",
+		RenameMethod:  "Greeter.Greet",
+		RenameMembers: map[string]string{"nick": "Name"},
+		AppendParams:  []Value{NewString("!")},
+	})
+	codec := NewCodec()
+	codec.Rewriter = rewriter
+	s := rpc.NewServer()
+	s.RegisterCodec(codec, "text/xml")
+	s.RegisterService(new(Greeter), "")
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	client := NewClient(ts.URL)
+	params, err := client.CallValues("legacy.greet", NewStruct(Member{"nick", NewString("Ivan")}))
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if len(params) != 2 {
+		t.Fatal("Expected two params, got", params)
+	}
+	nick, ok := params[0].Member("nick")
+	if !ok || nick.Text != "Ivan" || params[1].Text != "Hello, Ivan!" {
+		t.Errorf("Unexpected response %v", params)
+	}
+
+	rewriter.SetRules()
+	_, err = client.CallValues("legacy.greet", NewStruct(Member{"nick", NewString("Ivan")}))
+	if err == nil {
+		t.Error("Expected legacy.greet to be unknown without rules")
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// RoundTrip encodes v, or the value v points to, as a param, decodes it back into a new value of the
+// same type and reports the first difference, e.g. a float losing digits to
+// the <double> encoding or a time losing its sub-second part. Nil and empty
+// slices, and nil and zero pointers, are told apart by neither XML-RPC nor
+// RoundTrip. Unexported fields aren't compared, as they aren't encoded.
+func RoundTrip(v interface{}) error {
+	var buffer bytes.Buffer
+	if err := RPC2XML(v, &buffer); err != nil {
+		return fmt.Errorf("xmlrpc: round trip: encoding: %v", err)
+	}
+	param, err := ParseValue(buffer.Bytes())
+	if err != nil {
+		return fmt.Errorf("xmlrpc: round trip: parsing: %v", err)
+	}
+	original := reflect.Indirect(reflect.ValueOf(v))
+	decoded := reflect.New(original.Type())
+	if err := param.Decode(decoded.Interface()); err != nil {
+		return fmt.Errorf("xmlrpc: round trip: decoding: %v", err)
+	}
+	if path, ok := equivalent(original, decoded.Elem(), ""); !ok {
+		return fmt.Errorf("xmlrpc: round trip changed %s", path)
+	}
+	return nil
+}
+
+// RoundTripValue encodes v in a methodCall, parses it back and reports the
+// changes found by Diff.
+func RoundTripValue(v Value) error {
+	_, params, err := ParseMethodCall(EncodeMethodCall("roundTrip", []Value{v}))
+	if err != nil {
+		return fmt.Errorf("xmlrpc: round trip: parsing: %v", err)
+	}
+	if len(params) != 1 {
+		return fmt.Errorf("xmlrpc: round trip: %d params parsed", len(params))
+	}
+	if changes := Diff(v, params[0]); len(changes) != 0 {
+		parts := make([]string, len(changes))
+		for i, c := range changes {
+			parts[i] = c.String()
+		}
+		return fmt.Errorf("xmlrpc: round trip changed %s", strings.Join(parts, "; "))
+	}
+	return nil
+}
+
+// equivalent reports whether a and b hold the same data as far as XML-RPC
+// is concerned, or else the path to the first difference with both values.
+func equivalent(a, b reflect.Value, path string) (string, bool) {
+	differ := func() (string, bool) {
+		if path == "" {
+			path = "value"
+		}
+		return fmt.Sprintf("%s: %v != %v", path, a.Interface(), b.Interface()), false
+	}
+
+	switch a.Type() {
+	case timeType:
+		if !a.Interface().(time.Time).Equal(b.Interface().(time.Time)) {
+			return differ()
+		}
+		return "", true
+	case bigIntType:
+		x, y := a.Interface().(big.Int), b.Interface().(big.Int)
+		if x.Cmp(&y) != 0 {
+			return differ()
+		}
+		return "", true
+	case bigFloatType:
+		x, y := a.Interface().(big.Float), b.Interface().(big.Float)
+		if x.Cmp(&y) != 0 {
+			return differ()
+		}
+		return "", true
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() && b.IsNil() || isZeroPointer(a) && isZeroPointer(b) {
+				return "", true
+			}
+			return differ()
+		}
+		return equivalent(a.Elem(), b.Elem(), path)
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() && b.IsNil() {
+				return "", true
+			}
+			return differ()
+		}
+		return equivalent(a.Elem(), b.Elem(), path)
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return differ()
+		}
+		for i := 0; i < a.Len(); i++ {
+			if p, ok := equivalent(a.Index(i), b.Index(i), fmt.Sprintf("%s[%d]", path, i)); !ok {
+				return p, false
+			}
+		}
+		return "", true
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return differ()
+		}
+		for _, key := range a.MapKeys() {
+			x, y := a.MapIndex(key), b.MapIndex(key)
+			if !y.IsValid() {
+				return differ()
+			}
+			if p, ok := equivalent(x, y, fmt.Sprintf("%s[%v]", path, key.Interface())); !ok {
+				return p, false
+			}
+		}
+		return "", true
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			sf := a.Type().Field(i)
+			if sf.PkgPath != "" || skipEncode(sf) || skipDecode(sf) {
+				continue
+			}
+			name := sf.Name
+			if path != "" {
+				name = path + "." + name
+			}
+			if p, ok := equivalent(a.Field(i), b.Field(i), name); !ok {
+				return p, false
+			}
+		}
+		return "", true
+	}
+
+	if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+		return differ()
+	}
+	return "", true
+}
+
+// isZeroPointer reports whether the pointer p is nil or points to a zero
+// value.
+func isZeroPointer(p reflect.Value) bool {
+	return p.IsNil() || p.Elem().IsZero()
+}
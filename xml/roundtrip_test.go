@@ -0,0 +1,79 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type RoundTripSample struct {
+	Int    int
+	Float  float64
+	Bool   bool
+	Text   string
+	Time   time.Time
+	Binary []byte
+	Big    big.Int
+	List   []GreetPerson
+	Matrix [][]int
+	Fixed  [3]string
+	Tree   Value
+}
+
+func TestGeneratorRoundTrip(t *testing.T) {
+	g := NewGenerator(1)
+	for i := 0; i < 200; i++ {
+		var sample RoundTripSample
+		if err := g.Fill(&sample); err != nil {
+			t.Fatal("Expected err to be nil, but got:", err)
+		}
+		if err := RoundTrip(&sample); err != nil {
+			t.Error("Expected the sample to round trip, got", err)
+		}
+	}
+}
+
+func TestGeneratorValueRoundTrip(t *testing.T) {
+	g := NewGenerator(2)
+	for i := 0; i < 200; i++ {
+		if err := RoundTripValue(g.Value()); err != nil {
+			t.Error("Expected the value tree to round trip, got", err)
+		}
+	}
+}
+
+func TestGeneratorSeed(t *testing.T) {
+	var a, b RoundTripSample
+	NewGenerator(3).Fill(&a)
+	NewGenerator(3).Fill(&b)
+	if _, ok := equivalent(reflect.ValueOf(a), reflect.ValueOf(b), ""); !ok {
+		t.Error("Expected the same seed to generate the same values")
+	}
+	if err := NewGenerator(3).Fill(a); err == nil {
+		t.Error("Expected an error filling a non-pointer")
+	}
+}
+
+func TestRoundTripReportsLoss(t *testing.T) {
+	err := RoundTrip(&GreetArgs{Person: GreetPerson{Name: "x"}, Suffix: "!"})
+	if err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+
+	sample := struct{ Ratio float64 }{1.0 / 3}
+	err = RoundTrip(sample)
+	if err == nil || !strings.Contains(err.Error(), "Ratio") {
+		t.Error("Expected the float precision loss at Ratio, got", err)
+	}
+
+	stamp := struct{ At time.Time }{time.Date(2020, 1, 2, 3, 4, 5, 600, time.Local)}
+	if err := RoundTrip(stamp); err == nil {
+		t.Error("Expected the sub-second loss to be reported")
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Router dispatches XML-RPC calls to handlers by method name, e.g.
+// "supervisor.*" to a server with the supervisor receiver, "system.*" to the
+// built-ins and "wp.*" to another server, where a single rpc.Server maps
+// every call to one of its Service.Method.
+//
+// Patterns are method names where * matches any run of characters, e.g.
+// "wp.*" or "*.getInfo". A call is routed to the handler of the same method,
+// if any, or else to the handler of the longest matching pattern, or else to
+// the Fallback handler. Calls matching nothing are answered with a method not
+// found fault.
+type Router struct {
+	// Fallback, if set, serves the calls matching no pattern.
+	Fallback http.Handler
+
+	mu       sync.RWMutex
+	handlers map[string]http.Handler
+}
+
+// NewRouter returns an empty Router.
+func NewRouter() *Router {
+	return &Router{handlers: make(map[string]http.Handler)}
+}
+
+// Handle routes the calls matching pattern to h, replacing the handler
+// previously routed the pattern, if any.
+func (rt *Router) Handle(pattern string, h http.Handler) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.handlers[pattern] = h
+}
+
+// Handler returns the handler the calls of method are routed to, or nil.
+func (rt *Router) Handler(method string) http.Handler {
+	rt.mu.RLock()
+	defer rt.mu.RUnlock()
+	if h, ok := rt.handlers[method]; ok {
+		return h
+	}
+
+	var (
+		best    string
+		handler http.Handler
+	)
+	for pattern, h := range rt.handlers {
+		if !strings.Contains(pattern, "*") || len(pattern) < len(best) {
+			continue
+		}
+		if len(pattern) == len(best) && pattern > best {
+			// keep the routing deterministic
+			continue
+		}
+		if ok, _ := path.Match(pattern, method); ok {
+			best, handler = pattern, h
+		}
+	}
+	if handler == nil {
+		return rt.Fallback
+	}
+	return handler
+}
+
+// ServeHTTP implements http.Handler.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method, _, err := readMethod(r, defaultOptions)
+	if err != nil {
+		writeFault(w, FaultDecode)
+		return
+	}
+	h := rt.Handler(method)
+	if h == nil {
+		fault := FaultInvalidMethodName
+		fault.String += ": " + method
+		writeFault(w, fault)
+		return
+	}
+	h.ServeHTTP(w, r)
+}
@@ -0,0 +1,61 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+func TestRouter(t *testing.T) {
+	math := rpc.NewServer()
+	math.RegisterCodec(NewCodec(), "text/xml")
+	math.RegisterService(new(Service1), "")
+	greetings := rpc.NewServer()
+	greetings.RegisterCodec(NewCodec(), "text/xml")
+	greetings.RegisterService(new(Greeter), "")
+
+	router := NewRouter()
+	router.Handle("Service1.*", math)
+	router.Handle("*.Greet", greetings)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	var res Service1Response
+	if err := client.Call("Service1.Multiply", &Service1Request{4, 2}, &res); err != nil || res.Result != 8 {
+		t.Error("Expected 8, got", res.Result, err)
+	}
+	var reply GreetReply
+	if err := client.Call("Greeter.Greet", &GreetArgs{GreetPerson{"Ivan"}, "!"}, &reply); err != nil || reply.Greeting != "Hello, Ivan!" {
+		t.Error("Expected greeting, got", reply.Greeting, err)
+	}
+
+	err := client.Call("wp.getPosts", &struct{}{}, nil)
+	if fault, ok := err.(Fault); !ok || fault.Code != FaultInvalidMethodName.Code {
+		t.Error("Expected method not found fault, got", err)
+	}
+
+	router.Fallback = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(EncodeMethodResponse([]Value{NewString("fallback")}))
+	})
+	var fallback struct{ Answer string }
+	if err := client.Call("wp.getPosts", &struct{}{}, &fallback); err != nil || fallback.Answer != "fallback" {
+		t.Error("Expected fallback answer, got", fallback.Answer, err)
+	}
+
+	// the exact method and the longest pattern win
+	router.Handle("Service1.Multiply", router.Fallback)
+	router.Handle("Service1.Mult*", greetings)
+	if h := router.Handler("Service1.Multiply"); h == nil || h == http.Handler(math) {
+		t.Error("Expected the exact handler")
+	}
+	if h := router.Handler("Service1.Multiplex"); h != http.Handler(greetings) {
+		t.Error("Expected the longest pattern handler")
+	}
+}
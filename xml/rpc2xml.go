@@ -9,44 +9,64 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"math"
+	"math/big"
 	"reflect"
 	"strings"
 	"time"
 )
 
 func rpcRequest2XML(method string, rpc interface{}) (string, error) {
+	return encodeRequest(method, rpc, defaultOptions)
+}
+
+func encodeRequest(method string, rpc interface{}, opts *Options) (string, error) {
 	buffer := bytes.NewBuffer(make([]byte, 0))
 	fmt.Fprintf(buffer, "<methodCall><methodName>%s</methodName>", method)
-	err := rpcParams2XML(rpc, buffer)
+	err := rpcParams2XML(rpc, buffer, opts)
 	fmt.Fprintf(buffer, "</methodCall>")
 	return buffer.String(), err
 }
 
 func rpcResponse2XMLStr(rpc interface{}) (string, error) {
 	buffer := bytes.NewBuffer(make([]byte, 0))
-	err := rpcResponse2XML(rpc, buffer)
+	err := rpcResponse2XML(rpc, buffer, defaultOptions)
 	return buffer.String(), err
 }
 
-func rpcResponse2XML(rpc interface{}, writer io.Writer) error {
+func rpcResponse2XML(rpc interface{}, writer io.Writer, opts *Options) error {
 	var err error
 
 	fmt.Fprintf(writer, "<methodResponse>")
-	err = rpcParams2XML(rpc, writer)
+	err = rpcParams2XML(rpc, writer, opts)
 	fmt.Fprintf(writer, "</methodResponse>")
 
 	return err
 }
 
-func rpcParams2XML(rpc interface{}, writer io.Writer) error {
+func rpcParams2XML(rpc interface{}, writer io.Writer, opts *Options) error {
 	var err error
+	state := newEncodeState(opts)
+	if opts.MaxEncodedSize > 0 {
+		state.limit = &limitedWriter{w: writer, max: opts.MaxEncodedSize}
+		writer = state.limit
+	}
 	fmt.Fprintf(writer, "<params>")
 
+	if opts.NamedParams {
+		fmt.Fprintf(writer, "<param>")
+		err = state.value2XML(reflect.ValueOf(rpc).Elem().Interface(), writer)
+		fmt.Fprintf(writer, "</param></params>")
+		return state.check(err)
+	}
+
 	// switch reflect.ValueOf(rpc).Elem().Kind() {
 	// case reflect.Struct:
-	for i := 0; i < reflect.ValueOf(rpc).Elem().NumField(); i++ {
+	for _, p := range paramValues(rpc) {
 		fmt.Fprintf(writer, "<param>")
-		err = RPC2XML(reflect.ValueOf(rpc).Elem().Field(i).Interface(), writer)
+		if e := state.value2XML(p, writer); err == nil {
+			err = e
+		}
 		fmt.Fprintf(writer, "</param>")
 	}
 
@@ -58,38 +78,250 @@ func rpcParams2XML(rpc interface{}, writer io.Writer) error {
 
 	fmt.Fprintf(writer, "</params>")
 
-	return err
+	return state.check(err)
 }
 
 func RPCParams2XMLForMulticall(rpc interface{}, writer io.Writer) error {
 	var err error
-	for i := 0; i < reflect.ValueOf(rpc).Elem().NumField(); i++ {
-		err = RPC2XML(reflect.ValueOf(rpc).Elem().Field(i).Interface(), writer)
+	for _, p := range paramValues(rpc) {
+		err = RPC2XML(p, writer)
 	}
 	return err
 }
 
+// paramValues returns the params of rpc, a pointer to struct: its fields, but
+// the skipped ones, with the elements of a variadic field as separate params.
+// The decode-only fields are written as their zero value.
+func paramValues(rpc interface{}) []interface{} {
+	v := reflect.ValueOf(rpc).Elem()
+	params := make([]interface{}, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if isSkipped(v.Type().Field(i)) {
+			continue
+		}
+		if skipEncode(v.Type().Field(i)) {
+			// decode-only, the zero value keeps the position
+			field = reflect.Zero(field.Type())
+		}
+		if names := enumNames(v.Type().Field(i)); names != nil {
+			params = append(params, enumValue{names, field})
+			continue
+		}
+		if isTuple(v.Type().Field(i)) {
+			params = append(params, tupleValue{field})
+			continue
+		}
+		if isBinaryString(v.Type().Field(i)) {
+			params = append(params, binaryString(field.Bytes()))
+			continue
+		}
+		if epoch, double := isEpoch(v.Type().Field(i)); epoch {
+			params = append(params, epochTime{field.Interface().(time.Time), double})
+			continue
+		}
+		if !isVariadic(v.Type().Field(i)) {
+			params = append(params, field.Interface())
+			continue
+		}
+		for j := 0; j < field.Len(); j++ {
+			params = append(params, field.Index(j).Interface())
+		}
+	}
+	return params
+}
+
 func RPC2XML(value interface{}, writer io.Writer) error {
+	return newEncodeState(defaultOptions).value2XML(value, writer)
+}
+
+// encodeState holds the options and the progress of an encoding.
+type encodeState struct {
+	opts *Options
+
+	// visiting holds the pointers and slices being encoded, to detect
+	// cycles.
+	visiting map[visit]bool
+
+	// limit, if set, is the writer enforcing the max encoded size.
+	limit *limitedWriter
+}
+
+// limitedWriter fails the writes beyond max bytes.
+type limitedWriter struct {
+	w   io.Writer
+	n   int
+	max int
+	err error
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if err := l.reserve(len(p)); err != nil {
+		return 0, err
+	}
+	l.n += len(p)
+	return l.w.Write(p)
+}
+
+// reserve fails if n more bytes don't fit.
+func (l *limitedWriter) reserve(n int) error {
+	if l.err == nil && n > l.max-l.n {
+		l.err = fmt.Errorf("xmlrpc: encoded size exceeds %d bytes", l.max)
+	}
+	return l.err
+}
+
+// check returns the error of the max size limit, if it's been hit, or err.
+func (e *encodeState) check(err error) error {
+	if e.limit != nil && e.limit.err != nil {
+		return e.limit.err
+	}
+	return err
+}
+
+type visit struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+func newEncodeState(opts *Options) *encodeState {
+	return &encodeState{opts: opts}
+}
+
+// enter marks the pointer or slice rv as being encoded, failing if it
+// already is, i.e. if it's part of a cycle.
+func (e *encodeState) enter(rv reflect.Value) error {
+	v := visit{ptr: rv.Pointer(), typ: rv.Type()}
+	if rv.Kind() == reflect.Slice {
+		v.len = rv.Len()
+	}
+	if e.visiting[v] {
+		return fmt.Errorf("xmlrpc: encountered a cycle via %s", rv.Type())
+	}
+	if e.visiting == nil {
+		e.visiting = make(map[visit]bool)
+	}
+	e.visiting[v] = true
+	return nil
+}
+
+func (e *encodeState) leave(rv reflect.Value) {
+	v := visit{ptr: rv.Pointer(), typ: rv.Type()}
+	if rv.Kind() == reflect.Slice {
+		v.len = rv.Len()
+	}
+	delete(e.visiting, v)
+}
+
+func (e *encodeState) value2XML(value interface{}, writer io.Writer) error {
+	if err := e.check(nil); err != nil {
+		// give up early once the max size is hit
+		return err
+	}
+	if ev, ok := value.(enumValue); ok {
+		return enum2XML(ev.names, ev.value, writer)
+	}
+	if tv, ok := value.(tupleValue); ok {
+		return e.tuple2XML(tv.value, writer)
+	}
+	if bs, ok := value.(binaryString); ok {
+		return e.binaryString2XML(bs, writer)
+	}
+	if et, ok := value.(epochTime); ok {
+		epoch2XML(et.t, et.double, writer)
+		return e.check(nil)
+	}
+	if _, ok := value.(null); ok {
+		fmt.Fprintf(writer, "<value><nil/></value>")
+		return nil
+	}
+	if len(e.opts.EncodeHooks) != 0 {
+		v, err := encodeHooks(e.opts.EncodeHooks, value)
+		if err != nil {
+			return err
+		}
+		value = v
+	}
+	if se, ok := value.(StaticEncoder); ok {
+		b, err := se.XMLRPCEncode(nil)
+		if err == nil {
+			_, err = writer.Write(b)
+		}
+		return e.check(err)
+	}
+	if !e.opts.customEncoding() && e.limit == nil {
+		if c := compiledCodec(reflect.TypeOf(value)); c != nil && !c.reflective {
+			return c.write(e, value, writer)
+		}
+	}
+	if v, ok := value.(Value); ok {
+		v.writeXML(writer)
+		return nil
+	}
+	if ok, err := e.text2XML(value, writer); ok {
+		return err
+	}
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Ptr && !rv.IsNil() && scalarByType(rv.Type()) == nil {
+		// encode the pointed value in place of the pointer
+		if err := e.enter(rv); err != nil {
+			return err
+		}
+		defer e.leave(rv)
+		return e.value2XML(rv.Elem().Interface(), writer)
+	}
+	if names := enumByType(reflect.TypeOf(value)); names != nil {
+		return enum2XML(names, reflect.ValueOf(value), writer)
+	}
+	var err error
 	fmt.Fprintf(writer, "<value>")
-	switch reflect.ValueOf(value).Kind() {
-	case reflect.Int:
-		fmt.Fprintf(writer, "<int>%d</int>", value.(int))
+	if bigNumber2XML(value, writer) {
+		fmt.Fprintf(writer, "</value>")
+		return nil
+	}
+	if ok, err := customScalar2XML(value, writer); ok {
+		fmt.Fprintf(writer, "</value>")
+		return err
+	}
+	switch rv := reflect.ValueOf(value); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n := rv.Int(); e.opts.I8 && n != int64(int32(n)) {
+			fmt.Fprintf(writer, "<i8>%d</i8>", n)
+		} else {
+			fmt.Fprintf(writer, "<int>%d</int>", n)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n := rv.Uint(); e.opts.I8 && n > math.MaxInt32 {
+			fmt.Fprintf(writer, "<i8>%d</i8>", n)
+		} else {
+			fmt.Fprintf(writer, "<int>%d</int>", n)
+		}
 	case reflect.Float64:
 		fmt.Fprintf(writer, "<double>%f</double>", value.(float64))
 	case reflect.String:
-		string2XML(value.(string), writer)
+		if e.opts.UntypedStrings {
+			fmt.Fprintf(writer, "%s", escapeString(value.(string)))
+		} else {
+			string2XML(value.(string), writer)
+		}
 	case reflect.Bool:
 		bool2XML(value.(bool), writer)
 	case reflect.Struct:
 		if reflect.TypeOf(value).String() != "time.Time" {
-			struct2XML(value, writer)
+			err = e.struct2XML(value, writer)
 		} else {
-			time2XML(value.(time.Time), writer)
+			e.opts.time2XML(value.(time.Time), writer)
 		}
 	case reflect.Slice, reflect.Array:
 		// FIXME: is it the best way to recognize '[]byte'?
 		if reflect.TypeOf(value).String() != "[]uint8" {
-			array2XML(value, writer)
+			err = e.array2XML(value, writer)
+		} else if e.limit != nil {
+			// check the size before expanding large data
+			err = e.limit.reserve(base64.StdEncoding.EncodedLen(len(value.([]byte))))
+			if err == nil {
+				base642XML(value.([]byte), writer)
+			}
 		} else {
 			base642XML(value.([]byte), writer)
 		}
@@ -99,7 +331,7 @@ func RPC2XML(value interface{}, writer io.Writer) error {
 		}
 	}
 	fmt.Fprintf(writer, "</value>")
-	return nil
+	return err
 }
 
 func bool2XML(value bool, writer io.Writer) {
@@ -113,49 +345,103 @@ func bool2XML(value bool, writer io.Writer) {
 }
 
 func string2XML(value string, writer io.Writer) {
+	fmt.Fprintf(writer, "<string>%s</string>", escapeString(value))
+}
+
+func escapeString(value string) string {
 	value = strings.Replace(value, "&", "&amp;", -1)
 	value = strings.Replace(value, "\"", "&quot;", -1)
 	value = strings.Replace(value, "<", "&lt;", -1)
 	value = strings.Replace(value, ">", "&gt;", -1)
-	fmt.Fprintf(writer, "<string>%s</string>", value)
+	return value
 }
 
 type XMLStruct interface {
 	MarshalXML() string
 }
 
-func struct2XML(value interface{}, writer io.Writer) {
+func (e *encodeState) struct2XML(value interface{}, writer io.Writer) error {
 	if xs, ok := value.(XMLStruct); ok {
 		fmt.Fprintf(writer, xs.MarshalXML())
-		return
+		return nil
 	}
 
+	if e.opts.JSONCompat {
+		return e.jsonStruct2XML(reflect.ValueOf(value), writer)
+	}
+
+	var err error
+
 	fmt.Fprintf(writer, "<struct>")
 	for i := 0; i < reflect.TypeOf(value).NumField(); i++ {
 		field := reflect.ValueOf(value).Field(i)
 		field_type := reflect.TypeOf(value).Field(i)
-		var name string
-		if field_type.Tag.Get("xml") != "" {
-			name = field_type.Tag.Get("xml")
-		} else {
-			name = field_type.Name
+		if field_type.PkgPath != "" || skipEncode(field_type) {
+			// unexported or not encoded field
+			continue
+		}
+		if e.opts.OmitNil && (field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface) && field.IsNil() {
+			continue
+		}
+		if ferr := e.member2XML(e.opts.memberName(field_type), field_type, field, writer); err == nil {
+			err = ferr
 		}
-		fmt.Fprintf(writer, "<member>")
-		fmt.Fprintf(writer, "<name>%s</name>", name)
-		RPC2XML(field.Interface(), writer)
-		fmt.Fprintf(writer, "</member>")
 	}
 	fmt.Fprintf(writer, "</struct>")
 
-	return
+	return err
+}
+
+// member2XML writes the struct field sf of value field as the member name,
+// honoring its xmlrpc tag.
+func (e *encodeState) member2XML(name string, sf reflect.StructField, field reflect.Value, writer io.Writer) error {
+	var err error
+	fmt.Fprintf(writer, "<member>")
+	fmt.Fprintf(writer, "<name>%s</name>", name)
+	if names := enumNames(sf); names != nil {
+		err = enum2XML(names, field, writer)
+	} else if isTuple(sf) {
+		err = e.tuple2XML(field, writer)
+	} else if isBinaryString(sf) {
+		err = e.binaryString2XML(field.Bytes(), writer)
+	} else if epoch, double := isEpoch(sf); epoch {
+		epoch2XML(field.Interface().(time.Time), double, writer)
+	} else {
+		err = e.value2XML(field.Interface(), writer)
+	}
+	fmt.Fprintf(writer, "</member>")
+	return err
 }
 
-func array2XML(value interface{}, writer io.Writer) {
+func (e *encodeState) array2XML(value interface{}, writer io.Writer) error {
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Slice && rv.Len() != 0 {
+		if err := e.enter(rv); err != nil {
+			return err
+		}
+		defer e.leave(rv)
+	}
+
+	var err error
 	fmt.Fprintf(writer, "<array><data>")
 	for i := 0; i < reflect.ValueOf(value).Len(); i++ {
-		RPC2XML(reflect.ValueOf(value).Index(i).Interface(), writer)
+		if ierr := e.value2XML(reflect.ValueOf(value).Index(i).Interface(), writer); err == nil {
+			err = ierr
+		}
+		if e.check(nil) != nil {
+			break
+		}
 	}
 	fmt.Fprintf(writer, "</data></array>")
+	return err
+}
+
+// time2XML writes t with the DateTimeFormat, or else the standard layout.
+func (o *Options) time2XML(t time.Time, writer io.Writer) {
+	if o.DateTimeFormat == "" {
+		time2XML(t, writer)
+		return
+	}
+	fmt.Fprintf(writer, "<dateTime.iso8601>%s</dateTime.iso8601>", escapeString(t.Format(o.DateTimeFormat)))
 }
 
 func time2XML(t time.Time, writer io.Writer) {
@@ -175,6 +461,30 @@ func time2XML(t time.Time, writer io.Writer) {
 		t.Hour(), t.Minute(), t.Second())
 }
 
+// bigNumber2XML writes big.Int and big.Float values (or non-nil pointers to
+// them) as <int> and <double>. It reports whether value was written.
+func bigNumber2XML(value interface{}, writer io.Writer) bool {
+	switch v := value.(type) {
+	case big.Int:
+		fmt.Fprintf(writer, "<int>%s</int>", v.String())
+	case *big.Int:
+		if v == nil {
+			return false
+		}
+		fmt.Fprintf(writer, "<int>%s</int>", v.String())
+	case big.Float:
+		fmt.Fprintf(writer, "<double>%s</double>", v.Text('f', -1))
+	case *big.Float:
+		if v == nil {
+			return false
+		}
+		fmt.Fprintf(writer, "<double>%s</double>", v.Text('f', -1))
+	default:
+		return false
+	}
+	return true
+}
+
 func base642XML(data []byte, writer io.Writer) {
 	str := base64.StdEncoding.EncodeToString(data)
 	fmt.Fprintf(writer, "<base64>%s</base64>", str)
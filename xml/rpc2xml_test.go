@@ -5,6 +5,7 @@
 package xml
 
 import (
+	"math/big"
 	"testing"
 	"time"
 )
@@ -74,3 +75,133 @@ func TestRpc2XmlNil(t *testing.T) {
 		t.Error("Got", xml)
 	}
 }
+
+type StructBigNumbersRpc2Xml struct {
+	Int   *big.Int
+	Float *big.Float
+	Nil   *big.Int
+}
+
+func TestRPC2XMLBigNumbers(t *testing.T) {
+	n, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	req := &StructBigNumbersRpc2Xml{n, big.NewFloat(0.25), nil}
+	xml, err := rpcResponse2XMLStr(req)
+	if err != nil {
+		t.Error("RPC2XML conversion failed", err)
+	}
+	expected := "<methodResponse><params><param><value><int>123456789012345678901234567890</int></value></param><param><value><double>0.25</double></value></param><param><value><nil/></value></param></params></methodResponse>"
+	if xml != expected {
+		t.Error("RPC2XML big numbers conversion failed")
+		t.Error("Expected", expected)
+		t.Error("Got", xml)
+	}
+}
+
+type StructVariadicRpc2Xml struct {
+	Group string
+	Names []string `xmlrpc:",variadic"`
+}
+
+type StructArrayParamRpc2Xml struct {
+	Group string
+	Names []string
+}
+
+func TestRPC2XMLVariadic(t *testing.T) {
+	xml, err := rpcRequest2XML("Some.Method", &StructVariadicRpc2Xml{"web", []string{"a", "b"}})
+	if err != nil {
+		t.Error("RPC2XML conversion failed", err)
+	}
+	expected := "<methodCall><methodName>Some.Method</methodName><params><param><value><string>web</string></value></param><param><value><string>a</string></value></param><param><value><string>b</string></value></param></params></methodCall>"
+	if xml != expected {
+		t.Error("RPC2XML variadic conversion failed")
+		t.Error("Expected", expected)
+		t.Error("Got", xml)
+	}
+
+	xml, err = rpcRequest2XML("Some.Method", &StructArrayParamRpc2Xml{"web", []string{"a", "b"}})
+	if err != nil {
+		t.Error("RPC2XML conversion failed", err)
+	}
+	expected = "<methodCall><methodName>Some.Method</methodName><params><param><value><string>web</string></value></param><param><value><array><data><value><string>a</string></value><value><string>b</string></value></data></array></value></param></params></methodCall>"
+	if xml != expected {
+		t.Error("RPC2XML array param conversion failed")
+		t.Error("Expected", expected)
+		t.Error("Got", xml)
+	}
+}
+
+type NodeRpc2Xml struct {
+	Name string
+	Next *NodeRpc2Xml
+	Data interface{}
+}
+
+func TestRPC2XMLCycles(t *testing.T) {
+	a := &NodeRpc2Xml{Name: "a"}
+	b := &NodeRpc2Xml{Name: "b", Next: a}
+	a.Next = b
+	if _, err := rpcRequest2XML("Some.Method", &struct{ Node *NodeRpc2Xml }{a}); err == nil {
+		t.Error("Expected pointer cycle to fail")
+	}
+
+	c := &NodeRpc2Xml{Name: "c"}
+	c.Data = c
+	if _, err := rpcRequest2XML("Some.Method", &struct{ Node *NodeRpc2Xml }{c}); err == nil {
+		t.Error("Expected interface cycle to fail")
+	}
+
+	s := []interface{}{nil}
+	s[0] = s
+	if _, err := rpcRequest2XML("Some.Method", &struct{ List []interface{} }{s}); err == nil {
+		t.Error("Expected slice cycle to fail")
+	}
+
+	// shared values aren't cycles
+	shared := &NodeRpc2Xml{Name: "shared"}
+	d := &NodeRpc2Xml{Name: "d", Next: shared, Data: shared}
+	xml, err := rpcRequest2XML("Some.Method", &struct{ Node *NodeRpc2Xml }{d})
+	if err != nil {
+		t.Error("RPC2XML conversion failed", err)
+	}
+	expected := "<methodCall><methodName>Some.Method</methodName><params><param><value><struct>" +
+		"<member><name>Name</name><value><string>d</string></value></member>" +
+		"<member><name>Next</name><value><struct><member><name>Name</name><value><string>shared</string></value></member><member><name>Next</name><value><nil/></value></member><member><name>Data</name><value></value></member></struct></value></member>" +
+		"<member><name>Data</name><value><struct><member><name>Name</name><value><string>shared</string></value></member><member><name>Next</name><value><nil/></value></member><member><name>Data</name><value></value></member></struct></value></member>" +
+		"</struct></value></param></params></methodCall>"
+	if xml != expected {
+		t.Error("Expected", expected)
+		t.Error("Got", xml)
+	}
+}
+
+func TestRPC2XMLMaxEncodedSize(t *testing.T) {
+	opts := &Options{MaxEncodedSize: 200}
+	if _, err := encodeRequest("Some.Method", &struct{ Names []string }{[]string{"a", "b"}}, opts); err != nil {
+		t.Error("RPC2XML conversion failed", err)
+	}
+
+	var buffer countingWriter
+	err := rpcParams2XML(&struct{ Ids []int }{make([]int, 1000000)}, &buffer, opts)
+	if err == nil {
+		t.Error("Expected a large array to fail")
+	}
+	if buffer > 200 {
+		t.Errorf("Expected at most 200 bytes to be written, got %d", buffer)
+	}
+
+	buffer = 0
+	if err := rpcParams2XML(&struct{ Data []byte }{make([]byte, 1000)}, &buffer, opts); err == nil {
+		t.Error("Expected large base64 data to fail")
+	}
+	if buffer > 200 {
+		t.Errorf("Expected at most 200 bytes to be written, got %d", buffer)
+	}
+}
+
+type countingWriter int
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
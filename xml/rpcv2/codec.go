@@ -0,0 +1,66 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package rpcv2 serves XML-RPC with the github.com/gorilla/rpc/v2 servers,
+// whose CodecRequest writes the responses and the errors apart:
+//
+//	s := rpc.NewServer()
+//	s.RegisterCodec(rpcv2.NewCodec(xml.NewCodec()), "text/xml")
+//	s.RegisterService(new(HelloService), "")
+//
+// The xml.Codec features are kept: aliases, options, hooks, envelopes,
+// stats, etc. The errors written by the server, e.g. for unknown methods,
+// are sent as faults with the status 200 OK, as XML-RPC wants.
+//
+// The package is an adapter to the gorilla/rpc v2 servers only: it's not a
+// v2 of this module, whose API stays the one of the package xml.
+package rpcv2
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/AlexStocks/gorilla-xmlrpc/xml"
+	rpc "github.com/gorilla/rpc/v2"
+)
+
+// Codec is a xml.Codec for the github.com/gorilla/rpc/v2 servers.
+type Codec struct {
+	*xml.Codec
+}
+
+// NewCodec returns a Codec serving with c.
+func NewCodec(c *xml.Codec) *Codec {
+	return &Codec{c}
+}
+
+// NewRequest returns a CodecRequest.
+func (c *Codec) NewRequest(r *http.Request) rpc.CodecRequest {
+	return &CodecRequest{c.Codec.NewRequest(r).(*xml.CodecRequest)}
+}
+
+// CodecRequest decodes and encodes a single request.
+type CodecRequest struct {
+	*xml.CodecRequest
+}
+
+// WriteResponse encodes the reply and writes it to w.
+func (c *CodecRequest) WriteResponse(w http.ResponseWriter, reply interface{}) {
+	c.CodecRequest.WriteResponse(w, reply, nil)
+}
+
+// WriteError writes err as a fault, whatever the status.
+func (c *CodecRequest) WriteError(w http.ResponseWriter, status int, err error) {
+	if _, merr := c.Method(); merr != nil {
+		// the request couldn't be parsed
+		var fault xml.Fault
+		if !errors.As(err, &fault) {
+			fault = xml.FaultDecode
+		}
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		xml.Fault2XML(fault, w)
+		return
+	}
+	c.CodecRequest.WriteResponse(w, nil, err)
+}
@@ -0,0 +1,77 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpcv2
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-xmlrpc/xml"
+	rpc "github.com/gorilla/rpc/v2"
+)
+
+type Arith struct{}
+
+type MultiplyArgs struct{ A, B int }
+
+type MultiplyReply struct{ Result int }
+
+func (Arith) Multiply(r *http.Request, args *MultiplyArgs, reply *MultiplyReply) error {
+	reply.Result = args.A * args.B
+	return nil
+}
+
+func (Arith) Fail(r *http.Request, args *MultiplyArgs, reply *MultiplyReply) error {
+	return errors.New("failed")
+}
+
+func newServer() *httptest.Server {
+	s := rpc.NewServer()
+	s.RegisterCodec(NewCodec(xml.NewCodec()), "text/xml")
+	s.RegisterService(Arith{}, "")
+	return httptest.NewServer(s)
+}
+
+func TestCall(t *testing.T) {
+	ts := newServer()
+	defer ts.Close()
+
+	var reply MultiplyReply
+	if err := xml.NewClient(ts.URL).Call("Arith.Multiply", &MultiplyArgs{3, 4}, &reply); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if reply.Result != 12 {
+		t.Errorf("Expected 12, got %d", reply.Result)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ts := newServer()
+	defer ts.Close()
+
+	client := xml.NewClient(ts.URL)
+	var reply MultiplyReply
+	err := client.Call("Arith.Fail", &MultiplyArgs{}, &reply)
+	if fault, ok := err.(xml.Fault); !ok || !strings.Contains(fault.String, "failed") {
+		t.Error("Expected the method error as a fault, got", err)
+	}
+	err = client.Call("Arith.Unknown", &MultiplyArgs{}, &reply)
+	if _, ok := err.(xml.Fault); !ok {
+		t.Error("Expected the unknown method as a fault, got", err)
+	}
+
+	resp, err := http.Post(ts.URL, "text/xml", strings.NewReader("<methodCall>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var res MultiplyReply
+	if err := xml.DecodeClientResponse(resp.Body, &res); err == nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected a parsing fault with 200 OK, got %v, %d", err, resp.StatusCode)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// RuntimeOptions hold Options adjustable while serving, so operators can
+// e.g. turn lenient parsing on for a misbehaving client without a restart.
+// It's safe for concurrent use; the zero value holds the default Options.
+//
+//	flags := xml.NewRuntimeOptions(codec.Options)
+//	codec.Tune = flags.Tune
+//	...
+//	flags.Update(func(o *xml.Options) { o.LenientBool = true })
+type RuntimeOptions struct {
+	mu sync.Mutex // serializes the updates
+	v  atomic.Value
+}
+
+// NewRuntimeOptions returns RuntimeOptions holding opts.
+func NewRuntimeOptions(opts Options) *RuntimeOptions {
+	ro := &RuntimeOptions{}
+	ro.Store(opts)
+	return ro
+}
+
+// Load returns the current Options.
+func (ro *RuntimeOptions) Load() Options {
+	opts, _ := ro.v.Load().(Options)
+	return opts
+}
+
+// Store replaces the current Options with opts.
+func (ro *RuntimeOptions) Store(opts Options) {
+	ro.mu.Lock()
+	ro.v.Store(opts)
+	ro.mu.Unlock()
+}
+
+// Update replaces the current Options with their copy changed by f.
+func (ro *RuntimeOptions) Update(f func(opts *Options)) {
+	ro.mu.Lock()
+	defer ro.mu.Unlock()
+	opts, _ := ro.v.Load().(Options)
+	f(&opts)
+	ro.v.Store(opts)
+}
+
+// Tune sets opts to the current Options; it's meant for Codec.Tune.
+func (ro *RuntimeOptions) Tune(r *http.Request, opts *Options) {
+	*opts = ro.Load()
+}
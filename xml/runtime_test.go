@@ -0,0 +1,69 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRuntimeOptions(t *testing.T) {
+	codec := NewCodec()
+	flags := NewRuntimeOptions(codec.Options)
+	codec.Tune = flags.Tune
+	ts := newTestServer(codec)
+	defer ts.Close()
+
+	call := `<methodCall><methodName>Service1.Multiply</methodName><params>
+<param><value><boolean>1</boolean></value></param>
+<param><value><int>3</int></value></param></params></methodCall>`
+	multiply := func() ([]Value, error) {
+		resp, err := http.Post(ts.URL, "text/xml", strings.NewReader(call))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return ParseMethodResponse(body)
+	}
+
+	if _, err := multiply(); err == nil {
+		t.Error("Expected <boolean> into int rejected by default")
+	}
+
+	flags.Update(func(o *Options) { o.LenientBool = true })
+	params, err := multiply()
+	if err != nil {
+		t.Fatal("Expected <boolean> into int accepted once flipped, got:", err)
+	}
+	if len(params) != 1 || params[0].Text != "3" {
+		t.Errorf("Expected 3, got %v", params)
+	}
+}
+
+func TestRuntimeOptionsConcurrent(t *testing.T) {
+	var flags RuntimeOptions
+	if flags.Load().LenientBool {
+		t.Error("Expected the zero RuntimeOptions to hold the default Options")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			flags.Update(func(o *Options) { o.MaxEncodedSize++ })
+			var opts Options
+			flags.Tune(nil, &opts)
+		}()
+	}
+	wg.Wait()
+	if n := flags.Load().MaxEncodedSize; n != 10 {
+		t.Errorf("Expected 10 updates, got %d", n)
+	}
+}
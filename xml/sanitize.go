@@ -0,0 +1,129 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FaultSanitizer cleans the faultStrings of a Codec before they leave the
+// server, so the clients don't learn its internals from the errors of the
+// methods. The codes are left as is. Every fault is logged in full to
+// Logger first, if set.
+type FaultSanitizer struct {
+	// MaxLength, if positive, truncates the faultStrings to MaxLength bytes,
+	// "..." included.
+	MaxLength int
+
+	// StripStackTraces cuts the faultStrings at the first line of a stack
+	// trace, e.g. "goroutine 1 [running]:", "Traceback (most recent call
+	// last):" or an indented frame.
+	StripStackTraces bool
+
+	// StripControl replaces the line breaks and tabs with spaces, and drops
+	// the other control characters.
+	StripControl bool
+
+	// Hostnames are the patterns of the internal hostnames replaced with
+	// "[host]", as for path.Match, e.g. "*.internal" or "db-*.example.com".
+	Hostnames []string
+
+	Logger Logger
+}
+
+// hostnameRE matches the dotted names in a faultString.
+var hostnameRE = regexp.MustCompile(`[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?)+`)
+
+// sanitize returns fault, raised as err by the call of method with the
+// correlation ID id, if any, cleaned. fs may be nil.
+//
+// stamp, if set, adds the correlation ID to the faultString once its stack
+// trace is cut, so the ID is cleaned too and MaxLength bounds the stamped
+// faultString, keeping the room for the ID.
+func (fs *FaultSanitizer) sanitize(method, id string, fault Fault, err error, stamp func(string) string) Fault {
+	if fs == nil {
+		if stamp != nil {
+			fault.String = stamp(fault.String)
+		}
+		return fault
+	}
+	if fs.Logger != nil {
+		if id != "" {
+			method += " [" + id + "]"
+		}
+		fs.Logger.Printf("xmlrpc: fault %d in %s: %v", fault.Code, method, err)
+	}
+	s := fault.String
+	if fs.StripStackTraces {
+		s = stripStackTrace(s)
+	}
+	if stamp != nil {
+		if room := fs.MaxLength - len(stamp("")); fs.MaxLength > 0 && len(s) > room {
+			if room < 0 {
+				room = 0
+			}
+			s = truncate(s, room)
+		}
+		s = stamp(s)
+	}
+	if len(fs.Hostnames) != 0 {
+		s = hostnameRE.ReplaceAllStringFunc(s, func(name string) string {
+			for _, pattern := range fs.Hostnames {
+				if ok, _ := path.Match(pattern, name); ok {
+					return "[host]"
+				}
+			}
+			return name
+		})
+	}
+	if fs.StripControl {
+		s = strings.Map(func(r rune) rune {
+			switch {
+			case r == '\n' || r == '\r' || r == '\t':
+				return ' '
+			case unicode.IsControl(r):
+				return -1
+			}
+			return r
+		}, s)
+	}
+	if fs.MaxLength > 0 && len(s) > fs.MaxLength {
+		s = truncate(s, fs.MaxLength)
+	}
+	fault.String = s
+	return fault
+}
+
+// stripStackTrace returns s up to the first line of a stack trace.
+func stripStackTrace(s string) string {
+	lines := strings.SplitAfter(s, "\n")
+	for i, line := range lines {
+		if i == 0 {
+			continue
+		}
+		trimmed := strings.TrimLeft(line, " ")
+		if strings.HasPrefix(line, "goroutine ") || strings.HasPrefix(line, "Traceback ") ||
+			strings.HasPrefix(line, "\t") || strings.HasPrefix(trimmed, "at ") || strings.HasPrefix(trimmed, "File \"") {
+			return strings.TrimRightFunc(strings.Join(lines[:i], ""), unicode.IsSpace)
+		}
+	}
+	return s
+}
+
+// truncate cuts s to n bytes, "..." included, on a rune boundary.
+func truncate(s string, n int) string {
+	cut, ellipsis := n-3, "..."
+	if n <= 3 {
+		cut, ellipsis = n, ""
+	}
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + ellipsis
+}
@@ -0,0 +1,89 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+type FailingService struct{}
+
+func (s *FailingService) Fail(r *http.Request, req *Service1Request, res *Service1Response) error {
+	return errors.New("dial db-3.prod.internal:5432: refused\x07\n" +
+		"goroutine 7 [running]:\nmain.handler()\n\t/src/main.go:12 +0x1d")
+}
+
+func TestFaultSanitizer(t *testing.T) {
+	logger := &testLogger{}
+	codec := NewCodec()
+	codec.FaultSanitizer = &FaultSanitizer{MaxLength: 40, StripStackTraces: true, StripControl: true,
+		Hostnames: []string{"*.internal"}, Logger: logger}
+	s := rpc.NewServer()
+	s.RegisterCodec(codec, "text/xml")
+	s.RegisterService(new(FailingService), "")
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var res Service1Response
+	err := NewClient(ts.URL).Call("FailingService.Fail", &Service1Request{1, 2}, &res)
+	fault, ok := err.(Fault)
+	if !ok {
+		t.Fatalf("Expected a fault, got %v", err)
+	}
+	if fault.Code != FaultApplicationError.Code {
+		t.Errorf("Expected the code kept, got %d", fault.Code)
+	}
+	if expected := "Application Error: dial [host]:5432: ..."; fault.String != expected {
+		t.Errorf("Expected %q, got %q", expected, fault.String)
+	}
+	if len(logger.lines) != 1 || !strings.Contains(logger.lines[0], "db-3.prod.internal") ||
+		!strings.Contains(logger.lines[0], "goroutine 7") {
+		t.Errorf("Expected the full error logged, got %q", logger.lines)
+	}
+}
+
+func TestFaultSanitizerStamp(t *testing.T) {
+	stamp := func(s string) string { return s + " (ref abc)" }
+	fs := &FaultSanitizer{StripStackTraces: true, StripControl: true, MaxLength: 24}
+	fault := fs.sanitize("m", "abc", Fault{Code: 1, String: "boom\ngoroutine 1 [running]:\nmain.main()"}, nil, stamp)
+	if expected := "boom (ref abc)"; fault.String != expected {
+		t.Errorf("Expected %q, got %q", expected, fault.String)
+	}
+	fault = fs.sanitize("m", "abc", Fault{Code: 1, String: "a rather long faultString"}, nil, stamp)
+	if expected := "a rather lo... (ref abc)"; fault.String != expected {
+		t.Errorf("Expected %q, got %q", expected, fault.String)
+	}
+	if fault = (*FaultSanitizer)(nil).sanitize("m", "abc", Fault{Code: 1, String: "boom"}, nil, stamp); fault.String != "boom (ref abc)" {
+		t.Errorf("Expected the fault stamped without a sanitizer, got %q", fault.String)
+	}
+}
+
+func TestFaultSanitizerStrings(t *testing.T) {
+	for _, test := range []struct {
+		fs       FaultSanitizer
+		in, want string
+	}{
+		{FaultSanitizer{}, "a\nb", "a\nb"},
+		{FaultSanitizer{StripControl: true}, "a\tb\x00c\r\n", "a bc  "},
+		{FaultSanitizer{StripStackTraces: true}, "boom\nTraceback (most recent call last):\n  File \"x.py\"", "boom"},
+		{FaultSanitizer{StripStackTraces: true}, "java.lang.Error: boom\n    at Foo.bar(Foo.java:1)", "java.lang.Error: boom"},
+		{FaultSanitizer{StripStackTraces: true}, "two\nlines", "two\nlines"},
+		{FaultSanitizer{Hostnames: []string{"db-*.example.com"}}, "db-1.example.com and www.example.com", "[host] and www.example.com"},
+		{FaultSanitizer{MaxLength: 7}, "héllo wörld", "hél..."},
+		{FaultSanitizer{MaxLength: 2}, "hello", "he"},
+		{FaultSanitizer{MaxLength: 5}, "hello", "hello"},
+	} {
+		fault := test.fs.sanitize("m", "", Fault{Code: 1, String: test.in}, nil, nil)
+		if fault.String != test.want {
+			t.Errorf("Expected %q sanitized as %q, got %q", test.in, test.want, fault.String)
+		}
+	}
+}
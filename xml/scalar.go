@@ -0,0 +1,102 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// ScalarEncoder formats v as the text content of a custom value tag.
+type ScalarEncoder func(v interface{}) (string, error)
+
+// ScalarDecoder parses the text content of a custom value tag.
+type ScalarDecoder func(text string) (interface{}, error)
+
+type scalarType struct {
+	tag    string
+	typ    reflect.Type
+	encode ScalarEncoder
+	decode ScalarDecoder
+}
+
+var scalars = struct {
+	sync.RWMutex
+	byTag  map[string]*scalarType
+	byType map[reflect.Type]*scalarType
+}{
+	byTag:  make(map[string]*scalarType),
+	byType: make(map[reflect.Type]*scalarType),
+}
+
+// RegisterScalar maps the nonstandard value tag (e.g. "decimal" or "uuid") to
+// the Go type of sample. Values of that type are encoded as <tag>text</tag>
+// using enc, and <tag> values are decoded into fields of that type using dec.
+// A registered tag may also be decoded into a string field, which receives the
+// text as is.
+func RegisterScalar(tag string, sample interface{}, enc ScalarEncoder, dec ScalarDecoder) {
+	st := &scalarType{
+		tag:    tag,
+		typ:    reflect.TypeOf(sample),
+		encode: enc,
+		decode: dec,
+	}
+
+	scalars.Lock()
+	defer scalars.Unlock()
+	scalars.byTag[tag] = st
+	scalars.byType[st.typ] = st
+}
+
+func scalarByTag(tag string) *scalarType {
+	scalars.RLock()
+	defer scalars.RUnlock()
+	return scalars.byTag[tag]
+}
+
+func scalarByType(typ reflect.Type) *scalarType {
+	scalars.RLock()
+	defer scalars.RUnlock()
+	return scalars.byType[typ]
+}
+
+// customScalar2Val decodes the first registered custom tag of value for the
+// field. It reports whether such a tag was found.
+func customScalar2Val(value value, field *reflect.Value) (interface{}, bool, error) {
+	for _, c := range value.Custom {
+		st := scalarByTag(c.XMLName.Local)
+		if st == nil {
+			continue
+		}
+		if field.Type() != st.typ && field.Kind() == reflect.String {
+			return c.Text, true, nil
+		}
+		val, err := st.decode(c.Text)
+		if err != nil {
+			fault := FaultInvalidParams
+			fault.String += fmt.Sprintf(": can't decode <%s>: %v", st.tag, err)
+			return nil, true, fault
+		}
+		return val, true, nil
+	}
+	return nil, false, nil
+}
+
+// customScalar2XML writes value using its registered custom tag. It reports
+// whether value's type was registered.
+func customScalar2XML(value interface{}, writer io.Writer) (bool, error) {
+	st := scalarByType(reflect.TypeOf(value))
+	if st == nil {
+		return false, nil
+	}
+	text, err := st.encode(value)
+	if err != nil {
+		return true, err
+	}
+	fmt.Fprintf(writer, "<%s>%s</%s>", st.tag, escapeString(text), st.tag)
+	return true, nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+type testUUID [16]byte
+
+func init() {
+	RegisterScalar("uuid", testUUID{},
+		func(v interface{}) (string, error) {
+			u := v.(testUUID)
+			return hex.EncodeToString(u[:]), nil
+		},
+		func(text string) (interface{}, error) {
+			var u testUUID
+			b, err := hex.DecodeString(text)
+			copy(u[:], b)
+			return u, err
+		})
+}
+
+type StructCustomScalar struct {
+	ID   testUUID
+	Text string
+}
+
+func TestCustomScalarRoundTrip(t *testing.T) {
+	id := testUUID{0xde, 0xad, 0xbe, 0xef}
+	req := &StructCustomScalar{id, "plain"}
+	xml, err := rpcResponse2XMLStr(req)
+	if err != nil {
+		t.Fatal("RPC2XML conversion failed", err)
+	}
+	expected := "<methodResponse><params><param><value><uuid>deadbeef000000000000000000000000</uuid></value></param><param><value><string>plain</string></value></param></params></methodResponse>"
+	if xml != expected {
+		t.Error("RPC2XML custom scalar conversion failed")
+		t.Error("Expected", expected)
+		t.Error("Got", xml)
+	}
+
+	res := new(StructCustomScalar)
+	if err := xml2RPC(xml, res); err != nil {
+		t.Fatal("XML2RPC conversion failed", err)
+	}
+	if !reflect.DeepEqual(req, res) {
+		t.Errorf("expected %v, got %v", req, res)
+	}
+}
+
+func TestCustomScalarIntoString(t *testing.T) {
+	var res struct{ ID string }
+	err := xml2RPC("<methodResponse><params><param><value><uuid>00ff</uuid></value></param></params></methodResponse>", &res)
+	if err != nil {
+		t.Fatal("XML2RPC conversion failed", err)
+	}
+	if res.ID != "00ff" {
+		t.Errorf("wrong ID: %q", res.ID)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SchemaError is returned by the client calls whose response doesn't fit the
+// expected schema, instead of decoding what fits and leaving the rest zero.
+type SchemaError struct {
+	Method string
+	Issues []ValidationIssue
+
+	// Raw is the response document.
+	Raw []byte
+}
+
+func (e *SchemaError) Error() string {
+	issues := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		issues[i] = issue.String()
+	}
+	return fmt.Sprintf("xmlrpc: response of %s doesn't fit the schema: %s", e.Method, strings.Join(issues, "; "))
+}
+
+// WithReplySchema returns a context making the client calls made with it
+// check their response against schema, a pointer to a params struct like a
+// reply, before decoding it. The members must have the types of the fields,
+// and the members of the fields tagged `xmlrpc:",required"` must be present;
+// other members may be missing, and unknown members are ignored. A response
+// not fitting makes the call fail with a SchemaError.
+//
+// A nil schema checks the response against the reply itself (see
+// Client.AssertReplies).
+func WithReplySchema(ctx context.Context, schema interface{}) context.Context {
+	return context.WithValue(ctx, replySchemaContextKey, &schema)
+}
+
+// decodeReply decodes the response of a call of method into reply, checking
+// it against the schema first, once transformed.
+func (c *Client) decodeReply(ctx context.Context, method string, resp []byte, reply interface{}) error {
+	resp = c.transformResponse(method, resp)
+	var schema interface{}
+	if s, ok := ctx.Value(replySchemaContextKey).(*interface{}); ok {
+		schema = *s
+		if schema == nil {
+			schema = reply
+		}
+	} else if c.AssertReplies {
+		schema = reply
+	}
+	if schema != nil {
+		if issues := validateDocument(resp, schema, true, &c.Options); len(issues) != 0 {
+			return &SchemaError{Method: method, Issues: issues, Raw: resp}
+		}
+	}
+	err := decodeRPC(string(resp), reply, &c.Options)
+	c.stats.fault(err)
+	return err
+}
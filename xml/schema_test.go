@@ -0,0 +1,127 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type schemaPost struct {
+	ID    int `xmlrpc:",required"`
+	Title string
+}
+
+type schemaReply struct {
+	Post schemaPost
+}
+
+func newSchemaServer(post string) *httptest.Server {
+	return httptest.NewServer(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		v, err := ParseValue([]byte(post))
+		return []Value{v}, err
+	}))
+}
+
+func TestReplySchema(t *testing.T) {
+	for _, tt := range []struct {
+		post  string
+		issue string
+	}{
+		{`<value><struct><member><name>ID</name><value><int>1</int></value></member></struct></value>`, ""},
+		{`<value><struct><member><name>Title</name><value>t</value></member></struct></value>`, "params[0].ID: missing member"},
+		{`<value><struct><member><name>ID</name><value>one</value></member></struct></value>`, "params[0].ID: type mismatch: string != int"},
+		{`<value><array><data><value><int>1</int></value></data></array></value>`, "params[0]: type mismatch: array != xml.schemaPost"},
+	} {
+		ts := newSchemaServer(tt.post)
+		client := NewClient(ts.URL)
+
+		var reply schemaReply
+		ctx := WithReplySchema(context.Background(), nil)
+		err := client.CallContext(ctx, "post.get", &struct{}{}, &reply)
+		if tt.issue == "" {
+			if err != nil {
+				t.Errorf("Expected %s to fit, got %v", tt.post, err)
+			}
+		} else if se, ok := err.(*SchemaError); !ok {
+			t.Errorf("Expected a SchemaError for %s, got %v", tt.post, err)
+		} else {
+			if len(se.Issues) != 1 || se.Issues[0].String() != tt.issue {
+				t.Errorf("Expected issue %q, got %v", tt.issue, se.Issues)
+			}
+			if se.Method != "post.get" || !strings.Contains(string(se.Raw), "<methodResponse>") {
+				t.Errorf("Expected the method and raw response attached, got %+v", se)
+			}
+		}
+		ts.Close()
+	}
+}
+
+func TestAssertReplies(t *testing.T) {
+	ts := newSchemaServer(`<value><struct><member><name>Title</name><value>t</value></member></struct></value>`)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	var reply schemaReply
+	if err := client.Call("post.get", &struct{}{}, &reply); err != nil {
+		t.Fatal("Expected no check by default, got:", err)
+	}
+	client.AssertReplies = true
+	if _, ok := client.Call("post.get", &struct{}{}, &reply).(*SchemaError); !ok {
+		t.Error("Expected a SchemaError with AssertReplies")
+	}
+	if err := client.Call("post.get", &struct{}{}, nil); err != nil {
+		t.Error("Expected void calls unchecked, got:", err)
+	}
+
+	// an explicit schema takes precedence
+	ctx := WithReplySchema(context.Background(), &struct{ Post struct{ Title string } }{})
+	if err := client.CallContext(ctx, "post.get", &struct{}{}, &reply); err != nil {
+		t.Error("Expected the response to fit the explicit schema, got:", err)
+	}
+}
+
+func TestReplySchemaOptions(t *testing.T) {
+	ts := newSchemaServer(`<value><struct><member><name>post_id</name><value><string>1</string></value></member>` +
+		`<member><name>title</name><value><int>42</int></value></member></struct></value>`)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+	client.AssertReplies = true
+
+	var reply struct {
+		Post struct {
+			PostID int `xmlrpc:",required"`
+			Title  string
+		}
+	}
+	if _, ok := client.Call("post.get", &struct{}{}, &reply).(*SchemaError); !ok {
+		t.Error("Expected a SchemaError without the options")
+	}
+	client.Options.MemberNames = SnakeCase
+	client.Options.CoerceStrings = true
+	if err := client.Call("post.get", &struct{}{}, &reply); err != nil {
+		t.Fatal("Expected the response to fit with the options, got:", err)
+	}
+	if reply.Post.PostID != 1 || reply.Post.Title != "42" {
+		t.Errorf("Unexpected decoding %+v", reply.Post)
+	}
+}
+
+func TestReplySchemaFault(t *testing.T) {
+	ts := httptest.NewServer(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		return nil, FaultInvalidParams
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL)
+	client.AssertReplies = true
+
+	var reply schemaReply
+	if _, ok := client.Call("post.get", &struct{}{}, &reply).(Fault); !ok {
+		t.Error("Expected the fault returned as is")
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package script serves XML-RPC methods written in Starlark, a Python
+// dialect embedded in Go, so operators define shims and mocks changed
+// without recompiling the server:
+//
+//	# mock.star
+//	def getUser(id):
+//		if id == 0:
+//			fault(404, "no such user")
+//		return {"id": id, "name": "user%d" % id}
+//
+//	m, err := script.Load("mock.star", nil)
+//	reg.RegisterProvider("users", m)
+//
+// The params and results are converted between XML-RPC and Starlark values:
+// ints, doubles, booleans and strings as such, base64 as bytes, nil as
+// None, arrays as lists (lists and tuples back), structs as dicts with
+// string keys, and dateTimes as their ISO 8601 strings.
+package script
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AlexStocks/gorilla-xmlrpc/xml"
+	"go.starlark.net/starlark"
+)
+
+// Methods are the methods defined by a Starlark script: its global
+// functions whose names don't start with an underscore. They implement
+// xml.MethodProvider, to be registered to a xml.Registry.
+//
+// The scripts may call fault(code, string) to fail with a fault; the other
+// errors are sent as application error faults.
+type Methods struct {
+	// MaxSteps, if positive, bounds the steps of the Starlark interpreter
+	// per call, failing the runaway calls.
+	MaxSteps uint64
+
+	globals starlark.StringDict
+}
+
+// predeclared are the builtins of the scripts.
+var predeclared = starlark.StringDict{
+	"fault": starlark.NewBuiltin("fault", builtinFault),
+}
+
+// Load executes the script filename, read from src if not nil as
+// starlark.ExecFile does, and returns the methods it defines. The script's
+// globals are frozen, so the methods may be called concurrently.
+func Load(filename string, src interface{}) (*Methods, error) {
+	thread := &starlark.Thread{Name: filename}
+	globals, err := starlark.ExecFile(thread, filename, src, predeclared)
+	if err != nil {
+		return nil, fmt.Errorf("xmlrpc: can't load %s: %v", filename, err)
+	}
+	globals.Freeze()
+	return &Methods{globals: globals}, nil
+}
+
+// ListMethods implements xml.MethodProvider.
+func (m *Methods) ListMethods() []string {
+	var names []string
+	for name, v := range m.globals {
+		if _, ok := v.(*starlark.Function); ok && !strings.HasPrefix(name, "_") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Call implements xml.MethodProvider.
+func (m *Methods) Call(method string, params xml.Value) (xml.Value, error) {
+	fn, ok := m.globals[method].(*starlark.Function)
+	if !ok || strings.HasPrefix(method, "_") {
+		fault := xml.FaultInvalidMethodName
+		fault.String += ": " + method
+		return xml.Value{}, fault
+	}
+	args := make(starlark.Tuple, len(params.Items))
+	for i, p := range params.Items {
+		arg, err := toStarlark(p)
+		if err != nil {
+			fault := xml.FaultInvalidParams
+			fault.String += fmt.Sprintf(": param %d: %v", i+1, err)
+			return xml.Value{}, fault
+		}
+		args[i] = arg
+	}
+
+	thread := &starlark.Thread{Name: method}
+	if m.MaxSteps > 0 {
+		thread.SetMaxExecutionSteps(m.MaxSteps)
+	}
+	result, err := starlark.Call(thread, fn, args, nil)
+	if err != nil {
+		var fault xml.Fault
+		if errors.As(err, &fault) {
+			return xml.Value{}, fault
+		}
+		return xml.Value{}, err
+	}
+	return fromStarlark(result)
+}
+
+// builtinFault implements fault(code, string).
+func builtinFault(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var fault xml.Fault
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &fault.Code, &fault.String); err != nil {
+		return nil, err
+	}
+	return nil, fault
+}
+
+// toStarlark converts the XML-RPC value v to Starlark.
+func toStarlark(v xml.Value) (starlark.Value, error) {
+	switch v.Kind {
+	case xml.KindNil:
+		return starlark.None, nil
+	case xml.KindInt, "i4", "i8":
+		i, err := v.Int()
+		if err != nil {
+			return nil, err
+		}
+		return starlark.MakeInt64(i), nil
+	case xml.KindDouble:
+		f, err := v.Double()
+		if err != nil {
+			return nil, err
+		}
+		return starlark.Float(f), nil
+	case xml.KindBoolean:
+		return starlark.Bool(v.Boolean()), nil
+	case xml.KindString, xml.KindDateTime, "":
+		return starlark.String(v.Text), nil
+	case xml.KindBase64:
+		b, err := v.Base64()
+		if err != nil {
+			return nil, err
+		}
+		return starlark.Bytes(b), nil
+	case xml.KindArray:
+		items := make([]starlark.Value, len(v.Items))
+		for i, item := range v.Items {
+			x, err := toStarlark(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = x
+		}
+		return starlark.NewList(items), nil
+	case xml.KindStruct:
+		dict := starlark.NewDict(len(v.Members))
+		for _, member := range v.Members {
+			x, err := toStarlark(member.Value)
+			if err != nil {
+				return nil, err
+			}
+			dict.SetKey(starlark.String(member.Name), x)
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("unsupported type <%s>", v.Kind)
+}
+
+// fromStarlark converts the Starlark value v to XML-RPC.
+func fromStarlark(v starlark.Value) (xml.Value, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return xml.Value{Kind: xml.KindNil}, nil
+	case starlark.Bool:
+		return xml.NewBoolean(bool(v)), nil
+	case starlark.Int:
+		i, ok := v.Int64()
+		if !ok {
+			return xml.Value{}, fmt.Errorf("xmlrpc: int %v out of range", v)
+		}
+		return xml.NewInt(i), nil
+	case starlark.Float:
+		return xml.NewDouble(float64(v)), nil
+	case starlark.String:
+		return xml.NewString(string(v)), nil
+	case starlark.Bytes:
+		return xml.NewBase64([]byte(v)), nil
+	case starlark.Indexable:
+		// lists and tuples
+		items := make([]xml.Value, v.Len())
+		for i := range items {
+			item, err := fromStarlark(v.Index(i))
+			if err != nil {
+				return xml.Value{}, err
+			}
+			items[i] = item
+		}
+		return xml.NewArray(items...), nil
+	case *starlark.Dict:
+		members := make([]xml.Member, 0, v.Len())
+		for _, kv := range v.Items() {
+			name, ok := kv[0].(starlark.String)
+			if !ok {
+				return xml.Value{}, fmt.Errorf("xmlrpc: dict key %v isn't a string", kv[0])
+			}
+			value, err := fromStarlark(kv[1])
+			if err != nil {
+				return xml.Value{}, err
+			}
+			members = append(members, xml.Member{Name: string(name), Value: value})
+		}
+		return xml.NewStruct(members...), nil
+	}
+	return xml.Value{}, fmt.Errorf("xmlrpc: can't send a Starlark %s", v.Type())
+}
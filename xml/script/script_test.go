@@ -0,0 +1,112 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package script
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-xmlrpc/xml"
+)
+
+const mock = `
+def add(a, b):
+    return a + b
+
+def echo(v):
+    return v
+
+def getUser(id):
+    if id == 0:
+        fault(404, "no such user")
+    return {"id": id, "name": "user%d" % id, "tags": ("a", "b"), "admin": False}
+
+def fail():
+    return 1 // 0
+
+def spin():
+    for i in range(1000000):
+        pass
+
+def _helper():
+    pass
+`
+
+func TestMethods(t *testing.T) {
+	m, err := Load("mock.star", mock)
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	m.MaxSteps = 10000
+	if names := m.ListMethods(); !reflect.DeepEqual(names, []string{"add", "echo", "fail", "getUser", "spin"}) {
+		t.Errorf("Expected the public functions listed, got %q", names)
+	}
+
+	reg := xml.NewRegistry(xml.NewCodec())
+	reg.RegisterProvider("mock", m)
+	ts := httptest.NewServer(reg)
+	defer ts.Close()
+	client := xml.NewClient(ts.URL)
+
+	reply, err := client.CallValues("mock.add", xml.NewInt(2), xml.NewInt(3))
+	if err != nil || len(reply) != 1 || reply[0].Text != "5" {
+		t.Errorf("Expected 5, got %v and %v", reply, err)
+	}
+
+	in := xml.NewStruct(
+		xml.Member{Name: "int", Value: xml.NewInt(1)},
+		xml.Member{Name: "double", Value: xml.NewDouble(1.5)},
+		xml.Member{Name: "bool", Value: xml.NewBoolean(true)},
+		xml.Member{Name: "string", Value: xml.NewString("x")},
+		xml.Member{Name: "base64", Value: xml.NewBase64([]byte("data"))},
+		xml.Member{Name: "nil", Value: xml.Value{Kind: xml.KindNil}},
+		xml.Member{Name: "array", Value: xml.NewArray(xml.NewInt(1), xml.NewString("y"))},
+	)
+	reply, err = client.CallValues("mock.echo", in)
+	if err != nil || len(reply) != 1 {
+		t.Fatalf("Expected the value echoed, got %v and %v", reply, err)
+	}
+	if diff := xml.Diff(in, reply[0]); len(diff) != 0 {
+		t.Errorf("Expected the value unchanged, got %v", diff)
+	}
+
+	var user struct {
+		Id    int
+		Name  string
+		Tags  []string
+		Admin bool
+	}
+	reply, err = client.CallValues("mock.getUser", xml.NewInt(7))
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if err := reply[0].Decode(&user); err != nil || user.Name != "user7" || len(user.Tags) != 2 {
+		t.Errorf("Expected the user decoded, got %+v and %v", user, err)
+	}
+
+	for _, test := range []struct {
+		method string
+		params []xml.Value
+		fault  string
+	}{
+		{"mock.getUser", []xml.Value{xml.NewInt(0)}, "404: no such user"},
+		{"mock.fail", nil, "Application Error: floored division by zero"},
+		{"mock.add", []xml.Value{xml.NewInt(1)}, "Application Error: function add missing 1 argument (b)"},
+		{"mock.spin", nil, "Application Error: Starlark computation cancelled: too many steps"},
+		{"mock._helper", nil, "Requested Method Not Found"},
+		{"mock.echo", []xml.Value{{Kind: xml.KindInt, Text: "x"}}, "Invalid Method Parameters: param 1"},
+	} {
+		_, err := client.CallValues(test.method, test.params...)
+		if err == nil || !strings.Contains(err.Error(), test.fault) {
+			t.Errorf("Expected %s to fail with %q, got %v", test.method, test.fault, err)
+		}
+	}
+
+	if _, err := Load("broken.star", "def f(:"); err == nil {
+		t.Error("Expected a broken script to fail loading")
+	}
+}
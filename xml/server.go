@@ -6,10 +6,15 @@ package xml
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/AlexStocks/gorilla-rpc"
 )
@@ -28,6 +33,65 @@ func NewCodec() *Codec {
 // Codec creates a CodecRequest to process each request.
 type Codec struct {
 	aliases map[string]string
+
+	// EncodeHook, if set, is applied to every encoded response.
+	EncodeHook EncodeHook
+
+	// Options tune the decoding of args and the encoding of replies.
+	Options Options
+
+	// Envelope, if set, unwraps every request and wraps every response.
+	Envelope Envelope
+
+	// Rewriter, if set, rewrites every request before it's decoded, and
+	// every response after it's encoded, before the EncodeHook.
+	Rewriter *Rewriter
+
+	// ResponseFilters are run, in order, on every response after it's
+	// encoded, before the Rewriter.
+	ResponseFilters []ResponseFilter
+
+	// ArenaSize, if positive, makes the slices of the args decoded into an
+	// Arena of slabs of ArenaSize bytes, reset and recycled once the response
+	// is written, saving most allocations of the busy servers. The methods
+	// must then not retain the slices of their args past the call.
+	ArenaSize int
+
+	// Tune, if set, adjusts the Options of every request, e.g. from a
+	// RuntimeOptions flipped by operators, or for the misbehaving clients.
+	Tune func(r *http.Request, opts *Options)
+
+	// Stats, if set, count the calls per method.
+	Stats *ServerStats
+
+	// ProfileLabels makes the calls run with the pprof label xmlrpc.method
+	// set to their method, so the profiles of a busy server tell the time
+	// spent by every method, decoding and encoding included.
+	ProfileLabels bool
+
+	// SlowCalls, if set, logs the slow calls, timed from the reading of the
+	// request to the writing of the response.
+	SlowCalls *SlowCalls
+
+	// FaultSanitizer, if set, cleans the faultStrings of the responses,
+	// logging the faults in full.
+	FaultSanitizer *FaultSanitizer
+
+	// CorrelationIDs, if set, tags every request with a correlation ID,
+	// included in its faultStrings and log lines.
+	CorrelationIDs *CorrelationIDs
+
+	// BinarySidecar, if positive, makes the base64 values of BinarySidecar
+	// bytes or more travel as binary parts of a multipart response, a third
+	// smaller (see SidecarContentType), to the clients accepting it; the
+	// others get them inline.
+	BinarySidecar int
+
+	// AfterCall, if set, is called after every response is written, with
+	// the time, CPU and allocations used by the call (see CallUsage).
+	AfterCall func(usage CallUsage)
+
+	arenas sync.Pool
 }
 
 // RegisterAlias creates a method alias
@@ -63,21 +127,67 @@ func (c *Codec) GetMethodName(method string) string {
 
 // NewRequest returns a CodecRequest.
 func (c *Codec) NewRequest(r *http.Request) rpc.CodecRequest {
+	start := time.Now()
 	rawxml, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		return &CodecRequest{err: err}
 	}
 	defer r.Body.Close()
+	if c.Envelope != nil {
+		if rawxml, err = c.Envelope.Unwrap(rawxml); err != nil {
+			return &CodecRequest{err: FaultDecode}
+		}
+	}
+
+	var called string
+	if c.Rewriter != nil {
+		called, rawxml = c.Rewriter.rewriteCall(rawxml)
+	}
+
+	if c.Options.IgnoreNamespaces || c.Options.IgnoreCase {
+		// the method name is read from the normalized document too
+		if rawxml, err = normalizeDocument(rawxml, &c.Options); err != nil {
+			return &CodecRequest{err: FaultDecode}
+		}
+	}
 
 	var request ServerRequest
-	if err := xml.Unmarshal(rawxml, &request); err != nil {
+	decoder := xml.NewDecoder(bytes.NewReader(rawxml))
+	decoder.CharsetReader = c.Options.charsetReader
+	if err := decoder.Decode(&request); err != nil {
 		return &CodecRequest{err: err}
 	}
 	request.rawxml = string(rawxml)
 	if method, ok := c.aliases[request.Method]; ok {
 		request.Method = method
 	}
-	return &CodecRequest{request: &request}
+	rej, _ := r.Context().Value(validationContextKey).(*rejection)
+	req := &CodecRequest{request: &request, hook: c.EncodeHook, envelope: c.Envelope, opts: c.Options, rej: rej,
+		rewriter: c.Rewriter, called: called, filters: c.ResponseFilters}
+	if c.Tune != nil {
+		c.Tune(r, &req.opts)
+	}
+	req.stats, req.sanitizer, req.after = c.Stats, c.FaultSanitizer, c.AfterCall
+	if c.CorrelationIDs != nil {
+		req.ids, req.id = c.CorrelationIDs, c.CorrelationIDs.id(r)
+	}
+	if c.BinarySidecar > 0 && acceptsSidecar(r) {
+		req.sidecar = c.BinarySidecar
+	}
+	if c.SlowCalls != nil {
+		req.slow, req.start, req.peer = c.SlowCalls, start, r.RemoteAddr
+	}
+	if c.ProfileLabels {
+		req.ctx = r.Context()
+	}
+	if c.ArenaSize > 0 {
+		arena, _ := c.arenas.Get().(*Arena)
+		if arena == nil || arena.size != c.ArenaSize {
+			arena = NewArena(c.ArenaSize)
+		}
+		req.opts.arena, req.arenas = arena, &c.arenas
+	}
+	return req
 }
 
 // ----------------------------------------------------------------------------
@@ -92,8 +202,28 @@ type ServerRequest struct {
 
 // CodecRequest decodes and encodes a single request.
 type CodecRequest struct {
-	request *ServerRequest
-	err     error
+	request   *ServerRequest
+	err       error
+	hook      EncodeHook
+	envelope  Envelope
+	opts      Options
+	rej       *rejection
+	rewriter  *Rewriter
+	called    string
+	filters   []ResponseFilter
+	arenas    *sync.Pool
+	stats     *ServerStats
+	inFlight  bool            // counted in stats
+	ctx       context.Context // to restore the profile labels, if set
+	slow      *SlowCalls
+	start     time.Time
+	peer      string
+	sanitizer *FaultSanitizer
+	ids       *CorrelationIDs
+	id        string // the correlation ID, if ids is set
+	sidecar   int    // the BinarySidecar threshold, if accepted
+	after     func(usage CallUsage)
+	usage     *usageMeter // started by ReadRequest, if after is set
 }
 
 // Method returns the RPC method for the current request.
@@ -110,9 +240,43 @@ func (c *CodecRequest) Method() (string, error) {
 //
 // args is the pointer to the Service.Args structure
 // it gets populated from temporary XML structure
+//
+// Args breaking the constraints of their xmlrpc tags are rejected, so the
+// method isn't called. The rpc server answers them with a plain 400 error
+// unless it is wrapped with ValidationFaults.
 func (c *CodecRequest) ReadRequest(args interface{}) error {
-	c.err = xml2RPC(c.request.rawxml, args)
+	if c.ctx != nil {
+		pprof.SetGoroutineLabels(pprof.WithLabels(c.ctx, pprof.Labels("xmlrpc.method", c.request.Method)))
+	}
+	if c.after != nil {
+		c.usage = startUsage()
+	}
+	start := time.Now()
+	c.err = decodeRPC(c.request.rawxml, args, &c.opts)
+	if c.stats != nil {
+		c.stats.begin(c.request.Method, time.Since(start))
+		c.inFlight = true
+	}
+	if c.err != nil {
+		return nil
+	}
 
+	if err := checkArgs(args); err != nil {
+		fault := err.(Fault)
+		if c.rej != nil {
+			c.rej.fault = &fault
+		}
+		// no response is written
+		if c.inFlight {
+			c.inFlight = false
+			c.stats.end(c.request.Method, 0, true)
+		}
+		if c.ctx != nil {
+			pprof.SetGoroutineLabels(c.ctx)
+		}
+		c.usage = nil
+		return fault
+	}
 	return nil
 }
 
@@ -124,22 +288,107 @@ func (c *CodecRequest) WriteResponse(w http.ResponseWriter, response interface{}
 	if c.err == nil {
 		c.err = methodErr
 	}
+	if c.usage != nil {
+		defer func(m *usageMeter) {
+			usage := CallUsage{Method: c.request.Method, ID: c.id, Err: c.err}
+			m.stop(&usage)
+			c.after(usage)
+		}(c.usage)
+		c.usage = nil
+	}
+	if c.ctx != nil {
+		defer pprof.SetGoroutineLabels(c.ctx)
+	}
+	if c.slow != nil {
+		defer func() {
+			if elapsed := time.Since(c.start); c.slow.slow(elapsed) {
+				raw := c.request.rawxml
+				c.slow.log(c.request.Method, c.peer, c.id, strings.Count(raw, "<param>"), len(raw), elapsed)
+			}
+		}()
+	}
+	if c.inFlight {
+		c.inFlight = false
+		defer func(start time.Time, failed bool) {
+			c.stats.end(c.request.Method, time.Since(start), failed)
+		}(time.Now(), c.err != nil)
+	}
 	buffer := bytes.NewBuffer(make([]byte, 0))
 	if c.err != nil {
-		var fault Fault
-		switch c.err.(type) {
-		case Fault:
-			fault = c.err.(Fault)
-		default:
+		fault, ok := asFault(c.err)
+		if !ok {
 			fault = FaultApplicationError
 			fault.String += fmt.Sprintf(": %v", c.err)
 		}
-		Fault2XML(fault, buffer)
-	} else {
-		rpcResponse2XML(response, buffer)
+		Fault2XML(c.fault(fault, c.err), buffer)
+	} else if err := rpcResponse2XML(response, buffer, &c.opts); err != nil {
+		fault := FaultInternalError
+		fault.String += fmt.Sprintf(": %v", err)
+		buffer.Reset()
+		Fault2XML(c.fault(fault, err), buffer)
+	}
+
+	if len(c.filters) != 0 {
+		buffer = bytes.NewBuffer(filterResponse(c.filters, c.request.Method, buffer.Bytes()))
+	}
+
+	if c.rewriter != nil {
+		buffer = bytes.NewBuffer(c.rewriter.rewriteResponse(c.called, buffer.Bytes()))
 	}
 
-	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	if c.hook != nil {
+		body, err := c.hook(c.request.Method, buffer.Bytes())
+		if err != nil {
+			fault := FaultInternalError
+			fault.String += fmt.Sprintf(": %v", err)
+			buffer.Reset()
+			Fault2XML(c.fault(fault, err), buffer)
+		} else {
+			buffer = bytes.NewBuffer(body)
+		}
+	}
+
+	if c.envelope != nil {
+		body, err := c.envelope.Wrap(buffer.Bytes())
+		if err != nil {
+			fault := FaultInternalError
+			fault.String += fmt.Sprintf(": %v", err)
+			buffer.Reset()
+			Fault2XML(c.fault(fault, err), buffer)
+		} else {
+			buffer = bytes.NewBuffer(body)
+		}
+	}
+
+	contentType := "text/xml; charset=utf-8"
+	if c.sidecar > 0 {
+		if body, ct, ok := sidecarResponse(buffer.Bytes(), c.sidecar); ok {
+			buffer, contentType = bytes.NewBuffer(body), ct
+		}
+	}
+	w.Header().Set("Content-Type", contentType)
+	if c.ids != nil {
+		w.Header().Set(CorrelationIDHeader, c.id)
+	}
 	buffer.WriteTo(w)
+
+	if arena := c.opts.arena; arena != nil && c.arenas != nil {
+		// the response is written, the args are done with
+		c.opts.arena = nil
+		arena.Reset()
+		c.arenas.Put(arena)
+	}
 	return nil
 }
+
+// fault returns fault, raised as err, as written in the response: stamped
+// with the correlation ID, if configured, and sanitized.
+func (c *CodecRequest) fault(fault Fault, err error) Fault {
+	var stamp func(string) string
+	if c.ids != nil {
+		stamp = func(s string) string {
+			return c.ids.stamp(Fault{String: s}, c.id).String
+		}
+	}
+	return c.sanitizer.sanitize(c.request.Method, c.id, fault, err, stamp)
+}
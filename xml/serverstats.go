@@ -0,0 +1,110 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ServerStats count the calls served by a Codec, per method (see
+// Codec.Stats); the calls of unknown methods aren't counted. It's also a
+// handler serving the counters as JSON, to mount on an admin mux:
+//
+//	stats := &xml.ServerStats{}
+//	codec.Stats = stats
+//	admin.Handle("/debug/xmlrpc", stats)
+//
+// The zero value is ready to use.
+type ServerStats struct {
+	mu      sync.Mutex
+	methods map[string]*methodCounters
+}
+
+// MethodStats are the counters of a method.
+type MethodStats struct {
+	Method    string  `json:"method"`
+	Calls     int     `json:"calls"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	InFlight  int     `json:"in_flight"`
+
+	// AvgDecode and AvgEncode are the mean times decoding the args and
+	// encoding the responses.
+	AvgDecode time.Duration `json:"avg_decode_ns"`
+	AvgEncode time.Duration `json:"avg_encode_ns"`
+}
+
+type methodCounters struct {
+	calls, errors, inFlight int
+	decoded                 int
+	decode, encode          time.Duration
+}
+
+// Snapshot returns the counters of the methods called, sorted by method.
+func (s *ServerStats) Snapshot() []MethodStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make([]MethodStats, 0, len(s.methods))
+	for method, c := range s.methods {
+		m := MethodStats{Method: method, Calls: c.calls, Errors: c.errors, InFlight: c.inFlight}
+		if c.calls > 0 {
+			m.ErrorRate = float64(c.errors) / float64(c.calls)
+			m.AvgEncode = c.encode / time.Duration(c.calls)
+		}
+		if c.decoded > 0 {
+			m.AvgDecode = c.decode / time.Duration(c.decoded)
+		}
+		stats = append(stats, m)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Method < stats[j].Method })
+	return stats
+}
+
+// ServeHTTP implements http.Handler.
+func (s *ServerStats) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Snapshot())
+}
+
+// counters returns the counters of method; s.mu must be held.
+func (s *ServerStats) counters(method string) *methodCounters {
+	c := s.methods[method]
+	if c == nil {
+		if s.methods == nil {
+			s.methods = make(map[string]*methodCounters)
+		}
+		c = &methodCounters{}
+		s.methods[method] = c
+	}
+	return c
+}
+
+// begin counts a call of method in flight, whose args were decoded in
+// decode.
+func (s *ServerStats) begin(method string, decode time.Duration) {
+	s.mu.Lock()
+	c := s.counters(method)
+	c.inFlight++
+	c.decoded++
+	c.decode += decode
+	s.mu.Unlock()
+}
+
+// end counts a call of method whose response was encoded in encode.
+func (s *ServerStats) end(method string, encode time.Duration, failed bool) {
+	s.mu.Lock()
+	c := s.counters(method)
+	c.inFlight--
+	c.calls++
+	if failed {
+		c.errors++
+	}
+	c.encode += encode
+	s.mu.Unlock()
+}
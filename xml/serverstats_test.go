@@ -0,0 +1,49 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServerStats(t *testing.T) {
+	stats := &ServerStats{}
+	codec := NewCodec()
+	codec.Stats = stats
+	ts := newTestServer(codec)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	var res Service1Response
+	for i := 0; i < 3; i++ {
+		client.Call("Service1.Multiply", &Service1Request{2, 3}, &res)
+	}
+	client.CallValues("Service1.Multiply", NewString("two"), NewInt(3))
+	client.CallValues("Service1.Unknown")
+
+	snapshot := stats.Snapshot()
+	if len(snapshot) != 1 {
+		t.Fatalf("Expected a single method counted, got %+v", snapshot)
+	}
+	m := snapshot[0]
+	if m.Method != "Service1.Multiply" || m.Calls != 4 || m.Errors != 1 || m.InFlight != 0 {
+		t.Errorf("Expected 4 calls with 1 error, got %+v", m)
+	}
+	if m.ErrorRate != 0.25 || m.AvgDecode <= 0 || m.AvgEncode <= 0 {
+		t.Errorf("Expected the error rate and times, got %+v", m)
+	}
+
+	w := httptest.NewRecorder()
+	stats.ServeHTTP(w, httptest.NewRequest("GET", "/debug/xmlrpc", nil))
+	var served []MethodStats
+	if err := json.Unmarshal(w.Body.Bytes(), &served); err != nil {
+		t.Fatal(err)
+	}
+	if len(served) != 1 || served[0] != m {
+		t.Errorf("Expected the snapshot served, got %s", w.Body)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Session calls the methods of an API requiring a token, got from a login
+// method, as their first param. The token is got on the first call, and got
+// again when a call fails with an expired token fault, the call being then
+// retried once. What an expired token fault is depends on the API (see
+// Expired).
+//
+//	s := &xml.Session{Client: client, Login: func(ctx context.Context) (interface{}, error) {
+//		var reply struct{ Token string }
+//		err := client.CallContext(ctx, "auth.login", &Credentials{user, password}, &reply)
+//		return reply.Token, err
+//	}}
+//	err := s.Call("posts.get", &post, postID)
+type Session struct {
+	// Client performs the calls.
+	Client *Client
+
+	// Login returns a new token.
+	Login func(ctx context.Context) (interface{}, error)
+
+	// Expired, if set, reports whether fault tells the token expired, in
+	// the dialect of the API, e.g. FaultCodes(401) or a test of the fault
+	// string. By default the faults whose string mentions an expired or
+	// invalid token are.
+	Expired func(fault Fault) bool
+
+	mu    sync.Mutex
+	token interface{}
+	valid bool
+	gen   uint64 // incremented on every login
+}
+
+// Call invokes method with the token followed by args, and decodes the
+// response into reply, which may be nil for void methods.
+func (s *Session) Call(method string, reply interface{}, args ...interface{}) error {
+	return s.CallContext(context.Background(), method, reply, args...)
+}
+
+// CallContext is like Call, but the call is cancelled when ctx is done.
+func (s *Session) CallContext(ctx context.Context, method string, reply interface{}, args ...interface{}) error {
+	for retried := false; ; retried = true {
+		token, gen, err := s.login(ctx)
+		if err != nil {
+			return err
+		}
+		err = s.call(ctx, method, reply, token, args)
+		if fault, ok := asFault(err); ok && !retried && s.expired(fault) {
+			s.invalidate(gen)
+			s.Client.stats.retry()
+			continue
+		}
+		return err
+	}
+}
+
+// Invalidate discards the token, so the next call logs in again, e.g. after
+// a logout.
+func (s *Session) Invalidate() {
+	s.mu.Lock()
+	s.valid = false
+	s.mu.Unlock()
+}
+
+// login returns the token, logging in if there's none, and its generation.
+func (s *Session) login(ctx context.Context) (interface{}, uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.valid {
+		return s.token, s.gen, nil
+	}
+	token, err := s.Login(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	s.token, s.valid = token, true
+	s.gen++
+	return token, s.gen, nil
+}
+
+// invalidate discards the token of generation gen, unless a concurrent call
+// already got a new one.
+func (s *Session) invalidate(gen uint64) {
+	s.mu.Lock()
+	if s.gen == gen {
+		s.valid = false
+	}
+	s.mu.Unlock()
+}
+
+func (s *Session) call(ctx context.Context, method string, reply, token interface{}, args []interface{}) error {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "<methodCall><methodName>%s</methodName><params>", escapeString(method))
+	if err := writeParamValues(append([]interface{}{token}, args...), &buffer, &s.Client.Options); err != nil {
+		return err
+	}
+	fmt.Fprintf(&buffer, "</params></methodCall>")
+
+	resp, err := s.Client.post(ctx, method, buffer.Bytes())
+	if err != nil {
+		return err
+	}
+	return s.Client.decodeReply(ctx, method, resp, reply)
+}
+
+func (s *Session) expired(fault Fault) bool {
+	if s.Expired != nil {
+		return s.Expired(fault)
+	}
+	return tokenExpired(fault)
+}
+
+// FaultCodes returns a predicate, e.g. for Session.Expired, reporting
+// whether a fault has one of codes.
+func FaultCodes(codes ...int) func(Fault) bool {
+	return func(fault Fault) bool {
+		for _, code := range codes {
+			if fault.Code == code {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// tokenExpired reports whether fault tells the token expired, or is
+// otherwise invalid.
+func tokenExpired(fault Fault) bool {
+	str := strings.ToLower(fault.String)
+	return strings.Contains(str, "expired") ||
+		strings.Contains(str, "token") && strings.Contains(str, "invalid")
+}
@@ -0,0 +1,144 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// tokenServer serves "login", returning a new token, and "echo", returning
+// its second param if the first is the current token.
+type tokenServer struct {
+	mu      sync.Mutex
+	current int
+	logins  int
+	echoes  int
+}
+
+func (s *tokenServer) serve(r *http.Request, method string, params []Value) ([]Value, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch method {
+	case "login":
+		s.logins++
+		s.current++
+		return []Value{{Kind: KindString, Text: "t" + strconv.Itoa(s.current)}}, nil
+	case "echo":
+		s.echoes++
+		if params[0].Text != "t"+strconv.Itoa(s.current) {
+			fault := FaultApplicationError
+			fault.String += ": session expired"
+			return nil, fault
+		}
+		return params[1:], nil
+	}
+	return nil, FaultInvalidParams
+}
+
+func newTestSession(s *tokenServer) (*Session, func()) {
+	ts := httptest.NewServer(MethodHandler(s.serve))
+	client := NewClient(ts.URL)
+	return &Session{Client: client, Login: func(ctx context.Context) (interface{}, error) {
+		var reply struct{ Token string }
+		err := client.CallContext(ctx, "login", &struct{}{}, &reply)
+		return reply.Token, err
+	}}, ts.Close
+}
+
+func TestSession(t *testing.T) {
+	srv := &tokenServer{}
+	session, done := newTestSession(srv)
+	defer done()
+
+	var reply struct{ N int }
+	for i := 1; i <= 2; i++ {
+		if err := session.Call("echo", &reply, i); err != nil {
+			t.Fatal(err)
+		}
+		if reply.N != i {
+			t.Errorf("Expected %d echoed, got %d", i, reply.N)
+		}
+	}
+	if srv.logins != 1 {
+		t.Errorf("Expected the token reused, got %d logins", srv.logins)
+	}
+
+	// the token expires
+	srv.current++
+	if err := session.Call("echo", &reply, 3); err != nil {
+		t.Fatal("Expected the call retried after a new login, got:", err)
+	}
+	if srv.logins != 2 || srv.echoes != 4 || reply.N != 3 {
+		t.Errorf("Expected 2 logins and 4 echoes, got %d and %d", srv.logins, srv.echoes)
+	}
+}
+
+func TestSessionRetriesOnce(t *testing.T) {
+	srv := &tokenServer{}
+	session, done := newTestSession(srv)
+	defer done()
+	login := session.Login
+	session.Login = func(ctx context.Context) (interface{}, error) {
+		login(ctx)
+		return "stale", nil
+	}
+
+	err := session.Call("echo", nil, 1)
+	if fault, ok := err.(Fault); !ok || !tokenExpired(fault) {
+		t.Errorf("Expected the expired token fault, got %v", err)
+	}
+	if srv.echoes != 2 {
+		t.Errorf("Expected a single retry, got %d echoes", srv.echoes)
+	}
+}
+
+func TestSessionInvalidate(t *testing.T) {
+	srv := &tokenServer{}
+	session, done := newTestSession(srv)
+	defer done()
+
+	session.Call("echo", nil, 1)
+	session.Invalidate()
+	session.Call("echo", nil, 2)
+	if srv.logins != 2 {
+		t.Errorf("Expected a new login after Invalidate, got %d logins", srv.logins)
+	}
+}
+
+func TestSessionExpired(t *testing.T) {
+	srv := &tokenServer{}
+	session, done := newTestSession(srv)
+	defer done()
+	session.Expired = FaultCodes(FaultInvalidParams.Code)
+
+	session.Call("echo", nil, 1)
+	srv.current++
+	if err := session.Call("echo", nil, 2); err == nil {
+		t.Error("Expected the expired token fault not retried when not matching Expired")
+	}
+	if srv.logins != 1 {
+		t.Errorf("Expected 1 login, got %d", srv.logins)
+	}
+
+	session.Expired = FaultCodes(FaultInvalidParams.Code, FaultApplicationError.Code)
+	if err := session.Call("echo", nil, 3); err != nil {
+		t.Error("Expected the call retried when matching Expired, got:", err)
+	}
+	if srv.logins != 2 {
+		t.Errorf("Expected 2 logins, got %d", srv.logins)
+	}
+}
+
+func TestFaultCodes(t *testing.T) {
+	expired := FaultCodes(401, 403)
+	if !expired(Fault{Code: 403}) || expired(Fault{Code: 404}) {
+		t.Error("Expected FaultCodes to match the faults of the codes only")
+	}
+}
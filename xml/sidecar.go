@@ -0,0 +1,146 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+// SidecarContentType is the content type of the responses whose large
+// base64 values travel as binary parts, the clients accepting it tell with
+// their Accept header.
+//
+// The response is a multipart/related document: its first part, the root,
+// is the XML response, where the values moved are written as
+// <base64 href="cid:ID"/>, ID being the Content-ID of their binary part.
+const SidecarContentType = "multipart/related"
+
+// sidecarRefRE matches the references to the binary parts.
+var sidecarRefRE = regexp.MustCompile(`<base64 href="cid:([^"]*)"\s*/>`)
+
+// acceptsSidecar reports whether the request r accepts the binary parts.
+func acceptsSidecar(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), SidecarContentType)
+}
+
+// sidecarResponse moves the base64 values of the response document doc
+// whose binaries are threshold bytes or more into binary parts. It returns
+// the multipart document and its content type, and reports whether any
+// value was moved.
+func sidecarResponse(doc []byte, threshold int) ([]byte, string, bool) {
+	var (
+		root  bytes.Buffer
+		parts [][]byte
+	)
+	open, end := []byte("<base64>"), []byte("</base64>")
+	rest := doc
+	for {
+		i := bytes.Index(rest, open)
+		if i < 0 {
+			break
+		}
+		j := bytes.Index(rest[i:], end)
+		if j < 0 {
+			break
+		}
+		text := rest[i+len(open) : i+j]
+		if base64.StdEncoding.DecodedLen(len(text)) >= threshold {
+			if data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(text)), "")); err == nil {
+				parts = append(parts, data)
+				root.Write(rest[:i])
+				fmt.Fprintf(&root, `<base64 href="cid:part%d"/>`, len(parts))
+				rest = rest[i+j+len(end):]
+				continue
+			}
+		}
+		root.Write(rest[:i+j+len(end)])
+		rest = rest[i+j+len(end):]
+	}
+	if len(parts) == 0 {
+		return nil, "", false
+	}
+	root.Write(rest)
+
+	var buffer bytes.Buffer
+	mw := multipart.NewWriter(&buffer)
+	w, _ := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"text/xml; charset=utf-8"},
+		"Content-Id":   {"<root>"},
+	})
+	w.Write(root.Bytes())
+	for i, data := range parts {
+		w, _ := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {"application/octet-stream"},
+			"Content-Transfer-Encoding": {"binary"},
+			"Content-Id":                {fmt.Sprintf("<part%d>", i+1)},
+		})
+		w.Write(data)
+	}
+	mw.Close()
+	contentType := mime.FormatMediaType(SidecarContentType, map[string]string{
+		"type": "text/xml", "start": "<root>", "boundary": mw.Boundary(),
+	})
+	return buffer.Bytes(), contentType, true
+}
+
+// inlineSidecar returns the response body of the content type contentType
+// as a plain XML document, with the binary parts of a multipart response
+// inlined as base64 values. The other responses are returned as is.
+func inlineSidecar(body []byte, contentType string) ([]byte, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != SidecarContentType {
+		return body, nil
+	}
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	var root []byte
+	parts := make(map[string][]byte)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("xmlrpc: can't read the multipart response: %v", err)
+		}
+		data, err := ioutil.ReadAll(part)
+		if err != nil {
+			return nil, fmt.Errorf("xmlrpc: can't read the multipart response: %v", err)
+		}
+		id := part.Header.Get("Content-Id")
+		if root == nil && (params["start"] == "" || id == params["start"]) {
+			root = data
+			continue
+		}
+		parts[strings.Trim(id, "<>")] = data
+	}
+	if root == nil {
+		return nil, fmt.Errorf("xmlrpc: no root part in the multipart response")
+	}
+
+	var missing string
+	doc := sidecarRefRE.ReplaceAllFunc(root, func(ref []byte) []byte {
+		id := string(sidecarRefRE.FindSubmatch(ref)[1])
+		data, ok := parts[id]
+		if !ok {
+			missing = id
+			return ref
+		}
+		return []byte("<base64>" + base64.StdEncoding.EncodeToString(data) + "</base64>")
+	})
+	if missing != "" {
+		return nil, fmt.Errorf("xmlrpc: missing binary part %s in the multipart response", missing)
+	}
+	return doc, nil
+}
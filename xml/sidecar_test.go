@@ -0,0 +1,119 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+type BlobArgs struct {
+	Size int
+}
+
+type BlobReply struct {
+	Data  []byte
+	Small []byte
+}
+
+type BlobService struct{}
+
+func (s *BlobService) Get(r *http.Request, args *BlobArgs, reply *BlobReply) error {
+	reply.Data = bytes.Repeat([]byte{0, 1, 2, 0xff}, args.Size/4)
+	reply.Small = []byte("hi")
+	return nil
+}
+
+func newBlobServer(threshold int) *httptest.Server {
+	codec := NewCodec()
+	codec.BinarySidecar = threshold
+	s := rpc.NewServer()
+	s.RegisterCodec(codec, "text/xml")
+	s.RegisterService(new(BlobService), "")
+	return httptest.NewServer(s)
+}
+
+func TestBinarySidecar(t *testing.T) {
+	ts := newBlobServer(1024)
+	defer ts.Close()
+
+	for _, sidecar := range []bool{true, false} {
+		client := NewClient(ts.URL)
+		client.BinarySidecar = sidecar
+		var reply BlobReply
+		if err := client.Call("BlobService.Get", &BlobArgs{Size: 64 << 10}, &reply); err != nil {
+			t.Fatal(err)
+		}
+		if len(reply.Data) != 64<<10 || reply.Data[3] != 0xff || string(reply.Small) != "hi" {
+			t.Errorf("Unexpected reply with sidecar %v: %d bytes, %q", sidecar, len(reply.Data), reply.Small)
+		}
+	}
+
+	post := func(accept string) (string, int) {
+		req, _ := http.NewRequest("POST", ts.URL, strings.NewReader(
+			`<methodCall><methodName>BlobService.Get</methodName><params><param><value><int>65536</int></value></param></params></methodCall>`))
+		req.Header.Set("Content-Type", "text/xml")
+		req.Header.Set("Accept", accept)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.Header.Get("Content-Type"), len(body)
+	}
+	inlineType, inlineSize := post("text/xml")
+	sidecarType, sidecarSize := post("multipart/related, text/xml")
+	if !strings.HasPrefix(inlineType, "text/xml") || !strings.HasPrefix(sidecarType, "multipart/related;") {
+		t.Errorf("Expected text/xml, then multipart/related, got %q and %q", inlineType, sidecarType)
+	}
+	if sidecarSize > inlineSize*4/5 {
+		t.Errorf("Expected the multipart response a third smaller, got %d bytes for %d", sidecarSize, inlineSize)
+	}
+}
+
+func TestBinarySidecarSmall(t *testing.T) {
+	ts := newBlobServer(1 << 20)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+	client.BinarySidecar = true
+	var reply BlobReply
+	if err := client.Call("BlobService.Get", &BlobArgs{Size: 1024}, &reply); err != nil {
+		t.Fatal(err)
+	}
+	if len(reply.Data) != 1024 {
+		t.Errorf("Expected 1024 bytes inline, got %d", len(reply.Data))
+	}
+}
+
+func TestInlineSidecar(t *testing.T) {
+	doc := []byte("<methodResponse><params><param><value><base64>" + strings.Repeat("AAEC", 100) +
+		"</base64></value></param></params></methodResponse>")
+	body, contentType, ok := sidecarResponse(doc, 10)
+	if !ok {
+		t.Fatal("Expected a binary part")
+	}
+	inlined, err := inlineSidecar(body, contentType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(inlined, doc) {
+		t.Errorf("Expected %s, got %s", doc, inlined)
+	}
+
+	if _, _, ok := sidecarResponse(doc, 1000); ok {
+		t.Error("Expected no binary part under the threshold")
+	}
+	broken := bytes.Replace(body, []byte("cid:part1"), []byte("cid:part2"), 1)
+	if _, err := inlineSidecar(broken, contentType); err == nil || !strings.Contains(err.Error(), "missing binary part part2") {
+		t.Errorf("Expected a missing part, got %v", err)
+	}
+}
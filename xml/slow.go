@@ -0,0 +1,35 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import "time"
+
+// Logger logs messages, e.g. a *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// SlowCalls logs the calls of a Client or a Codec lasting Threshold or more,
+// with their method, their number of params, the size of the request and
+// the peer: the URL for a client, the remote address for a server.
+type SlowCalls struct {
+	Threshold time.Duration
+	Logger    Logger
+}
+
+// slow reports whether a call lasting elapsed is slow. s may be nil.
+func (s *SlowCalls) slow(elapsed time.Duration) bool {
+	return s != nil && elapsed >= s.Threshold
+}
+
+// log logs the slow call of method with peer and the correlation ID id, if
+// any, whose request document has params params and size bytes.
+func (s *SlowCalls) log(method, peer, id string, params, size int, elapsed time.Duration) {
+	if id != "" {
+		method += " [" + id + "]"
+	}
+	s.Logger.Printf("xmlrpc: slow call %s with %s took %v (%d params, %d bytes)",
+		method, peer, elapsed, params, size)
+}
@@ -0,0 +1,72 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+	l.mu.Unlock()
+}
+
+func TestClientSlowCalls(t *testing.T) {
+	ts := httptest.NewServer(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		if method == "slow" {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return params, nil
+	}))
+	defer ts.Close()
+	logger := &testLogger{}
+	client := NewClient(ts.URL)
+	client.SlowCalls = &SlowCalls{Threshold: 10 * time.Millisecond, Logger: logger}
+
+	client.CallValues("fast", NewInt(1))
+	client.CallValues("slow", NewInt(1), NewInt(2))
+	if len(logger.lines) != 1 {
+		t.Fatalf("Expected the slow call logged only, got %q", logger.lines)
+	}
+	if line := logger.lines[0]; !strings.HasPrefix(line, "xmlrpc: slow call slow with "+ts.URL+" took ") ||
+		!strings.Contains(line, "(2 params, ") {
+		t.Errorf("Expected the method, peer and params logged, got %q", line)
+	}
+}
+
+
+func TestCodecSlowCalls(t *testing.T) {
+	logger := &testLogger{}
+	codec := NewCodec()
+	codec.SlowCalls = &SlowCalls{Threshold: 10 * time.Millisecond, Logger: logger}
+	ts := newTestServer(codec)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	var res Service1Response
+	client.Call("Service1.Multiply", &Service1Request{1, 2}, &res)
+	if len(logger.lines) != 0 {
+		t.Errorf("Expected no fast call logged, got %q", logger.lines)
+	}
+
+	codec.SlowCalls.Threshold = 0
+	client.Call("Service1.Multiply", &Service1Request{1, 2}, &res)
+	if len(logger.lines) != 1 || !strings.Contains(logger.lines[0], "slow call Service1.Multiply with 127.0.0.1:") ||
+		!strings.Contains(logger.lines[0], "(2 params, ") {
+		t.Errorf("Expected the call logged with its peer, got %q", logger.lines)
+	}
+}
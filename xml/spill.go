@@ -0,0 +1,124 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// Spill makes the responses read by Client.CallReader buffered in a
+// temporary file once larger than Threshold bytes, instead of in memory, so
+// the memory stays bounded during occasional giant responses. The file is
+// removed once the response is read.
+type Spill struct {
+	Threshold int64
+
+	// Dir is the directory of the files, os.TempDir() if empty.
+	Dir string
+}
+
+// CallReader invokes method with args like CallContext, but passes the
+// response document to read instead of decoding it, e.g. to ProjectReader.
+// The response is buffered before, so the connection is released meanwhile,
+// in a temporary file if it's larger than the Spill threshold.
+//
+// The responses of clients with an Envelope, a Debug function, a Hedge, a
+// BinarySidecar, or coalescing or caching the calls of method are buffered
+// in memory.
+func (c *Client) CallReader(ctx context.Context, method string, args interface{}, read func(r io.Reader) error) error {
+	request, err := encodeRequest(method, args, &c.Options)
+	if err != nil {
+		return err
+	}
+	if c.Spill == nil || c.Envelope != nil || c.Debug != nil || c.Hedge.applies(ctx, method) ||
+		c.BinarySidecar || c.coalesces(method) || c.Cache.applies(method) {
+		resp, err := c.post(ctx, method, []byte(request))
+		if err != nil {
+			return err
+		}
+		return read(bytes.NewReader(resp))
+	}
+
+	ctx, done, err := c.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	document, body, contentType, err := c.prepare(method, []byte(request))
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	resp, err := c.do(ctx, c.URL, contentType, body)
+	if err != nil {
+		c.observe(method, document, len(body), 0, time.Since(start), err)
+		return err
+	}
+	buffer := &spillBuffer{spill: c.Spill}
+	defer buffer.Close()
+	n, err := io.Copy(buffer, limitSize(resp.Body, c.MaxResponseSize))
+	resp.Body.Close()
+	c.observe(method, document, len(body), n, time.Since(start), err)
+	if err != nil {
+		return err
+	}
+
+	r, err := buffer.reader()
+	if err != nil {
+		return err
+	}
+	return read(r)
+}
+
+// spillBuffer buffers in memory up to the spill threshold, then in a
+// temporary file.
+type spillBuffer struct {
+	spill  *Spill
+	memory bytes.Buffer
+	file   *os.File
+}
+
+func (b *spillBuffer) Write(p []byte) (int, error) {
+	if b.file == nil && int64(b.memory.Len()+len(p)) > b.spill.Threshold {
+		file, err := ioutil.TempFile(b.spill.Dir, "xmlrpc-response-")
+		if err != nil {
+			return 0, err
+		}
+		b.file = file
+		if _, err := b.memory.WriteTo(file); err != nil {
+			return 0, err
+		}
+	}
+	if b.file != nil {
+		return b.file.Write(p)
+	}
+	return b.memory.Write(p)
+}
+
+// reader returns the buffered content.
+func (b *spillBuffer) reader() (io.Reader, error) {
+	if b.file == nil {
+		return bytes.NewReader(b.memory.Bytes()), nil
+	}
+	if _, err := b.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return b.file, nil
+}
+
+// Close removes the file, if any.
+func (b *spillBuffer) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	b.file.Close()
+	return os.Remove(b.file.Name())
+}
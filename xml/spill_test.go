@@ -0,0 +1,84 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCallReaderSpill(t *testing.T) {
+	ts := httptest.NewServer(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		items := make([]Value, 100)
+		for i := range items {
+			items[i] = NewStruct(Member{"name", NewString("p")}, Member{"log", NewString(strings.Repeat("x", 100))})
+		}
+		return []Value{NewArray(items...)}, nil
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "spill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, threshold := range []int64{1 << 20, 1024} {
+		client := NewClient(ts.URL)
+		client.Spill = &Spill{Threshold: threshold, Dir: dir}
+
+		var spilled bool
+		var params []Value
+		err := client.CallReader(context.Background(), "all", &struct{}{}, func(r io.Reader) error {
+			_, spilled = r.(*os.File)
+			files, _ := ioutil.ReadDir(dir)
+			if spilled != (len(files) == 1) {
+				t.Errorf("Expected the spilled response in %s, got %d files", dir, len(files))
+			}
+			var err error
+			params, err = ProjectReader(r, "params[0][*].name")
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if spilled != (threshold == 1024) {
+			t.Errorf("Expected spilled %v with threshold %d", !spilled, threshold)
+		}
+		if len(params) != 1 || len(params[0].Items) != 100 || params[0].Items[99].String() !=
+			"<value><struct><member><name>name</name><value><string>p</string></value></member></struct></value>" {
+			t.Errorf("Expected the names projected, got %v", params)
+		}
+		if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+			t.Errorf("Expected the spilled response removed, got %d files", len(files))
+		}
+		if s := client.Stats(); s.Calls != 1 || s.BytesIn < 10000 {
+			t.Errorf("Expected the call counted, got %+v", s)
+		}
+	}
+}
+
+func TestCallReaderFault(t *testing.T) {
+	ts := httptest.NewServer(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		return nil, FaultInvalidParams
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL)
+	client.Spill = &Spill{Threshold: 10}
+
+	err := client.CallReader(context.Background(), "m", &struct{}{}, func(r io.Reader) error {
+		_, err := ProjectReader(r, "params[0]")
+		return err
+	})
+	if fault, ok := err.(Fault); !ok || fault.Code != FaultInvalidParams.Code {
+		t.Errorf("Expected the fault read, got %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// StaticEncoder is implemented by the types encoding themselves without
+// reflection, like the ones given methods by GenerateCodecs. The codec
+// encodes their values with XMLRPCEncode.
+type StaticEncoder interface {
+	// XMLRPCEncode appends the <value> of the receiver to b.
+	XMLRPCEncode(b []byte) ([]byte, error)
+}
+
+// StaticDecoder is implemented by the types decoding themselves without
+// reflection, like the ones given methods by GenerateCodecs. The codec
+// decodes the fields of the type with XMLRPCDecode.
+type StaticDecoder interface {
+	// XMLRPCDecode decodes v into the receiver.
+	XMLRPCDecode(v Value) error
+}
+
+// AppendInt appends the <value> of the int n to b.
+func AppendInt(b []byte, n int64) []byte {
+	b = append(b, "<value><int>"...)
+	b = strconv.AppendInt(b, n, 10)
+	return append(b, "</int></value>"...)
+}
+
+// AppendDouble appends the <value> of the double f to b.
+func AppendDouble(b []byte, f float64) []byte {
+	b = append(b, "<value><double>"...)
+	b = strconv.AppendFloat(b, f, 'f', 6, 64)
+	return append(b, "</double></value>"...)
+}
+
+// AppendString appends the <value> of the string s to b.
+func AppendString(b []byte, s string) []byte {
+	b = append(b, "<value><string>"...)
+	b = appendEscaped(b, s)
+	return append(b, "</string></value>"...)
+}
+
+// AppendBoolean appends the <value> of the boolean v to b.
+func AppendBoolean(b []byte, v bool) []byte {
+	if v {
+		return append(b, "<value><boolean>1</boolean></value>"...)
+	}
+	return append(b, "<value><boolean>0</boolean></value>"...)
+}
+
+// AppendDateTime appends the <value> of the dateTime.iso8601 t to b.
+func AppendDateTime(b []byte, t time.Time) []byte {
+	b, _ = appendTime(nil, b, reflect.ValueOf(t))
+	return b
+}
+
+// AppendBase64 appends the <value> of the base64 data to b.
+func AppendBase64(b []byte, data []byte) []byte {
+	b = append(b, "<value><base64>"...)
+	n := len(b)
+	b = append(b, make([]byte, base64.StdEncoding.EncodedLen(len(data)))...)
+	base64.StdEncoding.Encode(b[n:], data)
+	return append(b, "</base64></value>"...)
+}
+
+// AppendValue appends the value tree v to b.
+func AppendValue(b []byte, v Value) []byte {
+	buffer := bytes.NewBuffer(b)
+	v.writeXML(buffer)
+	return buffer.Bytes()
+}
+
+// expectKind returns an invalid params fault if v isn't of one of kinds.
+func expectKind(v Value, kinds ...Kind) error {
+	for _, k := range kinds {
+		if v.Kind == k {
+			return nil
+		}
+	}
+	return invalidParams("expected <%s>, got <%s>", kinds[0], v.Kind)
+}
+
+// DecodeInt decodes the int v.
+func DecodeInt(v Value) (int64, error) {
+	if err := expectKind(v, KindInt); err != nil {
+		return 0, err
+	}
+	return v.Int()
+}
+
+// DecodeDouble decodes the double v.
+func DecodeDouble(v Value) (float64, error) {
+	if err := expectKind(v, KindDouble); err != nil {
+		return 0, err
+	}
+	return v.Double()
+}
+
+// DecodeString decodes the string v.
+func DecodeString(v Value) (string, error) {
+	if err := expectKind(v, KindString); err != nil {
+		return "", err
+	}
+	return v.Text, nil
+}
+
+// DecodeBoolean decodes the boolean v.
+func DecodeBoolean(v Value) (bool, error) {
+	if err := expectKind(v, KindBoolean); err != nil {
+		return false, err
+	}
+	return v.Boolean(), nil
+}
+
+// DecodeDateTime decodes the dateTime.iso8601 v.
+func DecodeDateTime(v Value) (time.Time, error) {
+	if err := expectKind(v, KindDateTime); err != nil {
+		return time.Time{}, err
+	}
+	return v.DateTime()
+}
+
+// DecodeBase64 decodes the base64 v.
+func DecodeBase64(v Value) ([]byte, error) {
+	if err := expectKind(v, KindBase64); err != nil {
+		return nil, err
+	}
+	return v.Base64()
+}
+
+// DecodeStruct returns the members of the struct v.
+func DecodeStruct(v Value) ([]Member, error) {
+	if err := expectKind(v, KindStruct); err != nil {
+		return nil, err
+	}
+	return v.Members, nil
+}
+
+// DecodeArray returns the items of the array v.
+func DecodeArray(v Value) ([]Value, error) {
+	if err := expectKind(v, KindArray); err != nil {
+		return nil, err
+	}
+	return v.Items, nil
+}
@@ -0,0 +1,36 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml_test
+
+import (
+	"time"
+
+	"github.com/AlexStocks/gorilla-xmlrpc/xml"
+)
+
+// StaticPost is given methods by xmlrpc-gen, in
+// static_fixture_xmlrpc_test.go.
+//
+//xmlrpc:generate
+type StaticPost struct {
+	ID       int64 `xml:"post_id"`
+	Title    string
+	Score    float64
+	Draft    bool
+	Created  time.Time
+	Cover    []byte
+	Tags     []string
+	Grid     [][]int
+	Author   StaticAuthor
+	Comments []StaticAuthor
+	Extra    xml.Value
+	internal int
+}
+
+//xmlrpc:generate
+type StaticAuthor struct {
+	Name string
+	Age  int
+}
@@ -0,0 +1,235 @@
+// Code generated by xmlrpc-gen from static_fixture_test.go. DO NOT EDIT.
+
+package xml_test
+
+import "github.com/AlexStocks/gorilla-xmlrpc/xml"
+
+// XMLRPCEncode implements xml.StaticEncoder.
+func (x StaticPost) XMLRPCEncode(b []byte) ([]byte, error) {
+	var err error
+	b = append(b, "<value><struct>"...)
+	b = append(b, "<member><name>post_id</name>"...)
+	b = xml.AppendInt(b, int64(x.ID))
+	b = append(b, "</member>"...)
+	b = append(b, "<member><name>Title</name>"...)
+	b = xml.AppendString(b, x.Title)
+	b = append(b, "</member>"...)
+	b = append(b, "<member><name>Score</name>"...)
+	b = xml.AppendDouble(b, x.Score)
+	b = append(b, "</member>"...)
+	b = append(b, "<member><name>Draft</name>"...)
+	b = xml.AppendBoolean(b, x.Draft)
+	b = append(b, "</member>"...)
+	b = append(b, "<member><name>Created</name>"...)
+	b = xml.AppendDateTime(b, x.Created)
+	b = append(b, "</member>"...)
+	b = append(b, "<member><name>Cover</name>"...)
+	b = xml.AppendBase64(b, x.Cover)
+	b = append(b, "</member>"...)
+	b = append(b, "<member><name>Tags</name>"...)
+	b = append(b, "<value><array><data>"...)
+	for _, item0 := range x.Tags {
+		b = xml.AppendString(b, item0)
+	}
+	b = append(b, "</data></array></value>"...)
+	b = append(b, "</member>"...)
+	b = append(b, "<member><name>Grid</name>"...)
+	b = append(b, "<value><array><data>"...)
+	for _, item0 := range x.Grid {
+		b = append(b, "<value><array><data>"...)
+		for _, item1 := range item0 {
+			b = xml.AppendInt(b, int64(item1))
+		}
+		b = append(b, "</data></array></value>"...)
+	}
+	b = append(b, "</data></array></value>"...)
+	b = append(b, "</member>"...)
+	b = append(b, "<member><name>Author</name>"...)
+	if b, err = x.Author.XMLRPCEncode(b); err != nil {
+		return b, err
+	}
+	b = append(b, "</member>"...)
+	b = append(b, "<member><name>Comments</name>"...)
+	b = append(b, "<value><array><data>"...)
+	for _, item0 := range x.Comments {
+		if b, err = item0.XMLRPCEncode(b); err != nil {
+			return b, err
+		}
+	}
+	b = append(b, "</data></array></value>"...)
+	b = append(b, "</member>"...)
+	b = append(b, "<member><name>Extra</name>"...)
+	b = xml.AppendValue(b, x.Extra)
+	b = append(b, "</member>"...)
+	return append(b, "</struct></value>"...), nil
+}
+
+// XMLRPCDecode implements xml.StaticDecoder.
+func (x *StaticPost) XMLRPCDecode(v xml.Value) error {
+	members, err := xml.DecodeStruct(v)
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		if m.Value.Kind == xml.KindNil {
+			continue
+		}
+		switch m.Name {
+		case "post_id", "iD", "ID":
+			{
+				d, err := xml.DecodeInt(m.Value)
+				if err != nil {
+					return err
+				}
+				x.ID = int64(d)
+			}
+		case "Title", "title":
+			{
+				d, err := xml.DecodeString(m.Value)
+				if err != nil {
+					return err
+				}
+				x.Title = d
+			}
+		case "Score", "score":
+			{
+				d, err := xml.DecodeDouble(m.Value)
+				if err != nil {
+					return err
+				}
+				x.Score = d
+			}
+		case "Draft", "draft":
+			{
+				d, err := xml.DecodeBoolean(m.Value)
+				if err != nil {
+					return err
+				}
+				x.Draft = d
+			}
+		case "Created", "created":
+			{
+				d, err := xml.DecodeDateTime(m.Value)
+				if err != nil {
+					return err
+				}
+				x.Created = d
+			}
+		case "Cover", "cover":
+			{
+				d, err := xml.DecodeBase64(m.Value)
+				if err != nil {
+					return err
+				}
+				x.Cover = d
+			}
+		case "Tags", "tags":
+			{
+				items0, err := xml.DecodeArray(m.Value)
+				if err != nil {
+					return err
+				}
+				x.Tags = make([]string, len(items0))
+				for i0, item0 := range items0 {
+					{
+						d, err := xml.DecodeString(item0)
+						if err != nil {
+							return err
+						}
+						x.Tags[i0] = d
+					}
+				}
+			}
+		case "Grid", "grid":
+			{
+				items0, err := xml.DecodeArray(m.Value)
+				if err != nil {
+					return err
+				}
+				x.Grid = make([][]int, len(items0))
+				for i0, item0 := range items0 {
+					{
+						items1, err := xml.DecodeArray(item0)
+						if err != nil {
+							return err
+						}
+						x.Grid[i0] = make([]int, len(items1))
+						for i1, item1 := range items1 {
+							{
+								d, err := xml.DecodeInt(item1)
+								if err != nil {
+									return err
+								}
+								x.Grid[i0][i1] = int(d)
+							}
+						}
+					}
+				}
+			}
+		case "Author", "author":
+			if err := x.Author.XMLRPCDecode(m.Value); err != nil {
+				return err
+			}
+		case "Comments", "comments":
+			{
+				items0, err := xml.DecodeArray(m.Value)
+				if err != nil {
+					return err
+				}
+				x.Comments = make([]StaticAuthor, len(items0))
+				for i0, item0 := range items0 {
+					if err := x.Comments[i0].XMLRPCDecode(item0); err != nil {
+						return err
+					}
+				}
+			}
+		case "Extra", "extra":
+			x.Extra = m.Value
+		}
+	}
+	return nil
+}
+
+// XMLRPCEncode implements xml.StaticEncoder.
+func (x StaticAuthor) XMLRPCEncode(b []byte) ([]byte, error) {
+	b = append(b, "<value><struct>"...)
+	b = append(b, "<member><name>Name</name>"...)
+	b = xml.AppendString(b, x.Name)
+	b = append(b, "</member>"...)
+	b = append(b, "<member><name>Age</name>"...)
+	b = xml.AppendInt(b, int64(x.Age))
+	b = append(b, "</member>"...)
+	return append(b, "</struct></value>"...), nil
+}
+
+// XMLRPCDecode implements xml.StaticDecoder.
+func (x *StaticAuthor) XMLRPCDecode(v xml.Value) error {
+	members, err := xml.DecodeStruct(v)
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		if m.Value.Kind == xml.KindNil {
+			continue
+		}
+		switch m.Name {
+		case "Name", "name":
+			{
+				d, err := xml.DecodeString(m.Value)
+				if err != nil {
+					return err
+				}
+				x.Name = d
+			}
+		case "Age", "age":
+			{
+				d, err := xml.DecodeInt(m.Value)
+				if err != nil {
+					return err
+				}
+				x.Age = int(d)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/AlexStocks/gorilla-xmlrpc/xml"
+)
+
+func staticPost() StaticPost {
+	return StaticPost{
+		ID:       7,
+		Title:    `"Hello" <world> & co`,
+		Score:    2.5,
+		Draft:    true,
+		Created:  time.Date(2021, 3, 4, 5, 6, 7, 0, time.Local),
+		Cover:    []byte{1, 2, 3},
+		Tags:     []string{"a", "b"},
+		Grid:     [][]int{{1, 2}, {3}},
+		Author:   StaticAuthor{Name: "Ann", Age: 30},
+		Comments: []StaticAuthor{{Name: "Bob", Age: 40}},
+		Extra:    xml.NewStruct(xml.Member{Name: "k", Value: xml.NewInt(1)}),
+	}
+}
+
+func TestStaticRoundTrip(t *testing.T) {
+	if err := xml.RoundTrip(staticPost()); err != nil {
+		t.Error("Expected the generated methods to round trip, got", err)
+	}
+}
+
+func TestStaticMatchesReflection(t *testing.T) {
+	reflective := struct {
+		Name string
+		Age  int
+	}{"Ann", 30}
+	var expected, encoded bytes.Buffer
+	xml.RPC2XML(reflective, &expected)
+	xml.RPC2XML(StaticAuthor{Name: "Ann", Age: 30}, &encoded)
+	if encoded.String() != expected.String() {
+		t.Errorf("Expected the generated encoding to match the reflective one:\n%s\n%s", encoded.String(), expected.String())
+	}
+}
+
+func TestStaticDecodeMismatch(t *testing.T) {
+	var author StaticAuthor
+	v := xml.NewStruct(xml.Member{Name: "age", Value: xml.NewString("old")})
+	if err := v.Decode(&author); err == nil {
+		t.Error("Expected a type mismatch error")
+	}
+	v = xml.NewStruct(xml.Member{Name: "name", Value: xml.NewString("Zed")}, xml.Member{Name: "unknown", Value: xml.NewInt(1)})
+	if err := v.Decode(&author); err != nil || author.Name != "Zed" {
+		t.Error("Expected the lowercase member to decode and the unknown one to be skipped, got", err, author)
+	}
+}
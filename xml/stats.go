@@ -0,0 +1,109 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"sync"
+	"time"
+)
+
+// ClientStats are the counters of a Client since it was created, e.g. to
+// publish with expvar.
+type ClientStats struct {
+	// Calls is the number of calls sent, Errors the number of them failing
+	// without a response, and Faults the number of fault responses by code.
+	Calls  int
+	Errors int
+	Faults map[int]int
+
+	// BytesOut and BytesIn are the sizes of the request and response bodies.
+	BytesOut int64
+	BytesIn  int64
+
+	// Retries is the number of requests sent again, by a Session after an
+	// expired token or to the Hedge endpoint.
+	Retries int
+
+	// Coalesced is the number of calls sharing the request of an identical
+	// call in progress (see Client.Coalesce).
+	Coalesced int
+
+	// InFlight is the number of calls in progress, and ConnsOpened the
+	// number of new connections the requests were sent on. Reused
+	// connections aren't counted, and ConnsOpened doesn't go down as
+	// connections close: it isn't the number of open connections.
+	InFlight    int
+	ConnsOpened int
+
+	// Latency is the histogram of the durations of the calls.
+	Latency Histogram
+}
+
+// clientStats accumulates the ClientStats of a client.
+type clientStats struct {
+	mu sync.Mutex
+	s  ClientStats
+}
+
+// Stats returns a snapshot of the counters of the client.
+func (c *Client) Stats() ClientStats {
+	c.stats.mu.Lock()
+	s := c.stats.s
+	s.Faults = make(map[int]int, len(c.stats.s.Faults))
+	for code, n := range c.stats.s.Faults {
+		s.Faults[code] = n
+	}
+	s.Latency.Buckets = append([]int(nil), s.Latency.Buckets...)
+	c.stats.mu.Unlock()
+
+	c.mu.Lock()
+	s.InFlight = len(c.cancels)
+	c.mu.Unlock()
+	return s
+}
+
+func (st *clientStats) call(out int, in int64, elapsed time.Duration, err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.s.Calls++
+	if err != nil {
+		st.s.Errors++
+	}
+	st.s.BytesOut += int64(out)
+	st.s.BytesIn += in
+	st.s.Latency.Observe(elapsed)
+}
+
+// fault counts err if it's a fault.
+func (st *clientStats) fault(err error) {
+	fault, ok := asFault(err)
+	if !ok {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.s.Faults == nil {
+		st.s.Faults = make(map[int]int)
+	}
+	st.s.Faults[fault.Code]++
+}
+
+func (st *clientStats) retry() {
+	st.mu.Lock()
+	st.s.Retries++
+	st.mu.Unlock()
+}
+
+func (st *clientStats) conn() {
+	st.mu.Lock()
+	st.s.ConnsOpened++
+	st.mu.Unlock()
+}
+
+func (st *clientStats) coalesce() {
+	st.mu.Lock()
+	st.s.Coalesced++
+	st.mu.Unlock()
+}
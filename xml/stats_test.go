@@ -0,0 +1,75 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestClientStats(t *testing.T) {
+	ts := httptest.NewServer(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		if method == "fail" {
+			return nil, FaultInvalidParams
+		}
+		return params, nil
+	}))
+	client := NewClient(ts.URL)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.CallValues("echo", NewString("hello"))
+		}()
+	}
+	wg.Wait()
+	opened := client.Stats().ConnsOpened
+	client.CallValues("echo")
+	if n := client.Stats().ConnsOpened; n != opened {
+		t.Errorf("Expected the idle connection to be reused, got %d connections opened after %d", n, opened)
+	}
+	client.CallValues("fail")
+	var reply struct{ S string }
+	client.Call("fail", &struct{}{}, &reply)
+	ts.Close()
+	client.CallValues("echo")
+
+	s := client.Stats()
+	if s.Calls != 9 || s.Errors != 1 {
+		t.Errorf("Expected 9 calls and 1 error, got %d and %d", s.Calls, s.Errors)
+	}
+	if len(s.Faults) != 1 || s.Faults[FaultInvalidParams.Code] != 2 {
+		t.Errorf("Expected 2 invalid params faults, got %v", s.Faults)
+	}
+	if s.BytesOut == 0 || s.BytesIn == 0 || s.ConnsOpened == 0 || s.InFlight != 0 {
+		t.Errorf("Expected the bytes and connections counted, got %+v", s)
+	}
+	if s.Latency.Count != 9 || s.Latency.Percentile(99) == 0 {
+		t.Errorf("Expected 9 latencies, got %+v", s.Latency)
+	}
+
+	// the snapshot doesn't change with the client
+	s.Faults[0] = 1
+	if _, ok := client.Stats().Faults[0]; ok {
+		t.Error("Expected the snapshot to be a copy")
+	}
+}
+
+func TestClientStatsRetries(t *testing.T) {
+	srv := &tokenServer{}
+	session, done := newTestSession(srv)
+	defer done()
+
+	session.Call("echo", nil, 1)
+	srv.current++
+	session.Call("echo", nil, 2)
+	if n := session.Client.Stats().Retries; n != 1 {
+		t.Errorf("Expected 1 retry, got %d", n)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+)
+
+// CallFunc serves a call from its method and params, as a MethodHandler but
+// with no HTTP request. A returned Fault is sent as is, other errors as
+// application error faults.
+type CallFunc func(method string, params []Value) ([]Value, error)
+
+// response returns the response document of the call document data.
+func (f CallFunc) response(data []byte) []byte {
+	method, params, err := ParseMethodCall(data)
+	if err != nil {
+		err = FaultDecode
+	} else {
+		params, err = f(method, params)
+	}
+	if err != nil {
+		fault, ok := asFault(err)
+		if !ok {
+			fault = FaultApplicationError
+			fault.String += fmt.Sprintf(": %v", err)
+		}
+		return fault.document()
+	}
+	return EncodeMethodResponse(params)
+}
+
+// ServeCGI serves the call of a CGI request, read from stdin, with f,
+// writing the response to stdout, so a tiny utility can expose XML-RPC
+// behind any web server without an HTTP stack of its own:
+//
+//	func main() {
+//		if err := xml.ServeCGI(serve); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+func ServeCGI(f CallFunc) error {
+	return serveCGI(os.Getenv, os.Stdin, os.Stdout, f)
+}
+
+func serveCGI(getenv func(string) string, r io.Reader, w io.Writer, f CallFunc) error {
+	if method := getenv("REQUEST_METHOD"); method != "" && method != "POST" {
+		_, err := fmt.Fprintf(w, "Status: 405 Method Not Allowed\r\nAllow: POST\r\nContent-Type: text/plain\r\n\r\n")
+		return err
+	}
+	if length, err := strconv.ParseInt(getenv("CONTENT_LENGTH"), 10, 64); err == nil {
+		r = io.LimitReader(r, length)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	resp := f.response(data)
+	if _, err := fmt.Fprintf(w, "Content-Type: text/xml; charset=utf-8\r\nContent-Length: %d\r\n\r\n", len(resp)); err != nil {
+		return err
+	}
+	_, err = w.Write(resp)
+	return err
+}
+
+// ServeStream serves with f the calls read one after another from r,
+// writing every response to w as soon as its call is read, until r is
+// exhausted. The documents are delimited by the end of their root element,
+// so the peer needn't close its side between the calls. It's the inetd
+// mode, where r and w are the connection, as stdin and stdout:
+//
+//	xml.ServeStream(os.Stdin, os.Stdout, serve)
+//
+// A document that isn't well-formed is answered with a parsing fault, and
+// ends the stream, which can't be resynchronized.
+func ServeStream(r io.Reader, w io.Writer, f CallFunc) error {
+	var read bytes.Buffer
+	d := xml.NewDecoder(io.TeeReader(r, &read))
+	d.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		// the documents are only delimited, and decoded by ParseMethodCall
+		return input, nil
+	}
+
+	var (
+		depth int
+		base  int64 // the offset in the stream of read, and the document
+	)
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF && depth == 0 {
+			return nil
+		}
+		if err != nil {
+			_, werr := w.Write(FaultDecode.document())
+			if werr != nil {
+				return werr
+			}
+			return err
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth > 0 {
+				continue
+			}
+			end := d.InputOffset()
+			if _, err := w.Write(f.response(read.Next(int(end - base)))); err != nil {
+				return err
+			}
+			base = end
+		}
+	}
+}
+
+// document returns the response document of the fault.
+func (f Fault) document() []byte {
+	var buffer bytes.Buffer
+	Fault2XML(f, &buffer)
+	return buffer.Bytes()
+}
@@ -0,0 +1,97 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func echoCall(method string, params []Value) ([]Value, error) {
+	if method == "fail" {
+		return nil, errors.New("failed")
+	}
+	return params, nil
+}
+
+func TestServeCGI(t *testing.T) {
+	call := string(EncodeMethodCall("echo", []Value{NewInt(42)}))
+	env := map[string]string{"REQUEST_METHOD": "POST", "CONTENT_LENGTH": strconv.Itoa(len(call))}
+	getenv := func(key string) string { return env[key] }
+
+	var out bytes.Buffer
+	if err := serveCGI(getenv, strings.NewReader(call+"trailing garbage"), &out, echoCall); err != nil {
+		t.Fatal(err)
+	}
+	resp := string(EncodeMethodResponse([]Value{NewInt(42)}))
+	expected := "Content-Type: text/xml; charset=utf-8\r\nContent-Length: " + strconv.Itoa(len(resp)) + "\r\n\r\n" + resp
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+
+	out.Reset()
+	env["REQUEST_METHOD"] = "GET"
+	serveCGI(getenv, strings.NewReader(""), &out, echoCall)
+	if !strings.HasPrefix(out.String(), "Status: 405 ") {
+		t.Errorf("Expected 405, got %q", out.String())
+	}
+}
+
+func TestServeStream(t *testing.T) {
+	in, client := io.Pipe()
+	out, server := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- ServeStream(in, server, echoCall)
+		server.Close()
+	}()
+	responses := bufio.NewReader(out)
+	readResponse := func() string {
+		resp, err := responses.ReadString('>')
+		for err == nil && !strings.HasSuffix(resp, "</methodResponse>") {
+			var more string
+			more, err = responses.ReadString('>')
+			resp += more
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// every response comes before the next call is sent
+	client.Write([]byte(`<?xml version="1.0"?>` + "\n" + string(EncodeMethodCall("echo", []Value{NewString("a")})) + "\n"))
+	if resp := readResponse(); resp != string(EncodeMethodResponse([]Value{NewString("a")})) {
+		t.Errorf("Unexpected response %s", resp)
+	}
+	client.Write(EncodeMethodCall("fail", nil))
+	if resp := readResponse(); !strings.Contains(resp, "Application Error: failed") {
+		t.Errorf("Unexpected response %s", resp)
+	}
+	client.Write(EncodeMethodCall("echo", []Value{NewInt(1), NewInt(2)}))
+	if resp := readResponse(); resp != string(EncodeMethodResponse([]Value{NewInt(1), NewInt(2)})) {
+		t.Errorf("Unexpected response %s", resp)
+	}
+	client.Close()
+	if err := <-done; err != nil {
+		t.Error("Expected the stream served, got", err)
+	}
+}
+
+func TestServeStreamMalformed(t *testing.T) {
+	var out bytes.Buffer
+	err := ServeStream(strings.NewReader("<methodCall><methodName>x</methodCall>"), &out, echoCall)
+	if err == nil {
+		t.Error("Expected the stream ended")
+	}
+	if !strings.Contains(out.String(), "Parsing error") {
+		t.Errorf("Expected a parsing fault, got %s", out.String())
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// systemdFirstFD is the first file descriptor passed by systemd.
+const systemdFirstFD = 3
+
+// SystemdListeners returns the sockets passed by systemd socket activation,
+// as told by the LISTEN_PID, LISTEN_FDS and LISTEN_FDNAMES variables, in
+// order, or none if the process isn't socket activated. The variables are
+// unset, so the child processes don't take the sockets for theirs.
+func SystemdListeners() ([]net.Listener, error) {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	return systemdListeners(os.Getenv, os.Getpid(), systemdFirstFD)
+}
+
+func systemdListeners(getenv func(string) string, pid, firstFD int) ([]net.Listener, error) {
+	if listenPID, err := strconv.Atoi(getenv("LISTEN_PID")); err != nil || listenPID != pid {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+	names := strings.Split(getenv("LISTEN_FDNAMES"), ":")
+
+	listeners := make([]net.Listener, 0, n)
+	for i := 0; i < n; i++ {
+		name := "LISTEN_FD_" + strconv.Itoa(firstFD+i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(firstFD+i), name)
+		l, err := net.FileListener(f)
+		// the listener has its own descriptor
+		f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("xmlrpc: systemd socket %s: %v", name, err)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// ListenAndServeSystemd serves handler, e.g. an rpc.Server with the XML-RPC
+// codec, on the sockets passed by systemd socket activation, or on the TCP
+// address addr if the process isn't socket activated, so a daemon runs the
+// same from a .socket unit or by hand:
+//
+//	log.Fatal(xml.ListenAndServeSystemd(":9001", s))
+//
+// It returns when serving any socket fails.
+func ListenAndServeSystemd(addr string, handler http.Handler) error {
+	listeners, err := SystemdListeners()
+	if err != nil {
+		return err
+	}
+	if len(listeners) == 0 {
+		return http.ListenAndServe(addr, handler)
+	}
+	return serveListeners(&http.Server{Handler: handler}, listeners)
+}
+
+// serveListeners serves server on every listener, until serving one fails.
+func serveListeners(server *http.Server, listeners []net.Listener) error {
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			errs <- server.Serve(l)
+		}(l)
+	}
+	err := <-errs
+	server.Close()
+	return err
+}
@@ -0,0 +1,63 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !windows
+// +build !windows
+
+package xml
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestSystemdListeners(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	f, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	// a descriptor of its own, closed by systemdListeners
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"LISTEN_PID": "42", "LISTEN_FDS": "1", "LISTEN_FDNAMES": "rpc"}
+	getenv := func(key string) string { return env[key] }
+
+	if listeners, err := systemdListeners(getenv, 7, fd); err != nil || len(listeners) != 0 {
+		t.Errorf("Expected no sockets for another process, got %v, %v", listeners, err)
+	}
+
+	listeners, err := systemdListeners(getenv, 42, fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listeners) != 1 || listeners[0].Addr().String() != l.Addr().String() {
+		t.Fatalf("Expected the socket passed, got %v", listeners)
+	}
+
+	server := &http.Server{Handler: EchoHandler}
+	done := make(chan error, 1)
+	go func() { done <- serveListeners(server, listeners) }()
+	res, err := NewClient("http://"+l.Addr().String()).CallValues("echo", NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0].Text != strconv.Itoa(7) {
+		t.Errorf("Expected the echo, got %v", res)
+	}
+	listeners[0].Close()
+	if err := <-done; err == nil {
+		t.Error("Expected the serving ended")
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"reflect"
+	"strings"
+)
+
+// tagOptions are the comma-separated options following the name in a
+// `xmlrpc:"name,opt1,key=value"` struct tag.
+type tagOptions string
+
+// parseTag splits the xmlrpc tag of field into the name and the options.
+func parseTag(field reflect.StructField) (string, tagOptions) {
+	tag := field.Tag.Get("xmlrpc")
+	if i := strings.Index(tag, ","); i >= 0 {
+		return tag[:i], tagOptions(tag[i+1:])
+	}
+	return tag, ""
+}
+
+// Contains reports whether the option name is present.
+func (o tagOptions) Contains(name string) bool {
+	_, ok := o.Get(name)
+	return ok
+}
+
+// Get returns the value of the option "key=value", or "" for a plain "key"
+// option, and reports whether the option is present.
+func (o tagOptions) Get(key string) (string, bool) {
+	s := string(o)
+	for s != "" {
+		var opt string
+		if i := strings.Index(s, ","); i >= 0 {
+			opt, s = s[:i], s[i+1:]
+		} else {
+			opt, s = s, ""
+		}
+		if opt == key {
+			return "", true
+		}
+		if strings.HasPrefix(opt, key+"=") {
+			return opt[len(key)+1:], true
+		}
+	}
+	return "", false
+}
+
+// cut splits off the option "key=value" ending the options, whose value may
+// contain commas, and returns the options before it.
+func (o tagOptions) cut(key string) (tagOptions, string, bool) {
+	s := string(o)
+	if strings.HasPrefix(s, key+"=") {
+		return "", s[len(key)+1:], true
+	}
+	if i := strings.Index(s, ","+key+"="); i >= 0 {
+		return tagOptions(s[:i]), s[i+len(key)+2:], true
+	}
+	return o, "", false
+}
+
+// tagName returns the name of the xmlrpc tag of field, as in
+// `xmlrpc:"user_id"`, or "" if none is given.
+func tagName(field reflect.StructField) string {
+	if name, _ := parseTag(field); name != "-" {
+		return name
+	}
+	return ""
+}
+
+// explicitName returns the member name given to field by its tags: the name
+// of the xmlrpc tag, or else of the xml tag, or "" if none is given.
+func explicitName(field reflect.StructField) string {
+	if name := tagName(field); name != "" {
+		return name
+	}
+	return field.Tag.Get("xml")
+}
+
+// isSkipped reports whether field is left out of the struct members and
+// tuple items, on encoding and decoding, as tagged with `xmlrpc:"-"`.
+func isSkipped(field reflect.StructField) bool {
+	return field.Tag.Get("xmlrpc") == "-"
+}
+
+// skipEncode reports whether field is left out of the encoded struct
+// members: skipped, or decode-only as tagged with `xmlrpc:",decodeonly"`.
+func skipEncode(field reflect.StructField) bool {
+	_, opts := parseTag(field)
+	return isSkipped(field) || opts.Contains("decodeonly")
+}
+
+// skipDecode reports whether field is never decoded: skipped, or
+// encode-only as tagged with `xmlrpc:",encodeonly"`, e.g. a password sent
+// to a service but never filled from its responses.
+func skipDecode(field reflect.StructField) bool {
+	_, opts := parseTag(field)
+	return isSkipped(field) || opts.Contains("encodeonly")
+}
+
+// jsonName returns the name of the json tag of field, if any.
+func jsonName(field reflect.StructField) string {
+	name := field.Tag.Get("json")
+	if i := strings.Index(name, ","); i >= 0 {
+		name = name[:i]
+	}
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// isVariadic reports whether field is a slice spread over the remaining
+// params, as tagged with `xmlrpc:",variadic"`.
+func isVariadic(field reflect.StructField) bool {
+	_, opts := parseTag(field)
+	return opts.Contains("variadic") && field.Type.Kind() == reflect.Slice
+}
@@ -0,0 +1,221 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type skippedAccount struct {
+	Login    string
+	Password string `xmlrpc:"-"`
+	Display  string `xmlrpc:"-"`
+}
+
+type namedAccount struct {
+	UserID int    `xmlrpc:"user_id"`
+	Email  string `xmlrpc:"e_mail,required"`
+	Login  string `xml:"login"`
+}
+
+func TestTagNames(t *testing.T) {
+	account := namedAccount{7, "ivan@example.com", "ivan"}
+	for _, opts := range []*Options{{}, {MemberNames: strings.ToUpper}} {
+		var buffer bytes.Buffer
+		if err := rpcParams2XML(&struct{ A namedAccount }{account}, &buffer, opts); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"<name>user_id</name>", "<name>e_mail</name>", "<name>login</name>"} {
+			if !strings.Contains(buffer.String(), name) {
+				t.Errorf("Expected %s, got %s", name, buffer.String())
+			}
+		}
+
+		var reply struct{ A namedAccount }
+		if err := decodeRPC(toResponse(buffer.String()), &reply, opts); err != nil {
+			t.Fatal(err)
+		}
+		if reply.A != account {
+			t.Errorf("Expected %+v, got %+v", account, reply.A)
+		}
+		if issues := Validate([]byte(toResponse(buffer.String())), &reply); len(issues) != 0 {
+			t.Errorf("Expected no issues, got %v", issues)
+		}
+	}
+
+	codec := Compile(namedAccount{})
+	data, err := codec.Encode(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("<name>user_id</name>")) || !bytes.Contains(data, []byte("<name>e_mail</name>")) {
+		t.Errorf("Expected the compiled codec to use the tag names, got %s", data)
+	}
+}
+
+func TestSkippedFieldsEncode(t *testing.T) {
+	account := skippedAccount{Login: "l", Password: "p", Display: "d"}
+	for _, opts := range []*Options{{}, {JSONCompat: true}} {
+		var buffer bytes.Buffer
+		if err := rpcParams2XML(&struct{ A skippedAccount }{account}, &buffer, opts); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(buffer.String(), "Password") || strings.Contains(buffer.String(), "Display") {
+			t.Errorf("Expected the skipped fields left out, got %s", buffer.String())
+		}
+		if !strings.Contains(buffer.String(), "<name>Login</name>") {
+			t.Errorf("Expected the login encoded, got %s", buffer.String())
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := rpcParams2XML(&struct {
+		T skippedAccount `xmlrpc:",tuple"`
+	}{account}, &buffer, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<params><param><value><array><data><value><string>l</string></value></data></array></value></param></params>"; buffer.String() != want {
+		t.Errorf("Expected %s, got %s", want, buffer.String())
+	}
+}
+
+func TestSkippedFieldsDecode(t *testing.T) {
+	resp := toResponse(`<params><param><value><struct>
+<member><name>Login</name><value><string>l</string></value></member>
+<member><name>Password</name><value><string>p</string></value></member>
+<member><name>display</name><value><string>d</string></value></member>
+</struct></value></param></params>`)
+	for _, opts := range []*Options{{}, {JSONCompat: true}, {MemberNames: SnakeCase}} {
+		var reply struct{ A skippedAccount }
+		if err := decodeRPC(resp, &reply, opts); err != nil {
+			t.Fatal(err)
+		}
+		if reply.A.Login != "l" || reply.A.Password != "" || reply.A.Display != "" {
+			t.Errorf("Expected the skipped fields untouched, got %+v", reply.A)
+		}
+	}
+	if issues := Validate([]byte(resp), &struct{ A skippedAccount }{}); len(issues) != 0 {
+		t.Errorf("Expected no issues, got %v", issues)
+	}
+}
+
+func TestSkippedParams(t *testing.T) {
+	args := struct {
+		Secret string `xmlrpc:"-"`
+		Name   string
+	}{"pw", "n"}
+	var buffer bytes.Buffer
+	if err := rpcParams2XML(&args, &buffer, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<params><param><value><string>n</string></value></param></params>"; buffer.String() != want {
+		t.Errorf("Expected %s, got %s", want, buffer.String())
+	}
+
+	var reply struct {
+		Secret string `xmlrpc:"-"`
+		Name   string
+		Age    int `default:"7"`
+	}
+	resp := toResponse(`<params><param><value><string>injected</string></value></param></params>`)
+	if err := decodeRPC(resp, &reply, &Options{StrictParams: true}); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Secret != "" || reply.Name != "injected" || reply.Age != 7 {
+		t.Errorf("Expected the skipped field taking no param, got %+v", reply)
+	}
+	if issues := Validate([]byte(resp), &reply); len(issues) != 0 {
+		t.Errorf("Expected no issues, got %v", issues)
+	}
+}
+
+func TestDirectedParams(t *testing.T) {
+	args := struct {
+		Password string `xmlrpc:",encodeonly"`
+		LastSeen string `xmlrpc:",decodeonly"`
+		Login    string
+	}{"p", "yesterday", "l"}
+	var buffer bytes.Buffer
+	if err := rpcParams2XML(&args, &buffer, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "<params><param><value><string>p</string></value></param>" +
+		"<param><value><string></string></value></param>" +
+		"<param><value><string>l</string></value></param></params>"
+	if buffer.String() != want {
+		t.Errorf("Expected %s, got %s", want, buffer.String())
+	}
+
+	reply := args
+	reply.Password = ""
+	resp := toResponse(`<params><param><value><string>leaked</string></value></param>` +
+		`<param><value><string>today</string></value></param>` +
+		`<param><value><string>m</string></value></param></params>`)
+	if err := decodeRPC(resp, &reply, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Password != "" || reply.LastSeen != "today" || reply.Login != "m" {
+		t.Errorf("Expected the encode-only param consumed unread, got %+v", reply)
+	}
+}
+
+type directedAccount struct {
+	Login    string
+	Password string `xmlrpc:",encodeonly"`
+	LastSeen string `xmlrpc:",decodeonly"`
+}
+
+func TestEncodeOnlyDecodeOnly(t *testing.T) {
+	account := directedAccount{Login: "l", Password: "p", LastSeen: "yesterday"}
+	for _, opts := range []*Options{{}, {JSONCompat: true}} {
+		var buffer bytes.Buffer
+		if err := rpcParams2XML(&struct{ A directedAccount }{account}, &buffer, opts); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buffer.String(), "<name>Password</name>") || strings.Contains(buffer.String(), "LastSeen") {
+			t.Errorf("Expected the password sent and not the last seen, got %s", buffer.String())
+		}
+	}
+
+	resp := toResponse(`<params><param><value><struct>
+<member><name>Login</name><value><string>l</string></value></member>
+<member><name>Password</name><value><string>p</string></value></member>
+<member><name>LastSeen</name><value><string>today</string></value></member>
+</struct></value></param></params>`)
+	for _, opts := range []*Options{{}, {JSONCompat: true}} {
+		var reply struct{ A directedAccount }
+		if err := decodeRPC(resp, &reply, opts); err != nil {
+			t.Fatal(err)
+		}
+		if reply.A.Password != "" || reply.A.LastSeen != "today" {
+			t.Errorf("Expected the last seen decoded and not the password, got %+v", reply.A)
+		}
+	}
+}
+
+func TestEncodeOnlyDecodeOnlyTuple(t *testing.T) {
+	type tuple struct {
+		A directedAccount `xmlrpc:",tuple"`
+	}
+	var buffer bytes.Buffer
+	if err := rpcParams2XML(&tuple{directedAccount{"l", "p", "yesterday"}}, &buffer, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "<params><param><value><array><data><value><string>l</string></value>" +
+		"<value><string>p</string></value><value><string></string></value></data></array></value></param></params>"
+	if buffer.String() != want {
+		t.Errorf("Expected %s, got %s", want, buffer.String())
+	}
+
+	var reply tuple
+	if err := decodeRPC(toResponse(strings.Replace(buffer.String(), "<string></string>", "<string>now</string>", 1)), &reply, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if reply.A.Login != "l" || reply.A.Password != "" || reply.A.LastSeen != "now" {
+		t.Errorf("Expected the password not decoded, got %+v", reply.A)
+	}
+}
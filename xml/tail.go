@@ -0,0 +1,152 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Defaults of a TailReader.
+const (
+	DefaultTailChunkSize = 64 << 10
+	DefaultTailInterval  = time.Second
+)
+
+// TailChunk is the reply of the offset-based tail convention of e.g.
+// supervisor.tailProcessStdoutLog: the bytes of the log from the offset
+// requested, the offset to request next, and whether more bytes than
+// requested were available, so the bytes skipped some.
+type TailChunk struct {
+	Bytes    string
+	Offset   int
+	Overflow bool
+}
+
+type tailReply struct {
+	Chunk TailChunk `xmlrpc:",tuple"`
+}
+
+// TailReader is a log tailed with the offset-based tail convention, read
+// as a stream. The method is only called when the stream is read and its
+// buffer is empty, so a slow reader doesn't make the server send more than
+// it reads. A TailReader may be closed while being read, but not read
+// concurrently.
+type TailReader struct {
+	// ChunkSize is the length requested per call, DefaultTailChunkSize if
+	// zero.
+	ChunkSize int
+
+	// Interval is the time waited before calling the method again when the
+	// log has no new bytes, DefaultTailInterval if zero.
+	Interval time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	method *BoundMethod
+	buf    []byte
+
+	mu        sync.Mutex
+	offset    int
+	overflows int
+}
+
+// TailLog returns a reader streaming the log tailed by calling method with
+// the constant leading params args, e.g. a process name, followed by the
+// offset and length, as supervisor.tailProcessStdoutLog:
+//
+//	r, err := client.TailLog(ctx, "supervisor.tailProcessStdoutLog", 0, "worker")
+//	io.Copy(os.Stdout, r)
+//
+// The stream starts at offset, from where the method returns the log. Read
+// blocks until the log has new bytes; it returns io.EOF once ctx is done or
+// the reader closed.
+func (c *Client) TailLog(ctx context.Context, method string, offset int, args ...interface{}) (*TailReader, error) {
+	m, err := c.Bind(method, args...)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &TailReader{ctx: ctx, cancel: cancel, method: m, offset: offset}, nil
+}
+
+// Read reads the next bytes of the log.
+func (t *TailReader) Read(p []byte) (int, error) {
+	for len(t.buf) == 0 {
+		if err := t.poll(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, t.buf)
+	t.buf = t.buf[n:]
+	return n, nil
+}
+
+// poll calls the method once, waiting for the interval first if the log
+// has no new bytes.
+func (t *TailReader) poll() error {
+	if t.ctx.Err() != nil {
+		return io.EOF
+	}
+	size := t.ChunkSize
+	if size <= 0 {
+		size = DefaultTailChunkSize
+	}
+	var reply tailReply
+	if err := t.method.CallContext(t.ctx, &reply, t.Offset(), size); err != nil {
+		if t.ctx.Err() != nil {
+			return io.EOF
+		}
+		return err
+	}
+	chunk := reply.Chunk
+	t.mu.Lock()
+	t.offset = chunk.Offset
+	if chunk.Overflow {
+		t.overflows++
+	}
+	t.mu.Unlock()
+	if chunk.Bytes != "" {
+		t.buf = []byte(chunk.Bytes)
+		return nil
+	}
+
+	interval := t.Interval
+	if interval <= 0 {
+		interval = DefaultTailInterval
+	}
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-t.ctx.Done():
+		return io.EOF
+	}
+}
+
+// Offset returns the offset of the log up to which the bytes were received.
+func (t *TailReader) Offset() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.offset
+}
+
+// Overflows returns the number of calls whose bytes skipped some of the
+// log, as it grew faster than read.
+func (t *TailReader) Overflows() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.overflows
+}
+
+// Close stops the stream: the reads in progress and the next ones return
+// io.EOF.
+func (t *TailReader) Close() error {
+	t.cancel()
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tailServer serves a growing log with the tail convention of
+// supervisor.tailProcessStdoutLog.
+type tailServer struct {
+	mu    sync.Mutex
+	log   string
+	calls int
+}
+
+func (s *tailServer) append(text string) {
+	s.mu.Lock()
+	s.log += text
+	s.mu.Unlock()
+}
+
+func (s *tailServer) handler(t *testing.T) http.Handler {
+	return MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.calls++
+		if len(params) != 3 || params[0].Text != "worker" {
+			t.Errorf("Unexpected params %v", params)
+		}
+		offset, _ := params[1].Int()
+		length, _ := params[2].Int()
+		if int(offset) > len(s.log) {
+			offset = int64(len(s.log))
+		}
+		data, overflow := s.log[offset:], false
+		if len(data) > int(length) {
+			data, overflow = data[len(data)-int(length):], true
+		}
+		return []Value{NewArray(NewString(data), NewInt(int64(len(s.log))), NewBoolean(overflow))}, nil
+	})
+}
+
+func TestTailLog(t *testing.T) {
+	s := &tailServer{log: "line 1\n"}
+	ts := httptest.NewServer(s.handler(t))
+	defer ts.Close()
+
+	r, err := NewClient(ts.URL).TailLog(context.Background(), "supervisor.tailProcessStdoutLog", 0, "worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Interval = 5 * time.Millisecond
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		s.append("line 2\n")
+		time.Sleep(20 * time.Millisecond)
+		s.append("line 3\n")
+	}()
+
+	buf := make([]byte, 21)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "line 1\nline 2\nline 3\n" {
+		t.Errorf("Unexpected stream %q", buf)
+	}
+	if r.Offset() != 21 || r.Overflows() != 0 {
+		t.Errorf("Expected offset 21 and no overflows, got %d and %d", r.Offset(), r.Overflows())
+	}
+
+	// the reads in progress are stopped
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		r.Close()
+	}()
+	if n, err := r.Read(buf); n != 0 || err != io.EOF {
+		t.Errorf("Expected io.EOF once closed, got %d, %v", n, err)
+	}
+}
+
+func TestTailLogOverflow(t *testing.T) {
+	s := &tailServer{log: strings.Repeat("x", 100) + "tail"}
+	ts := httptest.NewServer(s.handler(t))
+	defer ts.Close()
+
+	r, err := NewClient(ts.URL).TailLog(context.Background(), "supervisor.tailProcessStdoutLog", 0, "worker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.ChunkSize = 10
+
+	// nothing is read before asked for
+	time.Sleep(10 * time.Millisecond)
+	s.mu.Lock()
+	calls := s.calls
+	s.mu.Unlock()
+	if calls != 0 {
+		t.Errorf("Expected no calls before reading, got %d", calls)
+	}
+
+	buf := make([]byte, 10)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "xxxxxxtail" || r.Overflows() != 1 || r.Offset() != 104 {
+		t.Errorf("Unexpected chunk %q, %d overflows, offset %d", buf, r.Overflows(), r.Offset())
+	}
+	r.Close()
+}
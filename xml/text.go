@@ -0,0 +1,118 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+)
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	stringerType      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	urlType           = reflect.TypeOf(url.URL{})
+)
+
+// isTextType reports whether the values of typ, or of the pointers to typ,
+// are encoded as <string> values with their MarshalText method, or their
+// String method for url.URL, and decoded with UnmarshalText, or url.Parse.
+// The types the package encodes otherwise, e.g. time.Time, aren't.
+func isTextType(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == timeType || typ == bigIntType || typ == bigFloatType || typ == valueType ||
+		enumByType(typ) != nil || scalarByType(typ) != nil || scalarByType(reflect.PtrTo(typ)) != nil {
+		return false
+	}
+	return typ == urlType || typ.Implements(textMarshalerType) || reflect.PtrTo(typ).Implements(textMarshalerType)
+}
+
+// text2XML writes value as a <string> value if it's of a text type, or a
+// fmt.Stringer with the Stringers option. It reports whether value was
+// written.
+func (e *encodeState) text2XML(value interface{}, writer io.Writer) (bool, error) {
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() || rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return false, nil
+	}
+	typ := rv.Type()
+	var text string
+	switch {
+	case isTextType(typ):
+		if rv.Kind() != reflect.Ptr {
+			// the methods may have pointer receivers
+			ptr := reflect.New(typ)
+			ptr.Elem().Set(rv)
+			rv = ptr
+		}
+		if u, ok := rv.Interface().(*url.URL); ok {
+			text = u.String()
+			break
+		}
+		b, err := rv.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return true, fmt.Errorf("xmlrpc: can't encode %s: %v", typ, err)
+		}
+		text = string(b)
+	case e.opts.Stringers && typ.Implements(stringerType) && typ != timeType && typ != valueType:
+		text = value.(fmt.Stringer).String()
+	default:
+		return false, nil
+	}
+	fmt.Fprintf(writer, "<value>")
+	if e.opts.UntypedStrings {
+		fmt.Fprintf(writer, "%s", escapeString(text))
+	} else {
+		string2XML(text, writer)
+	}
+	fmt.Fprintf(writer, "</value>")
+	return true, e.check(nil)
+}
+
+// text2Field decodes a <string> or untyped value into the field of a text
+// type. It reports whether value was handled, leaving the other values to
+// value2Field.
+func text2Field(value value, field *reflect.Value) (bool, error) {
+	typ := field.Type()
+	if !isTextType(typ) {
+		return false, nil
+	}
+	text, ok := stringText(value)
+	if !ok {
+		return false, nil
+	}
+	if typ.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(typ.Elem()))
+		}
+	} else if !field.CanAddr() {
+		return false, nil
+	}
+	ptr := *field
+	if typ.Kind() != reflect.Ptr {
+		ptr = field.Addr()
+	}
+
+	if u, ok := ptr.Interface().(*url.URL); ok {
+		parsed, err := url.Parse(text)
+		if err != nil {
+			return true, invalidParams("can't decode %s: %v", typ, err)
+		}
+		*u = *parsed
+		return true, nil
+	}
+	tu, ok := ptr.Interface().(encoding.TextUnmarshaler)
+	if !ok {
+		return false, nil
+	}
+	if err := tu.UnmarshalText([]byte(text)); err != nil {
+		return true, invalidParams("can't decode %s: %v", typ, err)
+	}
+	return true, nil
+}
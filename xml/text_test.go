@@ -0,0 +1,99 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// level has its text methods on the pointer receiver.
+type level int
+
+func (l *level) MarshalText() ([]byte, error) {
+	return []byte(strings.Repeat("*", int(*l))), nil
+}
+
+func (l *level) UnmarshalText(b []byte) error {
+	if strings.Trim(string(b), "*") != "" {
+		return errors.New("not stars")
+	}
+	*l = level(len(b))
+	return nil
+}
+
+type textArgs struct {
+	IP    net.IP
+	URL   url.URL
+	Link  *url.URL
+	Level level
+	IPs   []net.IP
+}
+
+func TestTextEncode(t *testing.T) {
+	link, _ := url.Parse("https://example.com/a?b=c&d=e")
+	args := &textArgs{
+		IP:    net.ParseIP("10.0.0.1"),
+		URL:   url.URL{Scheme: "http", Host: "h"},
+		Link:  link,
+		Level: 3,
+		IPs:   []net.IP{net.ParseIP("::1")},
+	}
+	var buffer bytes.Buffer
+	if err := rpcParams2XML(args, &buffer, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "<params><param><value><string>10.0.0.1</string></value></param>" +
+		"<param><value><string>http://h</string></value></param>" +
+		"<param><value><string>https://example.com/a?b=c&amp;d=e</string></value></param>" +
+		"<param><value><string>***</string></value></param>" +
+		"<param><value><array><data><value><string>::1</string></value></data></array></value></param></params>"
+	if buffer.String() != want {
+		t.Errorf("Expected %q, got %q", want, buffer.String())
+	}
+
+	var reply textArgs
+	if err := decodeRPC(toResponse(buffer.String()), &reply, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if !reply.IP.Equal(args.IP) || reply.URL != args.URL || reply.Link.String() != link.String() ||
+		reply.Level != 3 || len(reply.IPs) != 1 || !reply.IPs[0].Equal(args.IPs[0]) {
+		t.Errorf("Expected %+v, got %+v", args, reply)
+	}
+}
+
+func TestTextDecodeErrors(t *testing.T) {
+	var reply struct{ Level level }
+	err := decodeRPC(toResponse("<params><param><value>stars</value></param></params>"), &reply, &Options{})
+	if fault, ok := err.(Fault); !ok || fault.Code != FaultInvalidParams.Code {
+		t.Error("Expected an invalid params fault, got:", err)
+	}
+}
+
+type stringer struct{ A, B int }
+
+func (s stringer) String() string { return "s" }
+
+func TestStringers(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := rpcParams2XML(&struct{ S stringer }{}, &buffer, &Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buffer.String(), "<struct>") {
+		t.Error("Expected the stringers encoded as usual by default, got", buffer.String())
+	}
+
+	buffer.Reset()
+	if err := rpcParams2XML(&struct{ S stringer }{}, &buffer, &Options{Stringers: true}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<params><param><value><string>s</string></value></param></params>"; buffer.String() != want {
+		t.Errorf("Expected %q, got %q", want, buffer.String())
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import "path"
+
+// ResponseTransformer rewrites a param of a response before it's decoded,
+// e.g. to normalize the quirks of a peer in one place.
+type ResponseTransformer func(v Value) Value
+
+// transformer is a ResponseTransformer of the methods matching pattern.
+type transformer struct {
+	pattern string
+	t       ResponseTransformer
+}
+
+// TransformResponses makes the client rewrite with t the params of the
+// responses to the methods matching pattern, as for path.Match, before
+// they're decoded or returned by CallValues. The transformers matching a
+// method are applied in the order they were added. The faults and the
+// documents read by CallReader are left as is.
+func (c *Client) TransformResponses(pattern string, t ResponseTransformer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.transformers = append(c.transformers, transformer{pattern, t})
+}
+
+// transforms returns the transformers of method.
+func (c *Client) transforms(method string) []ResponseTransformer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ts []ResponseTransformer
+	for _, t := range c.transformers {
+		if ok, _ := path.Match(t.pattern, method); ok {
+			ts = append(ts, t.t)
+		}
+	}
+	return ts
+}
+
+// transformParams applies the transformers of method to params.
+func (c *Client) transformParams(method string, params []Value) []Value {
+	for _, t := range c.transforms(method) {
+		for i := range params {
+			params[i] = t(params[i])
+		}
+	}
+	return params
+}
+
+// transformResponse applies the transformers of method to the response
+// document resp, which is returned as is if it's a fault or malformed.
+func (c *Client) transformResponse(method string, resp []byte) []byte {
+	if len(c.transforms(method)) == 0 {
+		return resp
+	}
+	params, err := ParseMethodResponse(resp)
+	if err != nil {
+		return resp
+	}
+	return EncodeMethodResponse(c.transformParams(method, params))
+}
+
+// UnwrapSingleton is a ResponseTransformer for the peers wrapping the
+// scalars in one-element arrays: such an array is replaced by its item.
+func UnwrapSingleton(v Value) Value {
+	if v.Kind == KindArray && len(v.Items) == 1 {
+		switch v.Items[0].Kind {
+		case KindArray, KindStruct:
+		default:
+			return v.Items[0]
+		}
+	}
+	return v
+}
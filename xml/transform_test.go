@@ -0,0 +1,64 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientTransformResponses(t *testing.T) {
+	ts := httptest.NewServer(MethodHandler(func(r *http.Request, method string, params []Value) ([]Value, error) {
+		if method == "fail" {
+			return nil, FaultApplicationError
+		}
+		// a peer wrapping its scalars
+		return []Value{NewArray(NewInt(7))}, nil
+	}))
+	defer ts.Close()
+	client := NewClient(ts.URL)
+	client.TransformResponses("get*", UnwrapSingleton)
+	client.TransformResponses("getDouble", func(v Value) Value {
+		n, _ := v.Int()
+		return NewInt(2 * n)
+	})
+
+	var reply struct{ N int }
+	if err := client.Call("getCount", &struct{}{}, &reply); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if reply.N != 7 {
+		t.Error("Expected the reply to be unwrapped, got", reply.N)
+	}
+	if err := client.Call("getDouble", &struct{}{}, &reply); err != nil || reply.N != 14 {
+		t.Error("Expected the transformers to be chained, got", reply.N, err)
+	}
+
+	params, err := client.CallValues("getCount")
+	if err != nil || len(params) != 1 || params[0].Kind != KindInt {
+		t.Error("Expected CallValues to be transformed, got", params, err)
+	}
+	params, err = client.CallValues("other")
+	if err != nil || len(params) != 1 || params[0].Kind != KindArray {
+		t.Error("Expected the other methods to be left as is, got", params, err)
+	}
+	err = client.Call("fail", &struct{}{}, &reply)
+	if fault, ok := err.(Fault); !ok || fault.Code != FaultApplicationError.Code {
+		t.Error("Expected the fault to be left as is, got:", err)
+	}
+}
+
+func TestUnwrapSingleton(t *testing.T) {
+	nested := NewArray(NewStruct(Member{"a", NewInt(1)}))
+	for _, v := range []Value{nested, NewArray(NewInt(1), NewInt(2)), NewString("s")} {
+		if len(Diff(v, UnwrapSingleton(v))) != 0 {
+			t.Errorf("Expected %v to be left as is", v)
+		}
+	}
+	if got := UnwrapSingleton(NewArray(NewString("s"))); got.Kind != KindString {
+		t.Error("Expected the scalar to be unwrapped, got", got)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+)
+
+// isTuple reports whether field is a struct traveling as an array of its
+// fields, as tagged with `xmlrpc:",tuple"`.
+func isTuple(field reflect.StructField) bool {
+	_, opts := parseTag(field)
+	return opts.Contains("tuple") && field.Type.Kind() == reflect.Struct
+}
+
+// tupleValue is a struct encoded as an array of its fields.
+type tupleValue struct {
+	value reflect.Value
+}
+
+// tupleFields returns the indexes of the exported fields of the struct typ,
+// but the skipped ones.
+func tupleFields(typ reflect.Type) []int {
+	var fields []int
+	for i := 0; i < typ.NumField(); i++ {
+		if typ.Field(i).PkgPath == "" && !isSkipped(typ.Field(i)) {
+			fields = append(fields, i)
+		}
+	}
+	return fields
+}
+
+// tuple2XML writes the struct rv as an array of its exported fields, in
+// order. The decode-only fields are written as their zero value, keeping the
+// positions.
+func (e *encodeState) tuple2XML(rv reflect.Value, writer io.Writer) error {
+	var err error
+	fmt.Fprintf(writer, "<value><array><data>")
+	for _, i := range tupleFields(rv.Type()) {
+		f := rv.Field(i)
+		if skipEncode(rv.Type().Field(i)) {
+			f = reflect.Zero(f.Type())
+		}
+		var ferr error
+		if names := enumNames(rv.Type().Field(i)); names != nil {
+			ferr = enum2XML(names, f, writer)
+		} else if epoch, double := isEpoch(rv.Type().Field(i)); epoch {
+			epoch2XML(f.Interface().(time.Time), double, writer)
+		} else {
+			ferr = e.value2XML(f.Interface(), writer)
+		}
+		if err == nil {
+			err = ferr
+		}
+	}
+	fmt.Fprintf(writer, "</data></array></value>")
+	return err
+}
+
+// tuple2Field decodes the items of the array value into the exported fields
+// of the struct field, in order. The number of items must match; the items of
+// the encode-only fields are ignored.
+func tuple2Field(value value, field *reflect.Value, opts *Options) error {
+	fields := tupleFields(field.Type())
+	if len(value.Array) != len(fields) {
+		return invalidParams("tuple length mismatch: %d items for %s", len(value.Array), field.Type())
+	}
+	for j, i := range fields {
+		if skipDecode(field.Type().Field(i)) {
+			continue
+		}
+		f := field.Field(i)
+		if err := member2Field(value.Array[j], field.Type().Field(i), &f, opts); err != nil {
+			return atStep(err, itemStep(j))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultChunkSize is the chunk size used by Upload when none is given.
+const DefaultChunkSize = 4 << 20
+
+// UploadChunk holds the args of a call of the chunked upload convention: a
+// binary too large for a single call is sent in order as consecutive chunks,
+// one per call, all with the ID of the upload. The last chunk is flagged
+// Final, possibly with no data.
+type UploadChunk struct {
+	ID     string
+	Offset int
+	Data   []byte
+	Final  bool
+}
+
+// UploadReply holds the reply to an UploadChunk.
+type UploadReply struct {
+	// Received is the size received so far.
+	Received int
+}
+
+// Upload sends the data read from r with the chunked upload convention,
+// calling method, e.g. "upload.Chunk" for an UploadAssembler registered as
+// "upload", once per chunk of chunkSize bytes (DefaultChunkSize if zero). It
+// returns the size sent.
+func (c *Client) Upload(method, id string, r io.Reader, chunkSize int) (int, error) {
+	return c.UploadContext(context.Background(), method, id, r, chunkSize)
+}
+
+// UploadContext is like Upload, but the upload is cancelled when ctx is done.
+func (c *Client) UploadContext(ctx context.Context, method, id string, r io.Reader, chunkSize int) (int, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	buf := make([]byte, chunkSize)
+	offset := 0
+	for {
+		n, err := io.ReadFull(r, buf)
+		final := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !final {
+			return offset, err
+		}
+
+		var reply UploadReply
+		args := &UploadChunk{ID: id, Offset: offset, Data: buf[:n], Final: final}
+		if err := c.CallContext(ctx, method, args, &reply); err != nil {
+			return offset, err
+		}
+		offset += n
+		if reply.Received != offset {
+			return offset, fmt.Errorf("xmlrpc: upload %s: server received %d bytes, sent %d", id, reply.Received, offset)
+		}
+		if final {
+			return offset, nil
+		}
+	}
+}
+
+type upload struct {
+	mu       sync.Mutex
+	w        io.Writer
+	received int
+	done     bool      // completed or dropped: no more chunks are written
+	last     time.Time // of the last chunk, guarded by the assembler
+}
+
+// drop closes the writer of the dropped upload u, once no chunk is being
+// written to it.
+func (u *upload) drop() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.done {
+		u.done = true
+		closeWriter(u.w)
+	}
+}
+
+// UploadAssembler is a service reassembling the uploads sent with the
+// chunked upload convention, e.g. by Client.Upload. Register it with
+//
+//    s.RegisterService(NewUploadAssembler(create, complete), "upload")
+//
+// so the chunks are sent to "upload.Chunk". Chunks out of order are rejected
+// with an invalid params fault, telling the size received so far in the
+// fault string. A chunk at offset 0 restarts its upload.
+//
+// The writers of the dropped uploads, idle, restarted, beyond MaxSize or
+// failing to write a chunk, are closed if they are io.Closers.
+type UploadAssembler struct {
+	// MaxSize, if positive, caps the size of an upload.
+	MaxSize int
+
+	// MaxPending, if positive, caps the uploads in progress: more are
+	// rejected until some complete or are dropped.
+	MaxPending int
+
+	// IdleTimeout, if positive, is the time after which the uploads with
+	// no new chunk are dropped, e.g. abandoned by their client.
+	IdleTimeout time.Duration
+
+	create   func(id string) (io.Writer, error)
+	complete func(id string, w io.Writer, size int) error
+
+	mu      sync.Mutex
+	uploads map[string]*upload
+}
+
+// NewUploadAssembler returns an UploadAssembler writing the chunks of an
+// upload to the writer returned by create on its first chunk, and calling
+// complete once its final chunk is written.
+func NewUploadAssembler(create func(id string) (io.Writer, error), complete func(id string, w io.Writer, size int) error) *UploadAssembler {
+	return &UploadAssembler{
+		create:   create,
+		complete: complete,
+		uploads:  make(map[string]*upload),
+	}
+}
+
+// Chunk receives a chunk of an upload.
+func (a *UploadAssembler) Chunk(r *http.Request, args *UploadChunk, reply *UploadReply) error {
+	now := time.Now()
+	a.mu.Lock()
+	dropped := a.dropIdle(now)
+	u, ok := a.uploads[args.ID]
+	if ok && args.Offset != 0 {
+		u.last = now
+	}
+	a.mu.Unlock()
+	for _, d := range dropped {
+		d.drop()
+	}
+
+	if !ok || args.Offset == 0 {
+		if args.Offset != 0 {
+			return invalidParams("unknown upload %s", args.ID)
+		}
+		// a new upload, or one restarted
+		var err error
+		if u, err = a.start(args.ID, now); err != nil {
+			return err
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.done {
+		return invalidParams("upload %s was dropped", args.ID)
+	}
+	if args.Offset != u.received {
+		return invalidParams("upload %s: offset %d, received %d", args.ID, args.Offset, u.received)
+	}
+	if a.MaxSize > 0 && u.received+len(args.Data) > a.MaxSize {
+		a.forget(args.ID, u)
+		u.done = true
+		closeWriter(u.w)
+		return invalidParams("upload %s exceeds %d bytes", args.ID, a.MaxSize)
+	}
+	if _, err := u.w.Write(args.Data); err != nil {
+		a.forget(args.ID, u)
+		u.done = true
+		closeWriter(u.w)
+		return err
+	}
+	u.received += len(args.Data)
+	reply.Received = u.received
+
+	if args.Final {
+		a.forget(args.ID, u)
+		u.done = true
+		return a.complete(args.ID, u.w, u.received)
+	}
+	return nil
+}
+
+// start starts the upload of id, replacing the one in progress, if any,
+// which is dropped.
+func (a *UploadAssembler) start(id string, now time.Time) (*upload, error) {
+	full := func() (int, bool) {
+		_, ok := a.uploads[id]
+		return len(a.uploads), !ok && a.MaxPending > 0 && len(a.uploads) >= a.MaxPending
+	}
+	a.mu.Lock()
+	n, rejected := full()
+	a.mu.Unlock()
+	if rejected {
+		return nil, invalidParams("upload %s: %d uploads in progress", id, n)
+	}
+
+	// created without the lock, as create may do I/O, e.g. create a file
+	w, err := a.create(id)
+	if err != nil {
+		return nil, err
+	}
+	u := &upload{w: w, last: now}
+
+	a.mu.Lock()
+	if n, rejected = full(); rejected {
+		// others started meanwhile
+		a.mu.Unlock()
+		closeWriter(w)
+		return nil, invalidParams("upload %s: %d uploads in progress", id, n)
+	}
+	old, ok := a.uploads[id]
+	a.uploads[id] = u
+	a.mu.Unlock()
+	if ok {
+		old.drop()
+	}
+	return u, nil
+}
+
+// forget forgets the upload u of id, unless it was restarted meanwhile.
+func (a *UploadAssembler) forget(id string, u *upload) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.uploads[id] == u {
+		delete(a.uploads, id)
+	}
+}
+
+// dropIdle forgets the uploads idle for IdleTimeout at now, and returns them
+// to drop once a.mu is released. a.mu is held.
+func (a *UploadAssembler) dropIdle(now time.Time) []*upload {
+	if a.IdleTimeout <= 0 {
+		return nil
+	}
+	var dropped []*upload
+	for id, u := range a.uploads {
+		if now.Sub(u.last) >= a.IdleTimeout {
+			delete(a.uploads, id)
+			dropped = append(dropped, u)
+		}
+	}
+	return dropped
+}
+
+// closeWriter closes the writer of a dropped upload, if it's an io.Closer.
+func closeWriter(w io.Writer) {
+	if c, ok := w.(io.Closer); ok {
+		c.Close()
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+func TestUpload(t *testing.T) {
+	files := make(map[string]*bytes.Buffer)
+	completed := make(map[string]int)
+	assembler := NewUploadAssembler(func(id string) (io.Writer, error) {
+		files[id] = new(bytes.Buffer)
+		return files[id], nil
+	}, func(id string, w io.Writer, size int) error {
+		completed[id] = size
+		return nil
+	})
+	assembler.MaxSize = 10000
+
+	s := rpc.NewServer()
+	s.RegisterCodec(NewCodec(), "text/xml")
+	s.RegisterService(assembler, "upload")
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	data := make([]byte, 7000)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	for _, size := range []int{3000, 3500} {
+		n, err := client.Upload("upload.Chunk", "blob", bytes.NewReader(data), size)
+		if err != nil {
+			t.Fatal("Expected err to be nil, but got:", err)
+		}
+		if n != len(data) || completed["blob"] != len(data) {
+			t.Errorf("Expected %d bytes to be uploaded, got %d, completed %d", len(data), n, completed["blob"])
+		}
+		if !bytes.Equal(files["blob"].Bytes(), data) {
+			t.Error("Uploaded data mismatch")
+		}
+	}
+
+	if _, err := client.Upload("upload.Chunk", "huge", bytes.NewReader(make([]byte, 20000)), 4000); err == nil {
+		t.Error("Expected upload beyond MaxSize to fail")
+	}
+
+	var reply UploadReply
+	err := client.Call("upload.Chunk", &UploadChunk{ID: "late", Offset: 10, Data: []byte("x")}, &reply)
+	if fault, ok := err.(Fault); !ok || fault.Code != FaultInvalidParams.Code {
+		t.Error("Expected invalid params fault, got", err)
+	}
+}
+
+func TestUploadAssemblerLimits(t *testing.T) {
+	files := make(map[string]*bytes.Buffer)
+	assembler := NewUploadAssembler(func(id string) (io.Writer, error) {
+		files[id] = new(bytes.Buffer)
+		return files[id], nil
+	}, func(id string, w io.Writer, size int) error {
+		return nil
+	})
+	assembler.MaxPending = 1
+	chunk := func(id string, offset int, data string) (int, error) {
+		var reply UploadReply
+		err := assembler.Chunk(nil, &UploadChunk{ID: id, Offset: offset, Data: []byte(data)}, &reply)
+		return reply.Received, err
+	}
+
+	if _, err := chunk("a", 0, "abc"); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if n, err := chunk("a", 0, "xy"); err != nil || n != 2 || files["a"].String() != "xy" {
+		t.Errorf("Expected the upload restarted, got %d, %q and %v", n, files["a"], err)
+	}
+	if _, err := chunk("b", 0, "b"); err == nil {
+		t.Error("Expected the uploads beyond MaxPending rejected")
+	}
+
+	assembler.IdleTimeout = time.Millisecond
+	time.Sleep(5 * time.Millisecond)
+	if _, err := chunk("a", 2, "z"); err == nil {
+		t.Error("Expected the idle upload dropped")
+	}
+	if _, err := chunk("b", 0, "b"); err != nil {
+		t.Error("Expected room for another upload, got", err)
+	}
+}
+
+// uploadFile is an upload writer recording its closing, whose writes block
+// while block is set and fail once failing is.
+type uploadFile struct {
+	mu      sync.Mutex
+	data    bytes.Buffer
+	closed  bool
+	writing bool
+	failing bool
+	block   chan struct{}
+	entered chan struct{}
+}
+
+func (f *uploadFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	f.writing = true
+	block, failing := f.block, f.failing
+	f.mu.Unlock()
+	if block != nil {
+		close(f.entered)
+		<-block
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writing = false
+	if failing {
+		return 0, errors.New("disk full")
+	}
+	return f.data.Write(p)
+}
+
+func (f *uploadFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.writing {
+		panic("closed while writing")
+	}
+	f.closed = true
+	return nil
+}
+
+func (f *uploadFile) isClosed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+func TestUploadAssemblerDrops(t *testing.T) {
+	var mu sync.Mutex
+	files := make(map[string][]*uploadFile)
+	createEntered, createRelease := make(chan struct{}), make(chan struct{})
+	assembler := NewUploadAssembler(func(id string) (io.Writer, error) {
+		if id == "slow" {
+			close(createEntered)
+			<-createRelease
+		}
+		f := &uploadFile{}
+		mu.Lock()
+		files[id] = append(files[id], f)
+		mu.Unlock()
+		return f, nil
+	}, func(id string, w io.Writer, size int) error {
+		return nil
+	})
+	assembler.MaxSize = 4
+	chunk := func(id string, offset int, data string) error {
+		return assembler.Chunk(nil, &UploadChunk{ID: id, Offset: offset, Data: []byte(data)}, &UploadReply{})
+	}
+	file := func(id string, i int) *uploadFile {
+		mu.Lock()
+		defer mu.Unlock()
+		return files[id][i]
+	}
+
+	// beyond MaxSize
+	if err := chunk("big", 0, "abc"); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if err := chunk("big", 3, "de"); err == nil || !file("big", 0).isClosed() {
+		t.Error("Expected the upload beyond MaxSize dropped and closed, got", err)
+	}
+	if err := chunk("big", 3, "d"); err == nil {
+		t.Error("Expected the dropped upload forgotten")
+	}
+
+	// failing to write
+	if err := chunk("fail", 0, "a"); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	file("fail", 0).failing = true
+	if err := chunk("fail", 1, "b"); err == nil || !file("fail", 0).isClosed() {
+		t.Error("Expected the failing upload dropped and closed, got", err)
+	}
+
+	// a restart closes the writer once its chunk is written
+	if err := chunk("busy", 0, "a"); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	busy := file("busy", 0)
+	busy.block, busy.entered = make(chan struct{}), make(chan struct{})
+	written := make(chan error)
+	go func() { written <- chunk("busy", 1, "b") }()
+	<-busy.entered
+	restarted := make(chan error)
+	go func() { restarted <- chunk("busy", 0, "x") }()
+	time.Sleep(10 * time.Millisecond)
+	if busy.isClosed() {
+		t.Error("Expected the writer left open while a chunk is written")
+	}
+	close(busy.block)
+	if err := <-written; err != nil {
+		t.Error("Expected err to be nil, but got:", err)
+	}
+	if err := <-restarted; err != nil || !busy.isClosed() {
+		t.Error("Expected the restarted upload closed, got", err)
+	}
+
+	// creating a writer doesn't hold back the other uploads
+	go chunk("slow", 0, "a")
+	<-createEntered
+	done := make(chan error)
+	go func() { done <- chunk("fast", 0, "a") }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error("Expected err to be nil, but got:", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected the upload not to wait for another writer created")
+	}
+	close(createRelease)
+}
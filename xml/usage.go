@@ -0,0 +1,76 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"runtime/metrics"
+	"time"
+)
+
+// CallUsage are the resources used by a call served by a Codec, from the
+// decoding of its args to the writing of its response, as reported to the
+// Codec's AfterCall hook, e.g. to find the methods or the payloads costing
+// the most in production.
+type CallUsage struct {
+	Method string
+
+	// ID is the correlation ID of the call, if the Codec has CorrelationIDs.
+	ID string
+
+	// Err is the error the call failed with, if any.
+	Err error
+
+	// Elapsed is the wall time of the call.
+	Elapsed time.Duration
+
+	// CPU is the user and system CPU time of the process during the call.
+	// It's only measured on Linux, and zero elsewhere.
+	CPU time.Duration
+
+	// Allocs and AllocBytes are the heap objects and bytes allocated during
+	// the call, read from the runtime metrics of the process.
+	//
+	// Like CPU, they are approximate: they include the usage of the calls
+	// served concurrently, and the runtime counts the allocations in batches.
+	// They are best aggregated over many calls.
+	Allocs     uint64
+	AllocBytes uint64
+}
+
+// usageMeter measures the usage of a call, started by startUsage.
+type usageMeter struct {
+	start   time.Time
+	cpu     time.Duration
+	samples []metrics.Sample
+}
+
+var usageMetrics = []string{"/gc/heap/allocs:objects", "/gc/heap/allocs:bytes"}
+
+// startUsage starts measuring the usage of a call. A meter holds no
+// resource, so one never stopped is just dropped.
+func startUsage() *usageMeter {
+	m := &usageMeter{samples: make([]metrics.Sample, len(usageMetrics))}
+	for i, name := range usageMetrics {
+		m.samples[i].Name = name
+	}
+	m.cpu = processCPU()
+	metrics.Read(m.samples)
+	m.start = time.Now()
+	return m
+}
+
+// stop stops the meter and fills the usage measured into u.
+func (m *usageMeter) stop(u *CallUsage) {
+	u.Elapsed = time.Since(m.start)
+	u.CPU = processCPU() - m.cpu
+	objects, bytes := m.samples[0].Value, m.samples[1].Value
+	metrics.Read(m.samples)
+	if objects.Kind() == metrics.KindUint64 {
+		u.Allocs = m.samples[0].Value.Uint64() - objects.Uint64()
+	}
+	if bytes.Kind() == metrics.KindUint64 {
+		u.AllocBytes = m.samples[1].Value.Uint64() - bytes.Uint64()
+	}
+}
@@ -0,0 +1,16 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package xml
+
+import "time"
+
+// processCPU returns the CPU time used by the process, which isn't measured
+// here.
+func processCPU() time.Duration {
+	return 0
+}
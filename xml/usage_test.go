@@ -0,0 +1,71 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+type HeavyService struct{}
+
+var heavySink [][]byte
+
+func (s *HeavyService) Burn(r *http.Request, req *Service1Request, res *Service1Response) error {
+	heavySink = nil
+	for i := 0; i < req.A; i++ {
+		heavySink = append(heavySink, make([]byte, 1024))
+	}
+	res.Result = len(heavySink)
+	return nil
+}
+
+func TestCodecAfterCall(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		usages []CallUsage
+	)
+	codec := NewCodec()
+	codec.AfterCall = func(usage CallUsage) {
+		mu.Lock()
+		usages = append(usages, usage)
+		mu.Unlock()
+	}
+	s := rpc.NewServer()
+	s.RegisterCodec(codec, "text/xml")
+	s.RegisterService(new(HeavyService), "")
+	s.RegisterService(new(FailingService), "")
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	var res Service1Response
+	if err := client.Call("HeavyService.Burn", &Service1Request{1024, 0}, &res); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	client.Call("FailingService.Fail", &Service1Request{1, 2}, &res)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(usages) != 2 {
+		t.Fatalf("Expected 2 calls reported, got %d", len(usages))
+	}
+	burn, fail := usages[0], usages[1]
+	if burn.Method != "HeavyService.Burn" || burn.Err != nil {
+		t.Errorf("Expected the method and no error, got %q and %v", burn.Method, burn.Err)
+	}
+	if fail.Method != "FailingService.Fail" || fail.Err == nil {
+		t.Errorf("Expected the error reported, got %q and %v", fail.Method, fail.Err)
+	}
+	for _, usage := range usages {
+		if usage.Elapsed < 0 || usage.CPU < 0 {
+			t.Errorf("Expected non-negative usage, got %+v", usage)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ValidationIssue describes a place where a document doesn't fit the target.
+type ValidationIssue struct {
+	// Path locates the offending node, e.g. "params[1].Address.Street".
+	Path    string
+	Message string
+}
+
+func (i ValidationIssue) String() string {
+	if i.Path == "" {
+		return i.Message
+	}
+	return i.Path + ": " + i.Message
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Validate checks the methodCall or methodResponse document data against
+// target, a pointer to a params struct, without decoding into it. It reports
+// type mismatches, missing and unknown members and params; no issues means
+// that data fits target.
+func Validate(data []byte, target interface{}) []ValidationIssue {
+	return validateDocument(data, target, false, defaultOptions)
+}
+
+// validateDocument validates data against target, as decoded with opts. With
+// schema, the unknown members and params are accepted, and only the members
+// of the fields tagged required must be present; a fault document is
+// accepted.
+func validateDocument(data []byte, target interface{}, schema bool, opts *Options) []ValidationIssue {
+	if opts.IgnoreNamespaces || opts.IgnoreCase {
+		if normalized, err := normalizeDocument(data, opts); err == nil {
+			data = normalized
+		}
+	}
+	var ret response
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = opts.charsetReader
+	if err := decoder.Decode(&ret); err != nil {
+		return []ValidationIssue{{Message: "malformed XML: " + err.Error()}}
+	}
+	if !ret.Fault.IsEmpty() {
+		if schema {
+			return nil
+		}
+		return []ValidationIssue{{Message: "document is a fault: " + getFaultResponse(ret.Fault).Error()}}
+	}
+
+	typ := reflect.TypeOf(target)
+	if typ == nil || typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Struct {
+		return []ValidationIssue{{Message: fmt.Sprintf("target must be a pointer to struct, not %v", typ)}}
+	}
+	typ = typ.Elem()
+
+	// the trial decodings aren't reported
+	quiet := *opts
+	quiet.Warnings = nil
+	v := &validator{schema: schema, opts: &quiet}
+	p := 0 // the param of the field
+	for i := 0; i < typ.NumField(); i++ {
+		path := fmt.Sprintf("params[%d]", p)
+		field := typ.Field(i)
+		if isSkipped(field) {
+			continue
+		}
+		if isVariadic(field) {
+			for j := p; j < len(ret.Params); j++ {
+				v.validate(fmt.Sprintf("params[%d]", j), ret.Params[j].Value, field.Type.Elem())
+			}
+			return v.issues
+		}
+		switch {
+		case p < len(ret.Params):
+			v.validateField(path, ret.Params[p].Value, field)
+		case field.Tag.Get("default") == "":
+			v.addf(path, "missing param for field %s", field.Name)
+		}
+		p++
+	}
+	for i := p; i < len(ret.Params) && !schema; i++ {
+		v.addf(fmt.Sprintf("params[%d]", i), "unknown param")
+	}
+	return v.issues
+}
+
+type validator struct {
+	issues []ValidationIssue
+	schema bool
+	opts   *Options
+}
+
+// member returns the field of the struct type typ the member name is
+// decoded into, as the decoder resolves it.
+func (v *validator) member(typ reflect.Type, name string) (reflect.StructField, bool) {
+	if v.opts.JSONCompat {
+		_, sf, ok := jsonMember(reflect.New(typ).Elem(), name)
+		return sf, ok
+	}
+	if sf, ok := v.opts.memberField(typ, name); ok {
+		return sf, true
+	}
+	return typ.FieldByName(uppercaseFirst(name))
+}
+
+// coerces reports whether value is decoded into typ with the coercions of
+// the options, e.g. CoerceStrings.
+func (v *validator) coerces(value value, typ reflect.Type) bool {
+	if !v.opts.CoerceStrings && !v.opts.LenientBool && len(v.opts.DecodeHooks) == 0 {
+		return false
+	}
+	field := reflect.New(typ).Elem()
+	return value2Field(value, &field, v.opts) == nil
+}
+
+func (v *validator) addf(path, format string, args ...interface{}) {
+	v.issues = append(v.issues, ValidationIssue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// validateField validates value against the struct field sf.
+func (v *validator) validateField(path string, value value, sf reflect.StructField) {
+	if names := enumNames(sf); names != nil {
+		v.validateEnum(path, value, names)
+		return
+	}
+	if isTuple(sf) {
+		v.validateTuple(path, value, sf.Type)
+		return
+	}
+	if isBinaryString(sf) && wireType(value) == "string" {
+		return
+	}
+	if epoch, _ := isEpoch(sf); epoch {
+		switch wire := wireType(value); wire {
+		case "int", "i4", "i8", "double":
+		default:
+			v.addf(path, "type mismatch: %s != Unix seconds", wire)
+		}
+		return
+	}
+	v.validate(path, value, sf.Type)
+}
+
+func (v *validator) validateTuple(path string, value value, typ reflect.Type) {
+	if wire := wireType(value); wire != "array" {
+		v.addf(path, "type mismatch: %s != tuple %s", wire, typ)
+		return
+	}
+	fields := tupleFields(typ)
+	if len(value.Array) != len(fields) {
+		v.addf(path, "length mismatch: %d items for tuple %s", len(value.Array), typ)
+		return
+	}
+	for j, i := range fields {
+		v.validateField(fmt.Sprintf("%s[%d]", path, j), value.Array[j], typ.Field(i))
+	}
+}
+
+func (v *validator) validateEnum(path string, value value, names []string) {
+	if name, i := enumIndex(names, value); i < 0 {
+		v.addf(path, "%q is not one of %s", name, strings.Join(names, "|"))
+	}
+}
+
+func (v *validator) validate(path string, value value, typ reflect.Type) {
+	wire := wireType(value)
+	if typ.Kind() == reflect.Interface {
+		return
+	}
+	if names := enumByType(typ); names != nil {
+		v.validateEnum(path, value, names)
+		return
+	}
+	if wire == "nil" {
+		switch typ.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map:
+		default:
+			v.addf(path, "nil can't be assigned to %s", typ)
+		}
+		return
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if emptyArray(value.Raw) {
+		switch {
+		case typ.Kind() == reflect.Array && typ.Len() != 0:
+			v.addf(path, "length mismatch: 0 items for %s", typ)
+			return
+		case typ.Kind() == reflect.Array, typ.Kind() == reflect.Slice:
+			return
+		}
+	}
+	if wire == "string" && isTextType(typ) {
+		return
+	}
+	if st := scalarByTag(wire); st != nil {
+		if typ != st.typ && typ.Kind() != reflect.String {
+			v.addf(path, "type mismatch: <%s> != %s", wire, typ)
+		}
+		return
+	}
+
+	switch wire {
+	case "struct":
+		if typ.Kind() != reflect.Struct || typ == timeType {
+			v.addf(path, "type mismatch: struct != %s", typ)
+			return
+		}
+		seen := make(map[string]bool)
+		for _, m := range value.Struct {
+			field, ok := v.member(typ, m.Name)
+			if ok && skipDecode(field) {
+				continue
+			}
+			if !ok {
+				if !v.schema {
+					v.addf(path+"."+m.Name, "unknown member")
+				}
+				continue
+			}
+			seen[field.Name] = true
+			v.validateField(path+"."+m.Name, m.Value, field)
+		}
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if seen[field.Name] || skipDecode(field) {
+				continue
+			}
+			if _, opts := parseTag(field); !v.schema || opts.Contains("required") {
+				v.addf(path+"."+field.Name, "missing member")
+			}
+		}
+	case "array":
+		if typ.Kind() != reflect.Slice && typ.Kind() != reflect.Array {
+			v.addf(path, "type mismatch: array != %s", typ)
+			return
+		}
+		if typ.Kind() == reflect.Array && len(value.Array) != typ.Len() {
+			v.addf(path, "length mismatch: %d items for %s", len(value.Array), typ)
+		}
+		for i, item := range value.Array {
+			v.validate(fmt.Sprintf("%s[%d]", path, i), item, typ.Elem())
+		}
+	default:
+		if typ.Kind() == reflect.Slice && typ.Elem().Kind() != reflect.Uint8 {
+			// a single value is decoded as one-element slice
+			typ = typ.Elem()
+		}
+		if !scalarFits(wire, value, typ) && !v.coerces(value, typ) {
+			v.addf(path, "type mismatch: %s != %s", wire, typ)
+		}
+	}
+}
+
+// scalarFits reports whether the decoder can assign a scalar of the wire type
+// to typ.
+func scalarFits(wire string, value value, typ reflect.Type) bool {
+	switch wire {
+	case "int", "i4", "i8", "double":
+		if typ == bigIntType && wire != "double" || typ == bigFloatType {
+			return true
+		}
+		if typ.Kind() == reflect.String {
+			text := value.Int + value.Int4 + value.Int8 + value.Double
+			return numberOverflows(text, wire != "double")
+		}
+		if wire == "double" {
+			return typ.Kind() == reflect.Float64
+		}
+		return isIntegerKind(typ.Kind())
+	case "boolean":
+		return typ.Kind() == reflect.Bool
+	case "dateTime.iso8601":
+		return typ == timeType
+	case "base64":
+		return typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8
+	default:
+		return typ.Kind() == reflect.String
+	}
+}
+
+// wireType returns the element name of the type of value, "nil" for <nil/>
+// and "string" for untyped values.
+func wireType(value value) string {
+	switch {
+	case value.Int != "":
+		return "int"
+	case value.Int4 != "":
+		return "i4"
+	case value.Int8 != "":
+		return "i8"
+	case value.Double != "":
+		return "double"
+	case value.String != "":
+		return "string"
+	case value.Boolean != "":
+		return "boolean"
+	case value.DateTime != "":
+		return "dateTime.iso8601"
+	case value.Base64 != "":
+		return "base64"
+	case len(value.Struct) != 0:
+		return "struct"
+	case len(value.Array) != 0:
+		return "array"
+	}
+	for _, c := range value.Custom {
+		if c.XMLName.Local == "nil" {
+			return "nil"
+		}
+		if scalarByTag(c.XMLName.Local) != nil {
+			return c.XMLName.Local
+		}
+	}
+	return "string"
+}
@@ -0,0 +1,52 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	data := []byte(`<methodCall><methodName>Service3.GetInfo</methodName><params>
+<param><value><struct>
+	<member><name>name</name><value><string>Johnny</string></value></member>
+	<member><name>Age</name><value><string>33</string></value></member>
+	<member><name>Nickname</name><value><string>JD</string></value></member>
+	<member><name>Address</name><value><struct>
+		<member><name>Number</name><value><int>221</int></value></member>
+		<member><name>Street</name><value><string>Baker str.</string></value></member>
+		<member><name>Country</name><value><string>London</string></value></member>
+	</struct></value></member>
+</struct></value></param>
+<param><value><int>1</int></value></param>
+</params></methodCall>`)
+
+	req := new(Service3Request)
+	issues := Validate(data, req)
+	expected := []ValidationIssue{
+		{"params[0].Age", "type mismatch: string != int"},
+		{"params[0].Nickname", "unknown member"},
+		{"params[0].Surname", "missing member"},
+		{"params[1]", "unknown param"},
+	}
+	if !reflect.DeepEqual(issues, expected) {
+		t.Errorf("expected %v, got %v", expected, issues)
+	}
+	if !reflect.DeepEqual(req, new(Service3Request)) {
+		t.Errorf("target was modified: %v", req)
+	}
+
+	if issues := Validate([]byte("<methodCall><params>"), req); len(issues) != 1 {
+		t.Errorf("expected malformed XML issue, got %v", issues)
+	}
+}
+
+func TestValidateValid(t *testing.T) {
+	data, _ := EncodeClientRequest("Some.Method", &StructXml2Rpc{Sub: SubStructXml2Rpc{Data: []int{1}}, Base64: []byte("x")})
+	if issues := Validate(data, new(StructXml2Rpc)); len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+// Validator1 is a service implementing the methods of the classic XML-RPC
+// validator1 suite, so a deployment can verify its protocol compliance with
+// the validators, or with RunValidator1.
+type Validator1 struct{}
+
+// validator1Methods are the methods of the suite.
+var validator1Methods = []string{
+	"arrayOfStructsTest", "countTheEntities", "easyStructTest", "echoStructTest",
+	"manyTypesTest", "moderateSizeArrayCheck", "nestedStructTest", "simpleStructReturnTest",
+}
+
+// RegisterValidator1 registers a Validator1 with s as the "validator1"
+// service and makes codec route the validator1.* methods to it.
+func RegisterValidator1(s *rpc.Server, codec *Codec) error {
+	if err := s.RegisterService(new(Validator1), "validator1"); err != nil {
+		return err
+	}
+	for _, method := range validator1Methods {
+		codec.RegisterAlias("validator1."+method, "validator1."+uppercaseFirst(method))
+	}
+	return nil
+}
+
+// SumReply is the sum returned by some validator1 methods.
+type SumReply struct {
+	Sum int
+}
+
+// EntityCounts is the reply of validator1.countTheEntities.
+type EntityCounts struct {
+	LeftAngleBrackets  int `xml:"ctLeftAngleBrackets"`
+	RightAngleBrackets int `xml:"ctRightAngleBrackets"`
+	Ampersands         int `xml:"ctAmpersands"`
+	Apostrophes        int `xml:"ctApostrophes"`
+	Quotes             int `xml:"ctQuotes"`
+}
+
+// Multiples is the reply of validator1.simpleStructReturnTest.
+type Multiples struct {
+	Times10   int `xml:"times10"`
+	Times100  int `xml:"times100"`
+	Times1000 int `xml:"times1000"`
+}
+
+// stoogesSum returns the sum of the moe, larry and curly members of the
+// struct v.
+func stoogesSum(v Value) (int, error) {
+	sum := 0
+	for _, name := range []string{"moe", "larry", "curly"} {
+		m, ok := v.Member(name)
+		if !ok {
+			return 0, invalidParams("missing member %s", name)
+		}
+		n, err := m.Int()
+		if err != nil {
+			return 0, invalidParams("member %s isn't an int", name)
+		}
+		sum += int(n)
+	}
+	return sum, nil
+}
+
+// ArrayOfStructsTest handles validator1.arrayOfStructsTest, returning the
+// sum of the curly members of the structs.
+func (Validator1) ArrayOfStructsTest(r *http.Request, args *struct{ Structs []Value }, reply *SumReply) error {
+	for _, s := range args.Structs {
+		curly, ok := s.Member("curly")
+		if !ok {
+			return invalidParams("missing member curly")
+		}
+		n, err := curly.Int()
+		if err != nil {
+			return invalidParams("member curly isn't an int")
+		}
+		reply.Sum += int(n)
+	}
+	return nil
+}
+
+// CountTheEntities handles validator1.countTheEntities, counting the
+// characters escaped in XML.
+func (Validator1) CountTheEntities(r *http.Request, args *struct{ S string }, reply *struct{ Counts EntityCounts }) error {
+	reply.Counts = EntityCounts{
+		LeftAngleBrackets:  strings.Count(args.S, "<"),
+		RightAngleBrackets: strings.Count(args.S, ">"),
+		Ampersands:         strings.Count(args.S, "&"),
+		Apostrophes:        strings.Count(args.S, "'"),
+		Quotes:             strings.Count(args.S, `"`),
+	}
+	return nil
+}
+
+// EasyStructTest handles validator1.easyStructTest, returning the sum of
+// the moe, larry and curly members.
+func (Validator1) EasyStructTest(r *http.Request, args *struct{ Stooges Value }, reply *SumReply) error {
+	sum, err := stoogesSum(args.Stooges)
+	reply.Sum = sum
+	return err
+}
+
+// EchoStructTest handles validator1.echoStructTest, returning its param.
+func (Validator1) EchoStructTest(r *http.Request, args *struct{ Struct Value }, reply *struct{ Struct Value }) error {
+	reply.Struct = args.Struct
+	return nil
+}
+
+// ManyTypesTest handles validator1.manyTypesTest, returning its params as
+// an array.
+func (Validator1) ManyTypesTest(r *http.Request, args *struct {
+	Number, Boolean, String, Double, DateTime, Base64 Value
+}, reply *struct{ Items []Value }) error {
+	reply.Items = []Value{args.Number, args.Boolean, args.String, args.Double, args.DateTime, args.Base64}
+	return nil
+}
+
+// ModerateSizeArrayCheck handles validator1.moderateSizeArrayCheck,
+// returning the first and last strings concatenated.
+func (Validator1) ModerateSizeArrayCheck(r *http.Request, args *struct{ Strings []string }, reply *struct{ S string }) error {
+	if len(args.Strings) == 0 {
+		return invalidParams("empty array")
+	}
+	reply.S = args.Strings[0] + args.Strings[len(args.Strings)-1]
+	return nil
+}
+
+// NestedStructTest handles validator1.nestedStructTest, returning the sum
+// of the moe, larry and curly members of the day April 1, 2000 of the
+// calendar.
+func (Validator1) NestedStructTest(r *http.Request, args *struct{ Calendar Value }, reply *SumReply) error {
+	day := args.Calendar
+	for _, name := range []string{"2000", "04", "01"} {
+		var ok bool
+		if day, ok = day.Member(name); !ok {
+			return invalidParams("missing member %s", name)
+		}
+	}
+	sum, err := stoogesSum(day)
+	reply.Sum = sum
+	return err
+}
+
+// SimpleStructReturnTest handles validator1.simpleStructReturnTest.
+func (Validator1) SimpleStructReturnTest(r *http.Request, args *struct{ N int }, reply *struct{ Multiples Multiples }) error {
+	reply.Multiples = Multiples{args.N * 10, args.N * 100, args.N * 1000}
+	return nil
+}
+
+// RunValidator1 runs the validator1 suite against the server of c, as the
+// validators do, reporting a result per method.
+func RunValidator1(ctx context.Context, c *Client) ConformanceReport {
+	stooges := func(moe, larry, curly int) Value {
+		return NewStruct(Member{"moe", NewInt(int64(moe))}, Member{"larry", NewInt(int64(larry))},
+			Member{"curly", NewInt(int64(curly))})
+	}
+	when := time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	strs := make([]Value, 150)
+	for i := range strs {
+		strs[i] = NewString(fmt.Sprintf("s%d", i))
+	}
+	day := func(v Value) Value {
+		return NewStruct(Member{"2000", NewStruct(Member{"04", NewStruct(Member{"01", v})})})
+	}
+
+	cases := []struct {
+		method string
+		params []Value
+		want   Value
+	}{
+		{"arrayOfStructsTest", []Value{NewArray(stooges(1, 2, 3), stooges(4, 5, 6))}, NewInt(9)},
+		{"countTheEntities", []Value{NewString(`<<>&'"'`)}, NewStruct(
+			Member{"ctLeftAngleBrackets", NewInt(2)}, Member{"ctRightAngleBrackets", NewInt(1)},
+			Member{"ctAmpersands", NewInt(1)}, Member{"ctApostrophes", NewInt(2)}, Member{"ctQuotes", NewInt(1)})},
+		{"easyStructTest", []Value{stooges(1, 2, 3)}, NewInt(6)},
+		{"echoStructTest", []Value{stooges(7, 8, 9)}, stooges(7, 8, 9)},
+		{"manyTypesTest", []Value{NewInt(1), NewBoolean(true), NewString("s"), NewDouble(1.5),
+			NewDateTime(when), NewBase64([]byte("b"))},
+			NewArray(NewInt(1), NewBoolean(true), NewString("s"), NewDouble(1.5), NewDateTime(when), NewBase64([]byte("b")))},
+		{"moderateSizeArrayCheck", []Value{NewArray(strs...)}, NewString("s0s149")},
+		{"nestedStructTest", []Value{day(stooges(1, 2, 3))}, NewInt(6)},
+		{"simpleStructReturnTest", []Value{NewInt(3)}, NewStruct(
+			Member{"times10", NewInt(30)}, Member{"times100", NewInt(300)}, Member{"times1000", NewInt(3000)})},
+	}
+
+	report := make(ConformanceReport, 0, len(cases))
+	for _, tc := range cases {
+		result := ConformanceResult{Case: "validator1." + tc.method}
+		params, err := c.CallValuesContext(ctx, result.Case, tc.params...)
+		switch {
+		case err != nil:
+			result.Err = err
+		case len(params) != 1:
+			result.Err = fmt.Errorf("xmlrpc: %d params returned", len(params))
+		default:
+			result.Changes = Diff(tc.want, params[0])
+		}
+		report = append(report, result)
+	}
+	return report
+}
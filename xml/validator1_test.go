@@ -0,0 +1,38 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlexStocks/gorilla-rpc"
+)
+
+func TestValidator1(t *testing.T) {
+	s := rpc.NewServer()
+	codec := NewCodec()
+	s.RegisterCodec(codec, "text/xml")
+	if err := RegisterValidator1(s, codec); err != nil {
+		t.Fatal("RegisterValidator1 failed", err)
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	client := NewClient(ts.URL)
+
+	report := RunValidator1(context.Background(), client)
+	if len(report) != len(validator1Methods) {
+		t.Errorf("Expected %d results, got %d", len(validator1Methods), len(report))
+	}
+	if failed := report.Failed(); len(failed) != 0 {
+		t.Errorf("Expected the suite to pass, got:\n%s", report)
+	}
+
+	_, err := client.CallValues("validator1.easyStructTest", NewStruct(Member{"moe", NewInt(1)}))
+	if fault, ok := err.(Fault); !ok || fault.Code != FaultInvalidParams.Code {
+		t.Error("Expected an invalid params fault, got:", err)
+	}
+}
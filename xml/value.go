@@ -0,0 +1,391 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rogpeppe/go-charset/charset"
+)
+
+// Kind is the XML-RPC type of a Value, named after its element. Tags
+// unknown to the spec keep their element name as Kind.
+type Kind string
+
+// XML-RPC types.
+const (
+	KindNil      Kind = "nil"
+	KindInt      Kind = "int"
+	KindDouble   Kind = "double"
+	KindBoolean  Kind = "boolean"
+	KindString   Kind = "string"
+	KindDateTime Kind = "dateTime.iso8601"
+	KindBase64   Kind = "base64"
+	KindStruct   Kind = "struct"
+	KindArray    Kind = "array"
+)
+
+// Value is a XML-RPC value tree, decoded without a target Go type.
+//
+// A struct or array field of type Value receives the value tree as is, and a
+// Value is encoded back unchanged, so Value can be used for parts of a
+// document whose shape isn't known in advance.
+type Value struct {
+	Kind Kind
+
+	// Text is the content of a scalar as it's on the wire.
+	Text string
+
+	// Members of a struct, in document order.
+	Members []Member
+
+	// Items of an array.
+	Items []Value
+}
+
+// Member is a named struct member.
+type Member struct {
+	Name  string
+	Value Value
+}
+
+var valueType = reflect.TypeOf(Value{})
+
+// NewInt returns an int Value.
+func NewInt(i int64) Value {
+	return Value{Kind: KindInt, Text: strconv.FormatInt(i, 10)}
+}
+
+// NewDouble returns a double Value.
+func NewDouble(f float64) Value {
+	return Value{Kind: KindDouble, Text: strconv.FormatFloat(f, 'f', -1, 64)}
+}
+
+// NewBoolean returns a boolean Value.
+func NewBoolean(b bool) Value {
+	if b {
+		return Value{Kind: KindBoolean, Text: "1"}
+	}
+	return Value{Kind: KindBoolean, Text: "0"}
+}
+
+// NewString returns a string Value.
+func NewString(s string) Value {
+	return Value{Kind: KindString, Text: s}
+}
+
+// NewDateTime returns a dateTime.iso8601 Value.
+func NewDateTime(t time.Time) Value {
+	return Value{Kind: KindDateTime, Text: t.Format("20060102T15:04:05")}
+}
+
+// localDateTime returns the dateTime.iso8601 Value of the instant t. The
+// value has no offset and is decoded as a local time, so t is formatted in
+// the local time zone.
+func localDateTime(t time.Time) Value {
+	return NewDateTime(t.In(time.Local))
+}
+
+// NewBase64 returns a base64 Value.
+func NewBase64(b []byte) Value {
+	return Value{Kind: KindBase64, Text: base64.StdEncoding.EncodeToString(b)}
+}
+
+// NewStruct returns a struct Value.
+func NewStruct(members ...Member) Value {
+	return Value{Kind: KindStruct, Members: members}
+}
+
+// NewArray returns an array Value.
+func NewArray(items ...Value) Value {
+	return Value{Kind: KindArray, Items: items}
+}
+
+// Int returns the integer of an int Value.
+func (v Value) Int() (int64, error) {
+	return strconv.ParseInt(strings.TrimSpace(v.Text), 10, 64)
+}
+
+// Double returns the number of a double or int Value.
+func (v Value) Double() (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(v.Text), 64)
+}
+
+// Boolean returns the truth of a boolean Value.
+func (v Value) Boolean() bool {
+	return xml2Bool(strings.TrimSpace(v.Text))
+}
+
+// DateTime returns the time of a dateTime.iso8601 Value.
+func (v Value) DateTime() (time.Time, error) {
+	return xml2DateTime(strings.TrimSpace(v.Text))
+}
+
+// Base64 returns the data of a base64 Value.
+func (v Value) Base64() ([]byte, error) {
+	return xml2Base64(strings.TrimSpace(v.Text))
+}
+
+// Member returns the value of the struct member name.
+func (v Value) Member(name string) (Value, bool) {
+	for _, m := range v.Members {
+		if m.Name == name {
+			return m.Value, true
+		}
+	}
+	return Value{}, false
+}
+
+// String returns the XML representation of v.
+func (v Value) String() string {
+	var buffer bytes.Buffer
+	v.writeXML(&buffer)
+	return buffer.String()
+}
+
+func (v Value) writeXML(writer io.Writer) {
+	fmt.Fprintf(writer, "<value>")
+	switch v.Kind {
+	case KindNil:
+		fmt.Fprintf(writer, "<nil/>")
+	case KindStruct:
+		fmt.Fprintf(writer, "<struct>")
+		for _, m := range v.Members {
+			fmt.Fprintf(writer, "<member><name>%s</name>", escapeString(m.Name))
+			m.Value.writeXML(writer)
+			fmt.Fprintf(writer, "</member>")
+		}
+		fmt.Fprintf(writer, "</struct>")
+	case KindArray:
+		fmt.Fprintf(writer, "<array><data>")
+		for _, item := range v.Items {
+			item.writeXML(writer)
+		}
+		fmt.Fprintf(writer, "</data></array>")
+	case "":
+		fmt.Fprintf(writer, "%s", escapeString(v.Text))
+	default:
+		fmt.Fprintf(writer, "<%s>%s</%s>", v.Kind, escapeString(v.Text), v.Kind)
+	}
+	fmt.Fprintf(writer, "</value>")
+}
+
+// ParseValue parses a single <value> element.
+func ParseValue(data []byte) (Value, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.CharsetReader = charset.NewReader
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return Value{}, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			if start.Name.Local != "value" {
+				return Value{}, fmt.Errorf("expected <value>, got <%s>", start.Name.Local)
+			}
+			return parseValue(d)
+		}
+	}
+}
+
+// value2Value converts the temporary unmarshalling structure into Value.
+func value2Value(v value) (Value, error) {
+	return ParseValue([]byte("<value>" + v.Raw + "</value>"))
+}
+
+// tokenSource is the token stream values are parsed from, an *xml.Decoder or
+// its rawTokens.
+type tokenSource interface {
+	Token() (xml.Token, error)
+	Skip() error
+}
+
+// rawTokens reads the raw tokens of a decoder, cheaper as the names aren't
+// resolved nor the elements matched, for the hot paths.
+type rawTokens struct {
+	*xml.Decoder
+}
+
+func (r rawTokens) Token() (xml.Token, error) {
+	return r.RawToken()
+}
+
+// Skip consumes the element whose start has been read, up to its end.
+func (r rawTokens) Skip() error {
+	for depth := 1; depth > 0; {
+		tok, err := r.RawToken()
+		if err != nil {
+			return err
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return nil
+}
+
+// parseValue parses the content of a <value> element, whose start has been
+// consumed, up to and including its end.
+func parseValue(d tokenSource) (Value, error) {
+	var (
+		v     Value
+		text  string
+		typed bool
+	)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return v, err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			text += string(t)
+		case xml.StartElement:
+			if typed {
+				return v, fmt.Errorf("<value> contains more than one element")
+			}
+			typed = true
+			if v, err = parseTyped(d, t); err != nil {
+				return v, err
+			}
+		case xml.EndElement:
+			if !typed {
+				// value without type element defaults to string
+				v = Value{Kind: KindString, Text: text}
+			}
+			return v, nil
+		}
+	}
+}
+
+// parseTyped parses the type element of a value.
+func parseTyped(d tokenSource, start xml.StartElement) (Value, error) {
+	switch kind := Kind(start.Name.Local); kind {
+	case "i4", "i8":
+		text, err := elementText(d)
+		return Value{Kind: KindInt, Text: strings.TrimSpace(text)}, err
+	case KindString:
+		text, err := elementText(d)
+		return Value{Kind: KindString, Text: text}, err
+	case KindNil:
+		return Value{Kind: KindNil}, d.Skip()
+	case KindStruct:
+		return parseStruct(d)
+	case KindArray:
+		return parseArray(d)
+	default:
+		text, err := elementText(d)
+		return Value{Kind: kind, Text: strings.TrimSpace(text)}, err
+	}
+}
+
+func parseStruct(d tokenSource) (Value, error) {
+	v := Value{Kind: KindStruct}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return v, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "member" {
+				return v, fmt.Errorf("unexpected <%s> in struct", t.Name.Local)
+			}
+			m, err := parseMember(d)
+			if err != nil {
+				return v, err
+			}
+			v.Members = append(v.Members, m)
+		case xml.EndElement:
+			return v, nil
+		}
+	}
+}
+
+func parseMember(d tokenSource) (Member, error) {
+	var m Member
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return m, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "name":
+				if m.Name, err = elementText(d); err != nil {
+					return m, err
+				}
+			case "value":
+				if m.Value, err = parseValue(d); err != nil {
+					return m, err
+				}
+			default:
+				return m, fmt.Errorf("unexpected <%s> in member", t.Name.Local)
+			}
+		case xml.EndElement:
+			return m, nil
+		}
+	}
+}
+
+func parseArray(d tokenSource) (Value, error) {
+	v := Value{Kind: KindArray}
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return v, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "data":
+				// items follow
+			case "value":
+				item, err := parseValue(d)
+				if err != nil {
+					return v, err
+				}
+				v.Items = append(v.Items, item)
+			default:
+				return v, fmt.Errorf("unexpected <%s> in array", t.Name.Local)
+			}
+		case xml.EndElement:
+			if t.Name.Local == "array" {
+				return v, nil
+			}
+		}
+	}
+}
+
+// elementText returns the character data of an element up to its end.
+func elementText(d tokenSource) (string, error) {
+	var text string
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return text, err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			text += string(t)
+		case xml.StartElement:
+			return text, fmt.Errorf("unexpected <%s> in scalar", t.Name.Local)
+		case xml.EndElement:
+			return text, nil
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseValue(t *testing.T) {
+	v, err := ParseValue([]byte(`<value><struct>
+	<member><name>name</name><value>untyped</value></member>
+	<member><name>id</name><value><i4> 42 </i4></value></member>
+	<member><name>tags</name><value><array><data>
+		<value><string> a </string></value>
+		<value><nil/></value>
+	</data></array></value></member>
+</struct></value>`))
+	if err != nil {
+		t.Fatal("ParseValue failed", err)
+	}
+	expected := NewStruct(
+		Member{"name", NewString("untyped")},
+		Member{"id", NewInt(42)},
+		Member{"tags", NewArray(NewString(" a "), Value{Kind: KindNil})},
+	)
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("expected %v, got %v", expected, v)
+	}
+
+	expectedXML := "<value><struct><member><name>name</name><value><string>untyped</string></value></member><member><name>id</name><value><int>42</int></value></member><member><name>tags</name><value><array><data><value><string> a </string></value><value><nil/></value></data></array></value></member></struct></value>"
+	if v.String() != expectedXML {
+		t.Errorf("expected %s, got %s", expectedXML, v.String())
+	}
+}
+
+type StructWithValue struct {
+	Method string
+	Params Value
+}
+
+func TestValueField(t *testing.T) {
+	req := &StructWithValue{"echo", NewArray(NewInt(1), NewString("two"))}
+	xml, err := rpcResponse2XMLStr(req)
+	if err != nil {
+		t.Fatal("RPC2XML conversion failed", err)
+	}
+
+	res := new(StructWithValue)
+	if err := xml2RPC(xml, res); err != nil {
+		t.Fatal("XML2RPC conversion failed", err)
+	}
+	if !reflect.DeepEqual(req, res) {
+		t.Errorf("expected %v, got %v", req, res)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2013 Ivan Danyliuk
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xml
+
+import "fmt"
+
+// WarningKind is the class of a Warning.
+type WarningKind int
+
+const (
+	// WarnCoercion is a value decoded into a field of another type, e.g.
+	// <int>1</int> into a bool with LenientBool.
+	WarnCoercion WarningKind = iota
+
+	// WarnDroppedMember is a struct member with no matching field, ignored
+	// as other members of the struct were decoded.
+	WarnDroppedMember
+
+	// WarnCharset is a document in an unsupported charset, decoded with a
+	// fallback.
+	WarnCharset
+
+	// WarnMixedContent is text dropped from a <value> holding a typed
+	// element.
+	WarnMixedContent
+)
+
+func (k WarningKind) String() string {
+	switch k {
+	case WarnCoercion:
+		return "coercion"
+	case WarnDroppedMember:
+		return "dropped member"
+	case WarnCharset:
+		return "charset"
+	case WarnMixedContent:
+		return "mixed content"
+	}
+	return fmt.Sprintf("WarningKind(%d)", int(k))
+}
+
+// Warning is a non-fatal anomaly met on decoding, reported to
+// Options.Warnings.
+type Warning struct {
+	Kind   WarningKind
+	Detail string
+}
+
+func (w Warning) String() string {
+	return w.Kind.String() + ": " + w.Detail
+}
+
+// warn reports a Warning to o.Warnings, if set.
+func (o *Options) warn(kind WarningKind, format string, args ...interface{}) {
+	if o.Warnings != nil {
+		o.Warnings(Warning{Kind: kind, Detail: fmt.Sprintf(format, args...)})
+	}
+}
@@ -9,13 +9,14 @@ import (
 	"encoding/base64"
 	"encoding/xml"
 	"fmt"
+	"math/big"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 	"unicode"
 	"unicode/utf8"
 
-	"github.com/rogpeppe/go-charset/charset"
 	_ "github.com/rogpeppe/go-charset/data"
 )
 
@@ -36,11 +37,21 @@ type value struct {
 	String   string   `xml:"string"`
 	Int      string   `xml:"int"`
 	Int4     string   `xml:"i4"`
+	Int8     string   `xml:"i8"`
 	Double   string   `xml:"double"`
 	Boolean  string   `xml:"boolean"`
 	DateTime string   `xml:"dateTime.iso8601"`
 	Base64   string   `xml:"base64"`
+	Custom   []custom `xml:",any"`
 	Raw      string   `xml:",innerxml"` // the value can be defualt string
+	Text     string   `xml:",chardata"` // the text around the typed element, if any
+}
+
+// custom holds an element unknown to the spec, e.g. a tag registered with
+// RegisterScalar.
+type custom struct {
+	XMLName xml.Name
+	Text    string `xml:",chardata"`
 }
 
 type member struct {
@@ -49,30 +60,92 @@ type member struct {
 }
 
 func xml2RPC(xmlraw string, rpc interface{}) error {
-	// Unmarshal raw XML into the temporal structure
+	return decodeRPC(xmlraw, rpc, defaultOptions)
+}
+
+func decodeRPC(xmlraw string, rpc interface{}, opts *Options) error {
+	// faults in params are located in the document as received
+	return located(decodeParams(xmlraw, rpc, opts), []byte(xmlraw), opts)
+}
+
+func decodeParams(xmlraw string, rpc interface{}, opts *Options) error {
+	if opts.IgnoreNamespaces || opts.IgnoreCase {
+		data, err := normalizeDocument([]byte(xmlraw), opts)
+		if err != nil {
+			return FaultDecode
+		}
+		xmlraw = string(data)
+	}
+
+	// Unmarshal raw XML into the temporal structure, left to xml.Unmarshal
+	// if the fast path can't
 	var ret response
-	decoder := xml.NewDecoder(bytes.NewReader([]byte(xmlraw)))
-	decoder.CharsetReader = charset.NewReader
-	err := decoder.Decode(&ret)
+	err := parseResponse([]byte(xmlraw), &ret)
 	if err != nil {
-		return FaultDecode
+		ret = response{}
+		decoder := xml.NewDecoder(bytes.NewReader([]byte(xmlraw)))
+		decoder.CharsetReader = opts.charsetReader
+		if err = decoder.Decode(&ret); err != nil {
+			return FaultDecode
+		}
 	}
 
 	if !ret.Fault.IsEmpty() {
 		return getFaultResponse(ret.Fault)
 	}
 
+	if rpc == nil {
+		// void call, the params are ignored
+		return nil
+	}
+
+	if opts.StrictParams && len(ret.Params) < requiredParams(reflect.TypeOf(rpc).Elem(), opts) {
+		fault := FaultWrongArgumentsNumber
+		fault.String += fmt.Sprintf(": got %d params", len(ret.Params))
+		return fault.withCause(ErrArityMismatch)
+	}
+
+	if opts.NamedParams {
+		if len(ret.Params) == 0 {
+			return nil
+		}
+		args := reflect.ValueOf(rpc).Elem()
+		return atStep(value2Field(ret.Params[0].Value, &args, opts), paramStep(0))
+	}
+
 	// Now, convert temporal structure into the
 	// passed rpc variable, according to it's structure
-	fieldNum := reflect.TypeOf(rpc).Elem().NumField()
-	//for i, param := range ret.Params {
-	for i := 0; i < fieldNum; i += 1 {
-		field := reflect.ValueOf(rpc).Elem().Field(i)
-		if len(ret.Params) > i {
-			err = value2Field(ret.Params[i].Value, &field)
-		} else if reflect.TypeOf(rpc).Elem().Field(i).Tag.Get("default") != "" {
-			err = value2Field(createValue(reflect.TypeOf(rpc).Elem().Field(i).Type.Kind(), reflect.TypeOf(rpc).Elem().Field(i).Tag.Get("default")), &field)
+	args := reflect.ValueOf(rpc).Elem()
+	plan := bindPlanOf(args.Type())
+	fieldNum := len(plan.fields)
+	p := 0 // the param of the field
+	for i, fp := range plan.fields {
+		if fp.omitted {
+			// never on the wire, nor written
+			continue
+		}
+		if fp.skip {
+			// encode-only, the param is consumed unread
+			p++
+			continue
+		}
+		field := args.Field(i)
+		if fp.variadic {
+			if i != fieldNum-1 {
+				return fmt.Errorf("xmlrpc: variadic field %s must be the last one", fp.sf.Name)
+			}
+			var rest []param
+			if len(ret.Params) > p {
+				rest = ret.Params[p:]
+			}
+			return params2Variadic(rest, p, &field, opts)
+		}
+		if len(ret.Params) > p {
+			err = atStep(fp.decode(ret.Params[p].Value, &field, opts), paramStep(p))
+		} else if def := fp.sf.Tag.Get("default"); def != "" {
+			err = value2Field(createValue(fp.sf.Type.Kind(), def), &field, opts)
 		}
+		p++
 		if err != nil {
 			return err
 		}
@@ -81,6 +154,46 @@ func xml2RPC(xmlraw string, rpc interface{}) error {
 	return nil
 }
 
+// member2Field decodes value into field, honoring the xmlrpc tag of the
+// struct field sf.
+func member2Field(value value, sf reflect.StructField, field *reflect.Value, opts *Options) error {
+	return newFieldPlan(sf).decode(value, field, opts)
+}
+
+// requiredParams returns the number of params needed to fill the fields of
+// typ, i.e. the fields before the last one that isn't variadic or defaulted.
+func requiredParams(typ reflect.Type, opts *Options) int {
+	if opts.NamedParams {
+		return 1
+	}
+	n, p := 0, 0
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if isSkipped(field) {
+			continue
+		}
+		p++
+		if !isVariadic(field) && field.Tag.Get("default") == "" {
+			n = p
+		}
+	}
+	return n
+}
+
+// params2Variadic decodes params, starting at index first of the call, into
+// the elements of the slice field.
+func params2Variadic(params []param, first int, field *reflect.Value, opts *Options) error {
+	slice := opts.arena.makeSlice(field.Type(), len(params))
+	for i, p := range params {
+		item := slice.Index(i)
+		if err := value2Field(p.Value, &item, opts); err != nil {
+			return atStep(err, paramStep(first+i))
+		}
+	}
+	field.Set(slice)
+	return nil
+}
+
 func createValue(kind reflect.Kind, val string) value {
 	v := value{}
 	if kind == reflect.Bool {
@@ -112,111 +225,294 @@ func getFaultResponse(fault faultValue) Fault {
 	return Fault{Code: code, String: str}
 }
 
-func value2Field(value value, field *reflect.Value) error {
+func value2Field(value value, field *reflect.Value, opts *Options) error {
 	if !field.CanSet() {
 		return FaultApplicationError
 	}
+	if value.Int8 != "" {
+		// decoded as <int>
+		value.Int, value.Int8 = value.Int8, ""
+	}
+
+	if strings.TrimSpace(value.Text) != "" && strings.Contains(value.Raw, "<") {
+		if elements, mixed := splitMixed(value.Raw); mixed {
+			if opts.StrictValues {
+				return invalidParams("<value> mixes text %q with an element", strings.TrimSpace(value.Text))
+			}
+			// the element takes precedence
+			opts.warn(WarnMixedContent, "text %q dropped before <%s>", strings.TrimSpace(value.Text), wireType(value))
+			value.Raw, value.Text = elements, ""
+		}
+	}
+
+	if len(opts.DecodeHooks) != 0 {
+		v, done, err := decodeHooks(opts.DecodeHooks, value, field)
+		if done || err != nil {
+			return err
+		}
+		value = v
+	}
+
+	if empty2Field(opts.EmptyValues, value, field) {
+		return nil
+	}
+
+	if names := enumByType(field.Type()); names != nil {
+		return enum2Field(names, value, field)
+	}
+
+	if field.CanAddr() {
+		if sd, ok := field.Addr().Interface().(StaticDecoder); ok {
+			v, err := value2Value(value)
+			if err != nil {
+				return FaultDecode
+			}
+			return sd.XMLRPCDecode(v)
+		}
+	}
+
+	if field.Type() == valueType {
+		v, err := value2Value(value)
+		if err != nil {
+			return FaultDecode
+		}
+		field.Set(reflect.ValueOf(v))
+		return nil
+	}
+
+	if ok, err := text2Field(value, field); ok {
+		return err
+	}
+
+	if ok, err := bigNumber2Field(value, field); ok {
+		return err
+	}
+
+	if opts.LenientBool {
+		if ok, err := lenientBool2Field(value, field); ok {
+			if err == nil {
+				opts.warn(WarnCoercion, "<%s> decoded into %s", wireType(value), field.Type())
+			}
+			return err
+		}
+	}
+
+	if opts.CoerceStrings {
+		if ok, err := coerceString2Field(value, field); ok {
+			if err == nil {
+				opts.warn(WarnCoercion, "<%s> decoded into %s", wireType(value), field.Type())
+			}
+			return err
+		}
+	}
+
+	if opts.BinaryStrings && binaryString2Field(value, field) {
+		return nil
+	}
 
 	var (
 		err error
 		val interface{}
 	)
 
+	custom, isCustom, err := customScalar2Val(value, field)
+	if err != nil {
+		return err
+	}
+
 	switch {
+	case isCustom:
+		val = custom
 	case value.Int != "":
-		val, _ = strconv.Atoi(value.Int)
+		val, _ = strconv.Atoi(strings.TrimSpace(value.Int))
 	case value.Int4 != "":
-		val, _ = strconv.Atoi(value.Int4)
+		val, _ = strconv.Atoi(strings.TrimSpace(value.Int4))
 	case value.Double != "":
-		val, _ = strconv.ParseFloat(value.Double, 64)
+		val, _ = strconv.ParseFloat(strings.TrimSpace(value.Double), 64)
+	case value.String != "" && field.Type() == timeType && len(opts.DateTimeLayouts) != 0:
+		val, err = opts.parseTime(strings.TrimSpace(value.String))
 	case value.String != "":
 		val = value.String
 	case value.Boolean != "":
-		val = xml2Bool(value.Boolean)
+		val = xml2Bool(strings.TrimSpace(value.Boolean))
 	case value.DateTime != "":
-		val, err = xml2DateTime(value.DateTime)
+		val, err = opts.parseTime(strings.TrimSpace(value.DateTime))
 	case value.Base64 != "":
-		val, err = xml2Base64(value.Base64)
+		val, err = opts.arena.decodeBase64(strings.TrimSpace(value.Base64))
 	case len(value.Struct) != 0:
 		if field.Kind() != reflect.Struct {
 			fault := FaultInvalidParams
 			fault.String += fmt.Sprintf("structure fields mismatch: %s != %s",
 				field.Kind(), reflect.Struct.String())
-			return fault
+			return mismatch(fault, value, field)
 		}
 		s := value.Struct
+		compiled := compiledCodec(field.Type()) != nil
+		plan := bindPlanOf(field.Type())
+		// the unknown members are dropped as long as others match a field,
+		// whatever their order; the first error is kept
+		var (
+			dropped []string
+			unknown string
+			matched bool
+		)
+		keep := func(merr error) {
+			if err == nil {
+				err = merr
+			}
+		}
 		for i := 0; i < len(s); i++ {
+			if opts.JSONCompat {
+				if f, sf, ok := jsonMember(*field, s[i].Name); ok {
+					keep(atStep(member2Field(s[i].Value, sf, &f, opts), memberStep(s[i].Name)))
+				} else {
+					dropped = append(dropped, s[i].Name)
+				}
+				continue
+			}
 			// Uppercase first letter for field name to deal with
 			// methods in lowercase, which cannot be used
 			field_name := uppercaseFirst(s[i].Name)
-			f := field.FieldByName(field_name)
-			err = value2Field(s[i].Value, &f)
+			var (
+				f  reflect.Value
+				fp *fieldPlan
+				ok bool
+			)
+			// the names given by the tags come first
+			fp, ok = plan.named[s[i].Name]
+			if !ok && compiled {
+				// the compiled types match the field names first
+				fp, ok = plan.field(field_name)
+			}
+			if !ok {
+				if sf, found := opts.memberField(field.Type(), s[i].Name); found {
+					fp, ok = plan.fields[sf.Index[0]], true
+				}
+			}
+			if !ok {
+				fp, ok = plan.field(field_name)
+			}
+			var sf reflect.StructField
+			if ok {
+				sf = fp.sf
+				f = field.Field(sf.Index[0])
+			} else {
+				// e.g. a field promoted from an embedded struct
+				f = field.FieldByName(field_name)
+				sf, ok = field.Type().FieldByName(field_name)
+			}
+			if ok && (fp != nil && fp.skip || fp == nil && skipDecode(sf)) {
+				// never written, whatever the peer sends
+				dropped = append(dropped, s[i].Name)
+				matched = true
+				continue
+			}
+			if !f.IsValid() {
+				dropped = append(dropped, s[i].Name)
+				if unknown == "" {
+					unknown = s[i].Name
+				}
+				continue
+			}
+			matched = true
+			if fp != nil {
+				keep(atStep(fp.decode(s[i].Value, &f, opts), memberStep(s[i].Name)))
+			} else if ok {
+				keep(atStep(member2Field(s[i].Value, sf, &f, opts), memberStep(s[i].Name)))
+			} else {
+				keep(atStep(value2Field(s[i].Value, &f, opts), memberStep(s[i].Name)))
+			}
+		}
+		if !matched && unknown != "" {
+			keep(atStep(FaultApplicationError.withCause(ErrUnknownMember), memberStep(unknown)))
+		}
+		if err == nil {
+			for _, name := range dropped {
+				opts.warn(WarnDroppedMember, "member %s of %s", name, field.Type())
+			}
+		}
+	case len(value.Array) != 0 && field.Kind() == reflect.Array:
+		a := value.Array
+		if len(a) != field.Len() {
+			return invalidParams("array length mismatch: %d items for %s", len(a), field.Type())
+		}
+		for i := 0; i < len(a); i++ {
+			item := field.Index(i)
+			if err := atStep(value2Field(a[i], &item, opts), itemStep(i)); err != nil {
+				return err
+			}
 		}
 	case len(value.Array) != 0:
 		a := value.Array
 		f := *field
-		slice := reflect.MakeSlice(reflect.TypeOf(f.Interface()), len(a), len(a))
+		slice := opts.arena.makeSlice(reflect.TypeOf(f.Interface()), len(a))
 		for i := 0; i < len(a); i++ {
 			item := slice.Index(i)
-			err = value2Field(a[i], &item)
+			if err := atStep(value2Field(a[i], &item, opts), itemStep(i)); err != nil {
+				return err
+			}
+		}
+		if f.Len() == 0 {
+			// no copy, keeping the slice in the arena
+			f = slice
+		} else {
+			f = reflect.AppendSlice(f, slice)
 		}
-		f = reflect.AppendSlice(f, slice)
 		val = f.Interface()
 
 	default:
 		// value field is default to string, see http://en.wikipedia.org/wiki/XML-RPC#Data_types
-		// also can be <nil/>
-		if value.Raw != "<nil/>" {
+		// also can be <nil/>, leaving the field as is, or an empty element
+		if field.Kind() == reflect.Slice && emptyArray(value.Raw) {
+			// an empty <array>, leaving the slice as is, whatever the type
+			// of its items
+			break
+		}
+		if field.Kind() == reflect.Array && emptyArray(value.Raw) {
+			if field.Len() != 0 {
+				return invalidParams("array length mismatch: 0 items for %s", field.Type())
+			}
+			break
+		}
+		switch strings.TrimSpace(value.Raw) {
+		case "<nil/>", "<nil></nil>":
+		case "<struct></struct>", "<struct/>":
+			// no members, e.g. with every member filtered out
+			if field.Kind() != reflect.Struct {
+				fault := FaultInvalidParams
+				fault.String += fmt.Sprintf("structure fields mismatch: %s != %s",
+					field.Kind(), reflect.Struct.String())
+				return &pathError{fault: fault, expected: field.Type().String(), got: "struct"}
+			}
+		case "<string></string>", "<string/>":
+			// an empty string, as the field may hold another
+			val = ""
+		case "<base64></base64>", "<base64/>":
+			// no bytes, e.g. the last chunk of an upload
+			val = []byte{}
+		default:
 			val = value.Raw
 		}
 	}
 
+	if n, ok := val.(int); ok && field.Type() != reflect.TypeOf(n) && isIntegerKind(field.Kind()) {
+		return int2Field(n, value, field)
+	}
+
 	assignFlag := false
 	if val != nil {
 		if reflect.TypeOf(val) != reflect.TypeOf(field.Interface()) {
 			if field.Kind() == reflect.Slice {
 				if reflect.TypeOf(field.Interface()).Elem().Kind() == reflect.TypeOf(val).Kind() {
-					// fieldSlice := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(val)), 1, 1)
-					fieldSlice := reflect.MakeSlice(reflect.TypeOf((*field).Interface()), 1, 1)
+					// a single value is decoded as the only item of the
+					// slice, whatever its type, e.g. dateTime or base64
+					fieldSlice := opts.arena.makeSlice(field.Type(), 1)
 					item := fieldSlice.Index(0)
-
-					// err = value2Field(value, &item)
-					switch item.Type().Kind() {
-					case reflect.Bool:
-						val = xml2Bool(value.Boolean)
-
-					case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int:
-						if value.Int4 != "" {
-							val, _ = strconv.Atoi(value.Int4)
-						} else {
-							val, _ = strconv.Atoi(value.Int)
-						}
-
-					case reflect.Float32, reflect.Float64:
-						val, _ = strconv.ParseFloat(value.Double, 64)
-
-					case reflect.String:
-						val = value.String
-
-					case reflect.Struct:
-						if field.Kind() != reflect.Struct {
-							fault := FaultInvalidParams
-							fault.String += fmt.Sprintf("structure fields mismatch: %s != %s",
-								field.Kind(), reflect.Struct.String())
-							return fault
-						}
-						s := value.Struct
-						for i := 0; i < len(s); i++ {
-							// Uppercase first letter for field name to deal with
-							// methods in lowercase, which cannot be used
-							field_name := uppercaseFirst(s[i].Name)
-							f := field.FieldByName(field_name)
-							err = value2Field(s[i].Value, &f)
-						}
-					default:
-						val = value.Raw
+					if item.Kind() == reflect.String {
+						item.SetString(value.String)
+					} else if err := value2Field(value, &item, opts); err != nil {
+						return err
 					}
-					item.Set(reflect.ValueOf(val))
 					field.Set(fieldSlice)
 
 					assignFlag = true
@@ -228,7 +524,7 @@ func value2Field(value value, field *reflect.Value) error {
 				fault.String += fmt.Sprintf(": fields type mismatch: value type %s != field type %s",
 					reflect.TypeOf(val),
 					reflect.TypeOf(field.Interface()))
-				return fault
+				return mismatch(fault, value, field)
 			}
 		}
 
@@ -240,6 +536,239 @@ func value2Field(value value, field *reflect.Value) error {
 	return err
 }
 
+// isIntegerKind reports whether kind is a signed or unsigned integer kind.
+func isIntegerKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// int2Field decodes the integer n into field, of any integer type, unless it
+// overflows.
+func int2Field(n int, value value, field *reflect.Value) error {
+	if field.Kind() >= reflect.Uint {
+		if n < 0 || field.OverflowUint(uint64(n)) {
+			return mismatch(invalidParams("%d overflows %s", n, field.Type()), value, field)
+		}
+		field.SetUint(uint64(n))
+		return nil
+	}
+	if field.OverflowInt(int64(n)) {
+		return mismatch(invalidParams("%d overflows %s", n, field.Type()), value, field)
+	}
+	field.SetInt(int64(n))
+	return nil
+}
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+)
+
+// bigNumber2Field decodes <int>, <i4> and <double> values into big.Int and
+// big.Float fields (or pointers to them), and numbers exceeding the native
+// int/float64 range into string fields. It reports whether value was handled.
+func bigNumber2Field(value value, field *reflect.Value) (bool, error) {
+	text, isInt := value.Int, true
+	if text == "" {
+		text = value.Int4
+	}
+	if text == "" {
+		text, isInt = value.Double, false
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return false, nil
+	}
+
+	typ := field.Type()
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	var num reflect.Value
+	switch {
+	case typ == bigIntType:
+		n, ok := new(big.Int).SetString(text, 10)
+		if !ok {
+			fault := FaultInvalidParams
+			fault.String += fmt.Sprintf(": can't convert %q to %s", text, typ)
+			return true, fault
+		}
+		num = reflect.ValueOf(n)
+	case typ == bigFloatType:
+		// Keep enough mantissa bits for every decimal digit on the wire.
+		prec := uint(len(text)) * 4
+		if prec < 64 {
+			prec = 64
+		}
+		f, _, err := big.ParseFloat(text, 10, prec, big.ToNearestEven)
+		if err != nil {
+			fault := FaultInvalidParams
+			fault.String += fmt.Sprintf(": can't convert %q to %s", text, typ)
+			return true, fault
+		}
+		num = reflect.ValueOf(f)
+	case field.Kind() == reflect.String && numberOverflows(text, isInt):
+		field.SetString(text)
+		return true, nil
+	default:
+		return false, nil
+	}
+
+	if field.Kind() == reflect.Ptr {
+		field.Set(num)
+	} else {
+		field.Set(num.Elem())
+	}
+	return true, nil
+}
+
+// numberOverflows reports whether text is out of the native int (or float64)
+// range.
+func numberOverflows(text string, isInt bool) bool {
+	var err error
+	if isInt {
+		_, err = strconv.Atoi(text)
+	} else {
+		_, err = strconv.ParseFloat(text, 64)
+	}
+	numErr, ok := err.(*strconv.NumError)
+	return ok && numErr.Err == strconv.ErrRange
+}
+
+// lenientBool2Field decodes <int> and <i4> values 0 and 1 into bool fields,
+// and <boolean> values into integer fields. It reports whether value was
+// handled.
+func lenientBool2Field(value value, field *reflect.Value) (bool, error) {
+	switch field.Kind() {
+	case reflect.Bool:
+		text := value.Int
+		if text == "" {
+			text = value.Int4
+		}
+		switch strings.TrimSpace(text) {
+		case "":
+			return false, nil
+		case "0":
+			field.SetBool(false)
+		case "1":
+			field.SetBool(true)
+		default:
+			fault := FaultInvalidParams
+			fault.String += fmt.Sprintf(": can't convert %q to bool", text)
+			return true, fault
+		}
+		return true, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value.Boolean == "" {
+			return false, nil
+		}
+		var n int64
+		if xml2Bool(value.Boolean) {
+			n = 1
+		}
+		if field.Kind() >= reflect.Uint {
+			field.SetUint(uint64(n))
+		} else {
+			field.SetInt(n)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// coerceString2Field decodes numeric <string> values into integer and float
+// fields, and <int>, <i4> and <double> values into string fields. It reports
+// whether value was handled.
+func coerceString2Field(value value, field *reflect.Value) (bool, error) {
+	switch field.Kind() {
+	case reflect.String:
+		text := value.Int
+		if text == "" {
+			text = value.Int4
+		}
+		if text == "" {
+			text = value.Double
+		}
+		if text == "" {
+			return false, nil
+		}
+		field.SetString(strings.TrimSpace(text))
+		return true, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if value.String == "" {
+			return false, nil
+		}
+		text := strings.TrimSpace(value.String)
+		var err error
+		switch field.Kind() {
+		case reflect.Float32, reflect.Float64:
+			var f float64
+			if f, err = strconv.ParseFloat(text, field.Type().Bits()); err == nil {
+				field.SetFloat(f)
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			var n uint64
+			if n, err = strconv.ParseUint(text, 10, field.Type().Bits()); err == nil {
+				field.SetUint(n)
+			}
+		default:
+			var n int64
+			if n, err = strconv.ParseInt(text, 10, field.Type().Bits()); err == nil {
+				field.SetInt(n)
+			}
+		}
+		if err != nil {
+			fault := FaultInvalidParams
+			fault.String += fmt.Sprintf(": can't convert %q to %s", value.String, field.Type())
+			return true, fault
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// splitMixed returns the inner XML raw of a <value> without its text, and
+// reports whether raw mixes text with an element.
+func splitMixed(raw string) (string, bool) {
+	var (
+		elements      bytes.Buffer
+		text, element bool
+		depth         int
+	)
+	d := xml.NewDecoder(strings.NewReader(raw))
+	for {
+		start := d.InputOffset()
+		tok, err := d.RawToken()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			element = true
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 {
+				text = text || len(bytes.TrimSpace(t)) != 0
+				continue
+			}
+		}
+		elements.WriteString(raw[start:d.InputOffset()])
+	}
+	return elements.String(), text && element
+}
+
 func xml2Bool(value string) bool {
 	var b bool
 	switch value {
@@ -251,7 +780,27 @@ func xml2Bool(value string) bool {
 	return b
 }
 
+// isoDateTimeLayouts are the layouts of the dateTime.iso8601 values beyond
+// the standard one: with an offset, in the extended format, or both. The
+// fractional seconds are accepted by any of them.
+var isoDateTimeLayouts = []string{
+	"20060102T15:04:05Z07:00",
+	"20060102T15:04:05Z0700",
+	"20060102T15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+}
+
 func xml2DateTime(value string) (time.Time, error) {
+	if len(value) != len("20060102T15:04:05") {
+		for _, layout := range isoDateTimeLayouts {
+			if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+				return t, nil
+			}
+		}
+	}
+
 	var (
 		year, month, day     int
 		hour, minute, second int
@@ -263,11 +812,24 @@ func xml2DateTime(value string) (time.Time, error) {
 	return t, err
 }
 
+// emptyArray reports whether raw is an empty <array>, e.g.
+// "<array><data>\n</data></array>" as WordPress sends them.
+func emptyArray(raw string) bool {
+	switch strings.Join(strings.Fields(raw), "") {
+	case "<array><data></data></array>", "<array><data/></array>", "<array></array>", "<array/>":
+		return true
+	}
+	return false
+}
+
 func xml2Base64(value string) ([]byte, error) {
 	return base64.StdEncoding.DecodeString(value)
 }
 
 func uppercaseFirst(in string) (out string) {
 	r, n := utf8.DecodeRuneInString(in)
+	if unicode.IsUpper(r) {
+		return in
+	}
 	return string(unicode.ToUpper(r)) + in[n:]
 }
@@ -5,7 +5,7 @@
 package xml
 
 import (
-	"fmt"
+	"math/big"
 	"reflect"
 	"strings"
 	"testing"
@@ -377,11 +377,606 @@ func TestSupervisorMulticallNilParams(t *testing.T) {
 	if err != nil {
 		t.Errorf("xml2RPC() = error:%s", err)
 	}
-	if lg := len(args.Methods[0].Params); lg != 1 {
-		t.Errorf("params len %d != 1", lg)
+	if lg := len(args.Methods[0].Params); lg != 0 {
+		t.Errorf("params len %d != 0", lg)
 	}
-	fmt.Printf("params[0]:%s\n", args.Methods[0].Params[0])
-	if lg := len(args.Methods[0].Params[0]); lg != 0 {
-		t.Errorf("params[0] len %d != 1", lg)
+}
+
+type StructBigNumbersXml2Rpc struct {
+	Big      *big.Int
+	BigValue big.Int
+	Float    *big.Float
+	Overflow string
+}
+
+func TestXML2RPCBigNumbers(t *testing.T) {
+	req := new(StructBigNumbersXml2Rpc)
+	err := xml2RPC(`<methodResponse><params>
+<param><value><int>123456789012345678901234567890</int></value></param>
+<param><value><i4>42</i4></value></param>
+<param><value><double>1.5e400</double></value></param>
+<param><value><int>99999999999999999999</int></value></param>
+</params></methodResponse>`, req)
+	if err != nil {
+		t.Fatal("XML2RPC conversion failed", err)
+	}
+	if req.Big.String() != "123456789012345678901234567890" {
+		t.Errorf("wrong big.Int: %s", req.Big)
+	}
+	if req.BigValue.Int64() != 42 {
+		t.Errorf("wrong big.Int value: %s", req.BigValue.String())
+	}
+	if req.Float.Text('e', 1) != "1.5e+400" {
+		t.Errorf("wrong big.Float: %s", req.Float.Text('e', 1))
+	}
+	if req.Overflow != "99999999999999999999" {
+		t.Errorf("wrong overflow string: %s", req.Overflow)
+	}
+}
+
+type StructVariadicXml2Rpc struct {
+	Group string
+	Ids   []int `xmlrpc:",variadic"`
+}
+
+func TestXML2RPCVariadic(t *testing.T) {
+	req := new(StructVariadicXml2Rpc)
+	err := xml2RPC("<methodCall><params><param><value><string>web</string></value></param><param><value><int>1</int></value></param><param><value><int>2</int></value></param></params></methodCall>", req)
+	if err != nil {
+		t.Fatal("XML2RPC conversion failed", err)
+	}
+	expected_req := &StructVariadicXml2Rpc{"web", []int{1, 2}}
+	if !reflect.DeepEqual(req, expected_req) {
+		t.Error("XML2RPC conversion failed")
+		t.Error("Expected", expected_req)
+		t.Error("Got", req)
+	}
+
+	req = new(StructVariadicXml2Rpc)
+	if err := xml2RPC("<methodCall><params><param><value><string>web</string></value></param></params></methodCall>", req); err != nil {
+		t.Fatal("XML2RPC conversion failed", err)
+	}
+	if len(req.Ids) != 0 {
+		t.Errorf("expected no ids, got %v", req.Ids)
+	}
+}
+
+type StructVoidXml2Rpc struct {
+	Ok   bool
+	Note string `default:"none"`
+}
+
+func TestXML2RPCVoid(t *testing.T) {
+	for _, xmlStr := range []string{
+		"<methodResponse><params/></methodResponse>",
+		"<methodResponse><params></params></methodResponse>",
+		"<methodResponse></methodResponse>",
+	} {
+		res := new(StructVoidXml2Rpc)
+		if err := xml2RPC(xmlStr, res); err != nil {
+			t.Error("XML2RPC conversion failed for", xmlStr, err)
+		}
+		if err := decodeRPC(xmlStr, res, &Options{StrictParams: true}); err == nil {
+			t.Error("Expected strict decoding to fail for", xmlStr)
+		}
+		if err := xml2RPC(xmlStr, nil); err != nil {
+			t.Error("XML2RPC conversion failed for nil reply", xmlStr, err)
+		}
+	}
+
+	res := new(StructVoidXml2Rpc)
+	xmlStr := "<methodResponse><params><param><value><boolean>1</boolean></value></param></params></methodResponse>"
+	if err := decodeRPC(xmlStr, res, &Options{StrictParams: true}); err != nil || !res.Ok {
+		t.Error("Expected strict decoding to succeed, got", res, err)
+	}
+
+	xmlStr = "<methodResponse><fault><value><struct><member><name>faultCode</name><value><int>1</int></value></member><member><name>faultString</name><value><string>boom</string></value></member></struct></value></fault></methodResponse>"
+	if err := xml2RPC(xmlStr, nil); err == nil {
+		t.Error("Expected a fault for nil reply")
+	}
+}
+
+type StructLenientBoolXml2Rpc struct {
+	Running bool
+	Flags   []bool
+	Count   int
+	Size    uint8
+}
+
+func TestXML2RPCLenientBool(t *testing.T) {
+	xmlStr := "<methodResponse><params>" +
+		"<param><value><int>1</int></value></param>" +
+		"<param><value><array><data><value><i4>0</i4></value><value><boolean>1</boolean></value></data></array></value></param>" +
+		"<param><value><boolean>1</boolean></value></param>" +
+		"<param><value><boolean>0</boolean></value></param>" +
+		"</params></methodResponse>"
+
+	res := new(StructLenientBoolXml2Rpc)
+	if err := xml2RPC(xmlStr, res); err == nil {
+		t.Error("Expected strict decoding to fail")
+	}
+
+	res = new(StructLenientBoolXml2Rpc)
+	if err := decodeRPC(xmlStr, res, &Options{LenientBool: true}); err != nil {
+		t.Fatal("XML2RPC conversion failed", err)
+	}
+	expected := &StructLenientBoolXml2Rpc{true, []bool{false, true}, 1, 0}
+	if !reflect.DeepEqual(res, expected) {
+		t.Error("Expected", expected)
+		t.Error("Got", res)
+	}
+
+	xmlStr = "<methodResponse><params><param><value><int>2</int></value></param></params></methodResponse>"
+	if err := decodeRPC(xmlStr, new(StructLenientBoolXml2Rpc), &Options{LenientBool: true}); err == nil {
+		t.Error("Expected 2 not to decode into bool")
+	}
+}
+
+type StructCoerceXml2Rpc struct {
+	Id    int
+	Ratio float64
+	Port  uint16
+	Code  string
+}
+
+func TestXML2RPCCoerceStrings(t *testing.T) {
+	xmlStr := "<methodResponse><params>" +
+		"<param><value><string>42</string></value></param>" +
+		"<param><value><string> 0.5 </string></value></param>" +
+		"<param><value><string>8080</string></value></param>" +
+		"<param><value><int>7</int></value></param>" +
+		"</params></methodResponse>"
+
+	if err := xml2RPC(xmlStr, new(StructCoerceXml2Rpc)); err == nil {
+		t.Error("Expected strict decoding to fail")
+	}
+
+	res := new(StructCoerceXml2Rpc)
+	if err := decodeRPC(xmlStr, res, &Options{CoerceStrings: true}); err != nil {
+		t.Fatal("XML2RPC conversion failed", err)
+	}
+	expected := &StructCoerceXml2Rpc{42, 0.5, 8080, "7"}
+	if !reflect.DeepEqual(res, expected) {
+		t.Error("Expected", expected)
+		t.Error("Got", res)
+	}
+
+	for _, xmlStr := range []string{
+		"<methodResponse><params><param><value><string>forty-two</string></value></param></params></methodResponse>",
+		"<methodResponse><params><param><value><int>0</int></value></param><param><value><double>0</double></value></param><param><value><string>70000</string></value></param></params></methodResponse>",
+	} {
+		if err := decodeRPC(xmlStr, new(StructCoerceXml2Rpc), &Options{CoerceStrings: true}); err == nil {
+			t.Error("Expected conversion to fail for", xmlStr)
+		}
+	}
+}
+
+type StructNamespacedXml2Rpc struct {
+	Name  string
+	Ptr   *int
+	Empty string
+	Tree  Value
+}
+
+func TestXML2RPCIgnoreNamespaces(t *testing.T) {
+	xmlStr := `<?xml version="1.0"?><ns:methodResponse xmlns:ns="urn:gateway"><ns:params>` +
+		`<ns:param><ns:value><ns:string>web</ns:string></ns:value></ns:param>` +
+		`<ns:param><ns:value><ns:nil/></ns:value></ns:param>` +
+		`<ns:param><ns:value><ns:string/></ns:value></ns:param>` +
+		`<ns:param><ns:value><ns:array><ns:data><ns:value><ns:i4>1</ns:i4></ns:value></ns:data></ns:array></ns:value></ns:param>` +
+		`</ns:params></ns:methodResponse>`
+
+	if err := xml2RPC(xmlStr, new(StructNamespacedXml2Rpc)); err == nil {
+		t.Error("Expected prefixed <ns:nil/> to fail without IgnoreNamespaces")
+	}
+
+	res := new(StructNamespacedXml2Rpc)
+	if err := decodeRPC(xmlStr, res, &Options{IgnoreNamespaces: true}); err != nil {
+		t.Fatal("XML2RPC conversion failed", err)
+	}
+	expected := &StructNamespacedXml2Rpc{Name: "web", Tree: NewArray(NewInt(1))}
+	if !reflect.DeepEqual(res, expected) {
+		t.Error("Expected", expected)
+		t.Error("Got", res)
+	}
+}
+
+type StructIgnoreCaseXml2Rpc struct {
+	Ok   bool
+	When time.Time
+	N    int
+}
+
+func TestXML2RPCIgnoreCase(t *testing.T) {
+	xmlStr := `<MethodResponse><Params>` +
+		`<Param><Value><Boolean>1</Boolean></Value></Param>` +
+		`<Param><Value><DateTime.ISO8601>20140517T10:20:30</DateTime.ISO8601></Value></Param>` +
+		`<Param><Value><Int>7</Int></Value></Param>` +
+		`</Params></MethodResponse>`
+
+	res := new(StructIgnoreCaseXml2Rpc)
+	if err := xml2RPC(xmlStr, res); err != nil || res.Ok {
+		t.Error("Expected <Params> to be ignored without IgnoreCase, got", res, err)
+	}
+
+	res = new(StructIgnoreCaseXml2Rpc)
+	if err := decodeRPC(xmlStr, res, &Options{IgnoreCase: true}); err != nil {
+		t.Fatal("XML2RPC conversion failed", err)
+	}
+	expected := &StructIgnoreCaseXml2Rpc{true, time.Date(2014, 5, 17, 10, 20, 30, 0, time.Local), 7}
+	if !reflect.DeepEqual(res, expected) {
+		t.Error("Expected", expected)
+		t.Error("Got", res)
+	}
+}
+
+type LocatedItem struct {
+	Data []int
+}
+
+func TestXML2RPCLocatedFault(t *testing.T) {
+	xmlraw := `<?xml version="1.0"?>
+<methodCall>
+  <methodName>Some.Method</methodName>
+  <params>
+    <param><value><int>1</int></value></param>
+    <param><value><string>two</string></value></param>
+    <param><value><struct>
+      <member><name>Data</name><value><array><data>
+        <value><int>1</int></value>
+        <value><boolean>1</boolean></value>
+      </data></array></value></member>
+    </struct></value></param>
+  </params>
+</methodCall>`
+	req := &struct {
+		A int
+		B string
+		C LocatedItem
+	}{}
+	err := xml2RPC(xmlraw, req)
+	fault, ok := asFault(err)
+	if !ok || fault.Code != FaultInvalidParams.Code {
+		t.Fatal("Expected invalid params fault, got", err)
+	}
+	for _, detail := range []string{
+		"at params[2].value.struct.member[Data].array.data.value[1]",
+		"(line 10, column 9)",
+		"expected int, got <boolean>",
+	} {
+		if !strings.Contains(fault.String, detail) {
+			t.Errorf("Expected %q in %q", detail, fault.String)
+		}
+	}
+}
+
+func TestXML2RPCWarnings(t *testing.T) {
+	var warnings []Warning
+	opts := &Options{LenientBool: true, CoerceStrings: true, Warnings: func(w Warning) {
+		warnings = append(warnings, w)
+	}}
+	xmlraw := `<methodResponse><params>
+		<param><value><int>1</int></value></param>
+		<param><value><string>42</string></value></param>
+		<param><value><struct>
+			<member><name>Nick</name><value><string>vano</string></value></member>
+			<member><name>Name</name><value><string>Ivan</string></value></member>
+		</struct></value></param>
+	</params></methodResponse>`
+	req := &struct {
+		Ok     bool
+		Answer int
+		Person struct{ Name string }
+	}{}
+	if err := decodeRPC(xmlraw, req, opts); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if !req.Ok || req.Answer != 42 || req.Person.Name != "Ivan" {
+		t.Errorf("Unexpected decoding %+v", req)
+	}
+
+	kinds := []WarningKind{WarnCoercion, WarnCoercion, WarnDroppedMember}
+	if len(warnings) != len(kinds) {
+		t.Fatalf("Expected %d warnings, got %v", len(kinds), warnings)
+	}
+	for i, kind := range kinds {
+		if warnings[i].Kind != kind {
+			t.Errorf("Expected warning %d to be %s, got %s", i, kind, warnings[i])
+		}
+	}
+	if warnings[2].Detail != "member Nick of struct { Name string }" {
+		t.Error("Unexpected warning", warnings[2])
+	}
+}
+
+func TestXML2RPCUnknownMemberOrder(t *testing.T) {
+	members := []string{
+		`<member><name>Nick</name><value><string>vano</string></value></member>`,
+		`<member><name>Name</name><value><string>Ivan</string></value></member>`,
+		`<member><name>Age</name><value><int>33</int></value></member>`,
+	}
+	orders := [][]int{{0, 1, 2}, {1, 0, 2}, {1, 2, 0}, {2, 1, 0}}
+	for _, order := range orders {
+		var dropped int
+		opts := &Options{Warnings: func(w Warning) {
+			if w.Kind == WarnDroppedMember {
+				dropped++
+			}
+		}}
+		var xmlraw string
+		for _, i := range order {
+			xmlraw += members[i]
+		}
+		var reply struct {
+			Person struct {
+				Name string
+				Age  int
+			}
+		}
+		err := decodeRPC(toResponse(`<params><param><value><struct>`+xmlraw+`</struct></value></param></params>`), &reply, opts)
+		if err != nil {
+			t.Errorf("Expected the unknown member dropped in order %v, got %v", order, err)
+		}
+		if reply.Person.Name != "Ivan" || reply.Person.Age != 33 || dropped != 1 {
+			t.Errorf("Unexpected decoding in order %v: %+v, %d dropped", order, reply, dropped)
+		}
+	}
+
+	// the first error is kept, whatever follows
+	var reply struct {
+		Person struct {
+			Name string
+			Age  int
+		}
+	}
+	xmlraw := `<member><name>Age</name><value><string>old</string></value></member>` + members[0] + members[1]
+	err := decodeRPC(toResponse(`<params><param><value><struct>`+xmlraw+`</struct></value></param></params>`), &reply, &Options{})
+	if err == nil || !strings.Contains(err.Error(), "Age") {
+		t.Error("Expected the error of Age, got", err)
+	}
+}
+
+func TestXML2RPCEmptyStruct(t *testing.T) {
+	reply := struct {
+		Person struct{ Name string }
+	}{}
+	reply.Person.Name = "kept"
+	if err := decodeRPC(toResponse(`<params><param><value><struct></struct></value></param></params>`), &reply, &Options{}); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if reply.Person.Name != "kept" {
+		t.Error("Expected the struct without members left as is, got", reply.Person)
+	}
+
+	for _, raw := range []string{"<struct></struct>", "<struct/>"} {
+		var scalar struct{ Name string }
+		err := decodeRPC(toResponse(`<params><param><value>`+raw+`</value></param></params>`), &scalar, &Options{})
+		if err == nil || !strings.Contains(err.Error(), "expected string, got <struct>") {
+			t.Errorf("Expected %s not to be decoded into a string, got %v", raw, err)
+		}
+	}
+}
+
+func TestXML2RPCEmptyString(t *testing.T) {
+	for _, raw := range []string{"<string></string>", "<string/>", " <string/> "} {
+		reply := struct{ Status string }{"stale"}
+		if err := decodeRPC(toResponse(`<params><param><value>`+raw+`</value></param></params>`), &reply, &Options{}); err != nil {
+			t.Fatal("Expected err to be nil, but got:", err)
+		}
+		if reply.Status != "" {
+			t.Errorf("Expected %s decoded as an empty string, got %q", raw, reply.Status)
+		}
+	}
+}
+
+func TestXML2RPCEmptyBase64(t *testing.T) {
+	for _, raw := range []string{"<base64></base64>", "<base64/>"} {
+		reply := struct{ Data []byte }{[]byte("stale")}
+		if err := decodeRPC(toResponse(`<params><param><value>`+raw+`</value></param></params>`), &reply, &Options{}); err != nil {
+			t.Fatal("Expected err to be nil, but got:", err)
+		}
+		if reply.Data == nil || len(reply.Data) != 0 {
+			t.Errorf("Expected %s decoded as no bytes, got %v", raw, reply.Data)
+		}
+	}
+}
+
+func TestXML2RPCTimeAndBytesSlices(t *testing.T) {
+	type Reply struct {
+		Times []time.Time
+		Blobs [][]byte
+	}
+	t1 := time.Date(2013, 1, 1, 10, 0, 0, 0, time.Local)
+	t2 := time.Date(2014, 2, 3, 4, 5, 6, 0, time.Local)
+
+	var arrays Reply
+	xmlraw, err := rpcResponse2XMLStr(&Reply{[]time.Time{t1, t2}, [][]byte{[]byte("hi"), {}}})
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if err := xml2RPC(xmlraw, &arrays); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if len(arrays.Times) != 2 || !arrays.Times[0].Equal(t1) || !arrays.Times[1].Equal(t2) ||
+		len(arrays.Blobs) != 2 || string(arrays.Blobs[0]) != "hi" || len(arrays.Blobs[1]) != 0 {
+		t.Errorf("Unexpected decoding %+v", arrays)
+	}
+
+	// single values are decoded as the only item
+	var single Reply
+	xmlraw = `<methodResponse><params>
+		<param><value><dateTime.iso8601>20130101T10:00:00</dateTime.iso8601></value></param>
+		<param><value><base64>aGk=</base64></value></param>
+	</params></methodResponse>`
+	if err := xml2RPC(xmlraw, &single); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if len(single.Times) != 1 || !single.Times[0].Equal(t1) || len(single.Blobs) != 1 || string(single.Blobs[0]) != "hi" {
+		t.Errorf("Unexpected decoding %+v", single)
+	}
+}
+
+func TestXML2RPCFixedArrays(t *testing.T) {
+	type Reply struct {
+		Point [3]int
+		Names [2]string
+	}
+	xmlraw, err := rpcResponse2XMLStr(&Reply{[3]int{1, 2, 3}, [2]string{"Ivan", "Johnny"}})
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	var reply Reply
+	if err := xml2RPC(xmlraw, &reply); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if reply.Point != [3]int{1, 2, 3} || reply.Names != [2]string{"Ivan", "Johnny"} {
+		t.Errorf("Unexpected decoding %+v", reply)
+	}
+
+	xmlraw = `<methodResponse><params><param><value><array><data>
+		<value><int>1</int></value><value><int>2</int></value>
+	</data></array></value></param></params></methodResponse>`
+	err = xml2RPC(xmlraw, &reply)
+	fault, ok := err.(Fault)
+	if !ok || fault.Code != FaultInvalidParams.Code || !strings.Contains(fault.String, "array length mismatch: 2 items for [3]int at params[0].value") {
+		t.Error("Expected length mismatch fault, got", err)
+	}
+	if issues := Validate([]byte(xmlraw), &reply); len(issues) == 0 || !strings.Contains(issues[0].String(), "length mismatch") {
+		t.Error("Expected length mismatch issue, got", issues)
+	}
+
+	for _, empty := range []string{"<array><data></data></array>", "<array><data/></array>"} {
+		xmlraw = `<methodResponse><params><param><value>` + empty + `</value></param></params></methodResponse>`
+		err = xml2RPC(xmlraw, &reply)
+		if fault, ok := asFault(err); !ok || !strings.Contains(fault.String, "array length mismatch: 0 items for [3]int at params[0].value") {
+			t.Error("Expected length mismatch fault for an empty array, got", err)
+		}
+		if issues := Validate([]byte(xmlraw), &reply); len(issues) == 0 || issues[0].String() != "params[0]: length mismatch: 0 items for [3]int" {
+			t.Error("Expected length mismatch issue for an empty array, got", issues)
+		}
+	}
+	var none struct{ Point [0]int }
+	if err := xml2RPC(`<methodResponse><params><param><value><array><data/></array></value></param></params></methodResponse>`, &none); err != nil {
+		t.Error("Expected an empty array decoded into [0]int, got", err)
+	}
+
+	// a bad item isn't hidden by a good one after it
+	xmlraw = `<methodResponse><params><param><value><array><data>
+		<value><string>x</string></value><value><int>1</int></value>
+	</data></array></value></param></params></methodResponse>`
+	var pair struct{ Point [2]int }
+	if _, ok := asFault(xml2RPC(xmlraw, &pair)); !ok {
+		t.Errorf("Expected a fault for the bad item of a [2]int, got %+v", pair)
+	}
+	var items struct{ Points []int }
+	if err := xml2RPC(xmlraw, &items); err == nil || !strings.Contains(err.Error(), "params[0].value.array.data.value[0]") {
+		t.Errorf("Expected a fault for the bad item of a []int, got %v, %+v", err, items)
+	}
+}
+
+type VersionTuple struct {
+	Name  string
+	Major int
+	Beta  bool
+}
+
+func TestXML2RPCTuple(t *testing.T) {
+	type Reply struct {
+		Version VersionTuple `xmlrpc:",tuple"`
+	}
+	xmlraw := `<methodResponse><params><param><value><array><data>
+		<value><string>supervisor</string></value>
+		<value><int>3</int></value>
+		<value><boolean>1</boolean></value>
+	</data></array></value></param></params></methodResponse>`
+	var reply Reply
+	if err := xml2RPC(xmlraw, &reply); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	expected := VersionTuple{"supervisor", 3, true}
+	if reply.Version != expected {
+		t.Errorf("Expected %+v, got %+v", expected, reply.Version)
+	}
+	if issues := Validate([]byte(xmlraw), &reply); len(issues) != 0 {
+		t.Error("Expected no issues, got", issues)
+	}
+
+	encoded, err := rpcResponse2XMLStr(&reply)
+	if err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if !strings.Contains(encoded, "<array><data><value><string>supervisor</string></value><value><int>3</int></value><value><boolean>1</boolean></value></data></array>") {
+		t.Error("Unexpected encoding", encoded)
+	}
+
+	nested := struct {
+		Info struct {
+			Version VersionTuple `xmlrpc:",tuple"`
+		}
+	}{}
+	nestedXML := `<methodResponse><params><param><value><struct><member><name>Version</name><value><array><data>
+		<value><string>supervisor</string></value><value><string>3</string></value><value><boolean>0</boolean></value>
+	</data></array></value></member></struct></value></param></params></methodResponse>`
+	err = xml2RPC(nestedXML, &nested)
+	if err == nil || !strings.Contains(err.Error(), "at params[0].value.struct.member[Version].array.data.value[1]") {
+		t.Error("Expected type mismatch of the second item, got", err)
+	}
+}
+
+func TestXML2RPCMixedValues(t *testing.T) {
+	xmlraw := `<methodResponse><params>
+		<param><value>
+			<int> 42 </int>
+		</value></param>
+		<param><value> answer <boolean>
+			1
+		</boolean></value></param>
+		<param><value>no <nil/></value></param>
+	</params></methodResponse>`
+	reply := &struct {
+		Answer int
+		Ok     bool
+		Name   *string
+	}{}
+
+	var warnings []Warning
+	opts := &Options{Warnings: func(w Warning) {
+		warnings = append(warnings, w)
+	}}
+	if err := decodeRPC(xmlraw, reply, opts); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if reply.Answer != 42 || !reply.Ok || reply.Name != nil {
+		t.Errorf("Unexpected decoding %+v", reply)
+	}
+	if len(warnings) != 2 || warnings[0].Kind != WarnMixedContent || warnings[1].Kind != WarnMixedContent {
+		t.Error("Expected two mixed content warnings, got", warnings)
+	}
+
+	opts.StrictValues = true
+	err := decodeRPC(xmlraw, reply, opts)
+	fault, ok := err.(Fault)
+	if !ok || fault.Code != FaultInvalidParams.Code || !strings.Contains(fault.String, `mixes text "answer"`) {
+		t.Error("Expected invalid params fault, got", err)
+	}
+}
+
+func TestXML2RPCEmptyArrays(t *testing.T) {
+	xmlraw := `<methodResponse><params>
+		<param><value><array><data>
+</data></array></value></param>
+		<param><value><array><data/></array></value></param>
+		<param><value><array><data></data></array></value></param>
+	</params></methodResponse>`
+	reply := &struct {
+		Terms  []struct{ Name string }
+		Counts []int
+		Names  []string
+	}{}
+	if err := decodeRPC(xmlraw, reply, &Options{}); err != nil {
+		t.Fatal("Expected err to be nil, but got:", err)
+	}
+	if len(reply.Terms) != 0 || len(reply.Counts) != 0 || len(reply.Names) != 0 {
+		t.Errorf("Expected empty slices, got %+v", reply)
 	}
 }